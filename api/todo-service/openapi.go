@@ -0,0 +1,9 @@
+package todo_service
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 document of the REST mapping of TodoService,
+// generated from the google.api.http options of todo-service.proto.
+//
+//go:embed todo-service.swagger.json
+var OpenAPI []byte
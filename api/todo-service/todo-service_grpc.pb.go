@@ -28,6 +28,70 @@ type TodoServiceClient interface {
 	CreateReminder(ctx context.Context, in *Reminder, opts ...grpc.CallOption) (*ReminderId, error)
 	RemoveReminder(ctx context.Context, in *ReminderId, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	GetRemindersByUserId(ctx context.Context, in *UserId, opts ...grpc.CallOption) (TodoService_GetRemindersByUserIdClient, error)
+	// WatchUserEvents streams changes of a user and their reminders as they
+	// happen. A client reconnecting after a failure passes the seq of the last
+	// event it received to get the events it missed.
+	WatchUserEvents(ctx context.Context, in *WatchUserEventsRequest, opts ...grpc.CallOption) (TodoService_WatchUserEventsClient, error)
+	// Webhooks receive the events of their user as JSON encoded UserEvent
+	// messages signed with the secret returned by CreateWebhook, see
+	// package webhooks. Webhooks failing repeatedly are disabled, deliveries
+	// failing repeatedly become dead letters until replayed.
+	CreateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error)
+	UpdateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteWebhook(ctx context.Context, in *WebhookId, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetWebhooksByUserId(ctx context.Context, in *UserId, opts ...grpc.CallOption) (TodoService_GetWebhooksByUserIdClient, error)
+	GetWebhookDeadLettersByUserId(ctx context.Context, in *UserId, opts ...grpc.CallOption) (TodoService_GetWebhookDeadLettersByUserIdClient, error)
+	ReplayWebhookDeadLetter(ctx context.Context, in *WebhookDeadLetterId, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// A hook token lets automations create reminders of its user over HTTP
+	// with POST /hooks/{token}, see package hooks. Rotating the token of a
+	// user replaces the previous one.
+	RotateHookToken(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*HookToken, error)
+	RevokeHookToken(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ExportCalendar returns the reminders of a user as an iCalendar file for
+	// calendar apps. ImportCalendar creates reminders from the to-dos and
+	// events of one, reminding at their alarms, and reports what it created,
+	// skipped and found in conflict with existing reminders.
+	ExportCalendar(ctx context.Context, in *ExportCalendarRequest, opts ...grpc.CallOption) (*Calendar, error)
+	ImportCalendar(ctx context.Context, in *ImportCalendarRequest, opts ...grpc.CallOption) (*ImportCalendarReport, error)
+	// ImportReminders creates reminders from a todo.txt or CSV file streamed
+	// in chunks after the options. Every line is checked like CreateReminder
+	// checks reminders, the report tells which were skipped and why.
+	ImportReminders(ctx context.Context, opts ...grpc.CallOption) (TodoService_ImportRemindersClient, error)
+	// Reminders are shared with other users and Telegram group chats, as
+	// viewers or editors. A share is an invitation until its recipient
+	// accepts it: users accept their own, group chats accept through a
+	// service account like the bot. Once accepted, the reminder is delivered
+	// to the recipient when it fires and users get it from
+	// GetRemindersByUserId next to their own. Owners and editors share a
+	// reminder, list and remove its shares and remove the reminder itself;
+	// recipients remove their own share to decline or leave. Sharing with a
+	// recipient again changes its role.
+	ShareReminder(ctx context.Context, in *ReminderShare, opts ...grpc.CallOption) (*ReminderShare, error)
+	AcceptReminderShare(ctx context.Context, in *ReminderShareKey, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RemoveReminderShare(ctx context.Context, in *ReminderShareKey, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetReminderShares(ctx context.Context, in *ReminderId, opts ...grpc.CallOption) (TodoService_GetReminderSharesClient, error)
+	// GetReminderInvitations streams the shares a recipient did not accept
+	// yet.
+	GetReminderInvitations(ctx context.Context, in *ShareRecipient, opts ...grpc.CallOption) (TodoService_GetReminderInvitationsClient, error)
+	// A calendar feed serves the upcoming reminders of a user at
+	// GET /calendar/{secret}.ics for calendar apps to subscribe to, see
+	// package calendarfeed. CreateCalendarFeed fails with ALREADY_EXISTS when
+	// the user has one, rotating the secret replaces its URL.
+	CreateCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*CalendarFeed, error)
+	RotateCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*CalendarFeed, error)
+	RevokeCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ExportUserData returns everything kept about a user: the profile,
+	// reminders, their deliveries, shares and the settings.
+	ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...grpc.CallOption) (*UserDataArchive, error)
+	// DeleteUser schedules the deletion of a user with all their data once a
+	// grace period passes, until which CancelUserDeletion keeps them. Deleting
+	// a user scheduled for deletion returns the existing schedule.
+	DeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserDeletion, error)
+	CancelUserDeletion(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListAuditEvents streams the changes of users and reminders made through
+	// the API, newest first, for support staff. Only service accounts may
+	// call it.
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (TodoService_ListAuditEventsClient, error)
 }
 
 type todoServiceClient struct {
@@ -106,6 +170,385 @@ func (x *todoServiceGetRemindersByUserIdClient) Recv() (*Reminder, error) {
 	return m, nil
 }
 
+func (c *todoServiceClient) WatchUserEvents(ctx context.Context, in *WatchUserEventsRequest, opts ...grpc.CallOption) (TodoService_WatchUserEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[1], "/todoservice.TodoService/WatchUserEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceWatchUserEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_WatchUserEventsClient interface {
+	Recv() (*UserEvent, error)
+	grpc.ClientStream
+}
+
+type todoServiceWatchUserEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceWatchUserEventsClient) Recv() (*UserEvent, error) {
+	m := new(UserEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) CreateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error) {
+	out := new(Webhook)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/CreateWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) UpdateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/UpdateWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) DeleteWebhook(ctx context.Context, in *WebhookId, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/DeleteWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) GetWebhooksByUserId(ctx context.Context, in *UserId, opts ...grpc.CallOption) (TodoService_GetWebhooksByUserIdClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[2], "/todoservice.TodoService/GetWebhooksByUserId", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceGetWebhooksByUserIdClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_GetWebhooksByUserIdClient interface {
+	Recv() (*Webhook, error)
+	grpc.ClientStream
+}
+
+type todoServiceGetWebhooksByUserIdClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceGetWebhooksByUserIdClient) Recv() (*Webhook, error) {
+	m := new(Webhook)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) GetWebhookDeadLettersByUserId(ctx context.Context, in *UserId, opts ...grpc.CallOption) (TodoService_GetWebhookDeadLettersByUserIdClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[3], "/todoservice.TodoService/GetWebhookDeadLettersByUserId", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceGetWebhookDeadLettersByUserIdClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_GetWebhookDeadLettersByUserIdClient interface {
+	Recv() (*WebhookDeadLetter, error)
+	grpc.ClientStream
+}
+
+type todoServiceGetWebhookDeadLettersByUserIdClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceGetWebhookDeadLettersByUserIdClient) Recv() (*WebhookDeadLetter, error) {
+	m := new(WebhookDeadLetter)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) ReplayWebhookDeadLetter(ctx context.Context, in *WebhookDeadLetterId, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/ReplayWebhookDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) RotateHookToken(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*HookToken, error) {
+	out := new(HookToken)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/RotateHookToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) RevokeHookToken(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/RevokeHookToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ExportCalendar(ctx context.Context, in *ExportCalendarRequest, opts ...grpc.CallOption) (*Calendar, error) {
+	out := new(Calendar)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/ExportCalendar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ImportCalendar(ctx context.Context, in *ImportCalendarRequest, opts ...grpc.CallOption) (*ImportCalendarReport, error) {
+	out := new(ImportCalendarReport)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/ImportCalendar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ImportReminders(ctx context.Context, opts ...grpc.CallOption) (TodoService_ImportRemindersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[4], "/todoservice.TodoService/ImportReminders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceImportRemindersClient{stream}
+	return x, nil
+}
+
+type TodoService_ImportRemindersClient interface {
+	Send(*ImportRemindersRequest) error
+	CloseAndRecv() (*ImportRemindersReport, error)
+	grpc.ClientStream
+}
+
+type todoServiceImportRemindersClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceImportRemindersClient) Send(m *ImportRemindersRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *todoServiceImportRemindersClient) CloseAndRecv() (*ImportRemindersReport, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportRemindersReport)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) ShareReminder(ctx context.Context, in *ReminderShare, opts ...grpc.CallOption) (*ReminderShare, error) {
+	out := new(ReminderShare)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/ShareReminder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) AcceptReminderShare(ctx context.Context, in *ReminderShareKey, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/AcceptReminderShare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) RemoveReminderShare(ctx context.Context, in *ReminderShareKey, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/RemoveReminderShare", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) GetReminderShares(ctx context.Context, in *ReminderId, opts ...grpc.CallOption) (TodoService_GetReminderSharesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[5], "/todoservice.TodoService/GetReminderShares", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceGetReminderSharesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_GetReminderSharesClient interface {
+	Recv() (*ReminderShare, error)
+	grpc.ClientStream
+}
+
+type todoServiceGetReminderSharesClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceGetReminderSharesClient) Recv() (*ReminderShare, error) {
+	m := new(ReminderShare)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) GetReminderInvitations(ctx context.Context, in *ShareRecipient, opts ...grpc.CallOption) (TodoService_GetReminderInvitationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[6], "/todoservice.TodoService/GetReminderInvitations", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceGetReminderInvitationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_GetReminderInvitationsClient interface {
+	Recv() (*ReminderShare, error)
+	grpc.ClientStream
+}
+
+type todoServiceGetReminderInvitationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceGetReminderInvitationsClient) Recv() (*ReminderShare, error) {
+	m := new(ReminderShare)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *todoServiceClient) CreateCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*CalendarFeed, error) {
+	out := new(CalendarFeed)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/CreateCalendarFeed", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) RotateCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*CalendarFeed, error) {
+	out := new(CalendarFeed)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/RotateCalendarFeed", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) RevokeCalendarFeed(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/RevokeCalendarFeed", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...grpc.CallOption) (*UserDataArchive, error) {
+	out := new(UserDataArchive)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/ExportUserData", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) DeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserDeletion, error) {
+	out := new(UserDeletion)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) CancelUserDeletion(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/todoservice.TodoService/CancelUserDeletion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *todoServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (TodoService_ListAuditEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TodoService_ServiceDesc.Streams[7], "/todoservice.TodoService/ListAuditEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &todoServiceListAuditEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TodoService_ListAuditEventsClient interface {
+	Recv() (*AuditEvent, error)
+	grpc.ClientStream
+}
+
+type todoServiceListAuditEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *todoServiceListAuditEventsClient) Recv() (*AuditEvent, error) {
+	m := new(AuditEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // TodoServiceServer is the server API for TodoService service.
 // All implementations must embed UnimplementedTodoServiceServer
 // for forward compatibility
@@ -115,131 +558,725 @@ type TodoServiceServer interface {
 	CreateReminder(context.Context, *Reminder) (*ReminderId, error)
 	RemoveReminder(context.Context, *ReminderId) (*emptypb.Empty, error)
 	GetRemindersByUserId(*UserId, TodoService_GetRemindersByUserIdServer) error
+	// WatchUserEvents streams changes of a user and their reminders as they
+	// happen. A client reconnecting after a failure passes the seq of the last
+	// event it received to get the events it missed.
+	WatchUserEvents(*WatchUserEventsRequest, TodoService_WatchUserEventsServer) error
+	// Webhooks receive the events of their user as JSON encoded UserEvent
+	// messages signed with the secret returned by CreateWebhook, see
+	// package webhooks. Webhooks failing repeatedly are disabled, deliveries
+	// failing repeatedly become dead letters until replayed.
+	CreateWebhook(context.Context, *Webhook) (*Webhook, error)
+	UpdateWebhook(context.Context, *Webhook) (*emptypb.Empty, error)
+	DeleteWebhook(context.Context, *WebhookId) (*emptypb.Empty, error)
+	GetWebhooksByUserId(*UserId, TodoService_GetWebhooksByUserIdServer) error
+	GetWebhookDeadLettersByUserId(*UserId, TodoService_GetWebhookDeadLettersByUserIdServer) error
+	ReplayWebhookDeadLetter(context.Context, *WebhookDeadLetterId) (*emptypb.Empty, error)
+	// A hook token lets automations create reminders of its user over HTTP
+	// with POST /hooks/{token}, see package hooks. Rotating the token of a
+	// user replaces the previous one.
+	RotateHookToken(context.Context, *UserId) (*HookToken, error)
+	RevokeHookToken(context.Context, *UserId) (*emptypb.Empty, error)
+	// ExportCalendar returns the reminders of a user as an iCalendar file for
+	// calendar apps. ImportCalendar creates reminders from the to-dos and
+	// events of one, reminding at their alarms, and reports what it created,
+	// skipped and found in conflict with existing reminders.
+	ExportCalendar(context.Context, *ExportCalendarRequest) (*Calendar, error)
+	ImportCalendar(context.Context, *ImportCalendarRequest) (*ImportCalendarReport, error)
+	// ImportReminders creates reminders from a todo.txt or CSV file streamed
+	// in chunks after the options. Every line is checked like CreateReminder
+	// checks reminders, the report tells which were skipped and why.
+	ImportReminders(TodoService_ImportRemindersServer) error
+	// Reminders are shared with other users and Telegram group chats, as
+	// viewers or editors. A share is an invitation until its recipient
+	// accepts it: users accept their own, group chats accept through a
+	// service account like the bot. Once accepted, the reminder is delivered
+	// to the recipient when it fires and users get it from
+	// GetRemindersByUserId next to their own. Owners and editors share a
+	// reminder, list and remove its shares and remove the reminder itself;
+	// recipients remove their own share to decline or leave. Sharing with a
+	// recipient again changes its role.
+	ShareReminder(context.Context, *ReminderShare) (*ReminderShare, error)
+	AcceptReminderShare(context.Context, *ReminderShareKey) (*emptypb.Empty, error)
+	RemoveReminderShare(context.Context, *ReminderShareKey) (*emptypb.Empty, error)
+	GetReminderShares(*ReminderId, TodoService_GetReminderSharesServer) error
+	// GetReminderInvitations streams the shares a recipient did not accept
+	// yet.
+	GetReminderInvitations(*ShareRecipient, TodoService_GetReminderInvitationsServer) error
+	// A calendar feed serves the upcoming reminders of a user at
+	// GET /calendar/{secret}.ics for calendar apps to subscribe to, see
+	// package calendarfeed. CreateCalendarFeed fails with ALREADY_EXISTS when
+	// the user has one, rotating the secret replaces its URL.
+	CreateCalendarFeed(context.Context, *UserId) (*CalendarFeed, error)
+	RotateCalendarFeed(context.Context, *UserId) (*CalendarFeed, error)
+	RevokeCalendarFeed(context.Context, *UserId) (*emptypb.Empty, error)
+	// ExportUserData returns everything kept about a user: the profile,
+	// reminders, their deliveries, shares and the settings.
+	ExportUserData(context.Context, *ExportUserDataRequest) (*UserDataArchive, error)
+	// DeleteUser schedules the deletion of a user with all their data once a
+	// grace period passes, until which CancelUserDeletion keeps them. Deleting
+	// a user scheduled for deletion returns the existing schedule.
+	DeleteUser(context.Context, *UserId) (*UserDeletion, error)
+	CancelUserDeletion(context.Context, *UserId) (*emptypb.Empty, error)
+	// ListAuditEvents streams the changes of users and reminders made through
+	// the API, newest first, for support staff. Only service accounts may
+	// call it.
+	ListAuditEvents(*ListAuditEventsRequest, TodoService_ListAuditEventsServer) error
+	mustEmbedUnimplementedTodoServiceServer()
+}
+
+// UnimplementedTodoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTodoServiceServer struct {
+}
+
+func (UnimplementedTodoServiceServer) SetUser(context.Context, *User) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUser not implemented")
+}
+func (UnimplementedTodoServiceServer) GetUser(context.Context, *UserId) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedTodoServiceServer) CreateReminder(context.Context, *Reminder) (*ReminderId, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReminder not implemented")
+}
+func (UnimplementedTodoServiceServer) RemoveReminder(context.Context, *ReminderId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveReminder not implemented")
+}
+func (UnimplementedTodoServiceServer) GetRemindersByUserId(*UserId, TodoService_GetRemindersByUserIdServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetRemindersByUserId not implemented")
+}
+func (UnimplementedTodoServiceServer) WatchUserEvents(*WatchUserEventsRequest, TodoService_WatchUserEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUserEvents not implemented")
+}
+func (UnimplementedTodoServiceServer) CreateWebhook(context.Context, *Webhook) (*Webhook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedTodoServiceServer) UpdateWebhook(context.Context, *Webhook) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWebhook not implemented")
+}
+func (UnimplementedTodoServiceServer) DeleteWebhook(context.Context, *WebhookId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedTodoServiceServer) GetWebhooksByUserId(*UserId, TodoService_GetWebhooksByUserIdServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetWebhooksByUserId not implemented")
+}
+func (UnimplementedTodoServiceServer) GetWebhookDeadLettersByUserId(*UserId, TodoService_GetWebhookDeadLettersByUserIdServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetWebhookDeadLettersByUserId not implemented")
+}
+func (UnimplementedTodoServiceServer) ReplayWebhookDeadLetter(context.Context, *WebhookDeadLetterId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayWebhookDeadLetter not implemented")
+}
+func (UnimplementedTodoServiceServer) RotateHookToken(context.Context, *UserId) (*HookToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateHookToken not implemented")
+}
+func (UnimplementedTodoServiceServer) RevokeHookToken(context.Context, *UserId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeHookToken not implemented")
+}
+func (UnimplementedTodoServiceServer) ExportCalendar(context.Context, *ExportCalendarRequest) (*Calendar, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportCalendar not implemented")
+}
+func (UnimplementedTodoServiceServer) ImportCalendar(context.Context, *ImportCalendarRequest) (*ImportCalendarReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportCalendar not implemented")
+}
+func (UnimplementedTodoServiceServer) ImportReminders(TodoService_ImportRemindersServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportReminders not implemented")
+}
+func (UnimplementedTodoServiceServer) ShareReminder(context.Context, *ReminderShare) (*ReminderShare, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShareReminder not implemented")
+}
+func (UnimplementedTodoServiceServer) AcceptReminderShare(context.Context, *ReminderShareKey) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptReminderShare not implemented")
+}
+func (UnimplementedTodoServiceServer) RemoveReminderShare(context.Context, *ReminderShareKey) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveReminderShare not implemented")
+}
+func (UnimplementedTodoServiceServer) GetReminderShares(*ReminderId, TodoService_GetReminderSharesServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetReminderShares not implemented")
+}
+func (UnimplementedTodoServiceServer) GetReminderInvitations(*ShareRecipient, TodoService_GetReminderInvitationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetReminderInvitations not implemented")
+}
+func (UnimplementedTodoServiceServer) CreateCalendarFeed(context.Context, *UserId) (*CalendarFeed, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCalendarFeed not implemented")
+}
+func (UnimplementedTodoServiceServer) RotateCalendarFeed(context.Context, *UserId) (*CalendarFeed, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateCalendarFeed not implemented")
+}
+func (UnimplementedTodoServiceServer) RevokeCalendarFeed(context.Context, *UserId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeCalendarFeed not implemented")
+}
+func (UnimplementedTodoServiceServer) ExportUserData(context.Context, *ExportUserDataRequest) (*UserDataArchive, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportUserData not implemented")
+}
+func (UnimplementedTodoServiceServer) DeleteUser(context.Context, *UserId) (*UserDeletion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedTodoServiceServer) CancelUserDeletion(context.Context, *UserId) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelUserDeletion not implemented")
+}
+func (UnimplementedTodoServiceServer) ListAuditEvents(*ListAuditEventsRequest, TodoService_ListAuditEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedTodoServiceServer) mustEmbedUnimplementedTodoServiceServer() {}
+
+// UnsafeTodoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TodoServiceServer will
+// result in compilation errors.
+type UnsafeTodoServiceServer interface {
 	mustEmbedUnimplementedTodoServiceServer()
 }
 
-// UnimplementedTodoServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedTodoServiceServer struct {
+func RegisterTodoServiceServer(s grpc.ServiceRegistrar, srv TodoServiceServer) {
+	s.RegisterService(&TodoService_ServiceDesc, srv)
+}
+
+func _TodoService_SetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(User)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).SetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/SetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).SetUser(ctx, req.(*User))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).GetUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_CreateReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Reminder)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).CreateReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/CreateReminder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).CreateReminder(ctx, req.(*Reminder))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_RemoveReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReminderId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).RemoveReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/RemoveReminder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).RemoveReminder(ctx, req.(*ReminderId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_GetRemindersByUserId_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(UserId)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).GetRemindersByUserId(m, &todoServiceGetRemindersByUserIdServer{stream})
+}
+
+type TodoService_GetRemindersByUserIdServer interface {
+	Send(*Reminder) error
+	grpc.ServerStream
+}
+
+type todoServiceGetRemindersByUserIdServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceGetRemindersByUserIdServer) Send(m *Reminder) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_WatchUserEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchUserEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).WatchUserEvents(m, &todoServiceWatchUserEventsServer{stream})
+}
+
+type TodoService_WatchUserEventsServer interface {
+	Send(*UserEvent) error
+	grpc.ServerStream
+}
+
+type todoServiceWatchUserEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceWatchUserEventsServer) Send(m *UserEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/CreateWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).CreateWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_UpdateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).UpdateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/UpdateWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).UpdateWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WebhookId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/DeleteWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).DeleteWebhook(ctx, req.(*WebhookId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_GetWebhooksByUserId_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(UserId)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).GetWebhooksByUserId(m, &todoServiceGetWebhooksByUserIdServer{stream})
+}
+
+type TodoService_GetWebhooksByUserIdServer interface {
+	Send(*Webhook) error
+	grpc.ServerStream
+}
+
+type todoServiceGetWebhooksByUserIdServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceGetWebhooksByUserIdServer) Send(m *Webhook) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_GetWebhookDeadLettersByUserId_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(UserId)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).GetWebhookDeadLettersByUserId(m, &todoServiceGetWebhookDeadLettersByUserIdServer{stream})
+}
+
+type TodoService_GetWebhookDeadLettersByUserIdServer interface {
+	Send(*WebhookDeadLetter) error
+	grpc.ServerStream
+}
+
+type todoServiceGetWebhookDeadLettersByUserIdServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceGetWebhookDeadLettersByUserIdServer) Send(m *WebhookDeadLetter) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_ReplayWebhookDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WebhookDeadLetterId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).ReplayWebhookDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/ReplayWebhookDeadLetter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).ReplayWebhookDeadLetter(ctx, req.(*WebhookDeadLetterId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_RotateHookToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).RotateHookToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/RotateHookToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).RotateHookToken(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedTodoServiceServer) SetUser(context.Context, *User) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SetUser not implemented")
+func _TodoService_RevokeHookToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).RevokeHookToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/RevokeHookToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).RevokeHookToken(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedTodoServiceServer) GetUser(context.Context, *UserId) (*User, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+
+func _TodoService_ExportCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportCalendarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).ExportCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/ExportCalendar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).ExportCalendar(ctx, req.(*ExportCalendarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedTodoServiceServer) CreateReminder(context.Context, *Reminder) (*ReminderId, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateReminder not implemented")
+
+func _TodoService_ImportCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportCalendarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).ImportCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/ImportCalendar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).ImportCalendar(ctx, req.(*ImportCalendarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedTodoServiceServer) RemoveReminder(context.Context, *ReminderId) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RemoveReminder not implemented")
+
+func _TodoService_ImportReminders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TodoServiceServer).ImportReminders(&todoServiceImportRemindersServer{stream})
 }
-func (UnimplementedTodoServiceServer) GetRemindersByUserId(*UserId, TodoService_GetRemindersByUserIdServer) error {
-	return status.Errorf(codes.Unimplemented, "method GetRemindersByUserId not implemented")
+
+type TodoService_ImportRemindersServer interface {
+	SendAndClose(*ImportRemindersReport) error
+	Recv() (*ImportRemindersRequest, error)
+	grpc.ServerStream
 }
-func (UnimplementedTodoServiceServer) mustEmbedUnimplementedTodoServiceServer() {}
 
-// UnsafeTodoServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to TodoServiceServer will
-// result in compilation errors.
-type UnsafeTodoServiceServer interface {
-	mustEmbedUnimplementedTodoServiceServer()
+type todoServiceImportRemindersServer struct {
+	grpc.ServerStream
 }
 
-func RegisterTodoServiceServer(s grpc.ServiceRegistrar, srv TodoServiceServer) {
-	s.RegisterService(&TodoService_ServiceDesc, srv)
+func (x *todoServiceImportRemindersServer) SendAndClose(m *ImportRemindersReport) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func _TodoService_SetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(User)
+func (x *todoServiceImportRemindersServer) Recv() (*ImportRemindersRequest, error) {
+	m := new(ImportRemindersRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _TodoService_ShareReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReminderShare)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TodoServiceServer).SetUser(ctx, in)
+		return srv.(TodoServiceServer).ShareReminder(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/todoservice.TodoService/SetUser",
+		FullMethod: "/todoservice.TodoService/ShareReminder",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TodoServiceServer).SetUser(ctx, req.(*User))
+		return srv.(TodoServiceServer).ShareReminder(ctx, req.(*ReminderShare))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TodoService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _TodoService_AcceptReminderShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReminderShareKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).AcceptReminderShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/AcceptReminderShare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).AcceptReminderShare(ctx, req.(*ReminderShareKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_RemoveReminderShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReminderShareKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).RemoveReminderShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/RemoveReminderShare",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).RemoveReminderShare(ctx, req.(*ReminderShareKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_GetReminderShares_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReminderId)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).GetReminderShares(m, &todoServiceGetReminderSharesServer{stream})
+}
+
+type TodoService_GetReminderSharesServer interface {
+	Send(*ReminderShare) error
+	grpc.ServerStream
+}
+
+type todoServiceGetReminderSharesServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceGetReminderSharesServer) Send(m *ReminderShare) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_GetReminderInvitations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ShareRecipient)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TodoServiceServer).GetReminderInvitations(m, &todoServiceGetReminderInvitationsServer{stream})
+}
+
+type TodoService_GetReminderInvitationsServer interface {
+	Send(*ReminderShare) error
+	grpc.ServerStream
+}
+
+type todoServiceGetReminderInvitationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *todoServiceGetReminderInvitationsServer) Send(m *ReminderShare) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TodoService_CreateCalendarFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UserId)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TodoServiceServer).GetUser(ctx, in)
+		return srv.(TodoServiceServer).CreateCalendarFeed(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/todoservice.TodoService/GetUser",
+		FullMethod: "/todoservice.TodoService/CreateCalendarFeed",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TodoServiceServer).GetUser(ctx, req.(*UserId))
+		return srv.(TodoServiceServer).CreateCalendarFeed(ctx, req.(*UserId))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TodoService_CreateReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Reminder)
+func _TodoService_RotateCalendarFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TodoServiceServer).CreateReminder(ctx, in)
+		return srv.(TodoServiceServer).RotateCalendarFeed(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/todoservice.TodoService/CreateReminder",
+		FullMethod: "/todoservice.TodoService/RotateCalendarFeed",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TodoServiceServer).CreateReminder(ctx, req.(*Reminder))
+		return srv.(TodoServiceServer).RotateCalendarFeed(ctx, req.(*UserId))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TodoService_RemoveReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ReminderId)
+func _TodoService_RevokeCalendarFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TodoServiceServer).RemoveReminder(ctx, in)
+		return srv.(TodoServiceServer).RevokeCalendarFeed(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/todoservice.TodoService/RemoveReminder",
+		FullMethod: "/todoservice.TodoService/RevokeCalendarFeed",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TodoServiceServer).RemoveReminder(ctx, req.(*ReminderId))
+		return srv.(TodoServiceServer).RevokeCalendarFeed(ctx, req.(*UserId))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TodoService_GetRemindersByUserId_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(UserId)
+func _TodoService_ExportUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).ExportUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/ExportUserData",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).ExportUserData(ctx, req.(*ExportUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).DeleteUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_CancelUserDeletion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TodoServiceServer).CancelUserDeletion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/todoservice.TodoService/CancelUserDeletion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TodoServiceServer).CancelUserDeletion(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TodoService_ListAuditEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListAuditEventsRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(TodoServiceServer).GetRemindersByUserId(m, &todoServiceGetRemindersByUserIdServer{stream})
+	return srv.(TodoServiceServer).ListAuditEvents(m, &todoServiceListAuditEventsServer{stream})
 }
 
-type TodoService_GetRemindersByUserIdServer interface {
-	Send(*Reminder) error
+type TodoService_ListAuditEventsServer interface {
+	Send(*AuditEvent) error
 	grpc.ServerStream
 }
 
-type todoServiceGetRemindersByUserIdServer struct {
+type todoServiceListAuditEventsServer struct {
 	grpc.ServerStream
 }
 
-func (x *todoServiceGetRemindersByUserIdServer) Send(m *Reminder) error {
+func (x *todoServiceListAuditEventsServer) Send(m *AuditEvent) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -266,6 +1303,74 @@ var TodoService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveReminder",
 			Handler:    _TodoService_RemoveReminder_Handler,
 		},
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _TodoService_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "UpdateWebhook",
+			Handler:    _TodoService_UpdateWebhook_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _TodoService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "ReplayWebhookDeadLetter",
+			Handler:    _TodoService_ReplayWebhookDeadLetter_Handler,
+		},
+		{
+			MethodName: "RotateHookToken",
+			Handler:    _TodoService_RotateHookToken_Handler,
+		},
+		{
+			MethodName: "RevokeHookToken",
+			Handler:    _TodoService_RevokeHookToken_Handler,
+		},
+		{
+			MethodName: "ExportCalendar",
+			Handler:    _TodoService_ExportCalendar_Handler,
+		},
+		{
+			MethodName: "ImportCalendar",
+			Handler:    _TodoService_ImportCalendar_Handler,
+		},
+		{
+			MethodName: "ShareReminder",
+			Handler:    _TodoService_ShareReminder_Handler,
+		},
+		{
+			MethodName: "AcceptReminderShare",
+			Handler:    _TodoService_AcceptReminderShare_Handler,
+		},
+		{
+			MethodName: "RemoveReminderShare",
+			Handler:    _TodoService_RemoveReminderShare_Handler,
+		},
+		{
+			MethodName: "CreateCalendarFeed",
+			Handler:    _TodoService_CreateCalendarFeed_Handler,
+		},
+		{
+			MethodName: "RotateCalendarFeed",
+			Handler:    _TodoService_RotateCalendarFeed_Handler,
+		},
+		{
+			MethodName: "RevokeCalendarFeed",
+			Handler:    _TodoService_RevokeCalendarFeed_Handler,
+		},
+		{
+			MethodName: "ExportUserData",
+			Handler:    _TodoService_ExportUserData_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _TodoService_DeleteUser_Handler,
+		},
+		{
+			MethodName: "CancelUserDeletion",
+			Handler:    _TodoService_CancelUserDeletion_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -273,6 +1378,41 @@ var TodoService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _TodoService_GetRemindersByUserId_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchUserEvents",
+			Handler:       _TodoService_WatchUserEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetWebhooksByUserId",
+			Handler:       _TodoService_GetWebhooksByUserId_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetWebhookDeadLettersByUserId",
+			Handler:       _TodoService_GetWebhookDeadLettersByUserId_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportReminders",
+			Handler:       _TodoService_ImportReminders_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetReminderShares",
+			Handler:       _TodoService_GetReminderShares_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetReminderInvitations",
+			Handler:       _TodoService_GetReminderInvitations_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListAuditEvents",
+			Handler:       _TodoService_ListAuditEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "todo-service.proto",
 }
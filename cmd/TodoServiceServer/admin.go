@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// adminCommand inspects and repairs data directly in the storage backend.
+func adminCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("admin: expected user, reminders, deliveries, purge or stats")
+	}
+
+	name := args[0]
+	args = args[1:]
+	if (name == "reminders" || name == "deliveries") && len(args) > 0 {
+		name += " " + args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("admin "+name, flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list what would change")
+	yes := fs.Bool("yes", false, "change without asking for confirmation")
+
+	var run func(context.Context, *admin.Admin) error
+	switch name {
+	case "user":
+		run = func(ctx context.Context, a *admin.Admin) error {
+			id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+			if fs.NArg() != 1 || err != nil {
+				return errors.New("admin user: expected user ID")
+			}
+
+			return a.ShowUser(ctx, id)
+		}
+	case "reminders list":
+		filter := reminderFilter(fs)
+		run = func(ctx context.Context, a *admin.Admin) error {
+			return a.ListReminders(ctx, *filter)
+		}
+	case "reminders cancel":
+		filter := reminderFilter(fs)
+		run = func(ctx context.Context, a *admin.Admin) error {
+			if filter.UserID == 0 && filter.Text == "" && filter.CreatedAfter.IsZero() && filter.CreatedBefore.IsZero() {
+				return errors.New("admin reminders cancel: expected -user, -text, -created-after or -created-before")
+			}
+
+			return a.CancelReminders(audit.NewContext(ctx, operator("reminders cancel")), *filter)
+		}
+	case "deliveries requeue":
+		filter := &admin.DeliveryFilter{}
+		fs.Int64Var(&filter.UserID, "user", 0, "only deliveries to this user")
+		fs.Func("channel", "only deliveries over this channel (telegram, email, webhook)", func(v string) error {
+			filter.Channel = strings.ToUpper(v)
+
+			return nil
+		})
+		timeVar(fs, &filter.FailedAfter, "failed-after", "only deliveries which failed since")
+		fs.IntVar(&filter.Limit, "limit", 0, "at most this many deliveries, 0 for all")
+		run = func(ctx context.Context, a *admin.Admin) error {
+			return a.RequeueDeliveries(ctx, *filter)
+		}
+	case "purge":
+		olderThan := fs.Duration("older-than", 30*24*time.Hour, "purge reminders removed at least this long ago")
+		run = func(ctx context.Context, a *admin.Admin) error {
+			return a.Purge(ctx, time.Now().Add(-*olderThan))
+		}
+	case "stats":
+		run = func(ctx context.Context, a *admin.Admin) error {
+			return a.Stats(ctx)
+		}
+	default:
+		return fmt.Errorf("admin: unknown command %q", name)
+	}
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	opts := []admin.Option{admin.WithDryRun(*dryRun)}
+	if *yes {
+		opts = append(opts, admin.WithConfirm(func(string) bool { return true }))
+	}
+
+	return run(ctx, admin.New(storage.repo, os.Stdout, opts...))
+}
+
+func reminderFilter(fs *flag.FlagSet) *admin.ReminderFilter {
+	filter := &admin.ReminderFilter{}
+	fs.Int64Var(&filter.UserID, "user", 0, "only reminders of this user")
+	fs.StringVar(&filter.Text, "text", "", "only reminders containing this text, ignoring case")
+	timeVar(fs, &filter.CreatedAfter, "created-after", "only reminders created since")
+	timeVar(fs, &filter.CreatedBefore, "created-before", "only reminders created before")
+	fs.BoolVar(&filter.Pending, "pending", false, "leave fired reminders out")
+	fs.IntVar(&filter.Limit, "limit", 0, "at most this many reminders, 0 for all")
+
+	return filter
+}
+
+// timeVar defines a flag taking an RFC 3339 time or a duration before now,
+// such as 2h.
+func timeVar(fs *flag.FlagSet, p *time.Time, name, usage string) {
+	fs.Func(name, usage+", as an RFC 3339 time or a duration ago", func(v string) error {
+		if d, err := time.ParseDuration(v); err == nil {
+			*p = time.Now().Add(-d)
+
+			return nil
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("%q is neither an RFC 3339 time nor a duration", v)
+		}
+		*p = t
+
+		return nil
+	})
+}
+
+// operator is the actor of changes made by the admin command, see package
+// audit.
+func operator(command string) audit.Actor {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	return audit.Actor{Caller: fmt.Sprintf("operator %q", name), Method: "admin " + command, RequestID: audit.NewRequestID()}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/backup"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// backupCommand writes the users and reminders of the storage backend to a
+// file or the standard output.
+func backupCommand(ctx context.Context, args []string) (err error) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "write to this file instead of the standard output")
+	batchSize := fs.Int("batch-size", 1000, "read this many users or reminders at a time")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	var tmp *os.File
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		// Write next to out first, so that out is either complete or not
+		// there.
+		tmp, err = os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tmp.Close()
+				os.Remove(tmp.Name())
+			}
+		}()
+
+		w = tmp
+	}
+
+	summary, err := backup.Write(ctx, w, storage.repo, *batchSize, time.Now())
+	if err != nil {
+		return fmt.Errorf("cannot back up: %w", err)
+	}
+
+	if tmp != nil {
+		if err = tmp.Sync(); err != nil {
+			return err
+		}
+		if err = tmp.Close(); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp.Name(), *out); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Backed up %d users and %d reminders, checksum %s\n", summary.Users, summary.Reminders, summary.Checksum)
+
+	return nil
+}
+
+// restoreCommand restores a backup into the storage backend, which must be
+// empty unless resuming the restore of the same backup.
+func restoreCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	verifyOnly := fs.Bool("verify-only", false, "only check the backup")
+	idsFile := fs.String("ids", "", "write the new IDs of reminders by their IDs in the backup to this file")
+	batchSize := fs.Int("batch-size", 1000, "restore this many users or reminders at a time")
+
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("restore: expected backup file")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	summary, err := backup.Verify(f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Verified %d users and %d reminders, checksum %s\n", summary.Users, summary.Reminders, summary.Checksum)
+	if *verifyOnly {
+		return nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	result, err := backup.Restore(ctx, f, storage.repo, summary.Checksum, *batchSize)
+	if errors.Is(err, backup.ErrNotEmpty) {
+		return fmt.Errorf("cannot restore: %w and has no restore of this backup to resume", err)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restore, run again to resume: %w", err)
+	}
+
+	if result.Resumed {
+		fmt.Fprintf(os.Stderr, "Resumed restore, %d reminders were restored before\n", result.Skipped)
+	}
+	fmt.Fprintf(os.Stderr, "Restored %d users and %d reminders\n", result.Users, result.Reminders-result.Skipped)
+
+	if *idsFile != "" {
+		return writeIDs(*idsFile, result.IDs)
+	}
+
+	return nil
+}
+
+// writeIDs writes the IDs of reminders in a backup and the IDs they were
+// restored under to path, a pair per line.
+func writeIDs(path string, ids map[int32]int32) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	sourceIds := make([]int32, 0, len(ids))
+	for sourceId := range ids {
+		sourceIds = append(sourceIds, sourceId)
+	}
+	slices.Sort(sourceIds)
+
+	for _, sourceId := range sourceIds {
+		if _, err := fmt.Fprintf(f, "%d\t%d\n", sourceId, ids[sourceId]); err != nil {
+			f.Close()
+
+			return err
+		}
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/gateway"
+)
+
+// serveGateway serves the REST gateway at addr until ctx is done, with TLS
+// when tlsConfig is set. The gateway calls a gRPC server of its own, with
+// the interceptors in opts but without TLS, on a loopback port.
+func serveGateway(ctx context.Context, addr string, server pb.TodoServiceServer, opts []grpc.ServerOption, tlsConfig *tls.Config) error {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("cannot listen for the gateway: %w", err)
+	}
+
+	s := grpc.NewServer(opts...)
+	pb.RegisterTodoServiceServer(s, server)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Printf("failed to serve gateway calls: %v", err)
+		}
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		s.Stop()
+
+		return fmt.Errorf("cannot connect the gateway: %w", err)
+	}
+
+	handler, err := gateway.New(ctx, conn)
+	if err != nil {
+		conn.Close()
+		s.Stop()
+
+		return fmt.Errorf("cannot create the gateway: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+		s.GracefulStop()
+	}()
+
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second, TLSConfig: tlsConfig}
+	go serveHTTP(ctx, "gateway", srv)
+
+	return nil
+}
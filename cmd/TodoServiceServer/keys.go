@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// keys manages the API keys accepted by the server.
+func keys(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("keys: expected issue, revoke or list")
+	}
+
+	fs := flag.NewFlagSet("keys "+args[0], flag.ExitOnError)
+
+	switch args[0] {
+	case "issue":
+		name := fs.String("name", "", "name of the key owner, e.g. the frontend")
+		userId := fs.Int64("user-id", 0, "issue a personal key acting only on this user instead of a service key")
+
+		cfg, err := config.Load(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("keys issue: -name is required")
+		}
+
+		var owner *int64
+		if *userId != 0 {
+			owner = userId
+		}
+
+		return issueKey(ctx, cfg, *name, owner)
+	case "revoke":
+		cfg, err := config.Load(fs, args[1:])
+		if err != nil {
+			return err
+		}
+
+		id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if fs.NArg() != 1 || err != nil {
+			return errors.New("keys revoke: expected key ID")
+		}
+
+		return revokeKey(ctx, cfg, id)
+	case "list":
+		cfg, err := config.Load(fs, args[1:])
+		if err != nil {
+			return err
+		}
+
+		return listKeys(ctx, cfg)
+	default:
+		return fmt.Errorf("keys: unknown command %q", args[0])
+	}
+}
+
+func issueKey(ctx context.Context, cfg *config.Config, name string, userId *int64) error {
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	id, err := storage.repo.CreateAPIKey(ctx, name, userId, hash)
+	if err != nil {
+		return fmt.Errorf("cannot store key: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Issued key %d, it is shown only once:\n", id)
+	fmt.Println(key)
+
+	return nil
+}
+
+func revokeKey(ctx context.Context, cfg *config.Config, id int64) error {
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	if err := storage.repo.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("cannot revoke key %d: %w", id, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Revoked key %d\n", id)
+
+	return nil
+}
+
+func listKeys(ctx context.Context, cfg *config.Config) error {
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	apiKeys, err := storage.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tUSER\tCREATED\tREVOKED")
+	for _, key := range apiKeys {
+		user, revoked := "any", ""
+		if key.UserID != nil {
+			user = strconv.FormatInt(*key.UserID, 10)
+		}
+		if key.RevokedAt != nil {
+			revoked = key.RevokedAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", key.ID, key.Name, user, key.CreatedAt.Format(time.DateTime), revoked)
+	}
+
+	return w.Flush()
+}
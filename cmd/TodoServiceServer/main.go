@@ -2,48 +2,309 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/cachedrepo"
+	"github.com/awakair/awakair_todo_bot/internal/calendarfeed"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/events"
+	"github.com/awakair/awakair_todo_bot/internal/hooks"
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
 	"github.com/awakair/awakair_todo_bot/internal/postgresrepo"
-	"github.com/awakair/awakair_todo_bot/internal/todoserviceserver"
+	"github.com/awakair/awakair_todo_bot/internal/ratelimit"
+	"github.com/awakair/awakair_todo_bot/internal/servertls"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+	"github.com/awakair/awakair_todo_bot/internal/web"
 )
 
-var (
-	port  = flag.Int("port", 50051, "The server port")
-	dbUrl = "postgres://" +
-		os.Getenv("DB_USER") +
-		":" +
-		os.Getenv("DB_PASSWORD") +
-		"@localhost:5432/" +
-		os.Getenv("DB_NAME")
-)
+const usage = `Usage:
+  %[1]s [flags]
+  %[1]s keys issue -name NAME [-user-id ID] [flags]
+  %[1]s keys revoke [flags] ID
+  %[1]s keys list [flags]
+  %[1]s admin user [flags] ID
+  %[1]s admin reminders list|cancel [-user ID] [-text TEXT] [-created-after TIME] [-created-before TIME] [flags]
+  %[1]s admin deliveries requeue [-user ID] [-channel KIND] [-failed-after TIME] [flags]
+  %[1]s admin purge [-older-than DURATION] [flags]
+  %[1]s admin stats [flags]
+  %[1]s backup [-out FILE] [flags]
+  %[1]s restore [-verify-only] [-ids FILE] [flags] FILE
+
+Run a command with -help to list its flags.
+`
 
 func main() {
-	pool, err := pgxpool.New(context.Background(), dbUrl)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		serve(ctx, args)
+
+		return
+	}
+
+	var err error
+	switch args[0] {
+	case "keys":
+		err = keys(ctx, args[1:])
+	case "admin":
+		err = adminCommand(ctx, args[1:])
+	case "backup":
+		err = backupCommand(ctx, args[1:])
+	case "restore":
+		err = restoreCommand(ctx, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(ctx context.Context, args []string) {
+	cfg, err := config.Load(flag.CommandLine, args)
 	if err != nil {
-		log.Fatalf("cannot connect to database with url %s: %v", dbUrl, err)
+		log.Fatal(err)
 	}
-	defer pool.Close()
 
-	flag.Parse()
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	slog.SetDefault(newLogger(cfg.Log))
+
+	storage, err := connect(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer storage.close()
+	repo := storage.repo
+
+	var (
+		serverRepo  todoserviceserver.Repo = repo
+		deleterOpts []userdata.Option
+	)
+	if cfg.Cache.Size > 0 {
+		cache := cachedrepo.New(repo, cfg.Cache.Size, cfg.Cache.TTL)
+		expvar.Publish("cache", expvar.Func(func() any { return cache.Stats() }))
+		if storage.pool != nil {
+			go postgresrepo.Listen(ctx, storage.pool, postgresrepo.CacheInvalidationChannel, cache.Purge, cache.Invalidate)
+		}
+
+		serverRepo = cache
+		// Postgres tells the caches of every replica about deleted users
+		// too, sqlite only has this one.
+		deleterOpts = append(deleterOpts, userdata.WithPurge(func(userId int64) {
+			cache.Invalidate("user:" + strconv.FormatInt(userId, 10))
+			cache.Invalidate("reminders:" + strconv.FormatInt(userId, 10))
+		}))
+	}
+
+	serverOpts := []todoserviceserver.Option{
+		todoserviceserver.WithHookTokens(repo),
+		todoserviceserver.WithCalendarFeeds(repo, cfg.CalendarFeeds.BaseURL),
+		todoserviceserver.WithUserData(repo, cfg.UserDeletion.GracePeriod),
+		todoserviceserver.WithAuditEvents(repo),
+		todoserviceserver.WithShares(repo),
+	}
+	go userdata.NewDeleter(repo, deleterOpts...).Run(ctx, cfg.UserDeletion.Interval)
+
+	if storage.events != nil {
+		feed := events.NewFeed(storage.events)
+		go postgresrepo.Listen(ctx, storage.pool, postgresrepo.UserEventsChannel, feed.WakeAll, feed.Notify)
+		go feed.Purge(ctx, cfg.Events.Retention, time.Hour)
+
+		serverOpts = append(serverOpts, todoserviceserver.WithEvents(feed))
+	}
+
+	dispatcher, err := newDispatcher(cfg.Notify, repo)
+	if err != nil {
+		log.Fatalf("failed to set up notifications: %v", err)
+	}
+	go dispatcher.Run(ctx, cfg.Notify.Interval)
+
+	if storage.webhooks != nil {
+		go newWebhookSender(cfg.Webhooks, storage.webhooks).Run(ctx, cfg.Webhooks.Interval)
+
+		serverOpts = append(serverOpts, todoserviceserver.WithWebhooks(storage.webhooks))
+	}
+
+	if cfg.DebugAddr != "" {
+		// http.DefaultServeMux, where expvar registers /debug/vars.
+		go serveHTTP(ctx, "debug", &http.Server{Addr: cfg.DebugAddr})
+	}
+
+	authenticator := auth.NewAuthenticator(repo, []byte(cfg.Auth.JWTSecret.Reveal()))
+
+	var limiterStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimits.Store == "postgres" {
+		// Validated by config.Load to come with the postgres backend.
+		limiterStore = repo.(ratelimit.Store)
+	}
+	limiter, err := ratelimit.New(cfg.RateLimits, limiterStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go limiter.PurgeFull(ctx, time.Hour)
+
+	idempotencyKeys := idempotency.New(repo, cfg.Idempotency.TTL)
+	go idempotencyKeys.PurgeExpired(ctx, time.Hour)
+
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterTodoServiceServer(s, todoserviceserver.New(postgresrepo.New(pool)))
+	interceptors := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			servertls.UnaryServerInterceptor(),
+			authenticator.UnaryServerInterceptor(),
+			audit.UnaryServerInterceptor(),
+			limiter.UnaryServerInterceptor(),
+			idempotencyKeys.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			servertls.StreamServerInterceptor(),
+			authenticator.StreamServerInterceptor(),
+			audit.StreamServerInterceptor(),
+			limiter.StreamServerInterceptor(),
+		),
+	}
+	opts := slices.Clone(interceptors)
+	var (
+		reloader      *servertls.Reloader
+		httpTLSConfig *tls.Config
+	)
+	if cfg.TLS.Enabled() {
+		reloader, err = servertls.New(cfg.TLS)
+		if err != nil {
+			log.Fatalf("failed to load TLS credentials: %v", err)
+		}
+		go reloadOnSignal(ctx, reloader)
+		if cfg.TLS.ReloadEvery > 0 {
+			go reloader.Watch(ctx, cfg.TLS.ReloadEvery)
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+		httpTLSConfig = reloader.HTTPTLSConfig()
+	}
+
+	server := todoserviceserver.New(serverRepo, serverOpts...)
+	s := grpc.NewServer(opts...)
+	pb.RegisterTodoServiceServer(s, server)
+
+	if cfg.Hooks.ListenAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(hooks.Pattern, hooks.NewHandler(repo, server, hooks.WithLimiter(limiter, cfg.Hooks.PerAddress)))
+
+		srv := &http.Server{Addr: cfg.Hooks.ListenAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second, TLSConfig: httpTLSConfig}
+		go serveHTTP(ctx, "hooks", srv)
+	}
+
+	if cfg.CalendarFeeds.ListenAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(calendarfeed.Pattern, calendarfeed.NewHandler(repo, serverRepo, calendarfeed.WithLimiter(limiter, cfg.CalendarFeeds.PerAddress)))
+
+		srv := &http.Server{Addr: cfg.CalendarFeeds.ListenAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second, TLSConfig: httpTLSConfig}
+		go serveHTTP(ctx, "calendar feeds", srv)
+	}
+
+	if cfg.Web.ListenAddr != "" {
+		handler, err := web.New(s, web.WithAllowedOrigins(cfg.Web.AllowedOrigins, cfg.Web.CORSMaxAge))
+		if err != nil {
+			log.Fatalf("failed to set up web: %v", err)
+		}
+
+		srv := &http.Server{Addr: cfg.Web.ListenAddr, Handler: handler, ReadHeaderTimeout: 10 * time.Second, TLSConfig: httpTLSConfig}
+		go serveHTTP(ctx, "web", srv)
+	}
+
+	if cfg.Gateway.ListenAddr != "" {
+		if err := serveGateway(ctx, cfg.Gateway.ListenAddr, server, interceptors, httpTLSConfig); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
 
 	log.Printf("server listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		log.Printf("failed to serve: %v", err)
 	}
 }
+
+// serveHTTP serves srv until ctx is done, with TLS when srv.TLSConfig is set.
+func serveHTTP(ctx context.Context, name string, srv *http.Server) {
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	log.Printf("%s server listening at %v", name, srv.Addr)
+
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("failed to serve %s: %v", name, err)
+	}
+}
+
+func reloadOnSignal(ctx context.Context, reloader *servertls.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := reloader.Reload(); err != nil {
+				log.Printf("Error reloading TLS certificates: %v", err)
+			} else {
+				log.Printf("TLS certificates were reloaded")
+			}
+		}
+	}
+}
+
+func newLogger(cfg config.Log) *slog.Logger {
+	var level slog.Level
+	// Validated by config.Load.
+	_ = level.UnmarshalText([]byte(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
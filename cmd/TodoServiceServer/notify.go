@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/egress"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/retry"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+// newDispatcher enables the channels configured in cfg.
+func newDispatcher(cfg config.Notify, store notify.Store) (*notify.Dispatcher, error) {
+	registry := notify.NewRegistry()
+	// Users choose the URLs of webhooks, but not the Bot API, which may be
+	// served locally.
+	registry.Register(pb.NotificationChannel_WEBHOOK, notify.NewWebhook(egress.NewClient(cfg.Timeout)))
+	if cfg.Telegram.BotToken != "" {
+		registry.Register(pb.NotificationChannel_TELEGRAM, notify.NewTelegram(&http.Client{Timeout: cfg.Timeout}, cfg.Telegram.APIURL, cfg.Telegram.BotToken.Reveal()))
+	}
+	if cfg.Email.SMTPAddr != "" {
+		email, err := notify.NewEmail(cfg.Email.SMTPAddr, cfg.Email.Username, cfg.Email.Password.Reveal(), cfg.Email.From)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(pb.NotificationChannel_EMAIL, email)
+	}
+
+	return notify.NewDispatcher(store, registry,
+		retry.WithMaxAttempts(cfg.MaxAttempts),
+		retry.WithBackoff(cfg.Backoff, cfg.MaxBackoff),
+		retry.WithTimeout(cfg.Timeout),
+	), nil
+}
+
+// newWebhookSender sends events to user webhooks as configured in cfg.
+func newWebhookSender(cfg config.Webhooks, store webhooks.Store) *webhooks.Sender {
+	return webhooks.NewSender(store, egress.NewClient(cfg.Timeout), cfg.DisableAfter,
+		retry.WithMaxAttempts(cfg.MaxAttempts),
+		retry.WithBackoff(cfg.Backoff, cfg.MaxBackoff),
+		retry.WithTimeout(cfg.Timeout),
+	)
+}
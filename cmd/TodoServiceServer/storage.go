@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/backup"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/events"
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo"
+	"github.com/awakair/awakair_todo_bot/internal/sqliterepo"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+// repo is implemented by every storage backend.
+type repo interface {
+	todoserviceserver.Repo
+	auth.KeyStore
+	idempotency.Store
+	notify.Store
+	todoserviceserver.HookTokenStore
+	todoserviceserver.CalendarFeedStore
+	todoserviceserver.UserDataStore
+	todoserviceserver.ShareStore
+	userdata.Store
+	admin.Store
+	backup.Source
+	backup.Target
+	audit.Store
+
+	CreateAPIKey(ctx context.Context, name string, userID *int64, hash []byte) (int64, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	ListAPIKeys(ctx context.Context) ([]auth.APIKey, error)
+}
+
+// webhookStore keeps webhooks and their deliveries.
+type webhookStore interface {
+	todoserviceserver.WebhookStore
+	webhooks.Store
+}
+
+// backend is an opened storage backend.
+type backend struct {
+	repo repo
+	// pool, events and webhooks are set only for the postgres backend.
+	pool     *pgxpool.Pool
+	events   events.Store
+	webhooks webhookStore
+	close    func()
+}
+
+// connect opens the storage backend selected by cfg and applies pending
+// migrations.
+func connect(ctx context.Context, cfg *config.Config) (*backend, error) {
+	if cfg.Storage.Backend == "sqlite" {
+		db, err := sqliterepo.Open(cfg.Storage.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open database %s: %w", cfg.Storage.SQLitePath, err)
+		}
+
+		repo := sqliterepo.New(db, sqliterepo.WithMaxActiveReminders(cfg.Quotas.MaxActiveReminders))
+		if err := repo.Migrate(ctx); err != nil {
+			db.Close()
+
+			return nil, fmt.Errorf("cannot migrate database %s: %w", cfg.Storage.SQLitePath, err)
+		}
+
+		return &backend{repo: repo, close: func() { db.Close() }}, nil
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.Database.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to database %s: %w", cfg.Database, err)
+	}
+
+	repo := postgresrepo.New(pool, postgresrepo.WithMaxActiveReminders(cfg.Quotas.MaxActiveReminders))
+	if err := repo.Migrate(ctx); err != nil {
+		pool.Close()
+
+		return nil, fmt.Errorf("cannot migrate database %s: %w", cfg.Database, err)
+	}
+
+	return &backend{repo: repo, pool: pool, events: repo, webhooks: repo, close: pool.Close}, nil
+}
@@ -0,0 +1,27 @@
+// Command todoctl is the command-line client of the TodoService,
+// see package todoctl.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/awakair/awakair_todo_bot/internal/todoctl"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := todoctl.Run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if errors.Is(err, todoctl.ErrUsage) {
+			os.Exit(2)
+		}
+
+		fmt.Fprintf(os.Stderr, "todoctl: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,81 @@
+package todoserviceserver
+
+import (
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+// auditDefaultLimit is how many events ListAuditEvents lists unless asked
+// for another number.
+const auditDefaultLimit = 100
+
+// WithAuditEvents enables ListAuditEvents, which is unimplemented
+// otherwise.
+func WithAuditEvents(store audit.Store) Option {
+	return func(s *TodoServiceServer) {
+		s.auditEvents = store
+	}
+}
+
+func (s *TodoServiceServer) ListAuditEvents(in *pb.ListAuditEventsRequest, stream pb.TodoService_ListAuditEventsServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ListAuditEvents with request %+v: %v", in, err)
+		}
+	}()
+
+	if s.auditEvents == nil {
+		return status.Error(codes.Unimplemented, "audit events are not supported by this storage backend")
+	}
+
+	if err = auth.AuthorizeService(stream.Context()); err != nil {
+		return err
+	}
+
+	if err = validate(in); err != nil {
+		return err
+	}
+
+	filter := audit.Filter{
+		UserID:     in.GetUserId(),
+		ReminderID: in.GetReminderId(),
+		Caller:     in.GetActor(),
+		RequestID:  in.GetRequestId(),
+		Method:     in.GetMethod(),
+		BeforeID:   in.GetBeforeId(),
+		Limit:      int(in.GetLimit()),
+	}
+	if in.GetSince() != nil {
+		filter.Since = in.GetSince().AsTime()
+	}
+	if in.GetUntil() != nil {
+		filter.Until = in.GetUntil().AsTime()
+	}
+	if filter.Limit == 0 {
+		filter.Limit = auditDefaultLimit
+	}
+
+	events, err := s.auditEvents.AuditEvents(stream.Context(), filter)
+	if err != nil {
+		return repoError(err)
+	}
+
+	for _, event := range events {
+		msg, err := event.Proto()
+		if err != nil {
+			return status.Errorf(codes.Internal, "cannot decode audit event %d: %v", event.ID, err)
+		}
+
+		if err = stream.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package todoserviceserver
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+)
+
+// StubAuditStore returns events and remembers the filter it was asked for.
+type StubAuditStore struct {
+	events []audit.Event
+	filter audit.Filter
+}
+
+func (as *StubAuditStore) AuditEvents(_ context.Context, filter audit.Filter) ([]audit.Event, error) {
+	as.filter = filter
+
+	return as.events, nil
+}
+
+func TestTodoServiceServer_ListAuditEvents(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+	now := time.Now().Truncate(time.Second)
+
+	store := &StubAuditStore{events: []audit.Event{{
+		ID:        7,
+		CreatedAt: now,
+		Actor:     audit.Actor{Caller: `user 42 ("test user")`, Method: "SetUser", RequestID: "abc"},
+		UserID:    keyUserId,
+		Before:    []byte(`{"id":"42"}`),
+		After:     []byte(`{"id":"42","language_code":"en"}`),
+	}}}
+	client, closer := server(ctx, &StubRepo{}, WithAuditEvents(store))
+	defer closer()
+
+	list := func(ctx context.Context, in *pb.ListAuditEventsRequest) ([]*pb.AuditEvent, error) {
+		stream, err := client.ListAuditEvents(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+
+		var events []*pb.AuditEvent
+		for {
+			event, err := stream.Recv()
+			if err == io.EOF {
+				return events, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	t.Run("events", func(t *testing.T) {
+		events, err := list(ctx, &pb.ListAuditEventsRequest{UserId: keyUserId, Method: "SetUser", Since: timestamppb.New(now)})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		expected := audit.Filter{UserID: keyUserId, Method: "SetUser", Since: now, Limit: auditDefaultLimit}
+		if !store.filter.Since.Equal(expected.Since) || store.filter.UserID != expected.UserID ||
+			store.filter.Method != expected.Method || store.filter.Limit != expected.Limit || !store.filter.Until.IsZero() {
+			t.Errorf("expected filter %+v got %+v", expected, store.filter)
+		}
+
+		if len(events) != 1 {
+			t.Fatalf("expected one event got %v", events)
+		}
+		event := events[0]
+		if event.GetId() != 7 || !event.GetTime().AsTime().Equal(now) || event.GetRequestId() != "abc" || event.GetUserId() != keyUserId {
+			t.Errorf("unexpected event %v", event)
+		}
+		if changed := event.GetChangedFields(); len(changed) != 1 || changed[0] != "language_code" {
+			t.Errorf("expected language_code changed got %v", changed)
+		}
+		if language := event.GetAfter().GetFields()["language_code"].GetStringValue(); language != "en" {
+			t.Errorf("expected language en after the change got %q", language)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		if _, err := list(ctx, &pb.ListAuditEventsRequest{Limit: 5}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if store.filter.Limit != 5 {
+			t.Errorf("expected limit 5 got %d", store.filter.Limit)
+		}
+
+		if _, err := list(ctx, &pb.ListAuditEventsRequest{Limit: 1001}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected code %v got %v", codes.InvalidArgument, err)
+		}
+	})
+
+	t.Run("personal key", func(t *testing.T) {
+		userCtx := withKey(context.Background(), userKey)
+		if _, err := list(userCtx, &pb.ListAuditEventsRequest{UserId: keyUserId}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected code %v got %v", codes.PermissionDenied, err)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		stream, _ := client.ListAuditEvents(ctx, &pb.ListAuditEventsRequest{})
+		if _, err := stream.Recv(); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected code %v got %v", codes.Unimplemented, err)
+		}
+	})
+}
+
+func TestTodoServiceServer_actor(t *testing.T) {
+	var actor audit.Actor
+	repo := &StubRepo{SetUserFunc: func(ctx context.Context, _ *pb.User) error {
+		actor, _ = audit.FromContext(ctx)
+
+		return nil
+	}}
+
+	ctx := withKey(context.Background(), userKey)
+	client, closer := server(ctx, repo)
+	defer closer()
+
+	var header metadata.MD
+	ctx = metadata.AppendToOutgoingContext(ctx, audit.MetadataKey, "request-1")
+	if _, err := client.SetUser(ctx, &pb.User{Id: keyUserId}, grpc.Header(&header)); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	expected := audit.Actor{Caller: `user 42 ("test user")`, Method: "SetUser", RequestID: "request-1"}
+	if actor != expected {
+		t.Errorf("expected actor %+v got %+v", expected, actor)
+	}
+	if ids := header.Get(audit.MetadataKey); len(ids) != 1 || ids[0] != "request-1" {
+		t.Errorf("expected request id returned got %v", ids)
+	}
+}
@@ -0,0 +1,302 @@
+package todoserviceserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/ical"
+)
+
+const (
+	// calendarUIDPrefix and calendarUIDSuffix surround the reminder ids in
+	// the UIDs of exported calendars, so that imports recognize them.
+	calendarUIDPrefix = "reminder-"
+	calendarUIDSuffix = "@awakair_todo_bot"
+
+	// importHorizon is how far ahead occurrences of imported to-dos and
+	// events become reminders, at most importMaxOccurrences of each.
+	importHorizon        = 365 * 24 * time.Hour
+	importMaxOccurrences = 50
+)
+
+// UserZone returns the time zone of the utc_offset of the user, UTC when
+// the user has none.
+func UserZone(user *pb.User) *time.Location {
+	if user.GetUtcOffset() == nil {
+		return time.UTC
+	}
+
+	offset := int(user.GetUtcOffset().GetValue())
+
+	return time.FixedZone(fmt.Sprintf("UTC%+03d:00", offset), offset*60*60)
+}
+
+// CalendarEntry returns a reminder as exported to calendars.
+func CalendarEntry(reminder *pb.Reminder) ical.Entry {
+	return ical.Entry{
+		UID:     calendarUIDPrefix + strconv.Itoa(int(reminder.GetId())) + calendarUIDSuffix,
+		Summary: reminder.GetReminderText(),
+		Time:    reminder.GetRemindTimestamp().AsTime(),
+	}
+}
+
+// exportedReminderId returns the reminder an exported calendar UID names.
+func exportedReminderId(uid string) (int32, bool) {
+	id, ok := strings.CutPrefix(uid, calendarUIDPrefix)
+	if !ok {
+		return 0, false
+	}
+	if id, ok = strings.CutSuffix(id, calendarUIDSuffix); !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(id, 10, 32)
+
+	return int32(n), err == nil
+}
+
+// zoneOf returns the time zone of a user, who may not exist yet.
+func (s *TodoServiceServer) zoneOf(ctx context.Context, userId int64) (*time.Location, error) {
+	user, err := s.repo.GetUser(ctx, userId)
+	if errors.Is(err, ErrNotFound) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return UserZone(user), nil
+}
+
+func (s *TodoServiceServer) ExportCalendar(ctx context.Context, in *pb.ExportCalendarRequest) (_ *pb.Calendar, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ExportCalendar with request %+v: %v", in, err)
+		}
+	}()
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	zone, err := s.zoneOf(ctx, in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := s.repo.GetRemindersByUserId(ctx, in.GetUserId())
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	entries := make([]ical.Entry, 0, len(reminders))
+	for _, reminder := range reminders {
+		entries = append(entries, CalendarEntry(reminder))
+	}
+
+	kind := "VTODO"
+	if in.GetComponent() == pb.ExportCalendarRequest_VEVENT {
+		kind = "VEVENT"
+	}
+
+	var ics strings.Builder
+	if err = ical.Export(&ics, kind, zone, time.Now(), entries); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.Calendar{Ics: ics.String()}, nil
+}
+
+func (s *TodoServiceServer) ImportCalendar(ctx context.Context, in *pb.ImportCalendarRequest) (_ *pb.ImportCalendarReport, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ImportCalendar for user %v: %v", in.GetUserId(), err)
+		}
+	}()
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	zone, err := s.zoneOf(ctx, in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := ical.Import(ctx, strings.NewReader(in.GetIcs()), zone)
+	if err != nil {
+		return nil, calendarError(err)
+	}
+
+	existing, err := s.repo.GetRemindersByUserId(ctx, in.GetUserId())
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	// Reminders with the same text and time are the same, whether they
+	// exist or are imported earlier in the calendar.
+	type key struct {
+		text string
+		at   time.Time
+	}
+	same := make(map[key]int32, len(existing))
+	byId := make(map[int32]*pb.Reminder, len(existing))
+	for _, reminder := range existing {
+		same[key{reminder.GetReminderText(), reminder.GetRemindTimestamp().AsTime().Truncate(time.Second)}] = reminder.GetId()
+		byId[reminder.GetId()] = reminder
+	}
+
+	// The reminders to create are created at once, all or none, after the
+	// whole calendar is read. Their outcomes are known by then.
+	var (
+		reminders []*pb.Reminder
+		created   [][]*pb.ImportedReminder
+		pending   = make(map[key]int)
+	)
+
+	report := &pb.ImportCalendarReport{}
+	skip := func(item ical.Item, reason string) {
+		report.Reminders = append(report.Reminders, &pb.ImportedReminder{Uid: item.UID, ReminderText: item.Summary, Outcome: pb.ImportedReminder_SKIPPED, Reason: reason})
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		if item.Summary == "" {
+			skip(item, "no summary")
+
+			continue
+		}
+		if item.Status == "CANCELLED" || item.Status == "COMPLETED" {
+			skip(item, "status is "+strings.ToLower(item.Status))
+
+			continue
+		}
+
+		times, err := item.Occurrences(now, now.Add(importHorizon), importMaxOccurrences)
+		if errors.Is(err, ical.ErrTooComplex) || ctx.Err() != nil {
+			return nil, calendarError(err)
+		}
+		if err != nil {
+			skip(item, err.Error())
+
+			continue
+		}
+		if len(times) == 0 {
+			skip(item, "no occurrence within the next year")
+
+			continue
+		}
+
+		exported, isExported := exportedReminderId(item.UID)
+
+		for _, at := range times {
+			imported := &pb.ImportedReminder{Uid: item.UID, ReminderText: item.Summary, RemindTimestamp: timestamppb.New(at)}
+			report.Reminders = append(report.Reminders, imported)
+			k := key{item.Summary, at.UTC()}
+
+			if id, ok := same[k]; ok {
+				imported.Outcome, imported.Reason, imported.ReminderId = pb.ImportedReminder_SKIPPED, "already exists", id
+
+				continue
+			}
+			if i, ok := pending[k]; ok {
+				created[i] = append(created[i], imported)
+
+				continue
+			}
+
+			if reminder, ok := byId[exported]; isExported && ok && !item.Recurring() {
+				imported.Outcome, imported.ReminderId = pb.ImportedReminder_CONFLICT, exported
+				imported.Reason = fmt.Sprintf("reminder changed since the export to %q at %s",
+					reminder.GetReminderText(), reminder.GetRemindTimestamp().AsTime().In(zone).Format(time.RFC3339))
+
+				continue
+			}
+
+			reminder := &pb.Reminder{UserId: in.GetUserId(), ReminderText: item.Summary, RemindTimestamp: imported.RemindTimestamp}
+			if err := validate(reminder); err != nil {
+				imported.Outcome, imported.Reason = pb.ImportedReminder_SKIPPED, status.Convert(err).Message()
+
+				continue
+			}
+
+			pending[k] = len(reminders)
+			reminders = append(reminders, reminder)
+			created = append(created, []*pb.ImportedReminder{imported})
+		}
+	}
+
+	var (
+		ids      []int32
+		quotaErr error
+	)
+	if !in.GetDryRun() && len(reminders) > 0 {
+		ids, err = s.repo.CreateReminders(ctx, in.GetUserId(), reminders)
+		if errors.Is(err, ErrQuotaExceeded) {
+			quotaErr = err
+		} else if err != nil {
+			return nil, repoError(err)
+		}
+	}
+
+	for i, copies := range created {
+		for j, imported := range copies {
+			switch {
+			case quotaErr != nil:
+				imported.Outcome, imported.Reason = pb.ImportedReminder_SKIPPED, quotaErr.Error()
+			case j == 0:
+				imported.Outcome = pb.ImportedReminder_CREATED
+			default:
+				imported.Outcome, imported.Reason = pb.ImportedReminder_SKIPPED, "already exists"
+			}
+			if quotaErr == nil && ids != nil {
+				imported.ReminderId = ids[i]
+			}
+		}
+	}
+
+	for _, imported := range report.Reminders {
+		switch imported.Outcome {
+		case pb.ImportedReminder_CREATED:
+			report.Created++
+		case pb.ImportedReminder_SKIPPED:
+			report.Skipped++
+		case pb.ImportedReminder_CONFLICT:
+			report.Conflicts++
+		}
+	}
+
+	if !in.GetDryRun() {
+		log.Printf("ImportCalendar for user %v created %d reminders", in.GetUserId(), report.Created)
+	}
+
+	return report, nil
+}
+
+// calendarError converts an error importing a calendar into a status error.
+func calendarError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Errorf(codes.InvalidArgument, "invalid calendar: %v", err)
+}
@@ -0,0 +1,246 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// calendarRepo returns a repo of user 42 at UTC+2 keeping the reminders
+// created in it.
+func calendarRepo(reminders *[]*pb.Reminder) *StubRepo {
+	return &StubRepo{
+		GetUserFunc: func(_ context.Context, id int64) (*pb.User, error) {
+			if id != keyUserId {
+				return nil, fmt.Errorf("user %d: %w", id, ErrNotFound)
+			}
+
+			return &pb.User{Id: id, UtcOffset: wrapperspb.Int32(2)}, nil
+		},
+		CreateReminderFunc: func(_ context.Context, reminder *pb.Reminder) (int32, error) {
+			reminder.Id = int32(len(*reminders) + 1)
+			*reminders = append(*reminders, reminder)
+
+			return reminder.Id, nil
+		},
+		CreateRemindersFunc: func(_ context.Context, _ int64, batch []*pb.Reminder) ([]int32, error) {
+			var ids []int32
+			for _, reminder := range batch {
+				reminder.Id = int32(len(*reminders) + 1)
+				*reminders = append(*reminders, reminder)
+				ids = append(ids, reminder.Id)
+			}
+
+			return ids, nil
+		},
+		GetRemindersByUserIdFunc: func(_ context.Context, userId int64) ([]*pb.Reminder, error) {
+			var own []*pb.Reminder
+			for _, reminder := range *reminders {
+				if reminder.GetUserId() == userId {
+					own = append(own, reminder)
+				}
+			}
+
+			return own, nil
+		},
+	}
+}
+
+func outcomes(report *pb.ImportCalendarReport) []string {
+	var got []string
+	for _, reminder := range report.GetReminders() {
+		got = append(got, fmt.Sprintf("%s %s %d", reminder.GetReminderText(), reminder.GetOutcome(), reminder.GetReminderId()))
+	}
+
+	return got
+}
+
+func TestTodoServiceServer_ExportCalendar(t *testing.T) {
+	ctx := withKey(context.Background(), userKey)
+	at := time.Now().Add(48 * time.Hour).Truncate(time.Minute)
+
+	reminders := []*pb.Reminder{
+		{Id: 1, UserId: keyUserId, ReminderText: "water the plants", RemindTimestamp: timestamppb.New(at)},
+		{Id: 2, UserId: keyUserId, ReminderText: "call mom", RemindTimestamp: timestamppb.New(at.Add(time.Hour))},
+	}
+	client, closer := server(ctx, calendarRepo(&reminders))
+	defer closer()
+
+	t.Run("components", func(t *testing.T) {
+		for component, expected := range map[pb.ExportCalendarRequest_Component]string{
+			pb.ExportCalendarRequest_COMPONENT_UNSPECIFIED: "BEGIN:VTODO",
+			pb.ExportCalendarRequest_VEVENT:                "BEGIN:VEVENT",
+		} {
+			calendar, err := client.ExportCalendar(ctx, &pb.ExportCalendarRequest{UserId: keyUserId, Component: component})
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			ics := calendar.GetIcs()
+			local := at.In(time.FixedZone("", 2*60*60)).Format("20060102T150405")
+			for _, s := range []string{expected, "UID:reminder-1@awakair_todo_bot", "SUMMARY:call mom", `TZID="UTC+02:00":` + local, "BEGIN:VALARM"} {
+				if !strings.Contains(ics, s) {
+					t.Errorf("expected %q in %s", s, ics)
+				}
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		calendar, err := client.ExportCalendar(ctx, &pb.ExportCalendarRequest{UserId: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		report, err := client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: calendar.GetIcs()})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		expected := []string{"water the plants SKIPPED 1", "call mom SKIPPED 2"}
+		if got := outcomes(report); fmt.Sprint(got) != fmt.Sprint(expected) || report.GetSkipped() != 2 {
+			t.Errorf("expected %v got %v", expected, got)
+		}
+
+		// Changing a reminder after the export makes the calendar conflict.
+		reminders[1] = &pb.Reminder{Id: 2, UserId: keyUserId, ReminderText: "call dad", RemindTimestamp: reminders[1].GetRemindTimestamp()}
+
+		report, err = client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: calendar.GetIcs()})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		expected = []string{"water the plants SKIPPED 1", "call mom CONFLICT 2"}
+		if got := outcomes(report); fmt.Sprint(got) != fmt.Sprint(expected) || report.GetConflicts() != 1 {
+			t.Errorf("expected %v got %v", expected, got)
+		}
+		if reason := report.GetReminders()[1].GetReason(); !strings.Contains(reason, `"call dad"`) {
+			t.Errorf("expected the changed reminder in the reason got %q", reason)
+		}
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := client.ExportCalendar(ctx, &pb.ExportCalendarRequest{UserId: keyUserId + 1})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+	})
+}
+
+func TestTodoServiceServer_ImportCalendar(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+
+	// Floating times are in the time zone of the user, UTC+2.
+	day := time.Now().UTC().AddDate(0, 0, 2)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 9, 0, 0, 0, time.FixedZone("", 2*60*60))
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR", "VERSION:2.0",
+		"BEGIN:VEVENT", "UID:standup", "SUMMARY:stand up",
+		"DTSTART:" + start.Format("20060102T150405"), "RRULE:FREQ=WEEKLY;COUNT=3", "END:VEVENT",
+		"BEGIN:VTODO", "UID:taxes", "SUMMARY:pay taxes", "DUE:20200101T000000Z", "END:VTODO",
+		"BEGIN:VTODO", "UID:done", "SUMMARY:done", "STATUS:COMPLETED", "DUE:" + start.UTC().Format("20060102T150405Z"), "END:VTODO",
+		"BEGIN:VEVENT", "UID:nameless", "DTSTART:" + start.Format("20060102T150405"), "END:VEVENT",
+		"BEGIN:VEVENT", "UID:hourly", "SUMMARY:hourly", "DTSTART:" + start.Format("20060102T150405"), "RRULE:FREQ=HOURLY", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:copy", "SUMMARY:stand up", "DTSTART:" + start.Format("20060102T150405"), "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	expected := []string{
+		"stand up CREATED 1", "stand up CREATED 2", "stand up CREATED 3",
+		"pay taxes SKIPPED 0", "done SKIPPED 0", " SKIPPED 0", "hourly SKIPPED 0",
+		"stand up SKIPPED 1",
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, calendarRepo(&reminders))
+		defer closer()
+
+		report, err := client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: ics, DryRun: true})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if len(reminders) != 0 {
+			t.Errorf("expected no reminders created got %v", reminders)
+		}
+		if report.GetCreated() != 3 || report.GetSkipped() != 5 || report.GetReminders()[0].GetReminderId() != 0 {
+			t.Errorf("expected 3 reminders to create got %v", outcomes(report))
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, calendarRepo(&reminders))
+		defer closer()
+
+		report, err := client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: ics})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if got := outcomes(report); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("expected %v got %v", expected, got)
+		}
+		for i, reminder := range reminders {
+			if at := reminder.GetRemindTimestamp().AsTime(); !at.Equal(start.AddDate(0, 0, 7*i)) {
+				t.Errorf("expected reminder %d at %v got %v", i, start.AddDate(0, 0, 7*i), at)
+			}
+		}
+
+		reasons := map[string]string{"taxes": "within the next year", "done": "completed", "nameless": "no summary", "hourly": "unsupported"}
+		for _, reminder := range report.GetReminders() {
+			if reason, ok := reasons[reminder.GetUid()]; ok && !strings.Contains(reminder.GetReason(), reason) {
+				t.Errorf("expected %s to be skipped for %q got %q", reminder.GetUid(), reason, reminder.GetReason())
+			}
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		sr := calendarRepo(&reminders)
+		create := sr.CreateRemindersFunc
+		sr.CreateRemindersFunc = func(ctx context.Context, userId int64, batch []*pb.Reminder) ([]int32, error) {
+			if len(reminders)+len(batch) > 1 {
+				return nil, fmt.Errorf("user %d: %w", userId, ErrQuotaExceeded)
+			}
+
+			return create(ctx, userId, batch)
+		}
+
+		client, closer := server(ctx, sr)
+		defer closer()
+
+		report, err := client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: ics})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		// The calendar is imported all or none.
+		if len(reminders) != 0 || report.GetCreated() != 0 || report.GetSkipped() != 8 || !strings.Contains(report.GetReminders()[2].GetReason(), "quota") {
+			t.Errorf("expected no reminders created over the quota got %v", outcomes(report))
+		}
+	})
+
+	t.Run("invalid calendar", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, calendarRepo(&reminders))
+		defer closer()
+
+		for _, ics := range []string{"", "BEGIN:VEVENT\r\nEND:VEVENT"} {
+			_, err := client.ImportCalendar(ctx, &pb.ImportCalendarRequest{UserId: keyUserId, Ics: ics})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument for %q got %v", ics, err)
+			}
+		}
+	})
+}
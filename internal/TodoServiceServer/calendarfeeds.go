@@ -0,0 +1,152 @@
+package todoserviceserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+// CalendarFeedSecretPrefix starts every calendar feed secret.
+const CalendarFeedSecretPrefix = "tdc_"
+
+// CalendarFeedVersion identifies the content of a calendar feed last served.
+type CalendarFeedVersion struct {
+	ETag string
+	// ModifiedAt is when the content changed to ETag, zero before the feed
+	// was first served.
+	ModifiedAt time.Time
+}
+
+// CalendarFeedStore keeps the hashes of the calendar feed secrets of users,
+// see package calendarfeed.
+type CalendarFeedStore interface {
+	// CreateCalendarFeed fails with ErrAlreadyExists when the user has a
+	// feed and with ErrNotFound when the user does not exist.
+	CreateCalendarFeed(ctx context.Context, userId int64, hash []byte) error
+	// RotateCalendarFeed replaces the secret of the feed of the user,
+	// failing with ErrNotFound when the user has none.
+	RotateCalendarFeed(ctx context.Context, userId int64, hash []byte) error
+	// DeleteCalendarFeed fails with ErrNotFound when the user has no feed.
+	DeleteCalendarFeed(ctx context.Context, userId int64) error
+	// FindCalendarFeed returns the user of the feed with the given secret
+	// hash and its version, ErrNotFound when there is none.
+	FindCalendarFeed(ctx context.Context, hash []byte) (int64, CalendarFeedVersion, error)
+	SetCalendarFeedVersion(ctx context.Context, userId int64, version CalendarFeedVersion) error
+}
+
+// WithCalendarFeeds enables the calendar feed RPCs, which are unimplemented
+// otherwise. baseURL is where the feeds are served, like
+// https://todo.example.com, empty to return their paths only.
+func WithCalendarFeeds(store CalendarFeedStore, baseURL string) Option {
+	return func(s *TodoServiceServer) {
+		s.feeds = store
+		s.feedsURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+var errNoCalendarFeeds = status.Error(codes.Unimplemented, "calendar feeds are not enabled")
+
+// newCalendarFeed returns a feed with a new secret and its hash.
+func (s *TodoServiceServer) newCalendarFeed() (*pb.CalendarFeed, []byte, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, nil, status.Error(codes.Internal, err.Error())
+	}
+	secret := CalendarFeedSecretPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	feed := &pb.CalendarFeed{Secret: secret, Url: s.feedsURL + "/calendar/" + secret + ".ics"}
+
+	return feed, HashSecret(secret), nil
+}
+
+func (s *TodoServiceServer) CreateCalendarFeed(ctx context.Context, in *pb.UserId) (_ *pb.CalendarFeed, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in CreateCalendarFeed with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("CreateCalendarFeed with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.feeds == nil {
+		return nil, errNoCalendarFeeds
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	feed, hash, err := s.newCalendarFeed()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.feeds.CreateCalendarFeed(ctx, in.GetId(), hash); err != nil {
+		return nil, repoError(err)
+	}
+
+	return feed, nil
+}
+
+func (s *TodoServiceServer) RotateCalendarFeed(ctx context.Context, in *pb.UserId) (_ *pb.CalendarFeed, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RotateCalendarFeed with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("RotateCalendarFeed with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.feeds == nil {
+		return nil, errNoCalendarFeeds
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	feed, hash, err := s.newCalendarFeed()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.feeds.RotateCalendarFeed(ctx, in.GetId(), hash); err != nil {
+		return nil, repoError(err)
+	}
+
+	return feed, nil
+}
+
+func (s *TodoServiceServer) RevokeCalendarFeed(ctx context.Context, in *pb.UserId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RevokeCalendarFeed with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("RevokeCalendarFeed with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.feeds == nil {
+		return nil, errNoCalendarFeeds
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.feeds.DeleteCalendarFeed(ctx, in.GetId()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
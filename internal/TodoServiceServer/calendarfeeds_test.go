@@ -0,0 +1,156 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// StubCalendarFeedStore keeps the feed secret hashes of the users in it,
+// nil for users without a feed.
+type StubCalendarFeedStore struct {
+	mu     sync.Mutex
+	hashes map[int64][]byte
+}
+
+func (fs *StubCalendarFeedStore) CreateCalendarFeed(_ context.Context, userId int64, hash []byte) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	stored, ok := fs.hashes[userId]
+	if !ok {
+		return fmt.Errorf("user %d: %w", userId, ErrNotFound)
+	}
+	if stored != nil {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, ErrAlreadyExists)
+	}
+	fs.hashes[userId] = hash
+
+	return nil
+}
+
+func (fs *StubCalendarFeedStore) RotateCalendarFeed(_ context.Context, userId int64, hash []byte) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.hashes[userId] == nil {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, ErrNotFound)
+	}
+	fs.hashes[userId] = hash
+
+	return nil
+}
+
+func (fs *StubCalendarFeedStore) DeleteCalendarFeed(_ context.Context, userId int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.hashes[userId] == nil {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, ErrNotFound)
+	}
+	fs.hashes[userId] = nil
+
+	return nil
+}
+
+func (fs *StubCalendarFeedStore) FindCalendarFeed(_ context.Context, hash []byte) (int64, CalendarFeedVersion, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for userId, stored := range fs.hashes {
+		if stored != nil && string(stored) == string(hash) {
+			return userId, CalendarFeedVersion{}, nil
+		}
+	}
+
+	return 0, CalendarFeedVersion{}, fmt.Errorf("calendar feed: %w", ErrNotFound)
+}
+
+func (fs *StubCalendarFeedStore) SetCalendarFeedVersion(context.Context, int64, CalendarFeedVersion) error {
+	return nil
+}
+
+func TestTodoServiceServer_calendarFeeds(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+	userCtx := withKey(context.Background(), userKey)
+
+	store := &StubCalendarFeedStore{hashes: map[int64][]byte{keyUserId: nil, keyUserId + 1: nil}}
+
+	client, closer := server(ctx, &StubRepo{}, WithCalendarFeeds(store, "https://todo.example.com/"))
+	defer closer()
+
+	find := func(secret string) int64 {
+		userId, _, _ := store.FindCalendarFeed(ctx, HashSecret(secret))
+
+		return userId
+	}
+
+	t.Run("create and rotate", func(t *testing.T) {
+		if _, err := client.RotateCalendarFeed(userCtx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound rotating a feed before creating it got %v", err)
+		}
+
+		first, err := client.CreateCalendarFeed(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if !strings.HasPrefix(first.GetSecret(), CalendarFeedSecretPrefix) || find(first.GetSecret()) != keyUserId {
+			t.Errorf("expected a secret of user %d got %q", keyUserId, first.GetSecret())
+		}
+		if expected := "https://todo.example.com/calendar/" + first.GetSecret() + ".ics"; first.GetUrl() != expected {
+			t.Errorf("expected url %q got %q", expected, first.GetUrl())
+		}
+
+		if _, err := client.CreateCalendarFeed(userCtx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.AlreadyExists {
+			t.Errorf("expected AlreadyExists creating a second feed got %v", err)
+		}
+
+		second, err := client.RotateCalendarFeed(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if find(second.GetSecret()) != keyUserId || find(first.GetSecret()) != 0 {
+			t.Errorf("expected secret %q to replace %q", second.GetSecret(), first.GetSecret())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := client.CreateCalendarFeed(userCtx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+		if _, err := client.RevokeCalendarFeed(userCtx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+		if _, err := client.CreateCalendarFeed(ctx, &pb.UserId{Id: keyUserId + 2}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for an unknown user got %v", err)
+		}
+		if _, err := client.RevokeCalendarFeed(ctx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for a user without feed got %v", err)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		if _, err := client.RevokeCalendarFeed(userCtx, &pb.UserId{Id: keyUserId}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if store.hashes[keyUserId] != nil {
+			t.Errorf("expected the feed of user %d to be revoked", keyUserId)
+		}
+	})
+
+	t.Run("without calendar feeds", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		if _, err := client.CreateCalendarFeed(ctx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected Unimplemented got %v", err)
+		}
+	})
+}
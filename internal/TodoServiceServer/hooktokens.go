@@ -0,0 +1,103 @@
+package todoserviceserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+// HookTokenPrefix starts every hook token.
+const HookTokenPrefix = "tdh_"
+
+// HookTokenStore keeps the hashes of the hook tokens of users,
+// see package hooks.
+type HookTokenStore interface {
+	// SetHookToken replaces the token of the user,
+	// failing with ErrNotFound when the user does not exist.
+	SetHookToken(ctx context.Context, userId int64, hash []byte) error
+	// DeleteHookToken fails with ErrNotFound when the user has no token.
+	DeleteHookToken(ctx context.Context, userId int64) error
+	// FindHookToken returns the user of the token with the given hash,
+	// ErrNotFound when there is none.
+	FindHookToken(ctx context.Context, hash []byte) (int64, error)
+}
+
+// WithHookTokens enables the hook token RPCs, which are unimplemented otherwise.
+func WithHookTokens(store HookTokenStore) Option {
+	return func(s *TodoServiceServer) {
+		s.hookTokens = store
+	}
+}
+
+var errNoHookTokens = status.Error(codes.Unimplemented, "hook tokens are not enabled")
+
+// HashSecret returns the hash stores keep for a secret given to a user, the
+// token of a HookTokenStore or the secret of a CalendarFeedStore.
+func HashSecret(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+
+	return hash[:]
+}
+
+func (s *TodoServiceServer) RotateHookToken(ctx context.Context, in *pb.UserId) (_ *pb.HookToken, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RotateHookToken with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("RotateHookToken with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.hookTokens == nil {
+		return nil, errNoHookTokens
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	token := HookTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	if err = s.hookTokens.SetHookToken(ctx, in.GetId(), HashSecret(token)); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &pb.HookToken{Token: token}, nil
+}
+
+func (s *TodoServiceServer) RevokeHookToken(ctx context.Context, in *pb.UserId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RevokeHookToken with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("RevokeHookToken with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.hookTokens == nil {
+		return nil, errNoHookTokens
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.hookTokens.DeleteHookToken(ctx, in.GetId()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,124 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// StubHookTokenStore keeps the token hashes of the users in it.
+type StubHookTokenStore struct {
+	mu     sync.Mutex
+	hashes map[int64][]byte
+}
+
+func (hs *StubHookTokenStore) SetHookToken(_ context.Context, userId int64, hash []byte) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if _, ok := hs.hashes[userId]; !ok {
+		return fmt.Errorf("user %d: %w", userId, ErrNotFound)
+	}
+	hs.hashes[userId] = hash
+
+	return nil
+}
+
+func (hs *StubHookTokenStore) DeleteHookToken(_ context.Context, userId int64) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.hashes[userId] == nil {
+		return fmt.Errorf("hook token of user %d: %w", userId, ErrNotFound)
+	}
+	hs.hashes[userId] = nil
+
+	return nil
+}
+
+func (hs *StubHookTokenStore) FindHookToken(_ context.Context, hash []byte) (int64, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	for userId, stored := range hs.hashes {
+		if stored != nil && string(stored) == string(hash) {
+			return userId, nil
+		}
+	}
+
+	return 0, fmt.Errorf("hook token: %w", ErrNotFound)
+}
+
+func TestTodoServiceServer_hookTokens(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+	userCtx := withKey(context.Background(), userKey)
+
+	store := &StubHookTokenStore{hashes: map[int64][]byte{keyUserId: nil, keyUserId + 1: nil}}
+
+	client, closer := server(ctx, &StubRepo{}, WithHookTokens(store))
+	defer closer()
+
+	find := func(token string) int64 {
+		userId, _ := store.FindHookToken(ctx, HashSecret(token))
+
+		return userId
+	}
+
+	t.Run("rotate", func(t *testing.T) {
+		first, err := client.RotateHookToken(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if !strings.HasPrefix(first.GetToken(), HookTokenPrefix) || find(first.GetToken()) != keyUserId {
+			t.Errorf("expected a token of user %d got %q", keyUserId, first.GetToken())
+		}
+
+		second, err := client.RotateHookToken(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if find(second.GetToken()) != keyUserId || find(first.GetToken()) != 0 {
+			t.Errorf("expected token %q to replace %q", second.GetToken(), first.GetToken())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := client.RotateHookToken(userCtx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+		if _, err := client.RevokeHookToken(userCtx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+		if _, err := client.RotateHookToken(ctx, &pb.UserId{Id: keyUserId + 2}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for an unknown user got %v", err)
+		}
+		if _, err := client.RevokeHookToken(ctx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for a user without token got %v", err)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		if _, err := client.RevokeHookToken(userCtx, &pb.UserId{Id: keyUserId}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if store.hashes[keyUserId] != nil {
+			t.Errorf("expected the token of user %d to be revoked", keyUserId)
+		}
+	})
+
+	t.Run("without hook tokens", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		if _, err := client.RotateHookToken(ctx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected Unimplemented got %v", err)
+		}
+	})
+}
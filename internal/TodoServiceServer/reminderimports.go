@@ -0,0 +1,411 @@
+package todoserviceserver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/todotxt"
+)
+
+const (
+	// importMaxBytes limits the files ImportReminders reads, which creates
+	// up to importBatchSize reminders at once and reports the first
+	// importMaxSkippedLines skipped lines.
+	importMaxBytes        = 16 << 20
+	importBatchSize       = 500
+	importMaxSkippedLines = 100
+	// importMaxLineBytes limits the lines of todo.txt files.
+	importMaxLineBytes = 64 << 10
+
+	// importDefaultHour is when imported reminders with only a date remind.
+	importDefaultHour = 9
+)
+
+// importTimeLayouts are the times ImportReminders reads in the time zone
+// of the user, after RFC 3339.
+var importTimeLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// parseImportTime reads a time of an imported reminder.
+func parseImportTime(value string, zone *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, zone); err == nil {
+			return t, nil
+		}
+	}
+	if d, err := time.ParseInLocation(todotxt.DateLayout, value, zone); err == nil {
+		return d.Add(importDefaultHour * time.Hour), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// chunkReader reads the chunks of an ImportReminders stream as one file.
+type chunkReader struct {
+	stream pb.TodoService_ImportRemindersServer
+	chunk  []byte
+	// left is how many more bytes the file may have.
+	left int
+	// err ended the stream, io.EOF at the end of the file.
+	err error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		in, err := r.stream.Recv()
+		switch {
+		case err != nil:
+			r.err = err
+		case in.GetOptions() != nil:
+			r.err = status.Error(codes.InvalidArgument, "options must only be sent first")
+		default:
+			if err := validate(in); err != nil {
+				r.err = err
+
+				continue
+			}
+
+			r.left -= len(in.GetChunk())
+			if r.left < 0 {
+				r.err = status.Errorf(codes.InvalidArgument, "file exceeds %d bytes", importMaxBytes)
+
+				continue
+			}
+			r.chunk = in.GetChunk()
+		}
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+
+	return n, nil
+}
+
+// readError converts an error reading the file into a status error,
+// unless the stream failed.
+func (r *chunkReader) readError(err error) error {
+	if r.err != nil && !errors.Is(r.err, io.EOF) {
+		return r.err
+	}
+
+	return status.Errorf(codes.InvalidArgument, "invalid file: %v", err)
+}
+
+// reminderImport collects the reminders of an ImportReminders stream.
+type reminderImport struct {
+	s       *TodoServiceServer
+	stream  pb.TodoService_ImportRemindersServer
+	options *pb.ImportRemindersOptions
+	report  *pb.ImportRemindersReport
+
+	// batch holds reminders to create, read from lines.
+	batch []*pb.Reminder
+	lines []int32
+	// quotaErr is set once the user has no room for more reminders.
+	quotaErr error
+}
+
+func (ri *reminderImport) skip(line int32, reason string) {
+	ri.report.Skipped++
+	if len(ri.report.SkippedLines) < importMaxSkippedLines {
+		ri.report.SkippedLines = append(ri.report.SkippedLines, &pb.SkippedLine{Line: line, Reason: reason})
+	}
+}
+
+// add creates a reminder read from line, once the batch is full.
+func (ri *reminderImport) add(line int32, text string, at time.Time) error {
+	if ri.quotaErr != nil {
+		ri.skip(line, ri.quotaErr.Error())
+
+		return nil
+	}
+
+	reminder := &pb.Reminder{UserId: ri.options.GetUserId(), ReminderText: text, RemindTimestamp: timestamppb.New(at)}
+	if err := validate(reminder); err != nil {
+		ri.skip(line, status.Convert(err).Message())
+
+		return nil
+	}
+
+	ri.batch = append(ri.batch, reminder)
+	ri.lines = append(ri.lines, line)
+	if len(ri.batch) >= importBatchSize {
+		return ri.flush()
+	}
+
+	return nil
+}
+
+// flush creates the reminders of the batch. When the quota has no room
+// for all of them, it creates them one by one until it is full.
+func (ri *reminderImport) flush() error {
+	ctx := ri.stream.Context()
+	batch, lines := ri.batch, ri.lines
+	ri.batch, ri.lines = nil, nil
+
+	if len(batch) == 0 || ri.options.GetDryRun() {
+		ri.report.Created += int32(len(batch))
+
+		return nil
+	}
+
+	_, err := ri.s.repo.CreateReminders(ctx, ri.options.GetUserId(), batch)
+	if err == nil {
+		ri.report.Created += int32(len(batch))
+
+		return nil
+	}
+	if !errors.Is(err, ErrQuotaExceeded) {
+		return repoError(err)
+	}
+
+	for i, reminder := range batch {
+		if ri.quotaErr == nil {
+			_, err := ri.s.repo.CreateReminder(ctx, reminder)
+			if err == nil {
+				ri.report.Created++
+
+				continue
+			}
+			if !errors.Is(err, ErrQuotaExceeded) {
+				return repoError(err)
+			}
+
+			ri.quotaErr = err
+		}
+
+		ri.skip(lines[i], ri.quotaErr.Error())
+	}
+
+	return nil
+}
+
+// readTodoTxt adds a reminder for every task of a todo.txt file which is
+// due and not done.
+func (ri *reminderImport) readTodoTxt(r *chunkReader, zone *time.Location) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, importMaxLineBytes)
+
+	var line int32
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		task, err := todotxt.Parse(scanner.Text())
+		if err != nil {
+			ri.skip(line, err.Error())
+
+			continue
+		}
+		if task.Done {
+			ri.skip(line, "task is done")
+
+			continue
+		}
+
+		due, ok := task.Tags["due"]
+		if !ok {
+			ri.skip(line, "task has no due: date")
+
+			continue
+		}
+		at, err := parseImportTime(due, zone)
+		if err != nil {
+			ri.skip(line, err.Error())
+
+			continue
+		}
+
+		if err := ri.add(line, task.Text, at); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return r.readError(err)
+	}
+
+	return nil
+}
+
+// readCSV adds a reminder for every row of a CSV file.
+func (ri *reminderImport) readCSV(r *chunkReader, zone *time.Location) error {
+	columns := ri.options.GetCsvColumns()
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	if columns.GetDelimiter() != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(columns.GetDelimiter())
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return r.readError(err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		index[strings.TrimSpace(name)] = i
+	}
+
+	find := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+
+		i, ok := index[name]
+		if !ok {
+			return 0, status.Errorf(codes.InvalidArgument, "CSV file has no column %q", name)
+		}
+
+		return i, nil
+	}
+	textColumn, err := find(columns.GetText())
+	if err != nil {
+		return err
+	}
+	remindAtColumn, err := find(columns.GetRemindAt())
+	if err != nil {
+		return err
+	}
+	timeColumn, err := find(columns.GetTime())
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			ri.skip(int32(parseErr.Line), parseErr.Err.Error())
+
+			continue
+		}
+		if err != nil {
+			return r.readError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(i int) string {
+			if i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		remindAt := field(remindAtColumn)
+		if timeColumn >= 0 && field(timeColumn) != "" {
+			remindAt += " " + field(timeColumn)
+		}
+		at, err := parseImportTime(remindAt, zone)
+		if err != nil {
+			ri.skip(int32(line), err.Error())
+
+			continue
+		}
+
+		if err := ri.add(int32(line), field(textColumn), at); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *TodoServiceServer) ImportReminders(stream pb.TodoService_ImportRemindersServer) (err error) {
+	var options *pb.ImportRemindersOptions
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ImportReminders with options %+v: %v", options, err)
+		}
+	}()
+
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "expected options")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = validate(first); err != nil {
+		return err
+	}
+
+	options = first.GetOptions()
+	if options == nil {
+		return status.Error(codes.InvalidArgument, "the first message must have the options")
+	}
+
+	if err = auth.AuthorizeUser(ctx, options.GetUserId()); err != nil {
+		return err
+	}
+
+	if options.GetFormat() == pb.ImportRemindersOptions_CSV && options.GetCsvColumns() == nil {
+		return status.Error(codes.InvalidArgument, "csv_columns are required for CSV files")
+	}
+
+	zone, err := s.zoneOf(ctx, options.GetUserId())
+	if err != nil {
+		return err
+	}
+
+	ri := &reminderImport{
+		s:       s,
+		stream:  stream,
+		options: options,
+		report:  &pb.ImportRemindersReport{},
+	}
+	r := &chunkReader{stream: stream, left: importMaxBytes}
+
+	if options.GetFormat() == pb.ImportRemindersOptions_CSV {
+		err = ri.readCSV(r, zone)
+	} else {
+		err = ri.readTodoTxt(r, zone)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = ri.flush(); err != nil {
+		return err
+	}
+
+	if !options.GetDryRun() {
+		log.Printf("ImportReminders for user %v created %d reminders", options.GetUserId(), ri.report.Created)
+	}
+
+	return stream.SendAndClose(ri.report)
+}
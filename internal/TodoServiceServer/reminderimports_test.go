@@ -0,0 +1,231 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// importRepo returns a calendarRepo creating reminders in batches, up to
+// quota reminders when quota is not zero.
+func importRepo(reminders *[]*pb.Reminder, quota int) *StubRepo {
+	sr := calendarRepo(reminders)
+	createReminder := sr.CreateReminderFunc
+
+	sr.CreateReminderFunc = func(ctx context.Context, reminder *pb.Reminder) (int32, error) {
+		if quota > 0 && len(*reminders) >= quota {
+			return 0, fmt.Errorf("user %d: %w", reminder.GetUserId(), ErrQuotaExceeded)
+		}
+
+		return createReminder(ctx, reminder)
+	}
+	sr.CreateRemindersFunc = func(ctx context.Context, userId int64, batch []*pb.Reminder) ([]int32, error) {
+		if quota > 0 && len(*reminders)+len(batch) > quota {
+			return nil, fmt.Errorf("user %d: %w", userId, ErrQuotaExceeded)
+		}
+
+		ids := make([]int32, 0, len(batch))
+		for _, reminder := range batch {
+			id, err := createReminder(ctx, reminder)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+
+		return ids, nil
+	}
+
+	return sr
+}
+
+// importReminders sends file in chunks of chunkSize bytes.
+func importReminders(ctx context.Context, client pb.TodoServiceClient, options *pb.ImportRemindersOptions, file string, chunkSize int) (*pb.ImportRemindersReport, error) {
+	stream, err := client.ImportReminders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.Send(&pb.ImportRemindersRequest{Part: &pb.ImportRemindersRequest_Options{Options: options}}); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(file); i += chunkSize {
+		chunk := []byte(file[i:min(i+chunkSize, len(file))])
+		if err := stream.Send(&pb.ImportRemindersRequest{Part: &pb.ImportRemindersRequest_Chunk{Chunk: chunk}}); err != nil {
+			break
+		}
+	}
+
+	return stream.CloseAndRecv()
+}
+
+func skippedLines(report *pb.ImportRemindersReport) []string {
+	var got []string
+	for _, line := range report.GetSkippedLines() {
+		got = append(got, fmt.Sprintf("%d %s", line.GetLine(), line.GetReason()))
+	}
+
+	return got
+}
+
+func TestTodoServiceServer_ImportReminders(t *testing.T) {
+	ctx := withKey(context.Background(), userKey)
+	year := time.Now().Year() + 1
+	zone := time.FixedZone("", 2*60*60)
+
+	t.Run("todo.txt", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, importRepo(&reminders, 0))
+		defer closer()
+
+		file := fmt.Sprintf("(A) %[1]d-04-30 Call mom @phone due:%[1]d-05-01\n"+
+			"\n"+
+			"x %[1]d-05-02 Water the plants due:%[1]d-05-02\n"+
+			"Buy milk +groceries\n"+
+			"Pay rent due:%[1]d-05-03T18:30\n"+
+			"Renew passport due:2001-01-01\n"+
+			"Book flights due:tomorrow\n", year)
+
+		report, err := importReminders(ctx, client, &pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_TODO_TXT}, file, 16)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if report.GetCreated() != 2 || report.GetSkipped() != 4 || len(reminders) != 2 {
+			t.Fatalf("expected 2 reminders created and 4 lines skipped got %v, %v", report, reminders)
+		}
+		expected := []struct {
+			text string
+			at   time.Time
+		}{
+			{"Call mom @phone", time.Date(year, 5, 1, 9, 0, 0, 0, zone)},
+			{"Pay rent", time.Date(year, 5, 3, 18, 30, 0, 0, zone)},
+		}
+		for i, reminder := range reminders {
+			if reminder.GetReminderText() != expected[i].text || !reminder.GetRemindTimestamp().AsTime().Equal(expected[i].at) {
+				t.Errorf("expected %q at %v got %v", expected[i].text, expected[i].at, reminder)
+			}
+		}
+
+		lines := skippedLines(report)
+		for i, prefix := range []string{"3 task is done", "4 task has no due: date", "6 validation error", "7 invalid time"} {
+			if i >= len(lines) || len(lines[i]) < len(prefix) || lines[i][:len(prefix)] != prefix {
+				t.Errorf("expected skipped line %q got %q", prefix, lines)
+			}
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, importRepo(&reminders, 0))
+		defer closer()
+
+		file := fmt.Sprintf("\ufeffTitle;Date;Time;Notes\n"+
+			"\"Call mom\nabout the trip\";%[1]d-05-01;18:30;\n"+
+			"Water the plants;%[1]d-05-02;;weekly\n"+
+			"Pay rent;someday;;\n"+
+			"Send \"report\";%[1]d-05-03;;\n", year)
+		options := &pb.ImportRemindersOptions{
+			UserId:     keyUserId,
+			Format:     pb.ImportRemindersOptions_CSV,
+			CsvColumns: &pb.CSVColumns{Text: "Title", RemindAt: "Date", Time: "Time", Delimiter: ";"},
+		}
+
+		report, err := importReminders(ctx, client, options, file, 7)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if report.GetCreated() != 2 || len(reminders) != 2 {
+			t.Fatalf("expected 2 reminders created got %v, %v", report, reminders)
+		}
+		if at := time.Date(year, 5, 1, 18, 30, 0, 0, zone); reminders[0].GetReminderText() != "Call mom\nabout the trip" || !reminders[0].GetRemindTimestamp().AsTime().Equal(at) {
+			t.Errorf("expected the first row at %v got %v", at, reminders[0])
+		}
+		if at := time.Date(year, 5, 2, 9, 0, 0, 0, zone); !reminders[1].GetRemindTimestamp().AsTime().Equal(at) {
+			t.Errorf("expected the second row at %v got %v", at, reminders[1])
+		}
+
+		lines := skippedLines(report)
+		if len(lines) != 2 || lines[0] != `5 invalid time "someday"` || lines[1][:2] != "6 " {
+			t.Errorf("expected lines 5 and 6 skipped got %q", lines)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, importRepo(&reminders, 0))
+		defer closer()
+
+		file := fmt.Sprintf("Call mom due:%[1]d-05-01\nPay rent due:%[1]d-05-03\n", year)
+		report, err := importReminders(ctx, client, &pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_TODO_TXT, DryRun: true}, file, 1024)
+		if err != nil || report.GetCreated() != 2 || len(reminders) != 0 {
+			t.Errorf("expected 2 reminders reported and none created got %v, %v, %v", report, reminders, err)
+		}
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, importRepo(&reminders, 2))
+		defer closer()
+
+		file := fmt.Sprintf("Call mom due:%[1]d-05-01\nPay rent due:%[1]d-05-03\nBuy milk due:%[1]d-05-04\nRenew passport due:%[1]d-05-05\n", year)
+		report, err := importReminders(ctx, client, &pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_TODO_TXT}, file, 1024)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if report.GetCreated() != 2 || report.GetSkipped() != 2 || len(reminders) != 2 {
+			t.Errorf("expected 2 reminders created and 2 over the quota got %v, %v", report, reminders)
+		}
+		if lines := report.GetSkippedLines(); len(lines) != 2 || lines[0].GetLine() != 3 || lines[1].GetLine() != 4 {
+			t.Errorf("expected lines 3 and 4 skipped got %v", lines)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		var reminders []*pb.Reminder
+		client, closer := server(ctx, importRepo(&reminders, 0))
+		defer closer()
+
+		todoTxt := &pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_TODO_TXT}
+		csvColumns := &pb.CSVColumns{Text: "Title", RemindAt: "Due"}
+
+		for name, test := range map[string]struct {
+			options  *pb.ImportRemindersOptions
+			file     string
+			expected codes.Code
+		}{
+			"other user":     {&pb.ImportRemindersOptions{UserId: keyUserId + 1, Format: pb.ImportRemindersOptions_TODO_TXT}, "", codes.PermissionDenied},
+			"no format":      {&pb.ImportRemindersOptions{UserId: keyUserId}, "", codes.InvalidArgument},
+			"no CSV columns": {&pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_CSV}, "Title,Due\n", codes.InvalidArgument},
+			"no column":      {&pb.ImportRemindersOptions{UserId: keyUserId, Format: pb.ImportRemindersOptions_CSV, CsvColumns: csvColumns}, "Title,Date\n", codes.InvalidArgument},
+			"long line":      {todoTxt, string(make([]byte, importMaxLineBytes+1)), codes.InvalidArgument},
+		} {
+			if _, err := importReminders(ctx, client, test.options, test.file, 1024); status.Code(err) != test.expected {
+				t.Errorf("%s: expected %v got %v", name, test.expected, err)
+			}
+		}
+
+		stream, err := client.ImportReminders(ctx)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if err := stream.Send(&pb.ImportRemindersRequest{Part: &pb.ImportRemindersRequest_Chunk{Chunk: []byte("Call mom")}}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument without options got %v", err)
+		}
+
+		if len(reminders) != 0 {
+			t.Errorf("expected no reminders created got %v", reminders)
+		}
+	})
+}
@@ -2,28 +2,132 @@ package todoserviceserver
 
 import (
 	"context"
+	"errors"
 	"log"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrQuotaExceeded is returned by CreateReminder and CreateReminders
+	// when the user already has the maximum number of active reminders.
+	ErrQuotaExceeded = errors.New("active reminders quota exceeded")
+	ErrAlreadyExists = errors.New("already exists")
 )
 
 type Repo interface {
 	SetUser(context.Context, *pb.User) error
-	// GetUser(context.Context, int) error
+	GetUser(context.Context, int64) (*pb.User, error)
+	CreateReminder(context.Context, *pb.Reminder) (int32, error)
+	// CreateReminders creates reminders of the user at once, all or none,
+	// and returns their ids in order. It fails with ErrQuotaExceeded
+	// unless all of them fit in the quota.
+	CreateReminders(ctx context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error)
+	GetReminder(context.Context, int32) (*pb.Reminder, error)
+	RemoveReminder(context.Context, int32) error
+	// GetRemindersByUserId returns the reminders which were not removed,
+	// ordered by their remind timestamp.
+	GetRemindersByUserId(context.Context, int64) ([]*pb.Reminder, error)
+}
+
+// EventSource is the part of events.Feed read by WatchUserEvents.
+type EventSource interface {
+	UserEventSeqRange(ctx context.Context) (oldest, latest int64, err error)
+	UserEvents(ctx context.Context, userId, afterSeq int64, limit int) ([]*pb.UserEvent, error)
+	Subscribe(userId int64) (wake <-chan struct{}, cancel func())
 }
 
+// eventsPageSize is how many events WatchUserEvents reads at once.
+const eventsPageSize = 100
+
 type TodoServiceServer struct {
-	repo Repo
+	repo       Repo
+	events     EventSource
+	webhooks   WebhookStore
+	hookTokens HookTokenStore
+	feeds      CalendarFeedStore
+	feedsURL   string
+	userData   UserDataStore
+	shares     ShareStore
+	// auditEvents is read by ListAuditEvents, the repo records them.
+	auditEvents audit.Store
+	// deletionGracePeriod is how long after DeleteUser users are deleted.
+	deletionGracePeriod time.Duration
 	pb.UnimplementedTodoServiceServer
 }
 
-func New(repo Repo) *TodoServiceServer {
-	return &TodoServiceServer{repo: repo}
+type Option func(*TodoServiceServer)
+
+// WithEvents enables WatchUserEvents, which is unimplemented otherwise.
+func WithEvents(events EventSource) Option {
+	return func(s *TodoServiceServer) {
+		s.events = events
+	}
+}
+
+func New(repo Repo, opts ...Option) *TodoServiceServer {
+	s := &TodoServiceServer{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// validator is made once, it caches the rules of each message type.
+var validator = sync.OnceValues(func() (*protovalidate.Validator, error) {
+	return protovalidate.New()
+})
+
+func validate(msg proto.Message) error {
+	v, err := validator()
+
+	if err != nil {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	if err = v.Validate(msg); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return nil
+}
+
+// repoError converts an error returned by the repo into a status error.
+func repoError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrQuotaExceeded):
+		st, detailsErr := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     "user",
+				Description: err.Error(),
+			}},
+		})
+		if detailsErr != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		return st.Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
 }
 
 func (s *TodoServiceServer) SetUser(ctx context.Context, user *pb.User) (_ *emptypb.Empty, err error) {
@@ -35,31 +139,199 @@ func (s *TodoServiceServer) SetUser(ctx context.Context, user *pb.User) (_ *empt
 		}
 	}()
 
-	v, err := protovalidate.New()
-
-	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+	if err = auth.AuthorizeUser(ctx, user.GetId()); err != nil {
+		return nil, err
 	}
 
-	if err = v.Validate(user); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if err = validate(user); err != nil {
+		return nil, err
 	}
 
 	err = s.repo.SetUser(ctx, user)
 
 	if err != nil {
-		return nil, status.Error(codes.ResourceExhausted, err.Error())
+		return nil, repoError(err)
 	}
 
 	return nil, nil
 }
 
-func (s *TodoServiceServer) AddReminder(ctx context.Context, in *pb.Reminder) (*pb.ReminderId, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method AddReminder not implemented")
+func (s *TodoServiceServer) GetUser(ctx context.Context, in *pb.UserId) (_ *pb.User, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetUser with id %v: %v", in.GetId(), err)
+		}
+	}()
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(ctx, in.GetId())
+
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return user, nil
+}
+
+func (s *TodoServiceServer) CreateReminder(ctx context.Context, in *pb.Reminder) (_ *pb.ReminderId, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in CreateReminder with reminder %+v: %v", in, err)
+		} else {
+			log.Printf("CreateReminder with reminder %+v was successful", in)
+		}
+	}()
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.CreateReminder(ctx, in)
+
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return &pb.ReminderId{Id: id}, nil
+}
+
+func (s *TodoServiceServer) RemoveReminder(ctx context.Context, in *pb.ReminderId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RemoveReminder with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("RemoveReminder with id %v was successful", in.GetId())
+		}
+	}()
+
+	reminder, err := s.repo.GetReminder(ctx, in.GetId())
+
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	if err = s.authorizeReminder(ctx, reminder, pb.ReminderShare_EDITOR); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.RemoveReminder(ctx, in.GetId())
+
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
 }
-func (s *TodoServiceServer) RemoveReminder(ctx context.Context, in *pb.ReminderId) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RemoveReminder not implemented")
+
+func (s *TodoServiceServer) GetRemindersByUserId(in *pb.UserId, stream pb.TodoService_GetRemindersByUserIdServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetRemindersByUserId with id %v: %v", in.GetId(), err)
+		}
+	}()
+
+	if err = auth.AuthorizeUser(stream.Context(), in.GetId()); err != nil {
+		return err
+	}
+
+	reminders, err := s.repo.GetRemindersByUserId(stream.Context(), in.GetId())
+
+	if err != nil {
+		return repoError(err)
+	}
+
+	if s.shares != nil {
+		shared, err := s.shares.GetSharedReminders(stream.Context(), in.GetId())
+		if err != nil {
+			return repoError(err)
+		}
+
+		if len(shared) > 0 {
+			// The repo may return a cached slice, which must stay as it is.
+			reminders = append(slices.Clip(reminders), shared...)
+			slices.SortStableFunc(reminders, func(a, b *pb.Reminder) int {
+				return a.GetRemindTimestamp().AsTime().Compare(b.GetRemindTimestamp().AsTime())
+			})
+		}
+	}
+
+	for _, reminder := range reminders {
+		if err = stream.Send(reminder); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
-func (s *TodoServiceServer) GetRemindersByUserId(in *pb.UserId, in1 pb.TodoService_GetRemindersByUserIdServer) error {
-	return status.Errorf(codes.Unimplemented, "method GetRemindersByUserId not implemented")
+
+func (s *TodoServiceServer) WatchUserEvents(in *pb.WatchUserEventsRequest, stream pb.TodoService_WatchUserEventsServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in WatchUserEvents with request %+v: %v", in, err)
+		}
+	}()
+
+	ctx := stream.Context()
+
+	if s.events == nil {
+		return status.Error(codes.Unimplemented, "user events are not supported by this storage backend")
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return err
+	}
+
+	if err = validate(in); err != nil {
+		return err
+	}
+
+	// Subscribing first, events recorded from now on wake the stream up
+	// even if they are recorded before the first read.
+	wake, cancel := s.events.Subscribe(in.GetUserId())
+	defer cancel()
+
+	oldest, afterSeq, err := s.events.UserEventSeqRange(ctx)
+	if err != nil {
+		return repoError(err)
+	}
+
+	if in.AfterSeq != nil {
+		if oldest > 0 && in.GetAfterSeq() < oldest-1 {
+			return status.Errorf(codes.OutOfRange, "events after %d are no longer kept, the oldest one is %d", in.GetAfterSeq(), oldest)
+		}
+
+		afterSeq = in.GetAfterSeq()
+	}
+
+	for {
+		events, err := s.events.UserEvents(ctx, in.GetUserId(), afterSeq, eventsPageSize)
+		if err != nil {
+			return repoError(err)
+		}
+
+		for _, event := range events {
+			if err = stream.Send(event); err != nil {
+				return err
+			}
+
+			afterSeq = event.GetSeq()
+		}
+
+		if len(events) == eventsPageSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		}
+	}
 }
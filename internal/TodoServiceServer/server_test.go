@@ -3,34 +3,104 @@ package todoserviceserver
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"sync"
 	"testing"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/events"
 )
 
 type StubRepo struct {
-	SetUserFunc func(context.Context, *pb.User) error
+	SetUserFunc              func(context.Context, *pb.User) error
+	GetUserFunc              func(context.Context, int64) (*pb.User, error)
+	CreateReminderFunc       func(context.Context, *pb.Reminder) (int32, error)
+	CreateRemindersFunc      func(context.Context, int64, []*pb.Reminder) ([]int32, error)
+	GetReminderFunc          func(context.Context, int32) (*pb.Reminder, error)
+	RemoveReminderFunc       func(context.Context, int32) error
+	GetRemindersByUserIdFunc func(context.Context, int64) ([]*pb.Reminder, error)
 }
 
 func (sr *StubRepo) SetUser(ctx context.Context, user *pb.User) error {
 	return sr.SetUserFunc(ctx, user)
 }
 
-func server(ctx context.Context, sr *StubRepo) (pb.TodoServiceClient, func()) {
+func (sr *StubRepo) GetUser(ctx context.Context, id int64) (*pb.User, error) {
+	return sr.GetUserFunc(ctx, id)
+}
+
+func (sr *StubRepo) CreateReminder(ctx context.Context, reminder *pb.Reminder) (int32, error) {
+	return sr.CreateReminderFunc(ctx, reminder)
+}
+
+func (sr *StubRepo) CreateReminders(ctx context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error) {
+	return sr.CreateRemindersFunc(ctx, userId, reminders)
+}
+
+func (sr *StubRepo) GetReminder(ctx context.Context, id int32) (*pb.Reminder, error) {
+	return sr.GetReminderFunc(ctx, id)
+}
+
+func (sr *StubRepo) RemoveReminder(ctx context.Context, id int32) error {
+	return sr.RemoveReminderFunc(ctx, id)
+}
+
+func (sr *StubRepo) GetRemindersByUserId(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	return sr.GetRemindersByUserIdFunc(ctx, userId)
+}
+
+const (
+	serviceKey = auth.APIKeyPrefix + "service"
+	userKey    = auth.APIKeyPrefix + "user"
+	keyUserId  = 42
+)
+
+type StubKeyStore map[string]auth.Caller
+
+func (ks StubKeyStore) FindAPIKey(_ context.Context, hash []byte) (auth.Caller, error) {
+	caller, ok := ks[string(hash)]
+	if !ok {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+
+	return caller, nil
+}
+
+func withKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+}
+
+func server(ctx context.Context, sr *StubRepo, opts ...Option) (pb.TodoServiceClient, func()) {
 	buffer := 101024 * 1024
 	lis := bufconn.Listen(buffer)
 
-	baseServer := grpc.NewServer()
-	pb.RegisterTodoServiceServer(baseServer, New(sr))
+	userId := int64(keyUserId)
+	authenticator := auth.NewAuthenticator(StubKeyStore{
+		string(auth.HashAPIKey(serviceKey)): {Name: "test frontend"},
+		string(auth.HashAPIKey(userKey)):    {Name: "test user", UserID: &userId},
+	}, nil)
+
+	baseServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor(), audit.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor(), audit.StreamServerInterceptor()),
+	)
+	pb.RegisterTodoServiceServer(baseServer, New(sr, opts...))
 	go func() {
 		if err := baseServer.Serve(lis); err != nil {
 			log.Fatalf("error serving server: %v", err)
@@ -58,8 +128,12 @@ func server(ctx context.Context, sr *StubRepo) (pb.TodoServiceClient, func()) {
 	return client, closer
 }
 
+func channels(channels ...*pb.NotificationChannel) *pb.NotificationChannels {
+	return &pb.NotificationChannels{Channels: channels}
+}
+
 func TestTodoServiceServer_SetUser(t *testing.T) {
-	ctx := context.Background()
+	ctx := withKey(context.Background(), serviceKey)
 
 	usersCount := 0
 
@@ -94,6 +168,10 @@ func TestTodoServiceServer_SetUser(t *testing.T) {
 				LanguageCode: nil,
 				UtcOffset:    wrapperspb.Int32(314159),
 			},
+			{Id: 0, NotificationChannels: channels(&pb.NotificationChannel{Address: "42"})},
+			{Id: 0, NotificationChannels: channels(&pb.NotificationChannel{Kind: pb.NotificationChannel_TELEGRAM, Address: "@alice"})},
+			{Id: 0, NotificationChannels: channels(&pb.NotificationChannel{Kind: pb.NotificationChannel_EMAIL, Address: "alice"})},
+			{Id: 0, NotificationChannels: channels(&pb.NotificationChannel{Kind: pb.NotificationChannel_WEBHOOK, Address: "ftp://example.com"})},
 		}
 
 		for _, user := range users {
@@ -138,6 +216,12 @@ func TestTodoServiceServer_SetUser(t *testing.T) {
 			{Id: 0},
 			{Id: 0, LanguageCode: wrapperspb.String("en")},
 			{Id: 0, UtcOffset: wrapperspb.Int32(0)},
+			{Id: 0, NotificationChannels: channels()},
+			{Id: 0, NotificationChannels: channels(
+				&pb.NotificationChannel{Kind: pb.NotificationChannel_TELEGRAM, Address: "-100"},
+				&pb.NotificationChannel{Kind: pb.NotificationChannel_EMAIL, Address: "alice@example.com"},
+				&pb.NotificationChannel{Kind: pb.NotificationChannel_WEBHOOK, Address: "https://example.com/hook"},
+			)},
 		}
 
 		for _, user := range users {
@@ -171,8 +255,8 @@ func TestTodoServiceServer_SetUser(t *testing.T) {
 
 		_, err := client.SetUser(ctx, user)
 
-		if status.Code(err) != codes.ResourceExhausted {
-			t.Errorf("expected ResourceExhausted error with user %+v got %v", user, err)
+		if status.Code(err) != codes.Internal {
+			t.Errorf("expected Internal error with user %+v got %v", user, err)
 		}
 
 		backupUsersCount := usersCount
@@ -182,3 +266,409 @@ func TestTodoServiceServer_SetUser(t *testing.T) {
 		}
 	})
 }
+
+func TestTodoServiceServer_SetUser_auth(t *testing.T) {
+	usersCount := 0
+
+	sr := &StubRepo{SetUserFunc: func(context.Context, *pb.User) error {
+		usersCount++
+
+		return nil
+	}}
+
+	client, closer := server(context.Background(), sr)
+	defer closer()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		user *pb.User
+		code codes.Code
+	}{
+		{"no token", context.Background(), &pb.User{Id: keyUserId}, codes.Unauthenticated},
+		{"unknown key", withKey(context.Background(), auth.APIKeyPrefix+"revoked"), &pb.User{Id: keyUserId}, codes.Unauthenticated},
+		{"jwt without secret", withKey(context.Background(), "a.b.c"), &pb.User{Id: keyUserId}, codes.Unauthenticated},
+		{"personal key for other user", withKey(context.Background(), userKey), &pb.User{Id: keyUserId + 1}, codes.PermissionDenied},
+		{"personal key for own user", withKey(context.Background(), userKey), &pb.User{Id: keyUserId}, codes.OK},
+		{"service key for any user", withKey(context.Background(), serviceKey), &pb.User{Id: keyUserId + 1}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersCount = 0
+
+			_, err := client.SetUser(tt.ctx, tt.user)
+
+			if status.Code(err) != tt.code {
+				t.Errorf("expected %v got %v", tt.code, err)
+			}
+
+			expectedCalls := 0
+			if tt.code == codes.OK {
+				expectedCalls = 1
+			}
+			if usersCount != expectedCalls {
+				t.Errorf("Expected %v calls of repo.SetUser, got %v calls", expectedCalls, usersCount)
+			}
+		})
+	}
+}
+
+func TestTodoServiceServer_CreateReminder(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+
+	created := 0
+	sr := &StubRepo{CreateReminderFunc: func(context.Context, *pb.Reminder) (int32, error) {
+		created++
+
+		return 7, nil
+	}}
+
+	client, closer := server(ctx, sr)
+	defer closer()
+
+	inOneHour := timestamppb.New(time.Now().Add(time.Hour))
+
+	t.Run("wrong reminder", func(t *testing.T) {
+		reminders := []*pb.Reminder{
+			{UserId: 1, ReminderText: "", RemindTimestamp: inOneHour},
+			{UserId: 1, ReminderText: "buy milk", RemindTimestamp: timestamppb.New(time.Now().Add(-time.Hour))},
+		}
+
+		for _, reminder := range reminders {
+			_, err := client.CreateReminder(ctx, reminder)
+
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument with reminder %+v got %v", reminder, err)
+			}
+		}
+
+		if created != 0 {
+			t.Errorf("Did not expected calls of repo.CreateReminder, got %v calls", created)
+		}
+	})
+
+	t.Run("regular reminder", func(t *testing.T) {
+		id, err := client.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: inOneHour})
+
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if id.GetId() != 7 {
+			t.Errorf("expected id 7 got %v", id.GetId())
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		sr.CreateReminderFunc = func(context.Context, *pb.Reminder) (int32, error) {
+			return 0, fmt.Errorf("user 1: %w", ErrQuotaExceeded)
+		}
+
+		_, err := client.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: inOneHour})
+
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("expected ResourceExhausted got %v", err)
+		}
+
+		details := status.Convert(err).Details()
+		if len(details) != 1 {
+			t.Fatalf("expected QuotaFailure detail got %v", details)
+		}
+		if _, ok := details[0].(*errdetails.QuotaFailure); !ok {
+			t.Errorf("expected QuotaFailure detail got %T", details[0])
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		sr.CreateReminderFunc = func(context.Context, *pb.Reminder) (int32, error) {
+			return 0, fmt.Errorf("user 1: %w", ErrNotFound)
+		}
+
+		_, err := client.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: inOneHour})
+
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound got %v", err)
+		}
+	})
+}
+
+func TestTodoServiceServer_RemoveReminder(t *testing.T) {
+	removed := 0
+	sr := &StubRepo{
+		GetReminderFunc: func(_ context.Context, id int32) (*pb.Reminder, error) {
+			if id != 1 {
+				return nil, ErrNotFound
+			}
+
+			return &pb.Reminder{Id: id, UserId: keyUserId + 1}, nil
+		},
+		RemoveReminderFunc: func(context.Context, int32) error {
+			removed++
+
+			return nil
+		},
+	}
+
+	client, closer := server(context.Background(), sr)
+	defer closer()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      int32
+		code    codes.Code
+		removed int
+	}{
+		{"unknown reminder", withKey(context.Background(), serviceKey), 2, codes.NotFound, 0},
+		{"reminder of other user", withKey(context.Background(), userKey), 1, codes.PermissionDenied, 0},
+		{"service key", withKey(context.Background(), serviceKey), 1, codes.OK, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed = 0
+
+			_, err := client.RemoveReminder(tt.ctx, &pb.ReminderId{Id: tt.id})
+
+			if status.Code(err) != tt.code {
+				t.Errorf("expected %v got %v", tt.code, err)
+			}
+			if removed != tt.removed {
+				t.Errorf("Expected %v calls of repo.RemoveReminder, got %v calls", tt.removed, removed)
+			}
+		})
+	}
+}
+
+func TestTodoServiceServer_GetRemindersByUserId(t *testing.T) {
+	reminders := []*pb.Reminder{
+		{Id: 1, UserId: keyUserId, ReminderText: "first"},
+		{Id: 2, UserId: keyUserId, ReminderText: "second"},
+	}
+	sr := &StubRepo{GetRemindersByUserIdFunc: func(context.Context, int64) ([]*pb.Reminder, error) {
+		return reminders, nil
+	}}
+
+	client, closer := server(context.Background(), sr)
+	defer closer()
+
+	t.Run("own reminders", func(t *testing.T) {
+		stream, err := client.GetRemindersByUserId(withKey(context.Background(), userKey), &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		var got []*pb.Reminder
+		for {
+			reminder, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+			got = append(got, reminder)
+		}
+
+		if len(got) != len(reminders) {
+			t.Fatalf("expected %v reminders got %v", len(reminders), len(got))
+		}
+		for i := range got {
+			if !proto.Equal(got[i], reminders[i]) {
+				t.Errorf("expected reminder %+v got %+v", reminders[i], got[i])
+			}
+		}
+	})
+
+	t.Run("reminders of other user", func(t *testing.T) {
+		stream, err := client.GetRemindersByUserId(withKey(context.Background(), userKey), &pb.UserId{Id: keyUserId + 1})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+	})
+}
+
+func TestTodoServiceServer_GetUser(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+
+	sr := &StubRepo{GetUserFunc: func(_ context.Context, id int64) (*pb.User, error) {
+		if id != 1 {
+			return nil, ErrNotFound
+		}
+
+		return &pb.User{Id: 1, LanguageCode: wrapperspb.String("en")}, nil
+	}}
+
+	client, closer := server(ctx, sr)
+	defer closer()
+
+	user, err := client.GetUser(ctx, &pb.UserId{Id: 1})
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if user.GetLanguageCode().GetValue() != "en" || user.GetUtcOffset() != nil {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	if _, err := client.GetUser(ctx, &pb.UserId{Id: 2}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound got %v", err)
+	}
+}
+
+// StubEventStore keeps events in memory, oldest first.
+type StubEventStore struct {
+	mu     sync.Mutex
+	events []*pb.UserEvent
+	// read, when set, receives a value after UserEvents unless it holds one.
+	read chan struct{}
+}
+
+func (es *StubEventStore) UserEventSeqRange(context.Context) (oldest, latest int64, err error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if len(es.events) == 0 {
+		return 0, 0, nil
+	}
+
+	return es.events[0].GetSeq(), es.events[len(es.events)-1].GetSeq(), nil
+}
+
+func (es *StubEventStore) UserEvents(_ context.Context, userId, afterSeq int64, limit int) ([]*pb.UserEvent, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	var events []*pb.UserEvent
+	for _, event := range es.events {
+		if event.GetUserId() == userId && event.GetSeq() > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	select {
+	case es.read <- struct{}{}:
+	default:
+	}
+
+	return events, nil
+}
+
+func (es *StubEventStore) DeleteUserEvents(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (es *StubEventStore) add(userId int64) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.events = append(es.events, &pb.UserEvent{
+		Seq:    int64(len(es.events)) + 1,
+		UserId: userId,
+		Event:  &pb.UserEvent_ReminderCreated{ReminderCreated: &pb.Reminder{UserId: userId}},
+	})
+}
+
+func TestTodoServiceServer_WatchUserEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(withKey(context.Background(), serviceKey), 10*time.Second)
+	defer cancel()
+
+	store := &StubEventStore{}
+	feed := events.NewFeed(store)
+	for i := 0; i < eventsPageSize+5; i++ {
+		store.add(keyUserId)
+	}
+	store.add(keyUserId + 1)
+
+	client, closer := server(ctx, &StubRepo{}, WithEvents(feed))
+	defer closer()
+
+	recv := func(t *testing.T, stream pb.TodoService_WatchUserEventsClient, wantSeq int64) {
+		t.Helper()
+
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if event.GetSeq() != wantSeq || event.GetUserId() != keyUserId {
+			t.Fatalf("expected event %v of user %v got %+v", wantSeq, keyUserId, event)
+		}
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		afterSeq := int64(2)
+		stream, err := client.WatchUserEvents(ctx, &pb.WatchUserEventsRequest{UserId: keyUserId, AfterSeq: &afterSeq})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		for seq := afterSeq + 1; seq <= eventsPageSize+5; seq++ {
+			recv(t, stream, seq)
+		}
+
+		store.add(keyUserId)
+		feed.Notify(fmt.Sprint(keyUserId))
+		recv(t, stream, eventsPageSize+7)
+	})
+
+	t.Run("new events only", func(t *testing.T) {
+		read := make(chan struct{}, 1)
+		store.mu.Lock()
+		store.read = read
+		store.mu.Unlock()
+
+		stream, err := client.WatchUserEvents(ctx, &pb.WatchUserEventsRequest{UserId: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		// Wait for the first read of the stream, events added before would be
+		// skipped.
+		select {
+		case <-read:
+		case <-ctx.Done():
+			t.Fatalf("stream did not read events: %v", ctx.Err())
+		}
+		store.mu.Lock()
+		store.read = nil
+		store.mu.Unlock()
+
+		store.add(keyUserId + 1)
+		store.add(keyUserId)
+		feed.WakeAll()
+		recv(t, stream, eventsPageSize+9)
+	})
+
+	t.Run("purged events", func(t *testing.T) {
+		store.mu.Lock()
+		store.events = store.events[10:]
+		store.mu.Unlock()
+
+		afterSeq := int64(5)
+		stream, _ := client.WatchUserEvents(ctx, &pb.WatchUserEventsRequest{UserId: keyUserId, AfterSeq: &afterSeq})
+		if _, err := stream.Recv(); status.Code(err) != codes.OutOfRange {
+			t.Errorf("expected OutOfRange got %v", err)
+		}
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		userCtx := withKey(context.Background(), userKey)
+		stream, _ := client.WatchUserEvents(userCtx, &pb.WatchUserEventsRequest{UserId: keyUserId + 1})
+		if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+	})
+
+	t.Run("without events", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		stream, _ := client.WatchUserEvents(ctx, &pb.WatchUserEventsRequest{UserId: keyUserId})
+		if _, err := stream.Recv(); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected Unimplemented got %v", err)
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package todoserviceserver
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+// ShareStore keeps who reminders are shared with. Shares of removed
+// reminders are kept but neither listed as invitations nor shared
+// reminders.
+type ShareStore interface {
+	// ShareReminder shares the reminder with the recipient of share, or
+	// changes the role of its existing share, and returns the share. It
+	// fails with ErrNotFound when the reminder or the recipient user does not
+	// exist.
+	ShareReminder(ctx context.Context, share *pb.ReminderShare) (*pb.ReminderShare, error)
+	// AcceptReminderShare fails with ErrNotFound when there is no such share.
+	AcceptReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error
+	// RemoveReminderShare fails with ErrNotFound when there is no such share.
+	RemoveReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error
+	// GetReminderShares returns the shares of the reminder, oldest first.
+	GetReminderShares(ctx context.Context, reminderId int32) ([]*pb.ReminderShare, error)
+	// GetReminderInvitations returns the shares the recipient did not
+	// accept yet, oldest first.
+	GetReminderInvitations(ctx context.Context, recipient *pb.ShareRecipient) ([]*pb.ReminderShare, error)
+	// GetSharedReminders returns the reminders shared with the user who
+	// accepted them, ordered by their remind timestamp.
+	GetSharedReminders(ctx context.Context, userId int64) ([]*pb.Reminder, error)
+}
+
+// WithShares enables the sharing RPCs, which are unimplemented otherwise,
+// and lets GetRemindersByUserId list shared reminders.
+func WithShares(store ShareStore) Option {
+	return func(s *TodoServiceServer) {
+		s.shares = store
+	}
+}
+
+var errNoShares = status.Error(codes.Unimplemented, "sharing reminders is not supported by this storage backend")
+
+// authorizeReminder lets callers acting on the owner of the reminder
+// through, and users who accepted it being shared with them in at least
+// role.
+func (s *TodoServiceServer) authorizeReminder(ctx context.Context, reminder *pb.Reminder, role pb.ReminderShare_Role) error {
+	err := auth.AuthorizeUser(ctx, reminder.GetUserId())
+	if status.Code(err) != codes.PermissionDenied || s.shares == nil {
+		return err
+	}
+
+	// Only personal callers are denied.
+	caller, _ := auth.FromContext(ctx)
+
+	shares, sharesErr := s.shares.GetReminderShares(ctx, reminder.GetId())
+	if sharesErr != nil {
+		return repoError(sharesErr)
+	}
+
+	for _, share := range shares {
+		if share.GetRecipient().GetUserId() == *caller.UserID && share.GetAccepted() && share.GetRole() >= role {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// authorizeRecipient lets callers acting on a recipient user through, and
+// service accounts for group chats.
+func authorizeRecipient(ctx context.Context, recipient *pb.ShareRecipient) error {
+	if userId, ok := recipient.GetRecipient().(*pb.ShareRecipient_UserId); ok {
+		return auth.AuthorizeUser(ctx, userId.UserId)
+	}
+
+	return auth.AuthorizeService(ctx)
+}
+
+func (s *TodoServiceServer) ShareReminder(ctx context.Context, in *pb.ReminderShare) (_ *pb.ReminderShare, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ShareReminder with share %+v: %v", in, err)
+		} else {
+			log.Printf("ShareReminder with share %+v was successful", in)
+		}
+	}()
+
+	if s.shares == nil {
+		return nil, errNoShares
+	}
+
+	reminder, err := s.repo.GetReminder(ctx, in.GetReminderId())
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	if err = s.authorizeReminder(ctx, reminder, pb.ReminderShare_EDITOR); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case in.GetRecipient().GetUserId() == reminder.GetUserId():
+		return nil, status.Error(codes.InvalidArgument, "a reminder cannot be shared with its owner")
+	case in.GetRecipient().GetChatId() != 0 && in.GetRole() != pb.ReminderShare_VIEWER:
+		return nil, status.Error(codes.InvalidArgument, "group chats can only be viewers")
+	}
+
+	share, err := s.shares.ShareReminder(ctx, in)
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return share, nil
+}
+
+func (s *TodoServiceServer) AcceptReminderShare(ctx context.Context, in *pb.ReminderShareKey) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in AcceptReminderShare with share %+v: %v", in, err)
+		} else {
+			log.Printf("AcceptReminderShare with share %+v was successful", in)
+		}
+	}()
+
+	if s.shares == nil {
+		return nil, errNoShares
+	}
+
+	if err = authorizeRecipient(ctx, in.GetRecipient()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	if err = s.shares.AcceptReminderShare(ctx, in.GetReminderId(), in.GetRecipient()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *TodoServiceServer) RemoveReminderShare(ctx context.Context, in *pb.ReminderShareKey) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in RemoveReminderShare with share %+v: %v", in, err)
+		} else {
+			log.Printf("RemoveReminderShare with share %+v was successful", in)
+		}
+	}()
+
+	if s.shares == nil {
+		return nil, errNoShares
+	}
+
+	// Recipients leave on their own, owners and editors remove anyone.
+	if err = authorizeRecipient(ctx, in.GetRecipient()); err != nil {
+		reminder, repoErr := s.repo.GetReminder(ctx, in.GetReminderId())
+		if repoErr != nil {
+			return nil, repoError(repoErr)
+		}
+
+		if err = s.authorizeReminder(ctx, reminder, pb.ReminderShare_EDITOR); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	if err = s.shares.RemoveReminderShare(ctx, in.GetReminderId(), in.GetRecipient()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *TodoServiceServer) GetReminderShares(in *pb.ReminderId, stream pb.TodoService_GetReminderSharesServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetReminderShares with id %v: %v", in.GetId(), err)
+		}
+	}()
+
+	ctx := stream.Context()
+
+	if s.shares == nil {
+		return errNoShares
+	}
+
+	reminder, err := s.repo.GetReminder(ctx, in.GetId())
+	if err != nil {
+		return repoError(err)
+	}
+
+	if err = s.authorizeReminder(ctx, reminder, pb.ReminderShare_EDITOR); err != nil {
+		return err
+	}
+
+	shares, err := s.shares.GetReminderShares(ctx, in.GetId())
+	if err != nil {
+		return repoError(err)
+	}
+
+	for _, share := range shares {
+		if err = stream.Send(share); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *TodoServiceServer) GetReminderInvitations(in *pb.ShareRecipient, stream pb.TodoService_GetReminderInvitationsServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetReminderInvitations with recipient %+v: %v", in, err)
+		}
+	}()
+
+	ctx := stream.Context()
+
+	if s.shares == nil {
+		return errNoShares
+	}
+
+	if err = authorizeRecipient(ctx, in); err != nil {
+		return err
+	}
+
+	if err = validate(in); err != nil {
+		return err
+	}
+
+	shares, err := s.shares.GetReminderInvitations(ctx, in)
+	if err != nil {
+		return repoError(err)
+	}
+
+	for _, share := range shares {
+		if err = stream.Send(share); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
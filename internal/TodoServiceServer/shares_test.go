@@ -0,0 +1,359 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// StubShareStore keeps shares, and returns shared for GetSharedReminders.
+type StubShareStore struct {
+	mu     sync.Mutex
+	shares []*pb.ReminderShare
+	shared []*pb.Reminder
+}
+
+func (ss *StubShareStore) find(reminderId int32, recipient *pb.ShareRecipient) int {
+	return slices.IndexFunc(ss.shares, func(share *pb.ReminderShare) bool {
+		return share.GetReminderId() == reminderId && proto.Equal(share.GetRecipient(), recipient)
+	})
+}
+
+func (ss *StubShareStore) ShareReminder(_ context.Context, share *pb.ReminderShare) (*pb.ReminderShare, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if i := ss.find(share.GetReminderId(), share.GetRecipient()); i >= 0 {
+		ss.shares[i].Role = share.GetRole()
+
+		return ss.shares[i], nil
+	}
+
+	share = proto.Clone(share).(*pb.ReminderShare)
+	share.CreatedAt = timestamppb.Now()
+	ss.shares = append(ss.shares, share)
+
+	return share, nil
+}
+
+func (ss *StubShareStore) AcceptReminderShare(_ context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	i := ss.find(reminderId, recipient)
+	if i < 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, ErrNotFound)
+	}
+	ss.shares[i].Accepted = true
+
+	return nil
+}
+
+func (ss *StubShareStore) RemoveReminderShare(_ context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	i := ss.find(reminderId, recipient)
+	if i < 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, ErrNotFound)
+	}
+	ss.shares = slices.Delete(ss.shares, i, i+1)
+
+	return nil
+}
+
+func (ss *StubShareStore) GetReminderShares(_ context.Context, reminderId int32) ([]*pb.ReminderShare, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var shares []*pb.ReminderShare
+	for _, share := range ss.shares {
+		if share.GetReminderId() == reminderId {
+			shares = append(shares, share)
+		}
+	}
+
+	return shares, nil
+}
+
+func (ss *StubShareStore) GetReminderInvitations(_ context.Context, recipient *pb.ShareRecipient) ([]*pb.ReminderShare, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var shares []*pb.ReminderShare
+	for _, share := range ss.shares {
+		if !share.GetAccepted() && proto.Equal(share.GetRecipient(), recipient) {
+			shares = append(shares, share)
+		}
+	}
+
+	return shares, nil
+}
+
+func (ss *StubShareStore) GetSharedReminders(context.Context, int64) ([]*pb.Reminder, error) {
+	return ss.shared, nil
+}
+
+func userRecipient(userId int64) *pb.ShareRecipient {
+	return &pb.ShareRecipient{Recipient: &pb.ShareRecipient_UserId{UserId: userId}}
+}
+
+func chatRecipient(chatId int64) *pb.ShareRecipient {
+	return &pb.ShareRecipient{Recipient: &pb.ShareRecipient_ChatId{ChatId: chatId}}
+}
+
+// shareRepo knows reminder 1 of the user of userKey and reminders 2 to 4
+// of another user, shared with the user of userKey as an editor, a viewer
+// and an editor who did not accept.
+func shareRepo() (*StubRepo, *StubShareStore) {
+	repo := &StubRepo{
+		GetReminderFunc: func(_ context.Context, id int32) (*pb.Reminder, error) {
+			switch id {
+			case 1:
+				return &pb.Reminder{Id: id, UserId: keyUserId}, nil
+			case 2, 3, 4:
+				return &pb.Reminder{Id: id, UserId: keyUserId + 1}, nil
+			default:
+				return nil, fmt.Errorf("reminder %d: %w", id, ErrNotFound)
+			}
+		},
+		RemoveReminderFunc: func(context.Context, int32) error { return nil },
+	}
+
+	store := &StubShareStore{shares: []*pb.ReminderShare{
+		{ReminderId: 2, Recipient: userRecipient(keyUserId), Role: pb.ReminderShare_EDITOR, Accepted: true},
+		{ReminderId: 3, Recipient: userRecipient(keyUserId), Role: pb.ReminderShare_VIEWER, Accepted: true},
+		{ReminderId: 4, Recipient: userRecipient(keyUserId), Role: pb.ReminderShare_EDITOR},
+	}}
+
+	return repo, store
+}
+
+func TestTodoServiceServer_ShareReminder(t *testing.T) {
+	repo, store := shareRepo()
+	client, closer := server(context.Background(), repo, WithShares(store))
+	defer closer()
+
+	userCtx := withKey(context.Background(), userKey)
+	tests := []struct {
+		name  string
+		share *pb.ReminderShare
+		code  codes.Code
+	}{
+		{"own reminder", &pb.ReminderShare{ReminderId: 1, Recipient: userRecipient(7), Role: pb.ReminderShare_EDITOR}, codes.OK},
+		{"group chat", &pb.ReminderShare{ReminderId: 1, Recipient: chatRecipient(-100), Role: pb.ReminderShare_VIEWER}, codes.OK},
+		{"as an editor", &pb.ReminderShare{ReminderId: 2, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER}, codes.OK},
+		{"as a viewer", &pb.ReminderShare{ReminderId: 3, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER}, codes.PermissionDenied},
+		{"as an editor who did not accept", &pb.ReminderShare{ReminderId: 4, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER}, codes.PermissionDenied},
+		{"unknown reminder", &pb.ReminderShare{ReminderId: 5, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER}, codes.NotFound},
+		{"with the owner", &pb.ReminderShare{ReminderId: 2, Recipient: userRecipient(keyUserId + 1), Role: pb.ReminderShare_VIEWER}, codes.InvalidArgument},
+		{"group chat as an editor", &pb.ReminderShare{ReminderId: 1, Recipient: chatRecipient(-100), Role: pb.ReminderShare_EDITOR}, codes.InvalidArgument},
+		{"positive chat id", &pb.ReminderShare{ReminderId: 1, Recipient: chatRecipient(100), Role: pb.ReminderShare_VIEWER}, codes.InvalidArgument},
+		{"no recipient", &pb.ReminderShare{ReminderId: 1, Role: pb.ReminderShare_VIEWER}, codes.InvalidArgument},
+		{"no role", &pb.ReminderShare{ReminderId: 1, Recipient: userRecipient(7)}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share, err := client.ShareReminder(userCtx, tt.share)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected code %v got %v", tt.code, err)
+			}
+
+			if err == nil && (share.GetAccepted() || share.GetCreatedAt() == nil || share.GetRole() != tt.share.GetRole()) {
+				t.Errorf("expected an invitation got %v", share)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		client, closer := server(context.Background(), repo)
+		defer closer()
+
+		_, err := client.ShareReminder(userCtx, &pb.ReminderShare{ReminderId: 1, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER})
+		if status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected code %v got %v", codes.Unimplemented, err)
+		}
+	})
+}
+
+func TestTodoServiceServer_AcceptReminderShare(t *testing.T) {
+	repo, store := shareRepo()
+	store.shares = append(store.shares, &pb.ReminderShare{ReminderId: 1, Recipient: chatRecipient(-100), Role: pb.ReminderShare_VIEWER})
+	client, closer := server(context.Background(), repo, WithShares(store))
+	defer closer()
+
+	userCtx := withKey(context.Background(), userKey)
+	serviceCtx := withKey(context.Background(), serviceKey)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		key  *pb.ReminderShareKey
+		code codes.Code
+	}{
+		{"own invitation", userCtx, &pb.ReminderShareKey{ReminderId: 4, Recipient: userRecipient(keyUserId)}, codes.OK},
+		{"invitation of other user", userCtx, &pb.ReminderShareKey{ReminderId: 4, Recipient: userRecipient(7)}, codes.PermissionDenied},
+		{"group chat with a personal key", userCtx, &pb.ReminderShareKey{ReminderId: 1, Recipient: chatRecipient(-100)}, codes.PermissionDenied},
+		{"group chat with a service key", serviceCtx, &pb.ReminderShareKey{ReminderId: 1, Recipient: chatRecipient(-100)}, codes.OK},
+		{"no invitation", serviceCtx, &pb.ReminderShareKey{ReminderId: 1, Recipient: userRecipient(7)}, codes.NotFound},
+		{"no recipient", serviceCtx, &pb.ReminderShareKey{ReminderId: 1}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.AcceptReminderShare(tt.ctx, tt.key); status.Code(err) != tt.code {
+				t.Errorf("expected code %v got %v", tt.code, err)
+			}
+		})
+	}
+
+	if !store.shares[2].GetAccepted() || !store.shares[3].GetAccepted() {
+		t.Errorf("expected the invitations accepted got %v", store.shares)
+	}
+}
+
+func TestTodoServiceServer_RemoveReminderShare(t *testing.T) {
+	repo, store := shareRepo()
+	store.shares = append(store.shares,
+		&pb.ReminderShare{ReminderId: 1, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER},
+		&pb.ReminderShare{ReminderId: 3, Recipient: userRecipient(7), Role: pb.ReminderShare_VIEWER},
+	)
+	client, closer := server(context.Background(), repo, WithShares(store))
+	defer closer()
+
+	userCtx := withKey(context.Background(), userKey)
+	tests := []struct {
+		name string
+		key  *pb.ReminderShareKey
+		code codes.Code
+	}{
+		{"leave", &pb.ReminderShareKey{ReminderId: 4, Recipient: userRecipient(keyUserId)}, codes.OK},
+		{"of own reminder", &pb.ReminderShareKey{ReminderId: 1, Recipient: userRecipient(7)}, codes.OK},
+		{"as a viewer", &pb.ReminderShareKey{ReminderId: 3, Recipient: userRecipient(7)}, codes.PermissionDenied},
+		{"removed", &pb.ReminderShareKey{ReminderId: 1, Recipient: userRecipient(7)}, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.RemoveReminderShare(userCtx, tt.key); status.Code(err) != tt.code {
+				t.Errorf("expected code %v got %v", tt.code, err)
+			}
+		})
+	}
+
+	if len(store.shares) != 3 {
+		t.Errorf("expected 3 shares left got %v", store.shares)
+	}
+}
+
+func TestTodoServiceServer_GetReminderShares(t *testing.T) {
+	repo, store := shareRepo()
+	client, closer := server(context.Background(), repo, WithShares(store))
+	defer closer()
+
+	list := func(ctx context.Context, id int32) ([]*pb.ReminderShare, error) {
+		stream, err := client.GetReminderShares(ctx, &pb.ReminderId{Id: id})
+		if err != nil {
+			return nil, err
+		}
+
+		var shares []*pb.ReminderShare
+		for {
+			share, err := stream.Recv()
+			if err == io.EOF {
+				return shares, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			shares = append(shares, share)
+		}
+	}
+
+	userCtx := withKey(context.Background(), userKey)
+	if shares, err := list(userCtx, 2); err != nil || len(shares) != 1 || !proto.Equal(shares[0], store.shares[0]) {
+		t.Errorf("expected the share of reminder 2 got %v, %v", shares, err)
+	}
+	if _, err := list(userCtx, 3); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected code %v got %v", codes.PermissionDenied, err)
+	}
+
+	t.Run("invitations", func(t *testing.T) {
+		stream, err := client.GetReminderInvitations(userCtx, userRecipient(keyUserId))
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		invitation, err := stream.Recv()
+		if err != nil || invitation.GetReminderId() != 4 {
+			t.Errorf("expected the invitation to reminder 4 got %v, %v", invitation, err)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Errorf("expected a single invitation got %v", err)
+		}
+
+		stream, _ = client.GetReminderInvitations(userCtx, chatRecipient(-100))
+		if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected code %v got %v", codes.PermissionDenied, err)
+		}
+	})
+}
+
+func TestTodoServiceServer_sharedReminders(t *testing.T) {
+	repo, store := shareRepo()
+	now := time.Now()
+	repo.GetRemindersByUserIdFunc = func(context.Context, int64) ([]*pb.Reminder, error) {
+		return []*pb.Reminder{
+			{Id: 1, UserId: keyUserId, RemindTimestamp: timestamppb.New(now.Add(1 * time.Hour))},
+			{Id: 5, UserId: keyUserId, RemindTimestamp: timestamppb.New(now.Add(3 * time.Hour))},
+		}, nil
+	}
+	store.shared = []*pb.Reminder{{Id: 2, UserId: keyUserId + 1, RemindTimestamp: timestamppb.New(now.Add(2 * time.Hour))}}
+
+	client, closer := server(context.Background(), repo, WithShares(store))
+	defer closer()
+
+	userCtx := withKey(context.Background(), userKey)
+
+	t.Run("GetRemindersByUserId", func(t *testing.T) {
+		stream, err := client.GetRemindersByUserId(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		var ids []int32
+		for {
+			reminder, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+			ids = append(ids, reminder.GetId())
+		}
+
+		if expected := []int32{1, 2, 5}; !slices.Equal(ids, expected) {
+			t.Errorf("expected reminders %v got %v", expected, ids)
+		}
+	})
+
+	t.Run("RemoveReminder", func(t *testing.T) {
+		for id, code := range map[int32]codes.Code{2: codes.OK, 3: codes.PermissionDenied, 4: codes.PermissionDenied} {
+			if _, err := client.RemoveReminder(userCtx, &pb.ReminderId{Id: id}); status.Code(err) != code {
+				t.Errorf("reminder %d: expected code %v got %v", id, code, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package todoserviceserver
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// UserDataStore exports users and schedules their deletion, which
+// userdata.Deleter carries out.
+type UserDataStore interface {
+	// GetUserData returns everything kept about the user, ErrNotFound when
+	// the user does not exist.
+	GetUserData(ctx context.Context, userId int64) (*userdata.Data, error)
+	// ScheduleUserDeletion schedules the deletion of the user at deleteAt
+	// unless it is scheduled already, and returns when the user is deleted.
+	// It fails with ErrNotFound when the user does not exist.
+	ScheduleUserDeletion(ctx context.Context, userId int64, deleteAt time.Time) (time.Time, error)
+	// CancelUserDeletion fails with ErrNotFound when the deletion of the
+	// user is not scheduled.
+	CancelUserDeletion(ctx context.Context, userId int64) error
+}
+
+// WithUserData enables ExportUserData, DeleteUser and CancelUserDeletion,
+// which are unimplemented otherwise. Users are deleted gracePeriod after
+// DeleteUser.
+func WithUserData(store UserDataStore, gracePeriod time.Duration) Option {
+	return func(s *TodoServiceServer) {
+		s.userData = store
+		s.deletionGracePeriod = gracePeriod
+	}
+}
+
+var errNoUserData = status.Error(codes.Unimplemented, "user data export and deletion are not enabled")
+
+func (s *TodoServiceServer) ExportUserData(ctx context.Context, in *pb.ExportUserDataRequest) (_ *pb.UserDataArchive, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ExportUserData with request %+v: %v", in, err)
+		} else {
+			log.Printf("ExportUserData with request %+v was successful", in)
+		}
+	}()
+
+	if s.userData == nil {
+		return nil, errNoUserData
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	data, err := s.userData.GetUserData(ctx, in.GetUserId())
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	archive := &pb.UserDataArchive{}
+	name := "user-" + strconv.FormatInt(in.GetUserId(), 10)
+
+	var buf bytes.Buffer
+	if in.GetFormat() == pb.ExportUserDataRequest_ZIP {
+		archive.ContentType, archive.Filename = "application/zip", name+".zip"
+		err = userdata.WriteZIP(&buf, data, time.Now())
+	} else {
+		archive.ContentType, archive.Filename = "application/json", name+".json"
+		err = userdata.WriteJSON(&buf, data, time.Now())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	archive.Data = buf.Bytes()
+
+	return archive, nil
+}
+
+func (s *TodoServiceServer) DeleteUser(ctx context.Context, in *pb.UserId) (_ *pb.UserDeletion, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in DeleteUser with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("DeleteUser with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.userData == nil {
+		return nil, errNoUserData
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	deleteAt, err := s.userData.ScheduleUserDeletion(ctx, in.GetId(), time.Now().Add(s.deletionGracePeriod))
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	return &pb.UserDeletion{UserId: in.GetId(), DeleteAt: timestamppb.New(deleteAt)}, nil
+}
+
+func (s *TodoServiceServer) CancelUserDeletion(ctx context.Context, in *pb.UserId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in CancelUserDeletion with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("CancelUserDeletion with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.userData == nil {
+		return nil, errNoUserData
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.userData.CancelUserDeletion(ctx, in.GetId()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,178 @@
+package todoserviceserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// StubUserDataStore knows the users in deleteAt, zero for users whose
+// deletion is not scheduled.
+type StubUserDataStore struct {
+	mu       sync.Mutex
+	deleteAt map[int64]time.Time
+}
+
+func (us *StubUserDataStore) GetUserData(_ context.Context, userId int64) (*userdata.Data, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	deleteAt, ok := us.deleteAt[userId]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", userId, ErrNotFound)
+	}
+
+	data := &userdata.Data{
+		Profile:   userdata.Profile{ID: userId},
+		Reminders: []userdata.Reminder{{ID: 1, Text: "water the plants"}},
+	}
+	if !deleteAt.IsZero() {
+		data.Settings.DeleteAt = &deleteAt
+	}
+
+	return data, nil
+}
+
+func (us *StubUserDataStore) ScheduleUserDeletion(_ context.Context, userId int64, deleteAt time.Time) (time.Time, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	scheduled, ok := us.deleteAt[userId]
+	if !ok {
+		return time.Time{}, fmt.Errorf("user %d: %w", userId, ErrNotFound)
+	}
+	if scheduled.IsZero() {
+		us.deleteAt[userId] = deleteAt
+		scheduled = deleteAt
+	}
+
+	return scheduled, nil
+}
+
+func (us *StubUserDataStore) CancelUserDeletion(_ context.Context, userId int64) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if us.deleteAt[userId].IsZero() {
+		return fmt.Errorf("deletion of user %d: %w", userId, ErrNotFound)
+	}
+	us.deleteAt[userId] = time.Time{}
+
+	return nil
+}
+
+func TestTodoServiceServer_ExportUserData(t *testing.T) {
+	ctx := withKey(context.Background(), userKey)
+
+	store := &StubUserDataStore{deleteAt: map[int64]time.Time{keyUserId: {}, keyUserId + 1: {}}}
+	client, closer := server(ctx, &StubRepo{}, WithUserData(store, time.Hour))
+	defer closer()
+
+	t.Run("json", func(t *testing.T) {
+		archive, err := client.ExportUserData(ctx, &pb.ExportUserDataRequest{UserId: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if archive.GetContentType() != "application/json" || archive.GetFilename() != fmt.Sprintf("user-%d.json", keyUserId) {
+			t.Errorf("expected a JSON file got %q named %q", archive.GetContentType(), archive.GetFilename())
+		}
+
+		var document struct {
+			Version   int                 `json:"version"`
+			UserID    int64               `json:"user_id"`
+			Reminders []userdata.Reminder `json:"reminders"`
+		}
+		if err := json.Unmarshal(archive.GetData(), &document); err != nil {
+			t.Fatalf("cannot decode export: %v", err)
+		}
+		if document.Version != userdata.Version || document.UserID != keyUserId || len(document.Reminders) != 1 {
+			t.Errorf("expected version %d of user %d with a reminder got %s", userdata.Version, keyUserId, archive.GetData())
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		archive, err := client.ExportUserData(ctx, &pb.ExportUserDataRequest{UserId: keyUserId, Format: pb.ExportUserDataRequest_ZIP})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if archive.GetContentType() != "application/zip" || archive.GetFilename() != fmt.Sprintf("user-%d.zip", keyUserId) {
+			t.Errorf("expected a ZIP file got %q named %q", archive.GetContentType(), archive.GetFilename())
+		}
+		if _, err := zip.NewReader(bytes.NewReader(archive.GetData()), int64(len(archive.GetData()))); err != nil {
+			t.Errorf("expected a ZIP archive got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := client.ExportUserData(ctx, &pb.ExportUserDataRequest{UserId: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied got %v", err)
+		}
+		if _, err := client.ExportUserData(ctx, &pb.ExportUserDataRequest{UserId: keyUserId, Format: 7}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for an unknown format got %v", err)
+		}
+
+		serviceCtx := withKey(context.Background(), serviceKey)
+		if _, err := client.ExportUserData(serviceCtx, &pb.ExportUserDataRequest{UserId: keyUserId + 2}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for an unknown user got %v", err)
+		}
+	})
+}
+
+func TestTodoServiceServer_DeleteUser(t *testing.T) {
+	ctx := withKey(context.Background(), userKey)
+
+	store := &StubUserDataStore{deleteAt: map[int64]time.Time{keyUserId: {}}}
+	client, closer := server(ctx, &StubRepo{}, WithUserData(store, 30*24*time.Hour))
+	defer closer()
+
+	if _, err := client.CancelUserDeletion(ctx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound cancelling an unscheduled deletion got %v", err)
+	}
+
+	before := time.Now()
+	deletion, err := client.DeleteUser(ctx, &pb.UserId{Id: keyUserId})
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if deleteAt := deletion.GetDeleteAt().AsTime(); deleteAt.Before(before.Add(30*24*time.Hour)) || deletion.GetUserId() != keyUserId {
+		t.Errorf("expected deletion in 30 days got %v", deletion)
+	}
+
+	again, err := client.DeleteUser(ctx, &pb.UserId{Id: keyUserId})
+	if err != nil || !again.GetDeleteAt().AsTime().Equal(deletion.GetDeleteAt().AsTime()) {
+		t.Errorf("expected the scheduled deletion %v got %v, %v", deletion, again, err)
+	}
+
+	if _, err := client.CancelUserDeletion(ctx, &pb.UserId{Id: keyUserId}); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if !store.deleteAt[keyUserId].IsZero() {
+		t.Errorf("expected the deletion of user %d cancelled", keyUserId)
+	}
+
+	if _, err := client.DeleteUser(ctx, &pb.UserId{Id: keyUserId + 1}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied got %v", err)
+	}
+
+	t.Run("without user data", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		if _, err := client.DeleteUser(ctx, &pb.UserId{Id: keyUserId}); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected Unimplemented got %v", err)
+		}
+	})
+}
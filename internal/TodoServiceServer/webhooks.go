@@ -0,0 +1,227 @@
+package todoserviceserver
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+// WebhookStore keeps the webhooks of users and their dead letters,
+// see package webhooks.
+type WebhookStore interface {
+	// CreateWebhook returns the id of the new webhook. The secret is set.
+	CreateWebhook(context.Context, *pb.Webhook) (int64, error)
+	// GetWebhook returns the webhook without its secret,
+	// as does GetWebhooksByUserId.
+	GetWebhook(context.Context, int64) (*pb.Webhook, error)
+	GetWebhooksByUserId(context.Context, int64) ([]*pb.Webhook, error)
+	// UpdateWebhook replaces the url and events of the webhook, and enabled
+	// when it is set.
+	UpdateWebhook(context.Context, *pb.Webhook) error
+	DeleteWebhook(context.Context, int64) error
+	GetWebhookDeadLetter(context.Context, int64) (*pb.WebhookDeadLetter, error)
+	GetWebhookDeadLettersByUserId(context.Context, int64) ([]*pb.WebhookDeadLetter, error)
+	ReplayWebhookDeadLetter(context.Context, int64) error
+}
+
+// WithWebhooks enables the webhook RPCs, which are unimplemented otherwise.
+func WithWebhooks(store WebhookStore) Option {
+	return func(s *TodoServiceServer) {
+		s.webhooks = store
+	}
+}
+
+var errNoWebhooks = status.Error(codes.Unimplemented, "webhooks are not supported by this storage backend")
+
+func (s *TodoServiceServer) CreateWebhook(ctx context.Context, in *pb.Webhook) (_ *pb.Webhook, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in CreateWebhook with webhook %+v: %v", in, err)
+		} else {
+			log.Printf("CreateWebhook with webhook %+v was successful", in)
+		}
+	}()
+
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+
+	if err = auth.AuthorizeUser(ctx, in.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	webhook := proto.Clone(in).(*pb.Webhook)
+	webhook.Enabled = proto.Bool(true)
+	webhook.DisabledReason = ""
+	if webhook.Secret, err = webhooks.NewSecret(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if webhook.Id, err = s.webhooks.CreateWebhook(ctx, webhook); err != nil {
+		return nil, repoError(err)
+	}
+
+	return webhook, nil
+}
+
+// authorizeWebhook returns the webhook with the given id if the caller in
+// ctx may act on its user.
+func (s *TodoServiceServer) authorizeWebhook(ctx context.Context, id int64) (*pb.Webhook, error) {
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+
+	webhook, err := s.webhooks.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	if err = auth.AuthorizeUser(ctx, webhook.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (s *TodoServiceServer) UpdateWebhook(ctx context.Context, in *pb.Webhook) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in UpdateWebhook with webhook %+v: %v", in, err)
+		} else {
+			log.Printf("UpdateWebhook with webhook %+v was successful", in)
+		}
+	}()
+
+	if _, err = s.authorizeWebhook(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err = validate(in); err != nil {
+		return nil, err
+	}
+
+	if err = s.webhooks.UpdateWebhook(ctx, in); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *TodoServiceServer) DeleteWebhook(ctx context.Context, in *pb.WebhookId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in DeleteWebhook with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("DeleteWebhook with id %v was successful", in.GetId())
+		}
+	}()
+
+	if _, err = s.authorizeWebhook(ctx, in.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.webhooks.DeleteWebhook(ctx, in.GetId()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *TodoServiceServer) GetWebhooksByUserId(in *pb.UserId, stream pb.TodoService_GetWebhooksByUserIdServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetWebhooksByUserId with id %v: %v", in.GetId(), err)
+		}
+	}()
+
+	if s.webhooks == nil {
+		return errNoWebhooks
+	}
+
+	if err = auth.AuthorizeUser(stream.Context(), in.GetId()); err != nil {
+		return err
+	}
+
+	hooks, err := s.webhooks.GetWebhooksByUserId(stream.Context(), in.GetId())
+	if err != nil {
+		return repoError(err)
+	}
+
+	for _, webhook := range hooks {
+		if err = stream.Send(webhook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *TodoServiceServer) GetWebhookDeadLettersByUserId(in *pb.UserId, stream pb.TodoService_GetWebhookDeadLettersByUserIdServer) (err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in GetWebhookDeadLettersByUserId with id %v: %v", in.GetId(), err)
+		}
+	}()
+
+	if s.webhooks == nil {
+		return errNoWebhooks
+	}
+
+	if err = auth.AuthorizeUser(stream.Context(), in.GetId()); err != nil {
+		return err
+	}
+
+	deadLetters, err := s.webhooks.GetWebhookDeadLettersByUserId(stream.Context(), in.GetId())
+	if err != nil {
+		return repoError(err)
+	}
+
+	for _, deadLetter := range deadLetters {
+		if err = stream.Send(deadLetter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *TodoServiceServer) ReplayWebhookDeadLetter(ctx context.Context, in *pb.WebhookDeadLetterId) (_ *emptypb.Empty, err error) {
+	defer func() {
+		if err != nil {
+			log.Printf("Error in ReplayWebhookDeadLetter with id %v: %v", in.GetId(), err)
+		} else {
+			log.Printf("ReplayWebhookDeadLetter with id %v was successful", in.GetId())
+		}
+	}()
+
+	if s.webhooks == nil {
+		return nil, errNoWebhooks
+	}
+
+	deadLetter, err := s.webhooks.GetWebhookDeadLetter(ctx, in.GetId())
+	if err != nil {
+		return nil, repoError(err)
+	}
+
+	if err = auth.AuthorizeUser(ctx, deadLetter.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.webhooks.ReplayWebhookDeadLetter(ctx, in.GetId()); err != nil {
+		return nil, repoError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,262 @@
+package todoserviceserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// StubWebhookStore keeps webhooks in memory.
+type StubWebhookStore struct {
+	mu          sync.Mutex
+	webhooks    map[int64]*pb.Webhook
+	deadLetters map[int64]*pb.WebhookDeadLetter
+	replayed    []int64
+}
+
+func (ws *StubWebhookStore) CreateWebhook(_ context.Context, webhook *pb.Webhook) (int64, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	id := int64(len(ws.webhooks) + 1)
+	ws.webhooks[id] = proto.Clone(webhook).(*pb.Webhook)
+	ws.webhooks[id].Id = id
+
+	return id, nil
+}
+
+func (ws *StubWebhookStore) GetWebhook(_ context.Context, id int64) (*pb.Webhook, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	webhook, ok := ws.webhooks[id]
+	if !ok {
+		return nil, fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+	}
+
+	webhook = proto.Clone(webhook).(*pb.Webhook)
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+func (ws *StubWebhookStore) GetWebhooksByUserId(ctx context.Context, userId int64) ([]*pb.Webhook, error) {
+	var hooks []*pb.Webhook
+	for id := int64(1); id <= int64(len(ws.webhooks)); id++ {
+		webhook, err := ws.GetWebhook(ctx, id)
+		if err == nil && webhook.GetUserId() == userId {
+			hooks = append(hooks, webhook)
+		}
+	}
+
+	return hooks, nil
+}
+
+func (ws *StubWebhookStore) UpdateWebhook(_ context.Context, webhook *pb.Webhook) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	stored := ws.webhooks[webhook.GetId()]
+	stored.Url, stored.Events = webhook.GetUrl(), webhook.GetEvents()
+	if webhook.Enabled != nil {
+		stored.Enabled = webhook.Enabled
+	}
+
+	return nil
+}
+
+func (ws *StubWebhookStore) DeleteWebhook(_ context.Context, id int64) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	delete(ws.webhooks, id)
+
+	return nil
+}
+
+func (ws *StubWebhookStore) GetWebhookDeadLetter(_ context.Context, id int64) (*pb.WebhookDeadLetter, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	deadLetter, ok := ws.deadLetters[id]
+	if !ok {
+		return nil, fmt.Errorf("dead letter %d: %w", id, ErrNotFound)
+	}
+
+	return deadLetter, nil
+}
+
+func (ws *StubWebhookStore) GetWebhookDeadLettersByUserId(_ context.Context, userId int64) ([]*pb.WebhookDeadLetter, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var deadLetters []*pb.WebhookDeadLetter
+	for _, deadLetter := range ws.deadLetters {
+		if deadLetter.GetUserId() == userId {
+			deadLetters = append(deadLetters, deadLetter)
+		}
+	}
+
+	return deadLetters, nil
+}
+
+func (ws *StubWebhookStore) ReplayWebhookDeadLetter(_ context.Context, id int64) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	delete(ws.deadLetters, id)
+	ws.replayed = append(ws.replayed, id)
+
+	return nil
+}
+
+func TestTodoServiceServer_webhooks(t *testing.T) {
+	ctx := withKey(context.Background(), serviceKey)
+	userCtx := withKey(context.Background(), userKey)
+
+	store := &StubWebhookStore{
+		webhooks: make(map[int64]*pb.Webhook),
+		deadLetters: map[int64]*pb.WebhookDeadLetter{
+			1: {Id: 1, WebhookId: 1, UserId: keyUserId},
+			2: {Id: 2, WebhookId: 2, UserId: keyUserId + 1},
+		},
+	}
+
+	client, closer := server(ctx, &StubRepo{}, WithWebhooks(store))
+	defer closer()
+
+	var created *pb.Webhook
+
+	t.Run("create", func(t *testing.T) {
+		var err error
+		created, err = client.CreateWebhook(userCtx, &pb.Webhook{
+			UserId: keyUserId,
+			Url:    "https://example.com/hook",
+			Events: []string{"reminder_fired"},
+			Secret: "chosen by the client",
+		})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if created.GetId() != 1 || !created.GetEnabled() || !strings.HasPrefix(created.GetSecret(), "whsec_") {
+			t.Errorf("expected enabled webhook 1 with a generated secret got %+v", created)
+		}
+	})
+
+	t.Run("invalid webhook", func(t *testing.T) {
+		for _, webhook := range []*pb.Webhook{
+			{UserId: keyUserId, Url: "example.com/hook"},
+			{UserId: keyUserId, Url: "ftp://example.com/hook"},
+			{UserId: keyUserId, Url: "https://example.com/hook", Events: []string{"reminder_snoozed"}},
+			{UserId: keyUserId, Url: "https://example.com/hook", Events: []string{"reminder_fired", "reminder_fired"}},
+		} {
+			if _, err := client.CreateWebhook(ctx, webhook); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument with webhook %+v got %v", webhook, err)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		stream, err := client.GetWebhooksByUserId(ctx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		webhook, err := stream.Recv()
+		if err != nil || webhook.GetId() != created.GetId() || webhook.GetSecret() != "" {
+			t.Errorf("expected webhook %d without its secret got %+v, %v", created.GetId(), webhook, err)
+		}
+		if _, err := stream.Recv(); err != io.EOF {
+			t.Errorf("expected EOF got %v", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		update := &pb.Webhook{Id: created.GetId(), UserId: keyUserId, Url: "https://example.com/other"}
+		if _, err := client.UpdateWebhook(userCtx, update); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if got := store.webhooks[created.GetId()]; got.GetUrl() != update.GetUrl() || !got.GetEnabled() {
+			t.Errorf("expected updated webhook left enabled got %+v", got)
+		}
+
+		update.Enabled = proto.Bool(false)
+		if _, err := client.UpdateWebhook(userCtx, update); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if got := store.webhooks[created.GetId()]; got.GetEnabled() {
+			t.Errorf("expected disabled webhook got %+v", got)
+		}
+
+		if _, err := client.UpdateWebhook(ctx, &pb.Webhook{Id: 100, Url: "https://example.com"}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound got %v", err)
+		}
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		stream, err := client.GetWebhookDeadLettersByUserId(userCtx, &pb.UserId{Id: keyUserId})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if deadLetter, err := stream.Recv(); err != nil || deadLetter.GetId() != 1 {
+			t.Errorf("expected dead letter 1 got %+v, %v", deadLetter, err)
+		}
+
+		if _, err := client.ReplayWebhookDeadLetter(userCtx, &pb.WebhookDeadLetterId{Id: 2}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied replaying a dead letter of another user got %v", err)
+		}
+		if _, err := client.ReplayWebhookDeadLetter(userCtx, &pb.WebhookDeadLetterId{Id: 1}); err != nil {
+			t.Errorf("did not expect error got %v", err)
+		}
+		if len(store.replayed) != 1 || store.replayed[0] != 1 {
+			t.Errorf("expected dead letter 1 to be replayed got %v", store.replayed)
+		}
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		other, err := client.CreateWebhook(ctx, &pb.Webhook{UserId: keyUserId + 1, Url: "https://example.com"})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if _, err := client.CreateWebhook(userCtx, &pb.Webhook{UserId: keyUserId + 1, Url: "https://example.com"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied creating got %v", err)
+		}
+		// The user in the request does not matter, the stored one does.
+		if _, err := client.UpdateWebhook(userCtx, &pb.Webhook{Id: other.GetId(), UserId: keyUserId, Url: "https://example.com"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied updating got %v", err)
+		}
+		if _, err := client.DeleteWebhook(userCtx, &pb.WebhookId{Id: other.GetId()}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied deleting got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if _, err := client.DeleteWebhook(userCtx, &pb.WebhookId{Id: created.GetId()}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if _, ok := store.webhooks[created.GetId()]; ok {
+			t.Errorf("expected webhook %d to be deleted", created.GetId())
+		}
+	})
+
+	t.Run("without webhooks", func(t *testing.T) {
+		client, closer := server(ctx, &StubRepo{})
+		defer closer()
+
+		if _, err := client.CreateWebhook(ctx, &pb.Webhook{UserId: keyUserId, Url: "https://example.com"}); status.Code(err) != codes.Unimplemented {
+			t.Errorf("expected Unimplemented got %v", err)
+		}
+	})
+}
@@ -0,0 +1,291 @@
+// Package admin implements the maintenance commands operators run with the
+// server binary directly against the storage backend: inspecting a user,
+// cancelling reminders in bulk, requeueing failed deliveries, purging
+// removed reminders and printing database statistics.
+//
+// Commands changing data list what they are about to change and ask for
+// confirmation first. In dry-run mode they stop after the list.
+package admin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type Admin struct {
+	store   Store
+	out     io.Writer
+	dryRun  bool
+	confirm func(question string) bool
+}
+
+type Option func(*Admin)
+
+// WithDryRun makes commands report what they would change without
+// changing it.
+func WithDryRun(dryRun bool) Option {
+	return func(a *Admin) {
+		a.dryRun = dryRun
+	}
+}
+
+// WithConfirm replaces the prompt on the standard input asking whether to
+// go on with a change, e.g. with one always saying yes.
+func WithConfirm(confirm func(question string) bool) Option {
+	return func(a *Admin) {
+		a.confirm = confirm
+	}
+}
+
+// New returns commands printing to out.
+func New(store Store, out io.Writer, opts ...Option) *Admin {
+	a := &Admin{store: store, out: out, confirm: Prompt(os.Stdin, os.Stderr)}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Prompt returns a confirmation asking questions on out and taking
+// "y" or "yes" read from in for an answer. Anything else is a no.
+func Prompt(in io.Reader, out io.Writer) func(string) bool {
+	reader := bufio.NewReader(in)
+
+	return func(question string) bool {
+		fmt.Fprintf(out, "%s [y/N] ", question)
+
+		answer, _ := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// proceed reports whether to apply verb to n items, printing why not
+// otherwise.
+func (a *Admin) proceed(n int64, verb, items string) bool {
+	switch {
+	case n == 0:
+		fmt.Fprintf(a.out, "No %s to %s\n", items, verb)
+
+		return false
+	case a.dryRun:
+		fmt.Fprintf(a.out, "Dry run, would %s %d %s\n", verb, n, items)
+
+		return false
+	case !a.confirm(fmt.Sprintf("%s %d %s?", capitalize(verb), n, items)):
+		fmt.Fprintln(a.out, "Aborted")
+
+		return false
+	}
+
+	return true
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// ShowUser prints the settings, reminders and failed deliveries of a user.
+func (a *Admin) ShowUser(ctx context.Context, id int64) error {
+	user, err := a.store.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	language, offset := "-", "-"
+	if user.GetLanguageCode() != nil {
+		language = user.GetLanguageCode().GetValue()
+	}
+	if user.GetUtcOffset() != nil {
+		offset = strconv.Itoa(int(user.GetUtcOffset().GetValue()))
+	}
+
+	fmt.Fprintf(a.out, "User %d, language %s, UTC offset %s\n", user.GetId(), language, offset)
+	for _, channel := range user.GetNotificationChannels().GetChannels() {
+		fmt.Fprintf(a.out, "Notified by %s at %s\n", channel.GetKind(), channel.GetAddress())
+	}
+
+	reminders, err := a.store.FindReminders(ctx, ReminderFilter{UserID: id})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "\n%d reminders\n", len(reminders))
+	if err := a.printReminders(reminders); err != nil {
+		return err
+	}
+
+	deliveries, err := a.store.FindFailedDeliveries(ctx, DeliveryFilter{UserID: id})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "\n%d failed deliveries\n", len(deliveries))
+
+	return a.printDeliveries(deliveries)
+}
+
+func (a *Admin) ListReminders(ctx context.Context, filter ReminderFilter) error {
+	reminders, err := a.store.FindReminders(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return a.printReminders(reminders)
+}
+
+// CancelReminders removes the reminders matching filter.
+func (a *Admin) CancelReminders(ctx context.Context, filter ReminderFilter) error {
+	reminders, err := a.store.FindReminders(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := a.printReminders(reminders); err != nil {
+		return err
+	}
+
+	if !a.proceed(int64(len(reminders)), "cancel", "reminders") {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(reminders))
+	for _, reminder := range reminders {
+		ids = append(ids, reminder.ID)
+	}
+
+	cancelled, err := a.store.CancelReminders(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Cancelled %d reminders\n", cancelled)
+
+	return nil
+}
+
+// RequeueDeliveries makes failed deliveries matching filter pending again,
+// with as many attempts left as new ones.
+func (a *Admin) RequeueDeliveries(ctx context.Context, filter DeliveryFilter) error {
+	deliveries, err := a.store.FindFailedDeliveries(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err := a.printDeliveries(deliveries); err != nil {
+		return err
+	}
+
+	if !a.proceed(int64(len(deliveries)), "requeue", "deliveries") {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+
+	requeued, err := a.store.RequeueDeliveries(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Requeued %d deliveries\n", requeued)
+
+	return nil
+}
+
+// Purge deletes reminders removed before the given time for good.
+func (a *Admin) Purge(ctx context.Context, before time.Time) error {
+	n, err := a.store.CountRemovedReminders(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "%d reminders removed before %s\n", n, before.Format(time.DateTime))
+	if !a.proceed(n, "purge", "reminders") {
+		return nil
+	}
+
+	purged, err := a.store.PurgeRemovedReminders(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Purged %d reminders\n", purged)
+
+	return nil
+}
+
+func (a *Admin) Stats(ctx context.Context) error {
+	stats, err := a.store.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Users\t%d\n", stats.Users)
+	fmt.Fprintf(w, "Pending reminders\t%d\n", stats.PendingReminders)
+	fmt.Fprintf(w, "Fired reminders\t%d\n", stats.FiredReminders)
+	fmt.Fprintf(w, "Removed reminders\t%d\n", stats.RemovedReminders)
+	for _, status := range []string{"pending", "sent", "failed"} {
+		fmt.Fprintf(w, "%s deliveries\t%d\n", capitalize(status), stats.Deliveries[status])
+	}
+	fmt.Fprintf(w, "Database size\t%.1f MiB\n", float64(stats.SizeBytes)/(1<<20))
+
+	return w.Flush()
+}
+
+func (a *Admin) printReminders(reminders []Reminder) error {
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER\tREMIND AT\tFIRED\tCREATED\tTEXT")
+	for _, reminder := range reminders {
+		fired := ""
+		if reminder.FiredAt != nil {
+			fired = reminder.FiredAt.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n", reminder.ID, reminder.UserID, reminder.RemindAt.Format(time.DateTime),
+			fired, reminder.CreatedAt.Format(time.DateTime), truncate(reminder.Text, 40))
+	}
+
+	return w.Flush()
+}
+
+func (a *Admin) printDeliveries(deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREMINDER\tUSER\tCHANNEL\tADDRESS\tATTEMPTS\tFAILED\tERROR")
+	for _, delivery := range deliveries {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\t%d\t%s\t%s\n", delivery.ID, delivery.ReminderID, delivery.UserID, delivery.Channel,
+			delivery.Address, delivery.Attempts, delivery.FailedAt.Format(time.DateTime), truncate(delivery.LastError, 60))
+	}
+
+	return w.Flush()
+}
+
+// truncate keeps tables readable with long texts.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+
+	return s
+}
@@ -0,0 +1,180 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/sqliterepo"
+)
+
+// store returns a database with user 42 and three of its reminders.
+func store(t *testing.T) *sqliterepo.SqliteRepo {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := sqliterepo.Open(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := sqliterepo.New(db)
+	if err := repo.Migrate(ctx); err != nil {
+		t.Fatalf("cannot migrate database: %v", err)
+	}
+
+	if err := repo.SetUser(ctx, &pb.User{Id: 42, LanguageCode: wrapperspb.String("en")}); err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+	for _, text := range []string{"spam 1", "spam 2", "water the plants"} {
+		reminder := &pb.Reminder{UserId: 42, ReminderText: text, RemindTimestamp: timestamppb.New(time.Now().Add(time.Hour))}
+		if _, err := repo.CreateReminder(ctx, reminder); err != nil {
+			t.Fatalf("cannot create reminder: %v", err)
+		}
+	}
+
+	return repo
+}
+
+// answer confirms with the given answer, counting the questions.
+func answer(yes bool, asked *int) admin.Option {
+	return admin.WithConfirm(func(string) bool {
+		*asked++
+
+		return yes
+	})
+}
+
+func TestAdmin_CancelReminders(t *testing.T) {
+	ctx := context.Background()
+	filter := admin.ReminderFilter{Text: "spam"}
+
+	tests := []struct {
+		name      string
+		dryRun    bool
+		yes       bool
+		asked     int
+		remaining int
+		output    string
+	}{
+		{"dry run", true, true, 0, 3, "Dry run, would cancel 2 reminders"},
+		{"declined", false, false, 1, 3, "Aborted"},
+		{"confirmed", false, true, 1, 1, "Cancelled 2 reminders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := store(t)
+			var out bytes.Buffer
+			asked := 0
+
+			a := admin.New(repo, &out, admin.WithDryRun(tt.dryRun), answer(tt.yes, &asked))
+			if err := a.CancelReminders(ctx, filter); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			if asked != tt.asked {
+				t.Errorf("expected %d questions got %d", tt.asked, asked)
+			}
+			if !strings.Contains(out.String(), "spam 2") || !strings.Contains(out.String(), tt.output) {
+				t.Errorf("expected the reminders and %q got %q", tt.output, out.String())
+			}
+
+			reminders, _ := repo.GetRemindersByUserId(ctx, 42)
+			if len(reminders) != tt.remaining {
+				t.Errorf("expected %d reminders left got %d", tt.remaining, len(reminders))
+			}
+		})
+	}
+
+	t.Run("nothing to cancel", func(t *testing.T) {
+		var out bytes.Buffer
+		asked := 0
+
+		a := admin.New(store(t), &out, answer(true, &asked))
+		if err := a.CancelReminders(ctx, admin.ReminderFilter{Text: "ham"}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if asked != 0 || out.String() != "No reminders to cancel\n" {
+			t.Errorf("expected no question got %d and %q", asked, out.String())
+		}
+	})
+}
+
+func TestAdmin_Purge(t *testing.T) {
+	ctx := context.Background()
+	repo := store(t)
+
+	reminders, _ := repo.GetRemindersByUserId(ctx, 42)
+	if err := repo.RemoveReminder(ctx, reminders[0].GetId()); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	var out bytes.Buffer
+	asked := 0
+	a := admin.New(repo, &out, answer(true, &asked))
+
+	if err := a.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || asked != 0 {
+		t.Errorf("expected nothing to purge got %q, %v", out.String(), err)
+	}
+	if err := a.Purge(ctx, time.Now().Add(time.Minute)); err != nil || asked != 1 || !strings.Contains(out.String(), "Purged 1 reminders") {
+		t.Errorf("expected one reminder purged got %q, %v", out.String(), err)
+	}
+
+	if stats, err := repo.Stats(ctx); err != nil || stats.RemovedReminders != 0 {
+		t.Errorf("expected no removed reminders got %+v, %v", stats, err)
+	}
+}
+
+func TestAdmin_ShowUser(t *testing.T) {
+	var out bytes.Buffer
+	a := admin.New(store(t), &out)
+
+	if err := a.ShowUser(context.Background(), 42); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	for _, expected := range []string{"User 42, language en, UTC offset -", "3 reminders", "water the plants", "0 failed deliveries"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in %q", expected, out.String())
+		}
+	}
+
+	if err := a.ShowUser(context.Background(), 7); err == nil {
+		t.Errorf("expected an error for an unknown user")
+	}
+}
+
+func TestAdmin_Stats(t *testing.T) {
+	var out bytes.Buffer
+	a := admin.New(store(t), &out)
+
+	if err := a.Stats(context.Background()); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if !strings.Contains(out.String(), "Pending reminders   3") {
+		t.Errorf("expected 3 pending reminders in %q", out.String())
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	var out bytes.Buffer
+	confirm := admin.Prompt(strings.NewReader("yes\nn\n\n"), &out)
+
+	for i, expected := range []bool{true, false, false, false} {
+		if got := confirm("Go on?"); got != expected {
+			t.Errorf("expected answer %d to be %v got %v", i, expected, got)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "Go on? [y/N] ") {
+		t.Errorf("expected the question got %q", out.String())
+	}
+}
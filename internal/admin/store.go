@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Store gives operators direct access to the data behind the API.
+// It is implemented by every storage backend.
+type Store interface {
+	GetUser(ctx context.Context, id int64) (*pb.User, error)
+	// FindReminders returns reminders which were not removed, ordered by id.
+	FindReminders(ctx context.Context, filter ReminderFilter) ([]Reminder, error)
+	// CancelReminders removes the reminders with the given ids like
+	// RemoveReminder, skipping removed ones, and returns how many it removed.
+	CancelReminders(ctx context.Context, ids []int32) (int64, error)
+	// FindFailedDeliveries returns given up deliveries of reminders which
+	// were not removed, ordered by id.
+	FindFailedDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error)
+	// RequeueDeliveries makes the failed deliveries with the given ids
+	// pending with no attempts made, and returns how many it requeued.
+	RequeueDeliveries(ctx context.Context, ids []int64) (int64, error)
+	// CountRemovedReminders returns how many reminders were removed before
+	// the given time, which PurgeRemovedReminders deletes for good along with
+	// their deliveries.
+	CountRemovedReminders(ctx context.Context, before time.Time) (int64, error)
+	PurgeRemovedReminders(ctx context.Context, before time.Time) (int64, error)
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// ReminderFilter selects reminders, zero fields match any.
+type ReminderFilter struct {
+	UserID int64
+	// Text is searched for in reminder texts, ignoring case.
+	Text          string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Pending leaves fired reminders out.
+	Pending bool
+	Limit   int
+}
+
+type Reminder struct {
+	ID        int32
+	UserID    int64
+	Text      string
+	RemindAt  time.Time
+	CreatedAt time.Time
+	// FiredAt is nil for pending reminders.
+	FiredAt *time.Time
+}
+
+// DeliveryFilter selects failed deliveries, zero fields match any.
+type DeliveryFilter struct {
+	UserID int64
+	// Channel is the kind of notification channel: TELEGRAM, EMAIL or WEBHOOK.
+	Channel     string
+	FailedAfter time.Time
+	Limit       int
+}
+
+type Delivery struct {
+	ID         int64
+	ReminderID int32
+	UserID     int64
+	Channel    string
+	Address    string
+	Attempts   int32
+	LastError  string
+	FailedAt   time.Time
+}
+
+type Stats struct {
+	Users            int64
+	PendingReminders int64
+	FiredReminders   int64
+	RemovedReminders int64
+	// Deliveries counts deliveries by status: pending, sent and failed.
+	Deliveries map[string]int64
+	// SizeBytes is the size of the database on disk.
+	SizeBytes int64
+}
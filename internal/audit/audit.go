@@ -0,0 +1,276 @@
+// Package audit records who changed users and reminders and how.
+//
+// The interceptors put the actor of every request into its context: the
+// caller, the RPC and a request ID, taken from "x-request-id" metadata or
+// generated and returned in the response headers. Storage backends write an
+// Event for every change of a user or reminder made in such a context, in
+// the transaction making the change, so the audit log holds exactly the
+// changes which happened. Changes made without an actor, like restores of
+// backups, are not recorded.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+// MetadataKey carries the request ID of a request and its response.
+const MetadataKey = "x-request-id"
+
+// maxRequestIDLen limits request IDs sent by clients, longer ones are
+// replaced by generated ones.
+const maxRequestIDLen = 128
+
+// Actor tells who made a change.
+type Actor struct {
+	// Caller is the caller as auth.Caller formats it, like `service "bot"`.
+	Caller    string
+	Method    string
+	RequestID string
+}
+
+type actorKey struct{}
+
+func NewContext(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func FromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+
+	return actor, ok
+}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// RequestID returns id, the request ID a client sent, or a new one when it
+// sent none or one too long.
+func RequestID(id string) string {
+	if id == "" || len(id) > maxRequestIDLen {
+		return NewRequestID()
+	}
+
+	return id
+}
+
+// requestID returns the request ID sent with the request in ctx, or a new
+// one.
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(MetadataKey); len(ids) > 0 {
+		return RequestID(ids[0])
+	}
+
+	return NewRequestID()
+}
+
+func actorOf(ctx context.Context, fullMethod string) Actor {
+	actor := Actor{Method: path.Base(fullMethod), RequestID: requestID(ctx)}
+	if caller, ok := auth.FromContext(ctx); ok {
+		actor.Caller = caller.String()
+	}
+
+	return actor
+}
+
+// UnaryServerInterceptor must run after the auth interceptor, which tells
+// the caller.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		actor := actorOf(ctx, info.FullMethod)
+		grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, actor.RequestID))
+
+		return handler(NewContext(ctx, actor), req)
+	}
+}
+
+// StreamServerInterceptor must run after the auth interceptor, which tells
+// the caller.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		actor := actorOf(ss.Context(), info.FullMethod)
+		ss.SetHeader(metadata.Pairs(MetadataKey, actor.RequestID))
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: NewContext(ss.Context(), actor)})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// Change is a change of a user, or of a reminder when ReminderID is set.
+// Before is nil for created users and reminders, After for removed ones.
+type Change struct {
+	UserID     int64
+	ReminderID int32
+	Before     proto.Message
+	After      proto.Message
+}
+
+// Event is a recorded change.
+type Event struct {
+	// ID and CreatedAt are set by the store.
+	ID        int64
+	CreatedAt time.Time
+	Actor
+	UserID     int64
+	ReminderID int32
+	// Before and After are the changed message as JSON, nil when it did not
+	// exist.
+	Before []byte
+	After  []byte
+}
+
+// Events returns the events to record for changes made in ctx, none when
+// ctx has no actor.
+func Events(ctx context.Context, changes ...Change) ([]Event, error) {
+	actor, ok := FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	events := make([]Event, 0, len(changes))
+	for _, change := range changes {
+		event := Event{Actor: actor, UserID: change.UserID, ReminderID: change.ReminderID}
+
+		var err error
+		if event.Before, err = marshal(change.Before); err != nil {
+			return nil, err
+		}
+		if event.After, err = marshal(change.After); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// marshal encodes msg like the REST gateway does.
+func marshal(msg proto.Message) ([]byte, error) {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil, nil
+	}
+
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson varies its whitespace on purpose.
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, b); err != nil {
+		return nil, err
+	}
+
+	return compact.Bytes(), nil
+}
+
+func unmarshal(b []byte) (*structpb.Struct, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Proto converts e into the message ListAuditEvents streams.
+func (e Event) Proto() (*pb.AuditEvent, error) {
+	before, err := unmarshal(e.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := unmarshal(e.After)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.AuditEvent{
+		Id:            e.ID,
+		Time:          timestamppb.New(e.CreatedAt),
+		Actor:         e.Caller,
+		RequestId:     e.RequestID,
+		Method:        e.Method,
+		UserId:        e.UserID,
+		ReminderId:    e.ReminderID,
+		Before:        before,
+		After:         after,
+		ChangedFields: changedFields(before, after),
+	}, nil
+}
+
+// changedFields returns the top-level fields which differ between before
+// and after, sorted.
+func changedFields(before, after *structpb.Struct) []string {
+	var changed []string
+	for name, value := range before.GetFields() {
+		if !proto.Equal(value, after.GetFields()[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range after.GetFields() {
+		if _, ok := before.GetFields()[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+
+	return changed
+}
+
+// Filter selects events, zero fields match any.
+type Filter struct {
+	UserID     int64
+	ReminderID int32
+	Caller     string
+	RequestID  string
+	Method     string
+	// Since and Until select events created at or after Since and before
+	// Until.
+	Since time.Time
+	Until time.Time
+	// BeforeID selects events with smaller ids, to page back from an event.
+	BeforeID int64
+	Limit    int
+}
+
+// Store keeps events. Events are never changed, they are only deleted
+// along with their user.
+type Store interface {
+	// AuditEvents returns the events matching filter, newest first.
+	AuditEvents(ctx context.Context, filter Filter) ([]Event, error)
+}
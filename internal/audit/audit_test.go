@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+func TestEvents(t *testing.T) {
+	change := Change{
+		UserID:     42,
+		ReminderID: 7,
+		Before:     &pb.Reminder{Id: 7, UserId: 42, ReminderText: "water the plants"},
+		After:      (*pb.Reminder)(nil),
+	}
+
+	if events, err := Events(context.Background(), change); err != nil || events != nil {
+		t.Errorf("expected no events without an actor got %+v, %v", events, err)
+	}
+
+	actor := Actor{Caller: `service "bot"`, Method: "RemoveReminder", RequestID: "abc"}
+	events, err := Events(NewContext(context.Background(), actor), change)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if len(events) != 1 || events[0].Actor != actor || events[0].UserID != 42 || events[0].ReminderID != 7 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if before := string(events[0].Before); before != `{"user_id":"42","reminder_text":"water the plants","id":7}` {
+		t.Errorf("unexpected state before %s", before)
+	}
+	if events[0].After != nil {
+		t.Errorf("expected no state after a removal got %s", events[0].After)
+	}
+}
+
+func TestEvent_Proto(t *testing.T) {
+	events, err := Events(NewContext(context.Background(), Actor{}), Change{
+		UserID: 42,
+		Before: &pb.User{Id: 42, LanguageCode: wrapperspb.String("en"), UtcOffset: wrapperspb.Int32(2)},
+		After:  &pb.User{Id: 42, LanguageCode: wrapperspb.String("de"), NotificationChannels: &pb.NotificationChannels{}},
+	})
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	event, err := events[0].Proto()
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	if expected := []string{"language_code", "notification_channels", "utc_offset"}; !slices.Equal(event.GetChangedFields(), expected) {
+		t.Errorf("expected changed fields %v got %v", expected, event.GetChangedFields())
+	}
+	if language := event.GetBefore().GetFields()["language_code"].GetStringValue(); language != "en" {
+		t.Errorf("expected language en before got %q", language)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID("abc"); id != "abc" {
+		t.Errorf("expected abc got %q", id)
+	}
+
+	for _, sent := range []string{"", strings.Repeat("a", maxRequestIDLen+1)} {
+		if id := RequestID(sent); len(id) != 32 {
+			t.Errorf("expected a new request id for %q got %q", sent, id)
+		}
+	}
+}
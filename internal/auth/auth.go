@@ -0,0 +1,141 @@
+// Package auth authenticates callers of the TodoService and decides which
+// users they may act on.
+//
+// Callers present either an API key issued with `keys issue` or a JWT signed
+// with the configured secret, as "authorization: Bearer <token>" metadata.
+// Service accounts (the bot frontends) may act on any user, personal tokens
+// only on the user they were issued for.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from JWTs.
+const APIKeyPrefix = "tdk_"
+
+var ErrKeyNotFound = errors.New("api key not found or revoked")
+
+type Caller struct {
+	Name string
+	// UserID is the only user a personal caller may act on.
+	// It is nil for service accounts.
+	UserID *int64
+}
+
+func (c Caller) IsService() bool {
+	return c.UserID == nil
+}
+
+func (c Caller) CanActOn(userID int64) bool {
+	return c.IsService() || *c.UserID == userID
+}
+
+func (c Caller) String() string {
+	if c.IsService() {
+		return fmt.Sprintf("service %q", c.Name)
+	}
+
+	return fmt.Sprintf("user %d (%q)", *c.UserID, c.Name)
+}
+
+type callerKey struct{}
+
+func NewContext(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func FromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+
+	return caller, ok
+}
+
+// AuthorizeUser returns a PermissionDenied status error unless the caller
+// in ctx may act on the user.
+func AuthorizeUser(ctx context.Context, userID int64) error {
+	caller, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing caller")
+	}
+
+	if !caller.CanActOn(userID) {
+		return status.Errorf(codes.PermissionDenied, "%v may not act on user %d", caller, userID)
+	}
+
+	return nil
+}
+
+// AuthorizeService returns a PermissionDenied status error unless the
+// caller in ctx is a service account.
+func AuthorizeService(ctx context.Context) error {
+	caller, ok := FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing caller")
+	}
+
+	if !caller.IsService() {
+		return status.Errorf(codes.PermissionDenied, "%v is not a service account", caller)
+	}
+
+	return nil
+}
+
+// APIKey describes an issued key, the key itself is never stored.
+type APIKey struct {
+	ID        int64
+	Name      string
+	UserID    *int64
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// KeyStore looks API keys up by their hash.
+type KeyStore interface {
+	FindAPIKey(ctx context.Context, hash []byte) (Caller, error)
+}
+
+// GenerateAPIKey returns a new random API key and the hash to store.
+// The key itself must only be shown to whoever requested it.
+func GenerateAPIKey() (key string, hash []byte, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, HashAPIKey(key), nil
+}
+
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+
+	return hash[:]
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization metadata must be a bearer token")
+	}
+
+	return token, nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var secret = []byte("test secret")
+
+type StubKeyStore map[string]Caller
+
+func (ks StubKeyStore) FindAPIKey(_ context.Context, hash []byte) (Caller, error) {
+	caller, ok := ks[string(hash)]
+	if !ok {
+		return Caller{}, ErrKeyNotFound
+	}
+
+	return caller, nil
+}
+
+func signed(t *testing.T, method jwt.SigningMethod, key any, c claims) string {
+	token, err := jwt.NewWithClaims(method, c).SignedString(key)
+	if err != nil {
+		t.Fatalf("cannot sign token: %v", err)
+	}
+
+	return token
+}
+
+func incoming(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		t.Errorf("expected key to start with %s got %s", APIKeyPrefix, key)
+	}
+
+	userId := int64(7)
+	a := NewAuthenticator(StubKeyStore{string(hash): {Name: "bot"}}, secret)
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	valid := []struct {
+		name   string
+		token  string
+		caller Caller
+	}{
+		{"api key", key, Caller{Name: "bot"}},
+		{
+			"service jwt",
+			signed(t, jwt.SigningMethodHS256, secret, claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "web", ExpiresAt: expiresAt}}),
+			Caller{Name: "web"},
+		},
+		{
+			"personal jwt",
+			signed(t, jwt.SigningMethodHS256, secret, claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "me", ExpiresAt: expiresAt}, UserID: &userId}),
+			Caller{Name: "me", UserID: &userId},
+		},
+	}
+
+	for _, tt := range valid {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, err := a.Authenticate(incoming(tt.token))
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			if caller.Name != tt.caller.Name || caller.IsService() != tt.caller.IsService() {
+				t.Errorf("expected caller %v got %v", tt.caller, caller)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"no metadata", context.Background()},
+		{"basic auth", metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic Ym90OmJvdA=="))},
+		{"unknown api key", incoming(APIKeyPrefix + "unknown")},
+		{"expired jwt", incoming(signed(t, jwt.SigningMethodHS256, secret, claims{RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "web", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		}}))},
+		{"jwt without expiration", incoming(signed(t, jwt.SigningMethodHS256, secret, claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "web"}}))},
+		{"jwt without subject", incoming(signed(t, jwt.SigningMethodHS256, secret, claims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}}))},
+		{"jwt with other secret", incoming(signed(t, jwt.SigningMethodHS256, []byte("other"), claims{RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "web", ExpiresAt: expiresAt,
+		}}))},
+		{"unsigned jwt", incoming(signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims{RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "web", ExpiresAt: expiresAt,
+		}}))},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.Authenticate(tt.ctx)
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("expected Unauthenticated got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeUser(t *testing.T) {
+	userId := int64(7)
+
+	if err := AuthorizeUser(context.Background(), userId); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without caller got %v", err)
+	}
+
+	service := NewContext(context.Background(), Caller{Name: "bot"})
+	if err := AuthorizeUser(service, userId+1); err != nil {
+		t.Errorf("did not expect error for service caller got %v", err)
+	}
+
+	personal := NewContext(context.Background(), Caller{Name: "me", UserID: &userId})
+	if err := AuthorizeUser(personal, userId); err != nil {
+		t.Errorf("did not expect error for own user got %v", err)
+	}
+	if err := AuthorizeUser(personal, userId+1); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for other user got %v", err)
+	}
+}
+
+func TestAuthorizeService(t *testing.T) {
+	userId := int64(7)
+
+	if err := AuthorizeService(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without caller got %v", err)
+	}
+	if err := AuthorizeService(NewContext(context.Background(), Caller{Name: "bot"})); err != nil {
+		t.Errorf("did not expect error for service caller got %v", err)
+	}
+	if err := AuthorizeService(NewContext(context.Background(), Caller{Name: "me", UserID: &userId})); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for personal caller got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Authenticator struct {
+	keys      KeyStore
+	jwtSecret []byte
+}
+
+// NewAuthenticator accepts API keys from keys and, unless jwtSecret is
+// empty, JWTs signed with HS256.
+func NewAuthenticator(keys KeyStore, jwtSecret []byte) *Authenticator {
+	return &Authenticator{keys: keys, jwtSecret: jwtSecret}
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	UserID *int64 `json:"user_id,omitempty"`
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context) (Caller, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return Caller{}, err
+	}
+
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		caller, err := a.keys.FindAPIKey(ctx, HashAPIKey(token))
+		if errors.Is(err, ErrKeyNotFound) {
+			return Caller{}, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err != nil {
+			log.Printf("Error looking up API key: %v", err)
+
+			return Caller{}, status.Error(codes.Unavailable, "cannot verify API key")
+		}
+
+		return caller, nil
+	}
+
+	if len(a.jwtSecret) == 0 {
+		return Caller{}, status.Error(codes.Unauthenticated, "unknown token format")
+	}
+
+	var c claims
+	_, err = jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return a.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Caller{}, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if c.Subject == "" {
+		return Caller{}, status.Error(codes.Unauthenticated, "invalid token: missing subject")
+	}
+
+	return Caller{Name: c.Subject, UserID: c.UserID}, nil
+}
+
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		caller, err := a.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(NewContext(ctx, caller), req)
+	}
+}
+
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		caller, err := a.Authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: NewContext(ss.Context(), caller)})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,269 @@
+// Package backup dumps the users and reminders of a storage backend into a
+// stream and restores them into an empty one, possibly of another backend.
+//
+// A backup is JSON Lines: a header with the Version of the format, a line
+// per user, a line per reminder and a trailer counting them, with the
+// SHA-256 of every line before it. Users come before their reminders.
+// Restores give reminders new IDs and record which, so an interrupted
+// restore picks up where it stopped when run again.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// Version of the format of backups. Restores refuse other versions.
+const Version = 1
+
+// ErrCorrupt is returned for backups which are malformed, truncated or do
+// not match their checksum.
+var ErrCorrupt = errors.New("backup is corrupt")
+
+type User struct {
+	ID           int64   `json:"id"`
+	LanguageCode *string `json:"language_code,omitempty"`
+	UTCOffset    *int32  `json:"utc_offset,omitempty"`
+	// NotificationChannels is the protojson encoding of
+	// todoservice.NotificationChannels as stored, nil when the user set
+	// none.
+	NotificationChannels json.RawMessage `json:"notification_channels,omitempty"`
+}
+
+// Reminder including fired ones and removed ones not purged yet, so a
+// restore neither fires them again nor lists them.
+type Reminder struct {
+	ID        int32      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Text      string     `json:"text"`
+	RemindAt  time.Time  `json:"remind_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	FiredAt   *time.Time `json:"fired_at,omitempty"`
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+}
+
+type Source interface {
+	// Backup calls users and reminders with batches of up to batchSize
+	// users, then reminders, ordered by ID, all read from one consistent
+	// snapshot of the backend.
+	Backup(ctx context.Context, batchSize int, users func([]User) error, reminders func([]Reminder) error) error
+}
+
+// Summary of a backup.
+type Summary struct {
+	Users     int64
+	Reminders int64
+	// Checksum is the hex SHA-256 of the backup without its trailer, which
+	// identifies it in restores.
+	Checksum string
+}
+
+// line is any line of a backup, telling which by Type.
+type line struct {
+	Type string `json:"type"`
+	// Set in headers.
+	Version   int        `json:"version,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	User     *User     `json:"user,omitempty"`
+	Reminder *Reminder `json:"reminder,omitempty"`
+
+	// Set in trailers.
+	Users     int64  `json:"users,omitempty"`
+	Reminders int64  `json:"reminders,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+const (
+	typeHeader   = "header"
+	typeUser     = "user"
+	typeReminder = "reminder"
+	typeTrailer  = "trailer"
+)
+
+// encoder writes lines, hashing all but trailers.
+type encoder struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+func (e *encoder) encode(l line) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if l.Type != typeTrailer {
+		e.hash.Write(b)
+	}
+	_, err = e.w.Write(b)
+
+	return err
+}
+
+// Write dumps the users and reminders of source to w in batches of
+// batchSize.
+func Write(ctx context.Context, w io.Writer, source Source, batchSize int, now time.Time) (Summary, error) {
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw, hash: sha256.New()}
+
+	var summary Summary
+	now = now.UTC()
+	if err := e.encode(line{Type: typeHeader, Version: Version, CreatedAt: &now}); err != nil {
+		return Summary{}, err
+	}
+
+	err := source.Backup(ctx, batchSize, func(users []User) error {
+		for i := range users {
+			if err := e.encode(line{Type: typeUser, User: &users[i]}); err != nil {
+				return err
+			}
+		}
+		summary.Users += int64(len(users))
+
+		return nil
+	}, func(reminders []Reminder) error {
+		for i := range reminders {
+			if err := e.encode(line{Type: typeReminder, Reminder: &reminders[i]}); err != nil {
+				return err
+			}
+		}
+		summary.Reminders += int64(len(reminders))
+
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+
+	summary.Checksum = hex.EncodeToString(e.hash.Sum(nil))
+	trailer := line{Type: typeTrailer, Users: summary.Users, Reminders: summary.Reminders, SHA256: summary.Checksum}
+	if err := e.encode(trailer); err != nil {
+		return Summary{}, err
+	}
+
+	return summary, bw.Flush()
+}
+
+// decoder reads the lines of a backup, checking their order and hashing
+// all but the trailer.
+type decoder struct {
+	r       *bufio.Reader
+	hash    hash.Hash
+	n       int
+	summary Summary
+	// last is the type of the line read before.
+	last string
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: bufio.NewReader(r), hash: sha256.New()}
+}
+
+// next returns the next user or reminder line, io.EOF after a trailer
+// matching the lines before it.
+func (d *decoder) next() (line, error) {
+	b, err := d.r.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		if len(b) == 0 && d.last == typeTrailer {
+			return line{}, io.EOF
+		}
+
+		return line{}, fmt.Errorf("%w: truncated after line %d", ErrCorrupt, d.n)
+	}
+	if err != nil {
+		return line{}, err
+	}
+	d.n++
+
+	var l line
+	if err := json.Unmarshal(b, &l); err != nil {
+		return line{}, fmt.Errorf("%w: line %d: %v", ErrCorrupt, d.n, err)
+	}
+	if err := d.check(l); err != nil {
+		return line{}, fmt.Errorf("%w: line %d: %v", ErrCorrupt, d.n, err)
+	}
+	d.last = l.Type
+
+	if l.Type == typeTrailer {
+		return d.next()
+	}
+	d.hash.Write(b)
+
+	switch l.Type {
+	case typeUser:
+		d.summary.Users++
+	case typeReminder:
+		d.summary.Reminders++
+	}
+
+	return l, nil
+}
+
+func (d *decoder) check(l line) error {
+	switch {
+	case d.last == "" && l.Type != typeHeader:
+		return errors.New("expected header")
+	case d.last == typeTrailer:
+		return errors.New("expected end after trailer")
+	}
+
+	switch l.Type {
+	case typeHeader:
+		if d.last != "" {
+			return errors.New("unexpected header")
+		}
+		if l.Version != Version {
+			return fmt.Errorf("expected version %d got %d", Version, l.Version)
+		}
+	case typeUser:
+		if d.last == typeReminder {
+			return errors.New("unexpected user after reminders")
+		}
+		if l.User == nil || l.User.ID == 0 {
+			return errors.New("expected user ID")
+		}
+		if channels := l.User.NotificationChannels; channels != nil && !json.Valid(channels) {
+			return errors.New("invalid notification channels")
+		}
+	case typeReminder:
+		if l.Reminder == nil || l.Reminder.ID == 0 || l.Reminder.UserID == 0 {
+			return errors.New("expected reminder and user ID")
+		}
+	case typeTrailer:
+		checksum := hex.EncodeToString(d.hash.Sum(nil))
+		if l.Users != d.summary.Users || l.Reminders != d.summary.Reminders {
+			return fmt.Errorf("expected %d users and %d reminders got %d and %d",
+				l.Users, l.Reminders, d.summary.Users, d.summary.Reminders)
+		}
+		if l.SHA256 != checksum {
+			return fmt.Errorf("expected checksum %s got %s", l.SHA256, checksum)
+		}
+		d.summary.Checksum = checksum
+	default:
+		return fmt.Errorf("unknown type %q", l.Type)
+	}
+
+	return nil
+}
+
+// Verify reads a backup from r, checking its format and checksum.
+func Verify(r io.Reader) (Summary, error) {
+	d := newDecoder(r)
+	for {
+		if _, err := d.next(); errors.Is(err, io.EOF) {
+			return d.summary, nil
+		} else if err != nil {
+			return Summary{}, err
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// StubSource backs up users and reminders in batches of their own.
+type StubSource struct {
+	users     [][]User
+	reminders [][]Reminder
+}
+
+func (ss StubSource) Backup(_ context.Context, _ int, users func([]User) error, reminders func([]Reminder) error) error {
+	for _, batch := range ss.users {
+		if err := users(batch); err != nil {
+			return err
+		}
+	}
+	for _, batch := range ss.reminders {
+		if err := reminders(batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func testBackup(t *testing.T) ([]byte, Summary) {
+	t.Helper()
+
+	remindAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	source := StubSource{
+		users: [][]User{{{ID: 1, NotificationChannels: json.RawMessage(`{"channels":[]}`)}, {ID: 2}}},
+		reminders: [][]Reminder{
+			{{ID: 1, UserID: 1, Text: "water the plants", RemindAt: remindAt, CreatedAt: remindAt}},
+			{{ID: 3, UserID: 2, Text: "call mom", RemindAt: remindAt, CreatedAt: remindAt, FiredAt: &remindAt}},
+		},
+	}
+
+	var buf bytes.Buffer
+	summary, err := Write(context.Background(), &buf, source, 100, remindAt)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	return buf.Bytes(), summary
+}
+
+func TestWrite(t *testing.T) {
+	b, summary := testBackup(t)
+
+	if summary.Users != 2 || summary.Reminders != 2 || len(summary.Checksum) != 64 {
+		t.Errorf("expected 2 users and 2 reminders with a checksum got %+v", summary)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected header, 4 lines and trailer got %q", lines)
+	}
+	if expected := `{"type":"header","version":1,"created_at":"2026-05-01T09:00:00Z"}`; lines[0] != expected {
+		t.Errorf("expected header %s got %s", expected, lines[0])
+	}
+
+	verified, err := Verify(bytes.NewReader(b))
+	if err != nil || verified != summary {
+		t.Errorf("expected %+v verified got %+v, %v", summary, verified, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	b, _ := testBackup(t)
+	lines := strings.SplitAfter(string(b), "\n")
+
+	for name, corrupt := range map[string]string{
+		"changed":     strings.Replace(string(b), "water", "wash", 1),
+		"truncated":   strings.Join(lines[:len(lines)-2], ""),
+		"no newline":  strings.TrimSuffix(string(b), "\n"),
+		"no header":   strings.Join(lines[1:], ""),
+		"reordered":   lines[0] + lines[3] + lines[1] + lines[2] + lines[4] + lines[5],
+		"after end":   string(b) + lines[1],
+		"version":     strings.Replace(string(b), `"version":1`, `"version":2`, 1),
+		"not json":    lines[0] + "users\n",
+		"unknown":     lines[0] + `{"type":"webhook"}` + "\n",
+		"no reminder": lines[0] + `{"type":"reminder"}` + "\n",
+	} {
+		if _, err := Verify(strings.NewReader(corrupt)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected ErrCorrupt got %v", name, err)
+		}
+	}
+}
+
+// StubTarget restores into memory, failing restores of reminders once
+// failAt were restored.
+type StubTarget struct {
+	started, finished bool
+	users             map[int64]User
+	ids               map[int32]int32
+	failAt            int
+}
+
+func (st *StubTarget) BeginRestore(context.Context, string) (bool, error) {
+	resumed := st.started
+	st.started = true
+
+	return resumed, nil
+}
+
+func (st *StubTarget) RestoreUsers(_ context.Context, users []User) error {
+	for _, user := range users {
+		st.users[user.ID] = user
+	}
+
+	return nil
+}
+
+func (st *StubTarget) RestoreReminders(_ context.Context, _ string, reminders []Reminder) ([]int32, error) {
+	if st.failAt > 0 && len(st.ids)+len(reminders) > st.failAt {
+		return nil, errors.New("database is down")
+	}
+
+	var ids []int32
+	for _, reminder := range reminders {
+		id := int32(len(st.ids) + 100)
+		st.ids[reminder.ID] = id
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (st *StubTarget) RestoredReminders(context.Context, string) (map[int32]int32, error) {
+	ids := make(map[int32]int32)
+	for sourceId, id := range st.ids {
+		ids[sourceId] = id
+	}
+
+	return ids, nil
+}
+
+func (st *StubTarget) FinishRestore(context.Context, string) error {
+	st.finished = true
+
+	return nil
+}
+
+func TestRestore(t *testing.T) {
+	b, summary := testBackup(t)
+	target := &StubTarget{users: make(map[int64]User), ids: make(map[int32]int32), failAt: 1}
+
+	if _, err := Restore(context.Background(), bytes.NewReader(b), target, summary.Checksum, 1); err == nil || target.finished {
+		t.Fatalf("expected the restore interrupted got %v", err)
+	}
+	if len(target.users) != 2 || len(target.ids) != 1 {
+		t.Fatalf("expected 2 users and 1 reminder restored got %v, %v", target.users, target.ids)
+	}
+
+	target.failAt = 0
+	result, err := Restore(context.Background(), bytes.NewReader(b), target, summary.Checksum, 1)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if !result.Resumed || result.Skipped != 1 || result.Summary != summary || !target.finished {
+		t.Errorf("expected the restore resumed and finished got %+v", result)
+	}
+	if expected := map[int32]int32{1: 100, 3: 101}; len(result.IDs) != 2 || result.IDs[1] != expected[1] || result.IDs[3] != expected[3] {
+		t.Errorf("expected IDs %v got %v", expected, result.IDs)
+	}
+
+	t.Run("other backup", func(t *testing.T) {
+		target := &StubTarget{users: make(map[int64]User), ids: make(map[int32]int32)}
+
+		if _, err := Restore(context.Background(), bytes.NewReader(b), target, strings.Repeat("0", 64), 1); !errors.Is(err, ErrCorrupt) || target.finished {
+			t.Errorf("expected ErrCorrupt got %v", err)
+		}
+	})
+}
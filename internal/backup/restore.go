@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotEmpty is returned restoring into a backend which has users but
+// no restore of the backup begun.
+var ErrNotEmpty = errors.New("database is not empty")
+
+type Target interface {
+	// BeginRestore records that the restore of the backup with checksum
+	// began. It returns true when resuming a restore of it which began
+	// before, and ErrNotEmpty when there is nothing to resume and the
+	// backend has users.
+	BeginRestore(ctx context.Context, checksum string) (bool, error)
+	// RestoreUsers inserts users, leaving users already there alone.
+	RestoreUsers(ctx context.Context, users []User) error
+	// RestoreReminders inserts reminders under new IDs and records them as
+	// restored from the backup with checksum, atomically. It returns the new
+	// IDs in the order of reminders.
+	RestoreReminders(ctx context.Context, checksum string, reminders []Reminder) ([]int32, error)
+	// RestoredReminders returns the IDs reminders restored from the backup
+	// with checksum got, by their IDs in the backup.
+	RestoredReminders(ctx context.Context, checksum string) (map[int32]int32, error)
+	// FinishRestore records that the restore of the backup with checksum
+	// finished.
+	FinishRestore(ctx context.Context, checksum string) error
+}
+
+// Result of a restore.
+type Result struct {
+	Summary
+	Resumed bool
+	// Skipped counts the reminders restored before resuming.
+	Skipped int64
+	// IDs maps the IDs of reminders in the backup to the IDs they were
+	// restored under, including the ones restored before resuming.
+	IDs map[int32]int32
+}
+
+// Restore inserts the users and reminders of the backup read from r into
+// target in batches of batchSize. The backup must have been verified to
+// have checksum, since batches are committed as they are read; a backup
+// read differently fails the restore, which may be resumed with the
+// original.
+func Restore(ctx context.Context, r io.Reader, target Target, checksum string, batchSize int) (Result, error) {
+	resumed, err := target.BeginRestore(ctx, checksum)
+	if err != nil {
+		return Result{}, err
+	}
+
+	ids, err := target.RestoredReminders(ctx, checksum)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Resumed: resumed, IDs: ids}
+
+	var (
+		users     []User
+		reminders []Reminder
+	)
+	flushUsers := func() error {
+		if len(users) == 0 {
+			return nil
+		}
+		if err := target.RestoreUsers(ctx, users); err != nil {
+			return fmt.Errorf("cannot restore users: %w", err)
+		}
+		users = users[:0]
+
+		return nil
+	}
+	flushReminders := func() error {
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		restored, err := target.RestoreReminders(ctx, checksum, reminders)
+		if err != nil {
+			return fmt.Errorf("cannot restore reminders: %w", err)
+		}
+		for i, id := range restored {
+			result.IDs[reminders[i].ID] = id
+		}
+		reminders = reminders[:0]
+
+		return nil
+	}
+
+	d := newDecoder(r)
+	for {
+		l, err := d.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+
+		switch l.Type {
+		case typeUser:
+			users = append(users, *l.User)
+			if len(users) >= batchSize {
+				err = flushUsers()
+			}
+		case typeReminder:
+			if err := flushUsers(); err != nil {
+				return Result{}, err
+			}
+			if _, ok := result.IDs[l.Reminder.ID]; ok {
+				result.Skipped++
+
+				continue
+			}
+
+			reminders = append(reminders, *l.Reminder)
+			if len(reminders) >= batchSize {
+				err = flushReminders()
+			}
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	if d.summary.Checksum != checksum {
+		return Result{}, fmt.Errorf("%w: expected checksum %s got %s", ErrCorrupt, checksum, d.summary.Checksum)
+	}
+	if err := flushUsers(); err != nil {
+		return Result{}, err
+	}
+	if err := flushReminders(); err != nil {
+		return Result{}, err
+	}
+	result.Summary = d.summary
+
+	return result, target.FinishRestore(ctx, checksum)
+}
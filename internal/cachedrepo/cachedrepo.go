@@ -0,0 +1,184 @@
+// Package cachedrepo caches users and their reminders in front of
+// a todoserviceserver.Repo.
+//
+// Entries are dropped when they expire, when they are written through
+// the cache and when Invalidate is called, e.g. on notifications about
+// writes made by other server replicas.
+package cachedrepo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+// Stats counts lookups served from the cache and from the repo.
+type Stats struct {
+	UserHits            int64 `json:"user_hits"`
+	UserMisses          int64 `json:"user_misses"`
+	RemindersHits       int64 `json:"reminders_hits"`
+	RemindersMisses     int64 `json:"reminders_misses"`
+	Invalidations       int64 `json:"invalidations"`
+	CachedUsers         int   `json:"cached_users"`
+	CachedReminderLists int   `json:"cached_reminder_lists"`
+}
+
+type CachedRepo struct {
+	repo  todoserviceserver.Repo
+	users *expirable.LRU[int64, *pb.User]
+	// reminders holds the reminders of a user, by user id.
+	reminders *expirable.LRU[int64, []*pb.Reminder]
+
+	// generation changes on every invalidation. Values loaded from the repo
+	// are cached only if it did not change meanwhile, otherwise they may
+	// have been read before a write which they would then outlive.
+	generation atomic.Int64
+
+	userHits, userMisses           atomic.Int64
+	remindersHits, remindersMisses atomic.Int64
+}
+
+// New caches up to size users and reminder lists of users for ttl.
+func New(repo todoserviceserver.Repo, size int, ttl time.Duration) *CachedRepo {
+	return &CachedRepo{
+		repo:      repo,
+		users:     expirable.NewLRU[int64, *pb.User](size, nil, ttl),
+		reminders: expirable.NewLRU[int64, []*pb.Reminder](size, nil, ttl),
+	}
+}
+
+func (cr *CachedRepo) SetUser(ctx context.Context, user *pb.User) error {
+	defer cr.invalidateUser(user.GetId())
+
+	return cr.repo.SetUser(ctx, user)
+}
+
+func (cr *CachedRepo) GetUser(ctx context.Context, id int64) (*pb.User, error) {
+	if user, ok := cr.users.Get(id); ok {
+		cr.userHits.Add(1)
+
+		return proto.Clone(user).(*pb.User), nil
+	}
+	cr.userMisses.Add(1)
+
+	generation := cr.generation.Load()
+	user, err := cr.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cr.generation.Load() == generation {
+		cr.users.Add(id, proto.Clone(user).(*pb.User))
+	}
+
+	return user, nil
+}
+
+func (cr *CachedRepo) CreateReminder(ctx context.Context, reminder *pb.Reminder) (int32, error) {
+	defer cr.invalidateReminders(reminder.GetUserId())
+
+	return cr.repo.CreateReminder(ctx, reminder)
+}
+
+func (cr *CachedRepo) CreateReminders(ctx context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error) {
+	defer cr.invalidateReminders(userId)
+
+	return cr.repo.CreateReminders(ctx, userId, reminders)
+}
+
+func (cr *CachedRepo) GetReminder(ctx context.Context, id int32) (*pb.Reminder, error) {
+	return cr.repo.GetReminder(ctx, id)
+}
+
+func (cr *CachedRepo) RemoveReminder(ctx context.Context, id int32) error {
+	reminder, err := cr.repo.GetReminder(ctx, id)
+	if err == nil {
+		defer cr.invalidateReminders(reminder.GetUserId())
+	}
+
+	return cr.repo.RemoveReminder(ctx, id)
+}
+
+func (cr *CachedRepo) GetRemindersByUserId(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	if reminders, ok := cr.reminders.Get(userId); ok {
+		cr.remindersHits.Add(1)
+
+		return cloneReminders(reminders), nil
+	}
+	cr.remindersMisses.Add(1)
+
+	generation := cr.generation.Load()
+	reminders, err := cr.repo.GetRemindersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cr.generation.Load() == generation {
+		cr.reminders.Add(userId, cloneReminders(reminders))
+	}
+
+	return reminders, nil
+}
+
+func cloneReminders(reminders []*pb.Reminder) []*pb.Reminder {
+	clones := make([]*pb.Reminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		clones = append(clones, proto.Clone(reminder).(*pb.Reminder))
+	}
+
+	return clones
+}
+
+func (cr *CachedRepo) invalidateUser(id int64) {
+	cr.generation.Add(1)
+	cr.users.Remove(id)
+}
+
+func (cr *CachedRepo) invalidateReminders(userId int64) {
+	cr.generation.Add(1)
+	cr.reminders.Remove(userId)
+}
+
+// Invalidate drops the entries named by payload, "user:ID" for a user or
+// "reminders:USER_ID" for the reminders of a user, as sent on
+// postgresrepo.CacheInvalidationChannel. Other payloads drop everything.
+func (cr *CachedRepo) Invalidate(payload string) {
+	kind, idString, _ := strings.Cut(payload, ":")
+	id, err := strconv.ParseInt(idString, 10, 64)
+
+	switch {
+	case err == nil && kind == "user":
+		cr.invalidateUser(id)
+	case err == nil && kind == "reminders":
+		cr.invalidateReminders(id)
+	default:
+		cr.Purge()
+	}
+}
+
+// Purge drops every entry.
+func (cr *CachedRepo) Purge() {
+	cr.generation.Add(1)
+	cr.users.Purge()
+	cr.reminders.Purge()
+}
+
+func (cr *CachedRepo) Stats() Stats {
+	return Stats{
+		UserHits:            cr.userHits.Load(),
+		UserMisses:          cr.userMisses.Load(),
+		RemindersHits:       cr.remindersHits.Load(),
+		RemindersMisses:     cr.remindersMisses.Load(),
+		Invalidations:       cr.generation.Load(),
+		CachedUsers:         cr.users.Len(),
+		CachedReminderLists: cr.reminders.Len(),
+	}
+}
@@ -0,0 +1,100 @@
+package cachedrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+	"github.com/awakair/awakair_todo_bot/internal/repotest"
+)
+
+func TestCachedRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, opts repotest.Options) todoserviceserver.Repo {
+		return New(memrepo.New(memrepo.WithMaxActiveReminders(opts.MaxActiveReminders)), 100, time.Minute)
+	})
+}
+
+func TestCachedRepo_caching(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New()
+	cr := New(repo, 100, time.Minute)
+
+	_ = cr.SetUser(ctx, &pb.User{Id: 1, LanguageCode: wrapperspb.String("en")})
+	_, _ = cr.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: timestamppb.Now()})
+
+	expectStats := func(t *testing.T, want Stats) {
+		t.Helper()
+
+		got := cr.Stats()
+		if got.UserHits != want.UserHits || got.UserMisses != want.UserMisses ||
+			got.RemindersHits != want.RemindersHits || got.RemindersMisses != want.RemindersMisses {
+			t.Errorf("expected stats %+v got %+v", want, got)
+		}
+	}
+
+	t.Run("read through", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, _ = cr.GetUser(ctx, 1)
+			_, _ = cr.GetRemindersByUserId(ctx, 1)
+		}
+
+		expectStats(t, Stats{UserHits: 2, UserMisses: 1, RemindersHits: 2, RemindersMisses: 1})
+	})
+
+	t.Run("write through", func(t *testing.T) {
+		_ = cr.SetUser(ctx, &pb.User{Id: 1, LanguageCode: wrapperspb.String("ru")})
+		id, _ := cr.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "buy bread", RemindTimestamp: timestamppb.Now()})
+
+		user, _ := cr.GetUser(ctx, 1)
+		if user.GetLanguageCode().GetValue() != "ru" {
+			t.Errorf("expected updated user got %+v", user)
+		}
+		reminders, _ := cr.GetRemindersByUserId(ctx, 1)
+		if len(reminders) != 2 {
+			t.Errorf("expected created reminder to be listed got %+v", reminders)
+		}
+
+		_ = cr.RemoveReminder(ctx, id)
+		reminders, _ = cr.GetRemindersByUserId(ctx, 1)
+		if len(reminders) != 1 {
+			t.Errorf("expected removed reminder not to be listed got %+v", reminders)
+		}
+
+		expectStats(t, Stats{UserHits: 2, UserMisses: 2, RemindersHits: 2, RemindersMisses: 3})
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		// Written behind the back of the cache, as by another replica.
+		_ = repo.SetUser(ctx, &pb.User{Id: 1, LanguageCode: wrapperspb.String("de")})
+
+		if user, _ := cr.GetUser(ctx, 1); user.GetLanguageCode().GetValue() != "ru" {
+			t.Fatalf("expected cached user got %+v", user)
+		}
+
+		cr.Invalidate("user:1")
+		if user, _ := cr.GetUser(ctx, 1); user.GetLanguageCode().GetValue() != "de" {
+			t.Errorf("expected invalidated user to be reloaded got %+v", user)
+		}
+
+		_, _ = cr.GetRemindersByUserId(ctx, 1)
+		cr.Invalidate("garbage")
+		if stats := cr.Stats(); stats.CachedUsers != 0 || stats.CachedReminderLists != 0 {
+			t.Errorf("expected unknown payload to purge the cache got %+v", stats)
+		}
+	})
+
+	t.Run("returned values are copies", func(t *testing.T) {
+		user, _ := cr.GetUser(ctx, 1)
+		user.LanguageCode = wrapperspb.String("fr")
+
+		if again, _ := cr.GetUser(ctx, 1); again.GetLanguageCode().GetValue() == "fr" {
+			t.Errorf("expected cached user not to be changed by callers")
+		}
+	})
+}
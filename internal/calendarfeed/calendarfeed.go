@@ -0,0 +1,207 @@
+// Package calendarfeed serves the upcoming reminders of users as iCalendar
+// feeds for calendar apps to subscribe to:
+//
+//	GET /calendar/<secret>.ics
+//
+// The secret, issued with CreateCalendarFeed, both authenticates the
+// request and names the user. Apps poll feeds, so responses carry an ETag
+// and Last-Modified and conditional requests for unchanged reminders are
+// answered with 304 Not Modified without building the feed.
+package calendarfeed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/httperror"
+	"github.com/awakair/awakair_todo_bot/internal/ical"
+	"github.com/awakair/awakair_todo_bot/internal/ratelimit"
+)
+
+// Pattern is where Handler must be registered on an http.ServeMux.
+const Pattern = "GET /calendar/{file}"
+
+// Repo is the part of todoserviceserver.Repo feeds are made of.
+type Repo interface {
+	GetUser(context.Context, int64) (*pb.User, error)
+	GetRemindersByUserId(context.Context, int64) ([]*pb.Reminder, error)
+}
+
+type Handler struct {
+	feeds      todoserviceserver.CalendarFeedStore
+	repo       Repo
+	limiter    *ratelimit.Limiter
+	perAddress config.Rate
+	now        func() time.Time
+}
+
+type Option func(*Handler)
+
+// WithLimiter limits requests to perAddress per client address to slow
+// down guessing secrets.
+func WithLimiter(limiter *ratelimit.Limiter, perAddress config.Rate) Option {
+	return func(h *Handler) {
+		h.limiter = limiter
+		h.perAddress = perAddress
+	}
+}
+
+// WithClock replaces time.Now, which decides which reminders are upcoming.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+func NewHandler(feeds todoserviceserver.CalendarFeedStore, repo Repo, opts ...Option) *Handler {
+	h := &Handler{feeds: feeds, repo: repo, now: time.Now}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.limiter != nil {
+		address, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			address = r.RemoteAddr
+		}
+
+		if err := h.limiter.AllowKey(ctx, "calendar-address:"+address, h.perAddress); err != nil {
+			httperror.Write(w, err)
+
+			return
+		}
+	}
+
+	secret, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	userId, version, err := h.feeds.FindCalendarFeed(ctx, todoserviceserver.HashSecret(secret))
+	if errors.Is(err, todoserviceserver.ErrNotFound) {
+		http.NotFound(w, r)
+
+		return
+	}
+	if err != nil {
+		log.Printf("Error finding calendar feed: %v", err)
+		http.Error(w, "cannot check calendar feed", http.StatusInternalServerError)
+
+		return
+	}
+
+	zone, entries, err := h.upcoming(ctx, userId)
+	if err != nil {
+		log.Printf("Error reading calendar feed of user %v: %v", userId, err)
+		http.Error(w, "cannot read reminders", http.StatusInternalServerError)
+
+		return
+	}
+
+	// The feed changed when its ETag did since it was served last.
+	if etag := entityTag(zone, entries); etag != version.ETag {
+		version = todoserviceserver.CalendarFeedVersion{ETag: etag, ModifiedAt: h.now().UTC().Truncate(time.Second)}
+		if err := h.feeds.SetCalendarFeedVersion(ctx, userId, version); err != nil {
+			log.Printf("Error setting calendar feed version of user %v: %v", userId, err)
+		}
+	}
+
+	w.Header().Set("ETag", version.ETag)
+	w.Header().Set("Last-Modified", version.ModifiedAt.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if notModified(r, version) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+
+	var feed bytes.Buffer
+	if err := ical.Export(&feed, "VEVENT", zone, version.ModifiedAt, entries); err != nil {
+		log.Printf("Error writing calendar feed of user %v: %v", userId, err)
+		http.Error(w, "cannot write calendar", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(feed.Len()))
+	if r.Method != http.MethodHead {
+		w.Write(feed.Bytes())
+	}
+}
+
+// upcoming returns the reminders of the user which did not fire yet and
+// the time zone of the user.
+func (h *Handler) upcoming(ctx context.Context, userId int64) (*time.Location, []ical.Entry, error) {
+	user, err := h.repo.GetUser(ctx, userId)
+	if err != nil && !errors.Is(err, todoserviceserver.ErrNotFound) {
+		return nil, nil, err
+	}
+
+	reminders, err := h.repo.GetRemindersByUserId(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := h.now()
+	var entries []ical.Entry
+	for _, reminder := range reminders {
+		if reminder.GetRemindTimestamp().AsTime().After(now) {
+			entries = append(entries, todoserviceserver.CalendarEntry(reminder))
+		}
+	}
+
+	return todoserviceserver.UserZone(user), entries, nil
+}
+
+// entityTag returns a strong entity tag of the feed of entries in zone.
+func entityTag(zone *time.Location, entries []ical.Entry) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", zone)
+	for _, entry := range entries {
+		fmt.Fprintf(hash, "%q %q %d\n", entry.UID, entry.Summary, entry.Time.Unix())
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified reports whether the conditional headers of r are satisfied by
+// version, preferring If-None-Match to If-Modified-Since like RFC 9110.
+func notModified(r *http.Request, version todoserviceserver.CalendarFeedVersion) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == version.ETag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+
+	return err == nil && !version.ModifiedAt.After(since)
+}
@@ -0,0 +1,210 @@
+package calendarfeed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+	"github.com/awakair/awakair_todo_bot/internal/ratelimit"
+)
+
+const (
+	secret = todoserviceserver.CalendarFeedSecretPrefix + "secret"
+	userId = 42
+)
+
+// StubCalendarFeedStore only knows secret, which belongs to userId, and
+// keeps the version of its feed.
+type StubCalendarFeedStore struct {
+	mu      sync.Mutex
+	version todoserviceserver.CalendarFeedVersion
+	sets    int
+}
+
+func (*StubCalendarFeedStore) CreateCalendarFeed(context.Context, int64, []byte) error { return nil }
+
+func (*StubCalendarFeedStore) RotateCalendarFeed(context.Context, int64, []byte) error { return nil }
+
+func (*StubCalendarFeedStore) DeleteCalendarFeed(context.Context, int64) error { return nil }
+
+func (fs *StubCalendarFeedStore) FindCalendarFeed(_ context.Context, hash []byte) (int64, todoserviceserver.CalendarFeedVersion, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if !bytes.Equal(hash, todoserviceserver.HashSecret(secret)) {
+		return 0, todoserviceserver.CalendarFeedVersion{}, fmt.Errorf("calendar feed: %w", todoserviceserver.ErrNotFound)
+	}
+
+	return userId, fs.version, nil
+}
+
+func (fs *StubCalendarFeedStore) SetCalendarFeedVersion(_ context.Context, _ int64, version todoserviceserver.CalendarFeedVersion) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.version = version
+	fs.sets++
+
+	return nil
+}
+
+func newServer(t *testing.T, now *time.Time, opts ...Option) (*httptest.Server, *memrepo.MemRepo, *StubCalendarFeedStore) {
+	t.Helper()
+
+	repo := memrepo.New()
+	if err := repo.SetUser(context.Background(), &pb.User{Id: userId, UtcOffset: wrapperspb.Int32(2)}); err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+
+	store := &StubCalendarFeedStore{}
+	opts = append(opts, WithClock(func() time.Time { return *now }))
+	mux := http.NewServeMux()
+	mux.Handle(Pattern, NewHandler(store, repo, opts...))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, repo, store
+}
+
+func get(t *testing.T, url string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("cannot create request: %v", err)
+	}
+	req.Header = header
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cannot get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("cannot read body: %v", err)
+	}
+
+	return resp, string(body)
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	srv, repo, store := newServer(t, &now)
+	url := srv.URL + "/calendar/" + secret + ".ics"
+
+	for _, reminder := range []*pb.Reminder{
+		{UserId: userId, ReminderText: "water the plants", RemindTimestamp: timestamppb.New(now.Add(3 * time.Hour))},
+		{UserId: userId, ReminderText: "long gone", RemindTimestamp: timestamppb.New(now.Add(-time.Hour))},
+	} {
+		if _, err := repo.CreateReminder(context.Background(), reminder); err != nil {
+			t.Fatalf("cannot create reminder: %v", err)
+		}
+	}
+
+	var first *http.Response
+
+	t.Run("feed", func(t *testing.T) {
+		resp, body := get(t, url, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, resp.StatusCode, body)
+		}
+		first = resp
+
+		if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/calendar") {
+			t.Errorf("expected a calendar got %q", got)
+		}
+		if resp.Header.Get("ETag") == "" || resp.Header.Get("Last-Modified") != now.UTC().Format(http.TimeFormat) {
+			t.Errorf("expected ETag and Last-Modified got %v", resp.Header)
+		}
+		if !strings.Contains(body, "SUMMARY:water the plants") || strings.Contains(body, "long gone") {
+			t.Errorf("expected only upcoming reminders in %s", body)
+		}
+		if !strings.Contains(body, `TZID="UTC+02:00"`) {
+			t.Errorf("expected the time zone of the user in %s", body)
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		for _, header := range []http.Header{
+			{"If-None-Match": {first.Header.Get("ETag")}},
+			{"If-None-Match": {`"other", W/` + first.Header.Get("ETag")}},
+			{"If-Modified-Since": {first.Header.Get("Last-Modified")}},
+		} {
+			resp, body := get(t, url, header)
+			if resp.StatusCode != http.StatusNotModified || body != "" {
+				t.Errorf("expected %d for %v got %d: %s", http.StatusNotModified, header, resp.StatusCode, body)
+			}
+		}
+
+		resp, _ := get(t, url, http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {first.Header.Get("Last-Modified")}})
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected If-None-Match to take precedence got %d", resp.StatusCode)
+		}
+		if store.sets != 1 {
+			t.Errorf("expected the version to be set once got %d", store.sets)
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		if _, err := repo.CreateReminder(context.Background(), &pb.Reminder{
+			UserId: userId, ReminderText: "call mom", RemindTimestamp: timestamppb.New(now.Add(time.Hour)),
+		}); err != nil {
+			t.Fatalf("cannot create reminder: %v", err)
+		}
+
+		resp, body := get(t, url, http.Header{"If-None-Match": {first.Header.Get("ETag")}})
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, "SUMMARY:call mom") {
+			t.Fatalf("expected the changed feed got %d: %s", resp.StatusCode, body)
+		}
+		if resp.Header.Get("ETag") == first.Header.Get("ETag") || resp.Header.Get("Last-Modified") != now.UTC().Format(http.TimeFormat) {
+			t.Errorf("expected a new version got %v", resp.Header)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, path := range []string{"/calendar/tdc_other.ics", "/calendar/" + secret, "/calendar/" + secret + ".ics/"} {
+			if resp, _ := get(t, srv.URL+path, nil); resp.StatusCode != http.StatusNotFound {
+				t.Errorf("expected %d for %s got %d", http.StatusNotFound, path, resp.StatusCode)
+			}
+		}
+	})
+}
+
+func TestHandler_rateLimits(t *testing.T) {
+	limiter, err := ratelimit.New(config.RateLimits{}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("cannot create limiter: %v", err)
+	}
+
+	now := time.Now()
+	srv, _, _ := newServer(t, &now, WithLimiter(limiter, config.Rate{PerSecond: 0.001, Burst: 2}))
+
+	if resp, _ := get(t, srv.URL+"/calendar/"+secret+".ics", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp, _ := get(t, srv.URL+"/calendar/tdc_other.ics", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp, _ := get(t, srv.URL+"/calendar/"+secret+".ics", nil); resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("expected %d with Retry-After got %d, %v", http.StatusTooManyRequests, resp.StatusCode, resp.Header)
+	}
+}
@@ -0,0 +1,699 @@
+// Package config loads the TodoService server configuration.
+//
+// Every setting can come from four places. When a setting is given in more
+// than one of them, the one higher in this list wins:
+//
+//  1. command-line flags (-db-host, -listen-addr, ...);
+//  2. environment variables (TODO_DB_HOST, TODO_LISTEN_ADDR, ...);
+//  3. the YAML file named by -config or TODO_CONFIG;
+//  4. built-in defaults, see Default.
+//
+// DB_USER, DB_PASSWORD and DB_NAME, used by deployments/compose.yml, are
+// accepted as fallbacks for TODO_DB_USER, TODO_DB_PASSWORD and TODO_DB_NAME.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	ListenAddr    string        `yaml:"listen_addr"`
+	DebugAddr     string        `yaml:"debug_addr"`
+	Storage       Storage       `yaml:"storage"`
+	Cache         Cache         `yaml:"cache"`
+	Database      Database      `yaml:"database"`
+	TLS           TLS           `yaml:"tls"`
+	Auth          Auth          `yaml:"auth"`
+	RateLimits    RateLimits    `yaml:"rate_limits"`
+	Quotas        Quotas        `yaml:"quotas"`
+	Idempotency   Idempotency   `yaml:"idempotency"`
+	Events        Events        `yaml:"events"`
+	Notify        Notify        `yaml:"notify"`
+	Webhooks      Webhooks      `yaml:"webhooks"`
+	Hooks         Hooks         `yaml:"hooks"`
+	CalendarFeeds CalendarFeeds `yaml:"calendar_feeds"`
+	UserDeletion  UserDeletion  `yaml:"user_deletion"`
+	Gateway       Gateway       `yaml:"gateway"`
+	Web           Web           `yaml:"web"`
+	Log           Log           `yaml:"log"`
+}
+
+// Storage selects where the server keeps its data. Backend is "postgres",
+// configured by Database, or "sqlite" for single-node installs keeping
+// everything in the file at SQLitePath.
+type Storage struct {
+	Backend    string `yaml:"backend"`
+	SQLitePath string `yaml:"sqlite_path"`
+}
+
+// Cache keeps up to Size users and reminder lists of users in memory for TTL.
+// Zero Size disables it.
+type Cache struct {
+	Size int           `yaml:"size"`
+	TTL  time.Duration `yaml:"ttl"`
+}
+
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password Secret `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"ssl_mode"`
+	MaxConns int32  `yaml:"max_conns"`
+	MinConns int32  `yaml:"min_conns"`
+}
+
+// TLS is enabled when both CertFile and KeyFile are set.
+// Setting ClientCAFile additionally requires clients to present
+// a certificate signed by one of its CAs.
+type TLS struct {
+	CertFile     string        `yaml:"cert_file"`
+	KeyFile      string        `yaml:"key_file"`
+	ClientCAFile string        `yaml:"client_ca_file"`
+	ReloadEvery  time.Duration `yaml:"reload_every"`
+}
+
+// Auth configures how callers are authenticated. API keys are always
+// accepted, JWTs only when JWTSecret is set.
+type Auth struct {
+	JWTSecret Secret `yaml:"jwt_secret"`
+}
+
+// RateLimits configures token buckets per caller and per user for every RPC.
+// Limits missing from Methods, keyed by RPC name, are taken from Default.
+type RateLimits struct {
+	// Store is "memory" to keep buckets in each replica
+	// or "postgres" to share them between replicas.
+	Store   string                  `yaml:"store"`
+	Default MethodLimits            `yaml:"default"`
+	Methods map[string]MethodLimits `yaml:"methods"`
+}
+
+type MethodLimits struct {
+	PerCaller Rate `yaml:"per_caller"`
+	PerUser   Rate `yaml:"per_user"`
+}
+
+// Rate of a token bucket refilled with PerSecond tokens up to Burst.
+// Zero PerSecond means no limit.
+type Rate struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
+}
+
+type Quotas struct {
+	// MaxActiveReminders a user may have, zero means no limit.
+	MaxActiveReminders int `yaml:"max_active_reminders"`
+}
+
+// For returns the limits of the RPC named method.
+func (r RateLimits) For(method string) MethodLimits {
+	limits := r.Default
+	if override, ok := r.Methods[method]; ok {
+		if override.PerCaller.PerSecond != 0 {
+			limits.PerCaller = override.PerCaller
+		}
+		if override.PerUser.PerSecond != 0 {
+			limits.PerUser = override.PerUser
+		}
+	}
+
+	return limits
+}
+
+func (r Rate) validate(name string) error {
+	if r.PerSecond < 0 {
+		return fmt.Errorf("%s.per_second: must not be negative", name)
+	}
+	if r.PerSecond > 0 && r.Burst < 1 {
+		return fmt.Errorf("%s.burst: must be positive", name)
+	}
+
+	return nil
+}
+
+type Idempotency struct {
+	// TTL is how long outcomes of requests with idempotency keys are kept.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type Events struct {
+	// Retention is how long events are kept for WatchUserEvents
+	// clients resuming after a disconnect.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Notify configures delivery of fired reminders. Telegram is enabled when
+// its BotToken is set and email when its SMTPAddr is set, webhooks always are.
+type Notify struct {
+	// Interval is how often due reminders and deliveries are looked for.
+	Interval time.Duration `yaml:"interval"`
+	// MaxAttempts to deliver a reminder to one channel before giving up.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the delay before the first retry, doubled for every next
+	// one up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Timeout of a single delivery attempt.
+	Timeout  time.Duration `yaml:"timeout"`
+	Telegram Telegram      `yaml:"telegram"`
+	Email    Email         `yaml:"email"`
+}
+
+type Telegram struct {
+	BotToken Secret `yaml:"bot_token"`
+	APIURL   string `yaml:"api_url"`
+}
+
+// Email is sent through the SMTP server at SMTPAddr, authenticating with
+// Username and Password when Username is set.
+type Email struct {
+	SMTPAddr string `yaml:"smtp_addr"`
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// Webhooks configures delivery of user events to the webhooks of users,
+// with the postgres backend only.
+type Webhooks struct {
+	// Interval is how often pending deliveries are looked for.
+	Interval time.Duration `yaml:"interval"`
+	// MaxAttempts to deliver an event before it becomes a dead letter.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the delay before the first retry, doubled for every next
+	// one up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Timeout of a single delivery attempt.
+	Timeout time.Duration `yaml:"timeout"`
+	// DisableAfter failed attempts in a row a webhook is disabled.
+	DisableAfter int `yaml:"disable_after"`
+}
+
+// Hooks serves POST /hooks/{token} at ListenAddr, creating reminders for
+// automations, see package hooks. Empty ListenAddr disables it.
+type Hooks struct {
+	ListenAddr string `yaml:"listen_addr"`
+	// PerAddress limits requests per client address, whether their token
+	// is valid or not. Valid ones are also limited like CreateReminder.
+	PerAddress Rate `yaml:"per_address"`
+}
+
+// CalendarFeeds serves GET /calendar/{secret}.ics at ListenAddr, the feeds
+// of upcoming reminders created with CreateCalendarFeed, see package
+// calendarfeed. Empty ListenAddr disables it.
+type CalendarFeeds struct {
+	ListenAddr string `yaml:"listen_addr"`
+	// BaseURL is where ListenAddr is reachable by calendar apps, like
+	// https://todo.example.com, prefixing the feed URLs returned by the
+	// RPCs. Empty BaseURL returns their paths only.
+	BaseURL string `yaml:"base_url"`
+	// PerAddress limits requests per client address, whether their secret
+	// is valid or not.
+	PerAddress Rate `yaml:"per_address"`
+}
+
+// UserDeletion configures the deletion of users requested with DeleteUser,
+// see package userdata.
+type UserDeletion struct {
+	// GracePeriod is how long after DeleteUser users are deleted, until
+	// which CancelUserDeletion keeps them.
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// Interval is how often due deletions are carried out.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Gateway serves the TodoService as REST/JSON at ListenAddr, see package
+// gateway. Empty ListenAddr disables it.
+type Gateway struct {
+	ListenAddr string `yaml:"listen_addr"`
+}
+
+// Web serves the TodoService to browsers over the Connect protocol and
+// gRPC-Web at ListenAddr, see package web. Empty ListenAddr disables it.
+type Web struct {
+	ListenAddr string `yaml:"listen_addr"`
+	// AllowedOrigins of the pages which may call the service, such as
+	// https://todo.example.com, or "*" for any.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// CORSMaxAge is how long browsers may cache answers to preflight requests.
+	CORSMaxAge time.Duration `yaml:"cors_max_age"`
+}
+
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Secret is a string that is never printed.
+// Use Reveal to get the actual value.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func Default() Config {
+	return Config{
+		ListenAddr: ":50051",
+		Storage: Storage{
+			Backend:    "postgres",
+			SQLitePath: "todo.db",
+		},
+		Cache: Cache{
+			Size: 10000,
+			TTL:  5 * time.Minute,
+		},
+		Database: Database{
+			Host:     "localhost",
+			Port:     5432,
+			SSLMode:  "prefer",
+			MaxConns: 4,
+		},
+		TLS: TLS{
+			ReloadEvery: time.Minute,
+		},
+		RateLimits: RateLimits{
+			Store: "memory",
+			Default: MethodLimits{
+				PerCaller: Rate{PerSecond: 100, Burst: 200},
+				PerUser:   Rate{PerSecond: 5, Burst: 20},
+			},
+			Methods: map[string]MethodLimits{
+				"CreateReminder":  {PerUser: Rate{PerSecond: 1, Burst: 10}},
+				"ImportCalendar":  {PerUser: Rate{PerSecond: 0.01, Burst: 3}},
+				"ImportReminders": {PerUser: Rate{PerSecond: 0.01, Burst: 3}},
+				"ExportUserData":  {PerUser: Rate{PerSecond: 0.001, Burst: 3}},
+			},
+		},
+		Quotas: Quotas{
+			MaxActiveReminders: 1000,
+		},
+		Idempotency: Idempotency{
+			TTL: 24 * time.Hour,
+		},
+		Events: Events{
+			Retention: 7 * 24 * time.Hour,
+		},
+		Notify: Notify{
+			Interval:    10 * time.Second,
+			MaxAttempts: 5,
+			Backoff:     30 * time.Second,
+			MaxBackoff:  time.Hour,
+			Timeout:     10 * time.Second,
+			Telegram: Telegram{
+				APIURL: "https://api.telegram.org",
+			},
+		},
+		Webhooks: Webhooks{
+			Interval:     10 * time.Second,
+			MaxAttempts:  8,
+			Backoff:      30 * time.Second,
+			MaxBackoff:   6 * time.Hour,
+			Timeout:      10 * time.Second,
+			DisableAfter: 50,
+		},
+		Hooks: Hooks{
+			PerAddress: Rate{PerSecond: 1, Burst: 10},
+		},
+		CalendarFeeds: CalendarFeeds{
+			PerAddress: Rate{PerSecond: 1, Burst: 10},
+		},
+		UserDeletion: UserDeletion{
+			GracePeriod: 30 * 24 * time.Hour,
+			Interval:    time.Minute,
+		},
+		Web: Web{
+			CORSMaxAge: 2 * time.Hour,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// DSN returns a libpq-style connection URL understood by pgxpool,
+// password included. Never log it, log the Database itself instead.
+func (d Database) DSN() string {
+	return d.url(url.UserPassword(d.User, d.Password.Reveal())).String()
+}
+
+// String returns the connection URL with the password redacted.
+func (d Database) String() string {
+	return d.url(url.UserPassword(d.User, d.Password.String())).String()
+}
+
+func (d Database) url(user *url.Userinfo) *url.URL {
+	query := url.Values{}
+	query.Set("sslmode", d.SSLMode)
+	query.Set("pool_max_conns", strconv.Itoa(int(d.MaxConns)))
+	query.Set("pool_min_conns", strconv.Itoa(int(d.MinConns)))
+
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     user,
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Name,
+		RawQuery: query.Encode(),
+	}
+}
+
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("listen_addr: %w", err))
+	}
+
+	if c.DebugAddr != "" {
+		if _, _, err := net.SplitHostPort(c.DebugAddr); err != nil {
+			errs = append(errs, fmt.Errorf("debug_addr: %w", err))
+		}
+	}
+
+	switch c.Storage.Backend {
+	case "postgres":
+		db := c.Database
+		if db.Host == "" {
+			errs = append(errs, errors.New("database.host: must not be empty"))
+		}
+		if db.Port < 1 || db.Port > 65535 {
+			errs = append(errs, fmt.Errorf("database.port: %d is out of range", db.Port))
+		}
+		if db.User == "" {
+			errs = append(errs, errors.New("database.user: must not be empty"))
+		}
+		if db.Name == "" {
+			errs = append(errs, errors.New("database.name: must not be empty"))
+		}
+		if !sslModes[db.SSLMode] {
+			errs = append(errs, fmt.Errorf("database.ssl_mode: unknown mode %q", db.SSLMode))
+		}
+		if db.MaxConns < 1 {
+			errs = append(errs, errors.New("database.max_conns: must be positive"))
+		}
+		if db.MinConns < 0 || db.MinConns > db.MaxConns {
+			errs = append(errs, errors.New("database.min_conns: must be between 0 and max_conns"))
+		}
+	case "sqlite":
+		if c.Storage.SQLitePath == "" {
+			errs = append(errs, errors.New("storage.sqlite_path: must not be empty"))
+		}
+		if c.RateLimits.Store == "postgres" {
+			errs = append(errs, errors.New("rate_limits.store: postgres requires the postgres storage backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: must be postgres or sqlite, got %q", c.Storage.Backend))
+	}
+
+	if c.Cache.Size < 0 {
+		errs = append(errs, errors.New("cache.size: must not be negative"))
+	}
+	if c.Cache.Size > 0 && c.Cache.TTL <= 0 {
+		errs = append(errs, errors.New("cache.ttl: must be positive"))
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls: cert_file and key_file must be set together"))
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		errs = append(errs, errors.New("tls.client_ca_file: requires cert_file and key_file"))
+	}
+	if c.TLS.ReloadEvery < 0 {
+		errs = append(errs, errors.New("tls.reload_every: must not be negative"))
+	}
+
+	if c.RateLimits.Store != "memory" && c.RateLimits.Store != "postgres" {
+		errs = append(errs, fmt.Errorf("rate_limits.store: must be memory or postgres, got %q", c.RateLimits.Store))
+	}
+	errs = append(errs,
+		c.RateLimits.Default.PerCaller.validate("rate_limits.default.per_caller"),
+		c.RateLimits.Default.PerUser.validate("rate_limits.default.per_user"),
+	)
+	for method, limits := range c.RateLimits.Methods {
+		errs = append(errs,
+			limits.PerCaller.validate("rate_limits.methods."+method+".per_caller"),
+			limits.PerUser.validate("rate_limits.methods."+method+".per_user"),
+		)
+	}
+	if c.Quotas.MaxActiveReminders < 0 {
+		errs = append(errs, errors.New("quotas.max_active_reminders: must not be negative"))
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency.ttl: must be positive"))
+	}
+
+	if c.Events.Retention <= 0 {
+		errs = append(errs, errors.New("events.retention: must be positive"))
+	}
+
+	errs = append(errs, c.Notify.validate()...)
+	errs = append(errs, c.Webhooks.validate()...)
+
+	if c.Hooks.ListenAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Hooks.ListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("hooks.listen_addr: %w", err))
+		}
+	}
+	if err := c.Hooks.PerAddress.validate("hooks.per_address"); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.CalendarFeeds.ListenAddr != "" {
+		if _, _, err := net.SplitHostPort(c.CalendarFeeds.ListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("calendar_feeds.listen_addr: %w", err))
+		}
+	}
+	if c.CalendarFeeds.BaseURL != "" {
+		if u, err := url.Parse(c.CalendarFeeds.BaseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("calendar_feeds.base_url: %q is not an http(s) URL", c.CalendarFeeds.BaseURL))
+		}
+	}
+	if err := c.CalendarFeeds.PerAddress.validate("calendar_feeds.per_address"); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.UserDeletion.GracePeriod < 0 {
+		errs = append(errs, errors.New("user_deletion.grace_period: must not be negative"))
+	}
+	if c.UserDeletion.Interval <= 0 {
+		errs = append(errs, errors.New("user_deletion.interval: must be positive"))
+	}
+
+	if c.Gateway.ListenAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Gateway.ListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("gateway.listen_addr: %w", err))
+		}
+	}
+
+	errs = append(errs, c.Web.validate()...)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log.format: must be text or json, got %q", c.Log.Format))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n Notify) validate() []error {
+	var errs []error
+
+	if n.Interval <= 0 {
+		errs = append(errs, errors.New("notify.interval: must be positive"))
+	}
+	if n.MaxAttempts < 1 {
+		errs = append(errs, errors.New("notify.max_attempts: must be positive"))
+	}
+	if n.Backoff <= 0 {
+		errs = append(errs, errors.New("notify.backoff: must be positive"))
+	}
+	if n.MaxBackoff < n.Backoff {
+		errs = append(errs, errors.New("notify.max_backoff: must not be less than backoff"))
+	}
+	if n.Timeout <= 0 {
+		errs = append(errs, errors.New("notify.timeout: must be positive"))
+	}
+
+	if n.Telegram.BotToken != "" {
+		if u, err := url.Parse(n.Telegram.APIURL); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("notify.telegram.api_url: %q is not an absolute URL", n.Telegram.APIURL))
+		}
+	}
+
+	if n.Email.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(n.Email.SMTPAddr); err != nil {
+			errs = append(errs, fmt.Errorf("notify.email.smtp_addr: %w", err))
+		}
+		if n.Email.From == "" {
+			errs = append(errs, errors.New("notify.email.from: must not be empty"))
+		}
+	}
+
+	return errs
+}
+
+func (w Webhooks) validate() []error {
+	var errs []error
+
+	if w.Interval <= 0 {
+		errs = append(errs, errors.New("webhooks.interval: must be positive"))
+	}
+	if w.MaxAttempts < 1 {
+		errs = append(errs, errors.New("webhooks.max_attempts: must be positive"))
+	}
+	if w.Backoff <= 0 {
+		errs = append(errs, errors.New("webhooks.backoff: must be positive"))
+	}
+	if w.MaxBackoff < w.Backoff {
+		errs = append(errs, errors.New("webhooks.max_backoff: must not be less than backoff"))
+	}
+	if w.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout: must be positive"))
+	}
+	if w.DisableAfter < 1 {
+		errs = append(errs, errors.New("webhooks.disable_after: must be positive"))
+	}
+
+	return errs
+}
+
+func (w Web) validate() []error {
+	var errs []error
+
+	if w.ListenAddr != "" {
+		if _, _, err := net.SplitHostPort(w.ListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("web.listen_addr: %w", err))
+		}
+	}
+	for _, origin := range w.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("web.allowed_origins: %q is not a scheme://host[:port] origin", origin))
+		}
+	}
+	if w.CORSMaxAge < 0 {
+		errs = append(errs, errors.New("web.cors_max_age: must not be negative"))
+	}
+
+	return errs
+}
+
+// Load registers the configuration flags on fs, parses args and resolves
+// the configuration. Callers may register their own flags on fs beforehand
+// and read the remaining arguments with fs.Args afterwards.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	path := fs.String("config", os.Getenv("TODO_CONFIG"), "path to the YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		fs.Func(s.flag, s.usage, func(v string) error {
+			flagValues[s.flag] = v
+
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		for _, env := range s.env {
+			if v, ok := os.LookupEnv(env); ok {
+				if err := s.set(&cfg, v); err != nil {
+					return nil, fmt.Errorf("%s: %w", env, err)
+				}
+
+				break
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flagValues[s.flag]; ok {
+			if err := s.set(&cfg, v); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
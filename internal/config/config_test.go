@@ -0,0 +1,242 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("cannot write config file: %v", err)
+	}
+
+	return path
+}
+
+func load(args ...string) (*Config, error) {
+	return Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+listen_addr: ":7000"
+database:
+  host: db.internal
+  user: file_user
+  password: file_password
+  name: todo
+  max_conns: 10
+`)
+
+	t.Run("file", func(t *testing.T) {
+		cfg, err := load("-config", path)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if cfg.ListenAddr != ":7000" || cfg.Database.Host != "db.internal" || cfg.Database.MaxConns != 10 {
+			t.Errorf("file values were not applied: %+v", cfg)
+		}
+		if cfg.Database.Port != 5432 || cfg.Log.Level != "info" {
+			t.Errorf("defaults were not kept: %+v", cfg)
+		}
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		t.Setenv("TODO_CONFIG", path)
+		t.Setenv("TODO_DB_HOST", "env.internal")
+		t.Setenv("DB_USER", "legacy_user")
+
+		cfg, err := load()
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if cfg.Database.Host != "env.internal" {
+			t.Errorf("expected host from env got %q", cfg.Database.Host)
+		}
+		if cfg.Database.User != "legacy_user" {
+			t.Errorf("expected user from legacy env got %q", cfg.Database.User)
+		}
+	})
+
+	t.Run("prefixed env overrides legacy env", func(t *testing.T) {
+		t.Setenv("TODO_DB_USER", "new_user")
+		t.Setenv("DB_USER", "legacy_user")
+
+		cfg, err := load("-config", path)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if cfg.Database.User != "new_user" {
+			t.Errorf("expected user from TODO_DB_USER got %q", cfg.Database.User)
+		}
+	})
+
+	t.Run("flags override env", func(t *testing.T) {
+		t.Setenv("TODO_DB_PORT", "6000")
+
+		cfg, err := load("-config", path, "-db-port", "6543", "-listen-addr", "127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if cfg.Database.Port != 6543 || cfg.ListenAddr != "127.0.0.1:9000" {
+			t.Errorf("flag values were not applied: %+v", cfg)
+		}
+	})
+
+	t.Run("unknown file field", func(t *testing.T) {
+		_, err := load("-config", writeConfig(t, "databse:\n  host: typo\n"))
+		if err == nil {
+			t.Errorf("expected error for unknown field")
+		}
+	})
+
+	t.Run("malformed env", func(t *testing.T) {
+		t.Setenv("TODO_DB_MAX_CONNS", "many")
+
+		_, err := load("-config", path)
+		if err == nil || !strings.Contains(err.Error(), "TODO_DB_MAX_CONNS") {
+			t.Errorf("expected error naming TODO_DB_MAX_CONNS got %v", err)
+		}
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Default()
+	valid.Database.User = "user"
+	valid.Database.Name = "todo"
+
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("did not expect error for valid config got %v", err)
+	}
+
+	broken := map[string]func(*Config){
+		"listen_addr":     func(c *Config) { c.ListenAddr = "50051" },
+		"storage.backend": func(c *Config) { c.Storage.Backend = "mysql" },
+		"storage.sqlite_path": func(c *Config) {
+			c.Storage = Storage{Backend: "sqlite"}
+		},
+		"database.host":      func(c *Config) { c.Database.Host = "" },
+		"database.port":      func(c *Config) { c.Database.Port = 70000 },
+		"database.user":      func(c *Config) { c.Database.User = "" },
+		"database.name":      func(c *Config) { c.Database.Name = "" },
+		"database.ssl_mode":  func(c *Config) { c.Database.SSLMode = "sometimes" },
+		"database.max_conns": func(c *Config) { c.Database.MaxConns = 0 },
+		"database.min_conns": func(c *Config) { c.Database.MinConns = 5 },
+		"tls":                func(c *Config) { c.TLS.CertFile = "cert.pem" },
+		"tls.client_ca_file": func(c *Config) { c.TLS.ClientCAFile = "ca.pem" },
+		"tls.reload_every":   func(c *Config) { c.TLS.ReloadEvery = -time.Second },
+		"rate_limits.store":  func(c *Config) { c.RateLimits.Store = "redis" },
+		"rate_limits.default.per_user.burst": func(c *Config) {
+			c.RateLimits.Default.PerUser = Rate{PerSecond: 1}
+		},
+		"rate_limits.methods.GetUser.per_caller.per_second": func(c *Config) {
+			c.RateLimits.Methods = map[string]MethodLimits{"GetUser": {PerCaller: Rate{PerSecond: -1}}}
+		},
+		"quotas.max_active_reminders": func(c *Config) { c.Quotas.MaxActiveReminders = -1 },
+		"idempotency.ttl":             func(c *Config) { c.Idempotency.TTL = 0 },
+		"events.retention":            func(c *Config) { c.Events.Retention = 0 },
+		"notify.interval":             func(c *Config) { c.Notify.Interval = 0 },
+		"notify.max_attempts":         func(c *Config) { c.Notify.MaxAttempts = 0 },
+		"notify.backoff":              func(c *Config) { c.Notify.Backoff = 0 },
+		"notify.max_backoff":          func(c *Config) { c.Notify.MaxBackoff = time.Second },
+		"notify.timeout":              func(c *Config) { c.Notify.Timeout = -time.Second },
+		"notify.telegram.api_url": func(c *Config) {
+			c.Notify.Telegram = Telegram{BotToken: "token", APIURL: "api.telegram.org"}
+		},
+		"notify.email.smtp_addr": func(c *Config) { c.Notify.Email = Email{SMTPAddr: "localhost", From: "bot@example.com"} },
+		"notify.email.from":      func(c *Config) { c.Notify.Email = Email{SMTPAddr: "localhost:25"} },
+		"webhooks.interval":      func(c *Config) { c.Webhooks.Interval = 0 },
+		"webhooks.max_attempts":  func(c *Config) { c.Webhooks.MaxAttempts = 0 },
+		"webhooks.backoff":       func(c *Config) { c.Webhooks.Backoff = -time.Second },
+		"webhooks.max_backoff":   func(c *Config) { c.Webhooks.MaxBackoff = time.Second },
+		"webhooks.timeout":       func(c *Config) { c.Webhooks.Timeout = 0 },
+		"webhooks.disable_after": func(c *Config) { c.Webhooks.DisableAfter = 0 },
+		"hooks.listen_addr":      func(c *Config) { c.Hooks.ListenAddr = "8080" },
+		"hooks.per_address.burst": func(c *Config) {
+			c.Hooks.PerAddress = Rate{PerSecond: 1}
+		},
+		"calendar_feeds.listen_addr": func(c *Config) { c.CalendarFeeds.ListenAddr = "calendar" },
+		"calendar_feeds.base_url":    func(c *Config) { c.CalendarFeeds.BaseURL = "todo.example.com" },
+		"calendar_feeds.per_address.per_second": func(c *Config) {
+			c.CalendarFeeds.PerAddress = Rate{PerSecond: -1, Burst: 1}
+		},
+		"user_deletion.grace_period": func(c *Config) { c.UserDeletion.GracePeriod = -time.Hour },
+		"user_deletion.interval":     func(c *Config) { c.UserDeletion.Interval = 0 },
+		"gateway.listen_addr":        func(c *Config) { c.Gateway.ListenAddr = "localhost" },
+		"web.listen_addr":            func(c *Config) { c.Web.ListenAddr = "web" },
+		"web.allowed_origins":        func(c *Config) { c.Web.AllowedOrigins = []string{"https://todo.example.com/app"} },
+		"web.cors_max_age":           func(c *Config) { c.Web.CORSMaxAge = -time.Second },
+		"log.level":                  func(c *Config) { c.Log.Level = "verbose" },
+		"log.format":                 func(c *Config) { c.Log.Format = "xml" },
+	}
+
+	for field, breakConfig := range broken {
+		cfg := valid
+		breakConfig(&cfg)
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("expected error mentioning %s got %v", field, err)
+		}
+	}
+
+	sqlite := Default()
+	sqlite.Storage.Backend = "sqlite"
+	if err := sqlite.Validate(); err != nil {
+		t.Errorf("did not expect database settings to be required by sqlite got %v", err)
+	}
+
+	sqlite.RateLimits.Store = "postgres"
+	if err := sqlite.Validate(); err == nil || !strings.Contains(err.Error(), "rate_limits.store") {
+		t.Errorf("expected error mentioning rate_limits.store got %v", err)
+	}
+}
+
+func TestRateLimits_For(t *testing.T) {
+	limits := Default().RateLimits
+
+	if got := limits.For("GetUser"); got != limits.Default {
+		t.Errorf("expected default limits for GetUser got %+v", got)
+	}
+
+	got := limits.For("CreateReminder")
+	if got.PerCaller != limits.Default.PerCaller {
+		t.Errorf("expected default per caller limit for CreateReminder got %+v", got.PerCaller)
+	}
+	if got.PerUser != limits.Methods["CreateReminder"].PerUser {
+		t.Errorf("expected overridden per user limit for CreateReminder got %+v", got.PerUser)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	db := Default().Database
+	db.User = "user"
+	db.Name = "todo"
+	db.Password = "hunter2"
+
+	for _, out := range []string{
+		db.String(),
+		fmt.Sprintf("%v", db),
+		fmt.Sprintf("%+v", db),
+		fmt.Sprintf("%#v", db),
+		fmt.Sprint(Config{Database: db}),
+	} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("password leaked in %q", out)
+		}
+	}
+
+	if !strings.Contains(db.DSN(), "hunter2") {
+		t.Errorf("expected password in DSN")
+	}
+}
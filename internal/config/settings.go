@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// setting binds one configuration field to its flag and environment variables.
+// Environment variables are tried in order, the first one set is used.
+type setting struct {
+	flag  string
+	env   []string
+	usage string
+	field func(*Config) any
+}
+
+var settings = []setting{
+	{
+		flag: "listen-addr", env: []string{"TODO_LISTEN_ADDR"},
+		usage: "address the gRPC server listens on",
+		field: func(c *Config) any { return &c.ListenAddr },
+	},
+	{
+		flag: "debug-addr", env: []string{"TODO_DEBUG_ADDR"},
+		usage: "address serving /debug/vars with cache statistics, none if empty",
+		field: func(c *Config) any { return &c.DebugAddr },
+	},
+	{
+		flag: "storage-backend", env: []string{"TODO_STORAGE_BACKEND"},
+		usage: "where to keep data (postgres, sqlite)",
+		field: func(c *Config) any { return &c.Storage.Backend },
+	},
+	{
+		flag: "sqlite-path", env: []string{"TODO_SQLITE_PATH"},
+		usage: "database file of the sqlite storage backend",
+		field: func(c *Config) any { return &c.Storage.SQLitePath },
+	},
+	{
+		flag: "cache-size", env: []string{"TODO_CACHE_SIZE"},
+		usage: "number of users and reminder lists cached in memory, 0 disables the cache",
+		field: func(c *Config) any { return &c.Cache.Size },
+	},
+	{
+		flag: "cache-ttl", env: []string{"TODO_CACHE_TTL"},
+		usage: "how long cached users and reminders are kept",
+		field: func(c *Config) any { return &c.Cache.TTL },
+	},
+	{
+		flag: "db-host", env: []string{"TODO_DB_HOST"},
+		usage: "database host",
+		field: func(c *Config) any { return &c.Database.Host },
+	},
+	{
+		flag: "db-port", env: []string{"TODO_DB_PORT"},
+		usage: "database port",
+		field: func(c *Config) any { return &c.Database.Port },
+	},
+	{
+		flag: "db-user", env: []string{"TODO_DB_USER", "DB_USER"},
+		usage: "database user",
+		field: func(c *Config) any { return &c.Database.User },
+	},
+	{
+		flag: "db-password", env: []string{"TODO_DB_PASSWORD", "DB_PASSWORD"},
+		usage: "database password, prefer the environment variable over this flag",
+		field: func(c *Config) any { return &c.Database.Password },
+	},
+	{
+		flag: "db-name", env: []string{"TODO_DB_NAME", "DB_NAME"},
+		usage: "database name",
+		field: func(c *Config) any { return &c.Database.Name },
+	},
+	{
+		flag: "db-ssl-mode", env: []string{"TODO_DB_SSL_MODE"},
+		usage: "database sslmode (disable, allow, prefer, require, verify-ca, verify-full)",
+		field: func(c *Config) any { return &c.Database.SSLMode },
+	},
+	{
+		flag: "db-max-conns", env: []string{"TODO_DB_MAX_CONNS"},
+		usage: "maximum size of the database connection pool",
+		field: func(c *Config) any { return &c.Database.MaxConns },
+	},
+	{
+		flag: "db-min-conns", env: []string{"TODO_DB_MIN_CONNS"},
+		usage: "minimum size of the database connection pool",
+		field: func(c *Config) any { return &c.Database.MinConns },
+	},
+	{
+		flag: "tls-cert-file", env: []string{"TODO_TLS_CERT_FILE"},
+		usage: "PEM certificate of the server, enables TLS together with -tls-key-file",
+		field: func(c *Config) any { return &c.TLS.CertFile },
+	},
+	{
+		flag: "tls-key-file", env: []string{"TODO_TLS_KEY_FILE"},
+		usage: "PEM private key of the server",
+		field: func(c *Config) any { return &c.TLS.KeyFile },
+	},
+	{
+		flag: "tls-client-ca-file", env: []string{"TODO_TLS_CLIENT_CA_FILE"},
+		usage: "PEM bundle of CAs trusted to sign client certificates, enables mutual TLS",
+		field: func(c *Config) any { return &c.TLS.ClientCAFile },
+	},
+	{
+		flag: "tls-reload-every", env: []string{"TODO_TLS_RELOAD_EVERY"},
+		usage: "how often certificate files are checked for changes, 0 disables the check",
+		field: func(c *Config) any { return &c.TLS.ReloadEvery },
+	},
+	{
+		flag: "auth-jwt-secret", env: []string{"TODO_AUTH_JWT_SECRET"},
+		usage: "HS256 secret of accepted JWTs, prefer the environment variable over this flag",
+		field: func(c *Config) any { return &c.Auth.JWTSecret },
+	},
+	{
+		flag: "rate-limits-store", env: []string{"TODO_RATE_LIMITS_STORE"},
+		usage: "where rate limit buckets are kept (memory, postgres)",
+		field: func(c *Config) any { return &c.RateLimits.Store },
+	},
+	{
+		flag: "max-active-reminders", env: []string{"TODO_MAX_ACTIVE_REMINDERS"},
+		usage: "maximum number of active reminders per user, 0 means no limit",
+		field: func(c *Config) any { return &c.Quotas.MaxActiveReminders },
+	},
+	{
+		flag: "idempotency-ttl", env: []string{"TODO_IDEMPOTENCY_TTL"},
+		usage: "how long outcomes of requests with idempotency keys are kept",
+		field: func(c *Config) any { return &c.Idempotency.TTL },
+	},
+	{
+		flag: "events-retention", env: []string{"TODO_EVENTS_RETENTION"},
+		usage: "how long user events are kept for clients resuming WatchUserEvents",
+		field: func(c *Config) any { return &c.Events.Retention },
+	},
+	{
+		flag: "notify-interval", env: []string{"TODO_NOTIFY_INTERVAL"},
+		usage: "how often due reminders are fired and deliveries attempted",
+		field: func(c *Config) any { return &c.Notify.Interval },
+	},
+	{
+		flag: "telegram-bot-token", env: []string{"TODO_TELEGRAM_BOT_TOKEN"},
+		usage: "token of the bot sending reminders to Telegram, prefer the environment variable over this flag",
+		field: func(c *Config) any { return &c.Notify.Telegram.BotToken },
+	},
+	{
+		flag: "smtp-addr", env: []string{"TODO_SMTP_ADDR"},
+		usage: "host:port of the SMTP server sending reminders by email, none if empty",
+		field: func(c *Config) any { return &c.Notify.Email.SMTPAddr },
+	},
+	{
+		flag: "smtp-username", env: []string{"TODO_SMTP_USERNAME"},
+		usage: "SMTP user name, no authentication if empty",
+		field: func(c *Config) any { return &c.Notify.Email.Username },
+	},
+	{
+		flag: "smtp-password", env: []string{"TODO_SMTP_PASSWORD"},
+		usage: "SMTP password, prefer the environment variable over this flag",
+		field: func(c *Config) any { return &c.Notify.Email.Password },
+	},
+	{
+		flag: "smtp-from", env: []string{"TODO_SMTP_FROM"},
+		usage: "sender address of reminders sent by email",
+		field: func(c *Config) any { return &c.Notify.Email.From },
+	},
+	{
+		flag: "webhooks-interval", env: []string{"TODO_WEBHOOKS_INTERVAL"},
+		usage: "how often events are sent to user webhooks",
+		field: func(c *Config) any { return &c.Webhooks.Interval },
+	},
+	{
+		flag: "hooks-addr", env: []string{"TODO_HOOKS_ADDR"},
+		usage: "address serving POST /hooks/{token} for automations creating reminders, none if empty",
+		field: func(c *Config) any { return &c.Hooks.ListenAddr },
+	},
+	{
+		flag: "calendar-feeds-addr", env: []string{"TODO_CALENDAR_FEEDS_ADDR"},
+		usage: "address serving GET /calendar/{secret}.ics for calendar apps, none if empty",
+		field: func(c *Config) any { return &c.CalendarFeeds.ListenAddr },
+	},
+	{
+		flag: "calendar-feeds-url", env: []string{"TODO_CALENDAR_FEEDS_URL"},
+		usage: "URL where calendar apps reach the calendar feeds address",
+		field: func(c *Config) any { return &c.CalendarFeeds.BaseURL },
+	},
+	{
+		flag: "gateway-addr", env: []string{"TODO_GATEWAY_ADDR"},
+		usage: "address serving the REST/JSON gateway and /openapi.json, none if empty",
+		field: func(c *Config) any { return &c.Gateway.ListenAddr },
+	},
+	{
+		flag: "web-addr", env: []string{"TODO_WEB_ADDR"},
+		usage: "address serving Connect and gRPC-Web for browsers, none if empty",
+		field: func(c *Config) any { return &c.Web.ListenAddr },
+	},
+	{
+		flag: "log-level", env: []string{"TODO_LOG_LEVEL"},
+		usage: "minimal log level (debug, info, warn, error)",
+		field: func(c *Config) any { return &c.Log.Level },
+	},
+	{
+		flag: "log-format", env: []string{"TODO_LOG_FORMAT"},
+		usage: "log format (text, json)",
+		field: func(c *Config) any { return &c.Log.Format },
+	},
+}
+
+func (s setting) set(cfg *Config, value string) error {
+	switch field := s.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *Secret:
+		*field = Secret(value)
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = v
+	case *int32:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		*field = int32(v)
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = v
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+
+	return nil
+}
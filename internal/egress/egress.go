@@ -0,0 +1,54 @@
+// Package egress makes HTTP clients for URLs given by users, such as those
+// of webhooks, which must not reach the network of the server: connections
+// to loopback, private, link-local and unspecified addresses are refused
+// once host names are resolved.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// Control refuses connections to addresses of the network of the server.
+// It is called by net.Dialer after resolving the address.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s: %w", ip, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+// NewClient returns a client with timeout whose connections are checked by
+// Control. It does not use proxies, which would be checked instead.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: Control}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
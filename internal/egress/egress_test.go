@@ -0,0 +1,45 @@
+package egress
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestControl(t *testing.T) {
+	for _, tt := range []struct {
+		address   string
+		forbidden bool
+	}{
+		{"93.184.215.14:443", false},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", false},
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"10.1.2.3:80", true},
+		{"192.168.0.1:80", true},
+		{"169.254.169.254:80", true},
+		{"[fe80::1]:80", true},
+		{"[fd00::1]:80", true},
+		{"0.0.0.0:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+	} {
+		err := Control("tcp", tt.address, nil)
+		if forbidden := errors.Is(err, ErrForbiddenAddress); forbidden != tt.forbidden {
+			t.Errorf("expected %s forbidden %v got %v", tt.address, tt.forbidden, err)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("did not expect the request to reach the server")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected %v got %v", ErrForbiddenAddress, err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package events fans notifications about new user events out to the
+// WatchUserEvents streams of the users.
+//
+// Notifications carry no events, they only wake the streams of a user up.
+// Every stream then reads the events it has not sent yet from the store at
+// its own pace, so a slow client neither blocks the others nor makes the
+// server buffer events for it.
+package events
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+type Store interface {
+	// UserEventSeqRange returns the seqs of the oldest and the latest kept
+	// events of all users, zeros when there are none.
+	UserEventSeqRange(ctx context.Context) (oldest, latest int64, err error)
+	// UserEvents returns up to limit events of the user with seqs greater
+	// than afterSeq, in the order of their seqs.
+	UserEvents(ctx context.Context, userId, afterSeq int64, limit int) ([]*pb.UserEvent, error)
+	DeleteUserEvents(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+type Feed struct {
+	Store
+
+	mu sync.Mutex
+	// subscribers holds the wake up channels of the streams of every user.
+	subscribers map[int64]map[chan struct{}]struct{}
+}
+
+func NewFeed(store Store) *Feed {
+	return &Feed{
+		Store:       store,
+		subscribers: make(map[int64]map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving a value whenever new events of the
+// user may be available. Wake ups coming while the previous one was not
+// received yet are merged into it. Call cancel once done.
+func (f *Feed) Subscribe(userId int64) (wake <-chan struct{}, cancel func()) {
+	ch := make(chan struct{}, 1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.subscribers[userId] == nil {
+		f.subscribers[userId] = make(map[chan struct{}]struct{})
+	}
+	f.subscribers[userId][ch] = struct{}{}
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		delete(f.subscribers[userId], ch)
+		if len(f.subscribers[userId]) == 0 {
+			delete(f.subscribers, userId)
+		}
+	}
+}
+
+func wakeUp(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Notify wakes up the streams of the user whose id is payload, as sent on
+// postgresrepo.UserEventsChannel.
+func (f *Feed) Notify(payload string) {
+	userId, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		log.Printf("Error in user events notification %q: %v", payload, err)
+		f.WakeAll()
+
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers[userId] {
+		wakeUp(ch)
+	}
+}
+
+// WakeAll wakes up every stream, e.g. after notifications may have been lost.
+func (f *Feed) WakeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, channels := range f.subscribers {
+		for ch := range channels {
+			wakeUp(ch)
+		}
+	}
+}
+
+// Purge deletes events older than retention every interval until ctx is done.
+func (f *Feed) Purge(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := f.DeleteUserEvents(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("Error deleting old user events: %v", err)
+			}
+		}
+	}
+}
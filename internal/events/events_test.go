@@ -0,0 +1,53 @@
+package events
+
+import (
+	"testing"
+)
+
+func woken(wake <-chan struct{}) bool {
+	select {
+	case <-wake:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFeed_Notify(t *testing.T) {
+	feed := NewFeed(nil)
+
+	first, cancelFirst := feed.Subscribe(1)
+	second, cancelSecond := feed.Subscribe(1)
+	other, cancelOther := feed.Subscribe(2)
+	defer cancelSecond()
+	defer cancelOther()
+
+	feed.Notify("1")
+	feed.Notify("1")
+
+	if !woken(first) || !woken(second) {
+		t.Errorf("expected every stream of user 1 to be woken up")
+	}
+	if woken(first) {
+		t.Errorf("expected wake ups to be merged")
+	}
+	if woken(other) {
+		t.Errorf("did not expect stream of user 2 to be woken up")
+	}
+
+	cancelFirst()
+	feed.Notify("1")
+	if woken(first) {
+		t.Errorf("did not expect canceled stream to be woken up")
+	}
+
+	feed.Notify("garbage")
+	if !woken(second) || !woken(other) {
+		t.Errorf("expected malformed notification to wake every stream up")
+	}
+
+	feed.WakeAll()
+	if !woken(second) || !woken(other) {
+		t.Errorf("expected WakeAll to wake every stream up")
+	}
+}
@@ -0,0 +1,68 @@
+// Package gateway serves the TodoService as REST/JSON for web and script
+// clients, translating requests to gRPC calls with grpc-gateway as mapped by
+// the google.api.http options of todo-service.proto.
+//
+// Messages are encoded with protojson using the field names of the proto,
+// so wrappers are plain values or null and timestamps RFC 3339 strings:
+//
+//	curl -H 'Authorization: Bearer tdk_...' https://todo.example.com/v1/users/42
+//	{"id":"42", "language_code":"en", "utc_offset":3}
+//
+// Server streams such as GET /v1/users/{id}/reminders are sent as one JSON
+// object per line, {"result": message} for every message and
+// {"error": status} when the stream fails.
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
+)
+
+// OpenAPIPath is where the OpenAPI document of the gateway is served.
+const OpenAPIPath = "/openapi.json"
+
+// forwardedHeaders are passed to the gRPC server as metadata of the same
+// name, besides Authorization, which grpc-gateway always passes.
+var forwardedHeaders = []string{idempotency.MetadataKey, audit.MetadataKey}
+
+func headerMatcher(key string) (string, bool) {
+	if name := strings.ToLower(key); slices.Contains(forwardedHeaders, name) {
+		return name, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// New returns a handler calling the TodoService through conn, so requests
+// are authenticated, limited and validated like any other call.
+func New(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	gateway := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+	)
+	if err := pb.RegisterTodoServiceHandler(ctx, gateway, conn); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gateway)
+	mux.HandleFunc("GET "+OpenAPIPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(pb.OpenAPI)
+	})
+
+	return mux, nil
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+)
+
+const key = auth.APIKeyPrefix + "service"
+
+// StubKeyStore only knows key, a service key.
+type StubKeyStore struct{}
+
+func (StubKeyStore) FindAPIKey(_ context.Context, hash []byte) (auth.Caller, error) {
+	if string(hash) != string(auth.HashAPIKey(key)) {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+
+	return auth.Caller{Name: "test"}, nil
+}
+
+func gateway(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	authenticator := auth.NewAuthenticator(StubKeyStore{}, nil)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	pb.RegisterTodoServiceServer(s, todoserviceserver.New(memrepo.New()))
+
+	lis := bufconn.Listen(1024 * 1024)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("cannot dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	handler, err := New(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("cannot create gateway: %v", err)
+	}
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("cannot create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cannot %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("cannot read response: %v", err)
+	}
+
+	return resp.StatusCode, string(got)
+}
+
+func TestGateway(t *testing.T) {
+	srv := gateway(t)
+
+	t.Run("user", func(t *testing.T) {
+		if code, body := do(t, srv, http.MethodPut, "/v1/users/42", `{"language_code": "en", "utc_offset": 3}`); code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, code, body)
+		}
+
+		code, body := do(t, srv, http.MethodGet, "/v1/users/42", "")
+		if code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, code, body)
+		}
+
+		var user map[string]any
+		if err := json.Unmarshal([]byte(body), &user); err != nil {
+			t.Fatalf("cannot decode %s: %v", body, err)
+		}
+		if user["id"] != "42" || user["language_code"] != "en" || user["utc_offset"] != 3.0 {
+			t.Errorf("expected user 42 with plain wrapper values got %s", body)
+		}
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		for _, text := range []string{"first", "second"} {
+			code, body := do(t, srv, http.MethodPost, "/v1/users/42/reminders", `{"reminder_text": "`+text+`", "remind_timestamp": "2100-01-02T03:04:05Z"}`)
+			if code != http.StatusOK || !strings.Contains(body, `"id"`) {
+				t.Fatalf("expected %d with an id got %d: %s", http.StatusOK, code, body)
+			}
+		}
+
+		code, body := do(t, srv, http.MethodGet, "/v1/users/42/reminders", "")
+		if code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, code, body)
+		}
+
+		var lines []string
+		scanner := bufio.NewScanner(strings.NewReader(body))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if len(lines) != 2 {
+			t.Fatalf("expected a line per reminder got %q", body)
+		}
+
+		var first struct {
+			Result map[string]any `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+			t.Fatalf("cannot decode %s: %v", lines[0], err)
+		}
+		if first.Result["reminder_text"] != "first" || first.Result["remind_timestamp"] != "2100-01-02T03:04:05Z" {
+			t.Errorf("expected the first reminder with an RFC 3339 timestamp got %s", lines[0])
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if code, body := do(t, srv, http.MethodPost, "/v1/users/42/reminders", `{"reminder_text": ""}`); code != http.StatusBadRequest {
+			t.Errorf("expected %d got %d: %s", http.StatusBadRequest, code, body)
+		}
+		if code, body := do(t, srv, http.MethodDelete, "/v1/reminders/100", ""); code != http.StatusNotFound {
+			t.Errorf("expected %d got %d: %s", http.StatusNotFound, code, body)
+		}
+
+		resp, err := http.Get(srv.URL + "/v1/users/42")
+		if err != nil {
+			t.Fatalf("cannot get: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected %d without authorization got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		code, body := do(t, srv, http.MethodGet, OpenAPIPath, "")
+		if code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, code)
+		}
+
+		var doc struct {
+			Paths map[string]any `json:"paths"`
+		}
+		if err := json.Unmarshal([]byte(body), &doc); err != nil {
+			t.Fatalf("cannot decode document: %v", err)
+		}
+		if _, ok := doc.Paths["/v1/users/{user_id}/reminders"]; !ok {
+			t.Errorf("expected the reminders path in %v", doc.Paths)
+		}
+	})
+}
+
+func TestHeaderMatcher(t *testing.T) {
+	for header, expected := range map[string]string{"Idempotency-Key": "idempotency-key", "X-Request-Id": "x-request-id"} {
+		if name, ok := headerMatcher(header); !ok || name != expected {
+			t.Errorf("expected %s to be forwarded as %s got %q, %v", header, expected, name, ok)
+		}
+	}
+	if _, ok := headerMatcher("X-Unknown"); ok {
+		t.Errorf("expected X-Unknown not to be forwarded")
+	}
+}
@@ -0,0 +1,215 @@
+// Package hooks lets automations which cannot call the TodoService, such as
+// Shortcuts, IFTTT or cron scripts, create reminders over plain HTTP:
+//
+//	curl -d text='water the plants' -d in='2 hours' https://todo.example.com/hooks/<token>
+//
+// The token, issued with RotateHookToken, both authenticates the request and
+// names the user. The body is either JSON or form data with the fields
+//
+//   - text, the reminder text;
+//   - time, when to remind as an RFC 3339 timestamp, or
+//   - in, how long from now to remind, e.g. "90m", "in 2 days" or
+//     "1 hour and 30 minutes".
+//
+// Reminders are created with CreateReminder of the TodoService on behalf of
+// the user, so they are validated, limited and stored like any other.
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/httperror"
+	"github.com/awakair/awakair_todo_bot/internal/offset"
+	"github.com/awakair/awakair_todo_bot/internal/ratelimit"
+)
+
+// Pattern is where Handler must be registered on an http.ServeMux.
+const Pattern = "POST /hooks/{token}"
+
+// createReminderMethod is the full name of CreateReminder, whose rate
+// limits apply to hooks.
+const createReminderMethod = "/todoservice.TodoService/CreateReminder"
+
+// maxBodySize of a request, far more than a reminder needs.
+const maxBodySize = 64 << 10
+
+// ReminderCreator is implemented by todoserviceserver.TodoServiceServer.
+type ReminderCreator interface {
+	CreateReminder(context.Context, *pb.Reminder) (*pb.ReminderId, error)
+}
+
+type Handler struct {
+	tokens     todoserviceserver.HookTokenStore
+	reminders  ReminderCreator
+	limiter    *ratelimit.Limiter
+	perAddress config.Rate
+	now        func() time.Time
+}
+
+type Option func(*Handler)
+
+// WithLimiter limits requests with the limits of CreateReminder, the caller
+// being the hook of the user, and additionally to perAddress per client
+// address to slow down guessing tokens.
+func WithLimiter(limiter *ratelimit.Limiter, perAddress config.Rate) Option {
+	return func(h *Handler) {
+		h.limiter = limiter
+		h.perAddress = perAddress
+	}
+}
+
+// WithClock replaces time.Now, which "in" is relative to.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+func NewHandler(tokens todoserviceserver.HookTokenStore, reminders ReminderCreator, opts ...Option) *Handler {
+	h := &Handler{tokens: tokens, reminders: reminders, now: time.Now}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// request is the body of a request, see the package documentation.
+type request struct {
+	Text string `json:"text"`
+	Time string `json:"time"`
+	In   string `json:"in"`
+}
+
+// response is the body of a successful request.
+type response struct {
+	ID              int32     `json:"id"`
+	RemindTimestamp time.Time `json:"remind_timestamp"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.limiter != nil {
+		address, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			address = r.RemoteAddr
+		}
+
+		if err := h.limiter.AllowKey(ctx, "hook-address:"+address, h.perAddress); err != nil {
+			httperror.Write(w, err)
+
+			return
+		}
+	}
+
+	userId, err := h.tokens.FindHookToken(ctx, todoserviceserver.HashSecret(r.PathValue("token")))
+	if errors.Is(err, todoserviceserver.ErrNotFound) {
+		httperror.Write(w, status.Error(codes.NotFound, "unknown hook token"))
+
+		return
+	}
+	if err != nil {
+		log.Printf("Error finding hook token: %v", err)
+		httperror.Write(w, status.Error(codes.Internal, "cannot check hook token"))
+
+		return
+	}
+
+	reminder, err := h.parse(w, r)
+	if err != nil {
+		httperror.Write(w, status.Error(codes.InvalidArgument, err.Error()))
+
+		return
+	}
+	reminder.UserId = userId
+
+	// Every user's hook is a caller of its own, so that one busy automation
+	// does not use up the per caller limits of all the others.
+	caller := auth.Caller{Name: "hook:" + strconv.FormatInt(userId, 10), UserID: &userId}
+	ctx = auth.NewContext(ctx, caller)
+	requestID := audit.RequestID(r.Header.Get("X-Request-Id"))
+	w.Header().Set("X-Request-Id", requestID)
+	ctx = audit.NewContext(ctx, audit.Actor{Caller: caller.String(), Method: "CreateReminder", RequestID: requestID})
+	if h.limiter != nil {
+		if err := h.limiter.Allow(ctx, createReminderMethod, reminder); err != nil {
+			httperror.Write(w, err)
+
+			return
+		}
+	}
+
+	id, err := h.reminders.CreateReminder(ctx, reminder)
+	if err != nil {
+		httperror.Write(w, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response{ID: id.GetId(), RemindTimestamp: reminder.GetRemindTimestamp().AsTime()})
+}
+
+// parse returns the reminder described by the body of r.
+func (h *Handler) parse(w http.ResponseWriter, r *http.Request) (*pb.Reminder, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("bad content type: %w", err)
+	}
+
+	var req request
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("bad JSON body: %w", err)
+		}
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBodySize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return nil, fmt.Errorf("bad form body: %w", err)
+		}
+		req = request{Text: r.PostFormValue("text"), Time: r.PostFormValue("time"), In: r.PostFormValue("in")}
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, use application/json or form data", mediaType)
+	}
+
+	var remindAt time.Time
+	switch {
+	case req.Time != "" && req.In != "":
+		return nil, errors.New("only one of time and in may be set")
+	case req.Time != "":
+		if remindAt, err = time.Parse(time.RFC3339, req.Time); err != nil {
+			return nil, fmt.Errorf("time: %w", err)
+		}
+	case req.In != "":
+		in, err := offset.Parse(req.In)
+		if err != nil {
+			return nil, fmt.Errorf("in: %w", err)
+		}
+		remindAt = h.now().Add(in)
+	default:
+		return nil, errors.New("either time or in must be set")
+	}
+
+	return &pb.Reminder{ReminderText: req.Text, RemindTimestamp: timestamppb.New(remindAt)}, nil
+}
@@ -0,0 +1,226 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+	"github.com/awakair/awakair_todo_bot/internal/ratelimit"
+)
+
+const (
+	token       = todoserviceserver.HookTokenPrefix + "token"
+	userId      = 42
+	otherToken  = todoserviceserver.HookTokenPrefix + "other-token"
+	otherUserId = 43
+)
+
+// StubHookTokenStore only knows token, which belongs to userId, and
+// otherToken, which belongs to otherUserId.
+type StubHookTokenStore struct{}
+
+func (StubHookTokenStore) SetHookToken(context.Context, int64, []byte) error { return nil }
+
+func (StubHookTokenStore) DeleteHookToken(context.Context, int64) error { return nil }
+
+func (StubHookTokenStore) FindHookToken(_ context.Context, hash []byte) (int64, error) {
+	switch {
+	case bytes.Equal(hash, todoserviceserver.HashSecret(token)):
+		return userId, nil
+	case bytes.Equal(hash, todoserviceserver.HashSecret(otherToken)):
+		return otherUserId, nil
+	default:
+		return 0, fmt.Errorf("hook token: %w", todoserviceserver.ErrNotFound)
+	}
+}
+
+func newServer(t *testing.T, now time.Time, opts ...Option) (*httptest.Server, *memrepo.MemRepo) {
+	t.Helper()
+
+	repo := memrepo.New()
+	for _, id := range []int64{userId, otherUserId} {
+		if err := repo.SetUser(context.Background(), &pb.User{Id: id}); err != nil {
+			t.Fatalf("cannot set user: %v", err)
+		}
+	}
+
+	opts = append(opts, WithClock(func() time.Time { return now }))
+	mux := http.NewServeMux()
+	mux.Handle(Pattern, NewHandler(StubHookTokenStore{}, todoserviceserver.New(repo), opts...))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, repo
+}
+
+func multipartBody(t *testing.T, fields map[string]string) (string, *bytes.Buffer) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatalf("cannot write field: %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("cannot close multipart writer: %v", err)
+	}
+
+	return mw.FormDataContentType(), body
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	at := now.Add(3 * time.Hour).UTC()
+	srv, repo := newServer(t, now)
+
+	form := url.Values{"text": {"water the plants"}, "in": {"2 hours"}}.Encode()
+	multipartType, multipart := multipartBody(t, map[string]string{"text": "call mom", "time": at.Format(time.RFC3339)})
+
+	for _, tc := range []struct {
+		name        string
+		token       string
+		contentType string
+		body        string
+		code        int
+		remindAt    time.Time
+	}{
+		{"json in", token, "application/json", `{"text": "stand up", "in": "90m"}`, http.StatusCreated, now.Add(90 * time.Minute)},
+		{"json time", token, "application/json; charset=utf-8", `{"text": "stand up", "time": "` + at.Format(time.RFC3339) + `"}`, http.StatusCreated, at},
+		{"form", token, "application/x-www-form-urlencoded", form, http.StatusCreated, now.Add(2 * time.Hour)},
+		{"multipart", token, multipartType, multipart.String(), http.StatusCreated, at},
+		{"unknown token", "tdh_other", "application/json", `{"text": "stand up", "in": "1h"}`, http.StatusNotFound, time.Time{}},
+		{"no time", token, "application/json", `{"text": "stand up"}`, http.StatusBadRequest, time.Time{}},
+		{"time and in", token, "application/json", `{"text": "stand up", "in": "1h", "time": "` + at.Format(time.RFC3339) + `"}`, http.StatusBadRequest, time.Time{}},
+		{"bad offset", token, "application/json", `{"text": "stand up", "in": "soon"}`, http.StatusBadRequest, time.Time{}},
+		{"past time", token, "application/json", `{"text": "stand up", "time": "2020-01-01T00:00:00Z"}`, http.StatusBadRequest, time.Time{}},
+		{"empty text", token, "application/json", `{"in": "1h"}`, http.StatusBadRequest, time.Time{}},
+		{"bad json", token, "application/json", `{"text": `, http.StatusBadRequest, time.Time{}},
+		{"text body", token, "text/plain", "stand up", http.StatusBadRequest, time.Time{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/hooks/"+tc.token, tc.contentType, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("cannot post: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("expected %d got %d", tc.code, resp.StatusCode)
+			}
+			if tc.code != http.StatusCreated {
+				return
+			}
+
+			var created response
+			if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+				t.Fatalf("cannot decode response: %v", err)
+			}
+			if !created.RemindTimestamp.Equal(tc.remindAt) {
+				t.Errorf("expected remind timestamp %v got %v", tc.remindAt, created.RemindTimestamp)
+			}
+
+			reminder, err := repo.GetReminder(context.Background(), created.ID)
+			if err != nil || reminder.GetUserId() != userId || !reminder.GetRemindTimestamp().AsTime().Equal(tc.remindAt) {
+				t.Errorf("expected reminder of user %d at %v got %+v, %v", userId, tc.remindAt, reminder, err)
+			}
+		})
+	}
+
+	t.Run("get", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/hooks/" + token)
+		if err != nil {
+			t.Fatalf("cannot get: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("expected %d got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+	})
+}
+
+func TestHandler_rateLimits(t *testing.T) {
+	limiter, err := ratelimit.New(config.RateLimits{
+		Methods: map[string]config.MethodLimits{
+			"CreateReminder": {PerUser: config.Rate{PerSecond: 0.001, Burst: 1}},
+		},
+	}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("cannot create limiter: %v", err)
+	}
+
+	srv, _ := newServer(t, time.Now(), WithLimiter(limiter, config.Rate{PerSecond: 0.001, Burst: 3}))
+
+	post := func(token string) *http.Response {
+		t.Helper()
+
+		resp, err := http.Post(srv.URL+"/hooks/"+token, "application/json", strings.NewReader(`{"text": "stand up", "in": "1h"}`))
+		if err != nil {
+			t.Fatalf("cannot post: %v", err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	if resp := post(token); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %d got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if resp := post(token); resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("expected %d with Retry-After per user got %d, %v", http.StatusTooManyRequests, resp.StatusCode, resp.Header)
+	}
+
+	if resp := post("tdh_other"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp := post("tdh_another"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected %d per address got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+}
+
+func TestHandler_callerLimits(t *testing.T) {
+	limiter, err := ratelimit.New(config.RateLimits{
+		Methods: map[string]config.MethodLimits{
+			"CreateReminder": {PerCaller: config.Rate{PerSecond: 0.001, Burst: 1}},
+		},
+	}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("cannot create limiter: %v", err)
+	}
+
+	srv, _ := newServer(t, time.Now(), WithLimiter(limiter, config.Rate{}))
+
+	for _, tc := range []struct {
+		token string
+		code  int
+	}{
+		{token, http.StatusCreated},
+		{token, http.StatusTooManyRequests},
+		{otherToken, http.StatusCreated},
+	} {
+		resp, err := http.Post(srv.URL+"/hooks/"+tc.token, "application/json", strings.NewReader(`{"text": "stand up", "in": "1h"}`))
+		if err != nil {
+			t.Fatalf("cannot post: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code {
+			t.Errorf("expected %d for %s got %d", tc.code, tc.token, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package httperror
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestWrite(t *testing.T) {
+	limited, err := status.New(codes.ResourceExhausted, "slow down").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("cannot add details: %v", err)
+	}
+
+	for _, tt := range []struct {
+		err        error
+		code       int
+		retryAfter string
+		body       string
+	}{
+		{status.Error(codes.NotFound, "unknown hook token"), http.StatusNotFound, "", `{"error":"unknown hook token"}`},
+		{limited.Err(), http.StatusTooManyRequests, "2", `{"error":"slow down"}`},
+		{errors.New("broken"), http.StatusInternalServerError, "", `{"error":"broken"}`},
+	} {
+		rec := httptest.NewRecorder()
+		Write(rec, tt.err)
+
+		if rec.Code != tt.code || rec.Header().Get("Retry-After") != tt.retryAfter || strings.TrimSpace(rec.Body.String()) != tt.body {
+			t.Errorf("expected %d with Retry-After %q and %s for %v got %d, %v, %s",
+				tt.code, tt.retryAfter, tt.body, tt.err, rec.Code, rec.Header(), rec.Body)
+		}
+	}
+}
@@ -0,0 +1,595 @@
+package ical
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+// ProdID identifies the calendars written by Export.
+const ProdID = "-//awakair//awakair_todo_bot//EN"
+
+// AllDayHour is when to remind of all-day items without an alarm.
+const AllDayHour = 9
+
+// minStart is the earliest start of items Import accepts, so that their
+// rules are not expanded over centuries before they occur.
+var minStart = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	ErrNoStart = errors.New("no start")
+	// ErrStartTooEarly is returned by Occurrences of items starting before
+	// 1900.
+	ErrStartTooEarly = errors.New("start before 1900")
+	// ErrRecurrence is returned by Occurrences of items recurring in ways
+	// which are not supported.
+	ErrRecurrence = errors.New("unsupported recurrence")
+	// ErrTooComplex is returned by Import and Occurrences once the rules
+	// of an import took too long to expand.
+	ErrTooComplex = errors.New("calendar too complex")
+)
+
+// Entry is a reminder written by Export.
+type Entry struct {
+	UID     string
+	Summary string
+	Time    time.Time
+}
+
+// Export writes entries as a calendar of VTODO or VEVENT components, each
+// with an alarm at its time. Times are written in zone, which is a fixed
+// offset zone as made by time.FixedZone, or UTC when zone is nil. stamp is
+// when the calendar was made.
+func Export(w io.Writer, kind string, zone *time.Location, stamp time.Time, entries []Entry) error {
+	if kind != "VTODO" && kind != "VEVENT" {
+		return fmt.Errorf("cannot export %s components", kind)
+	}
+
+	calendar := &component{name: "VCALENDAR"}
+	calendar.add("VERSION", "2.0")
+	calendar.add("PRODID", ProdID)
+	calendar.add("CALSCALE", "GREGORIAN")
+
+	var tzid string
+	if zone != nil && zone != time.UTC {
+		tzid = zone.String()
+		_, offset := stamp.In(zone).Zone()
+
+		standard := &component{name: "STANDARD"}
+		standard.add("DTSTART", "19700101T000000")
+		standard.add("TZOFFSETFROM", formatOffset(offset))
+		standard.add("TZOFFSETTO", formatOffset(offset))
+		standard.addText("TZNAME", tzid)
+
+		timezone := &component{name: "VTIMEZONE", components: []*component{standard}}
+		timezone.addText("TZID", tzid)
+		calendar.components = append(calendar.components, timezone)
+	}
+
+	for _, entry := range entries {
+		item := &component{name: kind}
+		item.addText("UID", entry.UID)
+		item.add("DTSTAMP", stamp.UTC().Format(dateTimeLayout+"Z"))
+
+		// To-dos are due when to remind, events start then.
+		name := "DTSTART"
+		if kind == "VTODO" {
+			name = "DUE"
+		}
+		if tzid != "" {
+			item.add(name, entry.Time.In(zone).Format(dateTimeLayout), "TZID", tzid)
+		} else {
+			item.add(name, entry.Time.UTC().Format(dateTimeLayout+"Z"))
+		}
+		item.addText("SUMMARY", entry.Summary)
+
+		alarm := &component{name: "VALARM"}
+		alarm.add("ACTION", "DISPLAY")
+		alarm.addText("DESCRIPTION", entry.Summary)
+		if kind == "VTODO" {
+			alarm.add("TRIGGER", "PT0S", "RELATED", "END")
+		} else {
+			alarm.add("TRIGGER", "PT0S")
+		}
+		item.components = append(item.components, alarm)
+
+		calendar.components = append(calendar.components, item)
+	}
+
+	return encode(w, calendar)
+}
+
+// Item is a VTODO or VEVENT read by Import.
+type Item struct {
+	// Component is VTODO or VEVENT.
+	Component string
+	UID       string
+	Summary   string
+	// Status is like CANCELLED or COMPLETED, empty when not given.
+	Status string
+
+	start time.Time
+	zone  zone
+	rule  *rule
+	// rdates and exdates are instants of starts added to or removed from
+	// the occurrences of rule.
+	rdates, exdates []time.Time
+	// alarm is how long after a start to remind.
+	alarm time.Duration
+	// budget is shared by the items and time zones of an import.
+	budget *budget
+	err    error
+}
+
+// Import reads the to-dos and events of the calendars in r. Times without
+// a time zone, and in time zones which are neither IANA names nor
+// defined by the calendar, are taken to be in floating. Expanding the
+// rules of the items, also by Occurrences, stops once ctx is done or
+// they took too long, with ErrTooComplex.
+func Import(ctx context.Context, r io.Reader, floating *time.Location) ([]Item, error) {
+	calendars, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBudget(ctx)
+
+	var items []Item
+	for _, calendar := range calendars {
+		zones := zones{floating: locationZone{floating}, defined: make(map[string]zone)}
+		for _, c := range calendar.components {
+			if c.name != "VTIMEZONE" {
+				continue
+			}
+
+			tzid, _ := c.get("TZID")
+			if z, err := parseTimezone(c, b); err == nil {
+				zones.defined[tzid.text()] = z
+			}
+		}
+
+		for _, c := range calendar.components {
+			if c.name == "VTODO" || c.name == "VEVENT" {
+				items = append(items, readItem(c, zones, b))
+			}
+		}
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return items, nil
+}
+
+func readItem(c *component, zones zones, b *budget) Item {
+	item := Item{Component: c.name, budget: b}
+	if uid, ok := c.get("UID"); ok {
+		item.UID = uid.text()
+	}
+	if summary, ok := c.get("SUMMARY"); ok {
+		item.Summary = strings.TrimSpace(summary.text())
+	}
+	if status, ok := c.get("STATUS"); ok {
+		item.Status = strings.ToUpper(status.value)
+	}
+
+	start, ok := c.get("DTSTART")
+	end, hasEnd := c.get("DUE")
+	if c.name == "VEVENT" {
+		end, hasEnd = c.get("DTEND")
+	}
+	if !ok && c.name == "VTODO" && hasEnd {
+		start, ok, hasEnd = end, true, false
+	}
+	if !ok {
+		item.err = ErrNoStart
+
+		return item
+	}
+
+	var allDay bool
+	item.start, item.zone, allDay, item.err = zones.parse(start)
+	if item.err != nil {
+		return item
+	}
+	if item.start.Before(minStart) {
+		item.err = ErrStartTooEarly
+
+		return item
+	}
+	first := item.zone.at(item.start)
+
+	// How long after the start the event ends or the to-do is due.
+	var length time.Duration
+	if hasEnd {
+		wall, z, _, err := zones.parse(end)
+		if err == nil {
+			length = z.at(wall).Sub(first)
+		}
+	} else if duration, ok := c.get("DURATION"); ok {
+		length, _ = parseDuration(duration.value)
+	}
+
+	// Without an alarm, remind when the to-do is due or the event starts.
+	if c.name == "VTODO" {
+		item.alarm = length
+	}
+	if allDay {
+		item.alarm += AllDayHour * time.Hour
+	}
+	for _, alarm := range c.components {
+		trigger, ok := alarm.get("TRIGGER")
+		if alarm.name != "VALARM" || !ok {
+			continue
+		}
+
+		if trigger.params["VALUE"] == "DATE-TIME" {
+			if at, err := time.Parse(dateTimeLayout+"Z", trigger.value); err == nil {
+				item.alarm = at.Sub(first)
+
+				break
+			}
+
+			continue
+		}
+
+		d, err := parseDuration(trigger.value)
+		if err != nil {
+			continue
+		}
+		if trigger.params["RELATED"] == "END" {
+			d += length
+		}
+		item.alarm = d
+
+		break
+	}
+
+	if rrule, ok := c.get("RRULE"); ok {
+		if item.rule, item.err = parseRule(rrule.value); item.err != nil {
+			item.err = fmt.Errorf("%w: %v", ErrRecurrence, item.err)
+
+			return item
+		}
+	}
+
+	for _, list := range []struct {
+		name  string
+		times *[]time.Time
+	}{{"RDATE", &item.rdates}, {"EXDATE", &item.exdates}} {
+		for _, p := range c.all(list.name) {
+			if p.params["VALUE"] == "PERIOD" {
+				continue
+			}
+
+			for _, value := range strings.Split(p.value, ",") {
+				p.value = value
+				if wall, z, _, err := zones.parse(p); err == nil {
+					*list.times = append(*list.times, z.at(wall))
+				}
+			}
+		}
+	}
+
+	return item
+}
+
+// Occurrences returns the times to remind of the item after after and up to
+// until, at most max of them.
+func (it Item) Occurrences(after, until time.Time, max int) ([]time.Time, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+
+	excluded := make(map[time.Time]bool, len(it.exdates))
+	for _, exdate := range it.exdates {
+		excluded[exdate.UTC()] = true
+	}
+
+	var times []time.Time
+	add := func(start time.Time) bool {
+		if excluded[start.UTC()] {
+			return true
+		}
+
+		if at := start.Add(it.alarm); at.After(after) && !at.After(until) {
+			times = append(times, at)
+		}
+
+		return len(times) < max
+	}
+
+	r := it.rule
+	if r == nil {
+		// Without a rule the start is the only occurrence but the rdates.
+		r = &rule{freq: "DAILY", interval: 1, count: 1}
+	}
+	r.expand(it.start, it.zone, until.Add(-it.alarm), it.budget, func(_, start time.Time) bool {
+		return add(start)
+	})
+	for _, start := range it.rdates {
+		add(start)
+	}
+	if it.budget.err != nil {
+		return nil, it.budget.err
+	}
+
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	times = slices.CompactFunc(times, time.Time.Equal)
+	if len(times) > max {
+		times = times[:max]
+	}
+
+	return times, nil
+}
+
+// Recurring reports whether the item has more than one occurrence.
+func (it Item) Recurring() bool {
+	return it.rule != nil || len(it.rdates) > 0
+}
+
+// zone turns wall clock times, which are in UTC, into instants.
+type zone interface {
+	at(wall time.Time) time.Time
+}
+
+type locationZone struct {
+	*time.Location
+}
+
+func (z locationZone) at(wall time.Time) time.Time {
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, z.Location)
+}
+
+// definedZone is a time zone defined by a VTIMEZONE, as the observances
+// switching between standard and daylight saving time.
+type definedZone struct {
+	observances []observance
+	// start is when the first observance began.
+	start  time.Time
+	budget *budget
+
+	// transitions are the onsets of the observances up to until in order,
+	// computed once and extended as later times are looked up.
+	transitions []transition
+	until       time.Time
+}
+
+type observance struct {
+	start    time.Time
+	rule     *rule
+	rdates   []time.Time
+	from, to int
+}
+
+// transition is an onset of an observance, as a wall clock time in the
+// offset before it, and the offset after it.
+type transition struct {
+	onset  time.Time
+	offset int
+}
+
+func (z *definedZone) at(wall time.Time) time.Time {
+	if wall.After(z.until) {
+		z.extend(wall)
+	}
+
+	// The observance in effect is the one which began last.
+	offset := z.observances[0].from
+	if i := sort.Search(len(z.transitions), func(i int) bool { return z.transitions[i].onset.After(wall) }); i > 0 {
+		offset = z.transitions[i-1].offset
+	}
+
+	return wall.Add(-time.Duration(offset) * time.Second).In(time.FixedZone("", offset))
+}
+
+// extend computes the transitions up to wall at least. The time they span
+// at least doubles every time, so that looking up ever later times does
+// not expand the rules over and over.
+func (z *definedZone) extend(wall time.Time) {
+	until := wall.AddDate(1, 0, 0)
+	if !z.until.IsZero() {
+		if doubled := z.until.AddDate(z.until.Year()-z.start.Year(), 0, 0); doubled.After(until) {
+			until = doubled
+		}
+	}
+
+	var transitions []transition
+	for _, o := range z.observances {
+		if o.rule != nil {
+			from := locationZone{time.FixedZone("", o.from)}
+			o.rule.expand(o.start, from, from.at(until), z.budget, func(onset, _ time.Time) bool {
+				transitions = append(transitions, transition{onset: onset, offset: o.to})
+
+				return true
+			})
+		} else if !o.start.After(until) {
+			transitions = append(transitions, transition{onset: o.start, offset: o.to})
+		}
+
+		for _, rdate := range o.rdates {
+			if !rdate.After(until) {
+				transitions = append(transitions, transition{onset: rdate, offset: o.to})
+			}
+		}
+	}
+
+	// Of observances beginning at once, the first one is in effect.
+	slices.SortStableFunc(transitions, func(a, b transition) int { return a.onset.Compare(b.onset) })
+	z.transitions = slices.CompactFunc(transitions, func(a, b transition) bool { return a.onset.Equal(b.onset) })
+	z.until = until
+}
+
+func parseTimezone(c *component, b *budget) (*definedZone, error) {
+	z := &definedZone{budget: b}
+	for _, sub := range c.components {
+		if sub.name != "STANDARD" && sub.name != "DAYLIGHT" {
+			continue
+		}
+
+		var (
+			o   observance
+			err error
+		)
+		start, _ := sub.get("DTSTART")
+		if o.start, err = time.Parse(dateTimeLayout, start.value); err != nil {
+			return nil, fmt.Errorf("DTSTART of %s: %w", sub.name, err)
+		}
+
+		from, _ := sub.get("TZOFFSETFROM")
+		to, _ := sub.get("TZOFFSETTO")
+		if o.from, err = parseOffset(from.value); err != nil {
+			return nil, err
+		}
+		if o.to, err = parseOffset(to.value); err != nil {
+			return nil, err
+		}
+
+		if rrule, ok := sub.get("RRULE"); ok {
+			if o.rule, err = parseRule(rrule.value); err != nil {
+				return nil, err
+			}
+		}
+		for _, rdate := range sub.all("RDATE") {
+			for _, value := range strings.Split(rdate.value, ",") {
+				if t, err := time.Parse(dateTimeLayout, value); err == nil {
+					o.rdates = append(o.rdates, t)
+				}
+			}
+		}
+
+		if len(z.observances) == 0 || o.start.Before(z.start) {
+			z.start = o.start
+		}
+		z.observances = append(z.observances, o)
+	}
+
+	if len(z.observances) == 0 {
+		return nil, errors.New("no observances")
+	}
+
+	return z, nil
+}
+
+type zones struct {
+	floating zone
+	defined  map[string]zone
+}
+
+// parse returns the wall clock time of a DATE or DATE-TIME property and
+// the zone it is in.
+func (zs zones) parse(p property) (wall time.Time, z zone, allDay bool, err error) {
+	value := p.value
+	if p.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		wall, err = time.Parse(dateLayout, value)
+
+		return wall, zs.floating, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		wall, err = time.Parse(dateTimeLayout+"Z", value)
+
+		return wall, locationZone{time.UTC}, false, err
+	}
+
+	wall, err = time.Parse(dateTimeLayout, value)
+
+	return wall, zs.resolve(p.params["TZID"]), false, err
+}
+
+// resolve prefers IANA time zones to the definitions in calendars, which
+// are often simplified.
+func (zs zones) resolve(tzid string) zone {
+	if tzid == "" {
+		return zs.floating
+	}
+
+	if tzid != "Local" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			return locationZone{loc}
+		}
+	}
+
+	if z, ok := zs.defined[tzid]; ok {
+		return z
+	}
+
+	return zs.floating
+}
+
+// parseOffset parses UTC offsets like -0500 or +053030 into seconds.
+func parseOffset(s string) (int, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+
+	var seconds int
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(s) {
+			break
+		}
+
+		n, err := strconv.Atoi(s[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", s)
+		}
+		seconds += n * unit
+	}
+
+	if s[0] == '-' {
+		seconds = -seconds
+	}
+
+	return seconds, nil
+}
+
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses durations like -PT15M or P1DT12H.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, nil
+}
@@ -0,0 +1,330 @@
+// Package ical reads and writes the iCalendar format of RFC 5545, as far as
+// reminders need it: calendars of to-dos and events with a start, a
+// summary, alarms and recurrence rules, in any time zone.
+//
+// Export writes reminders as a calendar. Import reads the to-dos and events
+// of a calendar as items, whose Occurrences are the times to remind at.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	// Calendars name time zones with IANA names, which must resolve on
+	// hosts without a zoneinfo database, too.
+	_ "time/tzdata"
+)
+
+// maxLine is the longest unfolded content line read.
+const maxLine = 1 << 20
+
+// foldAt is the length in octets at which content lines are folded.
+const foldAt = 75
+
+// component is a BEGIN/END block of content lines, like VCALENDAR or VEVENT.
+type component struct {
+	name       string
+	properties []property
+	components []*component
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	// value as written, with text still escaped.
+	value string
+}
+
+func (c *component) get(name string) (property, bool) {
+	for _, p := range c.properties {
+		if p.name == name {
+			return p, true
+		}
+	}
+
+	return property{}, false
+}
+
+func (c *component) all(name string) []property {
+	var props []property
+	for _, p := range c.properties {
+		if p.name == name {
+			props = append(props, p)
+		}
+	}
+
+	return props
+}
+
+func (c *component) add(name, value string, params ...string) {
+	p := property{name: name, value: value}
+	for i := 0; i+1 < len(params); i += 2 {
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[params[i]] = params[i+1]
+	}
+
+	c.properties = append(c.properties, p)
+}
+
+func (c *component) addText(name, text string) {
+	c.add(name, escapeText(text))
+}
+
+// text returns the value of a TEXT property.
+func (p property) text() string {
+	var b strings.Builder
+	for i := 0; i < len(p.value); i++ {
+		if p.value[i] != '\\' || i+1 == len(p.value) {
+			b.WriteByte(p.value[i])
+
+			continue
+		}
+
+		i++
+		switch p.value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(p.value[i])
+		}
+	}
+
+	return b.String()
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// parse reads the VCALENDAR components of r.
+func parse(r io.Reader) ([]*component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		calendars []*component
+		open      []*component
+	)
+	for _, line := range lines {
+		if line.text == "" {
+			continue
+		}
+
+		p, err := parseLine(line.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+
+		switch p.name {
+		case "BEGIN":
+			name := strings.ToUpper(p.value)
+			if len(open) == 0 && name != "VCALENDAR" {
+				return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR got BEGIN:%s", line.number, p.value)
+			}
+
+			c := &component{name: name}
+			if len(open) > 0 {
+				parent := open[len(open)-1]
+				parent.components = append(parent.components, c)
+			} else {
+				calendars = append(calendars, c)
+			}
+			open = append(open, c)
+		case "END":
+			if len(open) == 0 || open[len(open)-1].name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", line.number, p.value)
+			}
+			open = open[:len(open)-1]
+		default:
+			if len(open) == 0 {
+				return nil, fmt.Errorf("line %d: property %s outside of VCALENDAR", line.number, p.name)
+			}
+			open[len(open)-1].properties = append(open[len(open)-1].properties, p)
+		}
+	}
+
+	if len(open) > 0 {
+		return nil, fmt.Errorf("missing END:%s", open[len(open)-1].name)
+	}
+	if len(calendars) == 0 {
+		return nil, errors.New("no VCALENDAR")
+	}
+
+	return calendars, nil
+}
+
+type line struct {
+	number int
+	text   string
+}
+
+// unfold joins the content lines of r continued on the following lines,
+// which start with a space or a tab.
+func unfold(r io.Reader) ([]line, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLine)
+
+	var lines []line
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			last := &lines[len(lines)-1]
+			if len(last.text)+len(text) > maxLine {
+				return nil, fmt.Errorf("line %d: longer than %d bytes", last.number, maxLine)
+			}
+			last.text += text[1:]
+
+			continue
+		}
+
+		lines = append(lines, line{number: number, text: text})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseLine parses name *(";" param) ":" value.
+func parseLine(s string) (property, error) {
+	end := strings.IndexAny(s, ";:")
+	if end <= 0 {
+		return property{}, fmt.Errorf("invalid content line %q", truncate(s))
+	}
+
+	p := property{name: strings.ToUpper(s[:end])}
+	s = s[end:]
+
+	for s[0] == ';' {
+		s = s[1:]
+
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return property{}, fmt.Errorf("invalid parameter of %s", p.name)
+		}
+		name := strings.ToUpper(s[:eq])
+		s = s[eq+1:]
+
+		// Parameter values are lists, quoted when they contain ; : or ,.
+		var values []string
+		for {
+			var value string
+			if strings.HasPrefix(s, `"`) {
+				closing := strings.IndexByte(s[1:], '"')
+				if closing < 0 {
+					return property{}, fmt.Errorf("unterminated quote in parameter %s of %s", name, p.name)
+				}
+				value, s = s[1:closing+1], s[closing+2:]
+			} else {
+				end := strings.IndexAny(s, ";:,")
+				if end < 0 {
+					return property{}, fmt.Errorf("missing value of %s", p.name)
+				}
+				value, s = s[:end], s[end:]
+			}
+			values = append(values, value)
+
+			if !strings.HasPrefix(s, ",") {
+				break
+			}
+			s = s[1:]
+		}
+
+		if p.params == nil {
+			p.params = make(map[string]string)
+		}
+		p.params[name] = strings.Join(values, ",")
+
+		if s == "" {
+			return property{}, fmt.Errorf("missing value of %s", p.name)
+		}
+	}
+
+	if s[0] != ':' {
+		return property{}, fmt.Errorf("invalid content line of %s", p.name)
+	}
+	p.value = s[1:]
+
+	return p, nil
+}
+
+// encode writes c as content lines folded at 75 octets.
+func encode(w io.Writer, c *component) error {
+	bw := bufio.NewWriter(w)
+
+	var write func(c *component)
+	write = func(c *component) {
+		writeLine(bw, "BEGIN:"+c.name)
+		for _, p := range c.properties {
+			var b strings.Builder
+			b.WriteString(p.name)
+			for _, name := range sortedKeys(p.params) {
+				value := p.params[name]
+				if strings.ContainsAny(value, ";:,") {
+					value = `"` + value + `"`
+				}
+				fmt.Fprintf(&b, ";%s=%s", name, value)
+			}
+			b.WriteString(":")
+			b.WriteString(p.value)
+
+			writeLine(bw, b.String())
+		}
+		for _, sub := range c.components {
+			write(sub)
+		}
+		writeLine(bw, "END:"+c.name)
+	}
+	write(c)
+
+	return bw.Flush()
+}
+
+func writeLine(w *bufio.Writer, s string) {
+	limit := foldAt
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The space starting continuation lines counts.
+		limit = foldAt - 1
+	}
+
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func truncate(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+
+	return s
+}
@@ -0,0 +1,340 @@
+package ical
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func calendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func TestExport_roundTrip(t *testing.T) {
+	zone := time.FixedZone("UTC+02:00", 2*60*60)
+	stamp := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{UID: "reminder-1@example.com", Summary: "water the plants", Time: time.Date(2026, 5, 2, 9, 30, 0, 0, zone)},
+		{UID: "reminder-2@example.com", Summary: "buy milk, eggs; bread\nand \\ butter", Time: time.Date(2026, 5, 3, 18, 0, 0, 0, zone)},
+		{UID: "reminder-3@example.com", Summary: strings.Repeat("позвонить маме ", 10), Time: time.Date(2026, 12, 31, 23, 59, 0, 0, zone)},
+	}
+
+	for _, kind := range []string{"VTODO", "VEVENT"} {
+		for _, z := range []*time.Location{nil, zone} {
+			var out bytes.Buffer
+			if err := Export(&out, kind, z, stamp, entries); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			for _, line := range strings.Split(out.String(), "\r\n") {
+				if len(line) > foldAt {
+					t.Errorf("expected lines folded at %d octets got %q", foldAt, line)
+				}
+			}
+			if z != nil && !strings.Contains(out.String(), `TZID="UTC+02:00":20260502T093000`) {
+				t.Errorf("expected times in the zone got %s", out.String())
+			}
+
+			// Read in another floating zone, which the times are not in.
+			items, err := Import(context.Background(), &out, time.FixedZone("", -5*60*60))
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+			if len(items) != len(entries) {
+				t.Fatalf("expected %d items got %d", len(entries), len(items))
+			}
+
+			for i, item := range items {
+				times, err := item.Occurrences(stamp, stamp.AddDate(1, 0, 0), 10)
+				if err != nil {
+					t.Fatalf("did not expect error got %v", err)
+				}
+
+				if item.Component != kind || item.UID != entries[i].UID || item.Summary != strings.TrimSpace(entries[i].Summary) ||
+					len(times) != 1 || !times[0].Equal(entries[i].Time) {
+					t.Errorf("expected %s %+v got %+v at %v", kind, entries[i], item, times)
+				}
+			}
+		}
+	}
+}
+
+func TestImport_recurrence(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	utc := func(s string) time.Time {
+		t, _ := time.Parse(dateTimeLayout+"Z", s)
+
+		return t
+	}
+	after := utc("20260101T000000Z")
+
+	tests := []struct {
+		name     string
+		lines    []string
+		expected []string
+	}{
+		{
+			"daily count",
+			[]string{"DTSTART:20260105T080000Z", "RRULE:FREQ=DAILY;COUNT=3"},
+			[]string{"20260105T080000Z", "20260106T080000Z", "20260107T080000Z"},
+		},
+		{
+			"weekly across daylight saving time",
+			[]string{"DTSTART;TZID=Europe/Berlin:20260319T090000", "RRULE:FREQ=WEEKLY;INTERVAL=1;UNTIL=20260403T000000Z"},
+			[]string{"20260319T080000Z", "20260326T080000Z", "20260402T070000Z"},
+		},
+		{
+			"weekly by day with interval",
+			[]string{"DTSTART:20260105T080000Z", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=5"},
+			[]string{"20260105T080000Z", "20260109T080000Z", "20260119T080000Z", "20260123T080000Z", "20260202T080000Z"},
+		},
+		{
+			"monthly on the 31st skips short months",
+			[]string{"DTSTART:20260131T080000Z", "RRULE:FREQ=MONTHLY;COUNT=3"},
+			[]string{"20260131T080000Z", "20260331T080000Z", "20260531T080000Z"},
+		},
+		{
+			"monthly on the last friday",
+			[]string{"DTSTART:20260130T170000Z", "RRULE:FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20260430"},
+			[]string{"20260130T170000Z", "20260227T170000Z", "20260327T170000Z", "20260424T170000Z"},
+		},
+		{
+			"monthly on the last day",
+			[]string{"DTSTART:20260131T080000Z", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3"},
+			[]string{"20260131T080000Z", "20260228T080000Z", "20260331T080000Z"},
+		},
+		{
+			"yearly on the second sunday of may",
+			[]string{"DTSTART;TZID=Europe/Berlin:20260510T100000", "RRULE:FREQ=YEARLY;BYMONTH=5;BYDAY=2SU;COUNT=2"},
+			[]string{"20260510T080000Z", "20270509T080000Z"},
+		},
+		{
+			"yearly on leap days",
+			[]string{"DTSTART:20240229T080000Z", "RRULE:FREQ=YEARLY"},
+			[]string{"20280229T080000Z"},
+		},
+		{
+			"started in the past",
+			[]string{"DTSTART:20251230T080000Z", "RRULE:FREQ=DAILY;COUNT=4"},
+			[]string{"20260101T080000Z", "20260102T080000Z"},
+		},
+		{
+			"exdate and rdate",
+			[]string{"DTSTART:20260105T080000Z", "RRULE:FREQ=DAILY;COUNT=3", "EXDATE:20260106T080000Z", "RDATE:20260110T120000Z,20260111T120000Z"},
+			[]string{"20260105T080000Z", "20260107T080000Z", "20260110T120000Z", "20260111T120000Z"},
+		},
+		{
+			"limited",
+			[]string{"DTSTART:20260105T080000Z", "RRULE:FREQ=DAILY"},
+			[]string{"20260105T080000Z", "20260106T080000Z", "20260107T080000Z", "20260108T080000Z", "20260109T080000Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append([]string{"BEGIN:VEVENT", "UID:1", "SUMMARY:test"}, tt.lines...)
+			items, err := Import(context.Background(), strings.NewReader(calendar(append(lines, "END:VEVENT")...)), berlin)
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			times, err := items[0].Occurrences(after, after.AddDate(5, 0, 0), 5)
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			var got []string
+			for _, at := range times {
+				got = append(got, at.UTC().Format(dateTimeLayout+"Z"))
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v got %v", tt.expected, got)
+			}
+			if !items[0].Recurring() {
+				t.Errorf("expected the item to recur")
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		items, err := Import(context.Background(), strings.NewReader(calendar("BEGIN:VEVENT", "DTSTART:20260105T080000Z", "RRULE:FREQ=HOURLY", "END:VEVENT")), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if _, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 5); err == nil || !strings.Contains(err.Error(), "unsupported") {
+			t.Errorf("expected an unsupported recurrence got %v", err)
+		}
+	})
+}
+
+func TestImport_timeZones(t *testing.T) {
+	// Custom Berlin is not an IANA name, so its definition is used.
+	timezone := []string{
+		"BEGIN:VTIMEZONE", "TZID:Custom Berlin",
+		"BEGIN:DAYLIGHT", "DTSTART:19810329T020000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0200", "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU", "END:DAYLIGHT",
+		"BEGIN:STANDARD", "DTSTART:19961027T030000", "TZOFFSETFROM:+0200", "TZOFFSETTO:+0100", "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU", "END:STANDARD",
+		"END:VTIMEZONE",
+	}
+	floating := time.FixedZone("", 3*60*60)
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		lines    []string
+		expected time.Time
+	}{
+		{"utc", []string{"DTSTART:20260601T090000Z"}, time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)},
+		{"floating", []string{"DTSTART:20260601T090000"}, time.Date(2026, 6, 1, 6, 0, 0, 0, time.UTC)},
+		{"iana", []string{"DTSTART;TZID=America/New_York:20260601T090000"}, time.Date(2026, 6, 1, 13, 0, 0, 0, time.UTC)},
+		{"defined in summer", []string{"DTSTART;TZID=Custom Berlin:20260601T090000"}, time.Date(2026, 6, 1, 7, 0, 0, 0, time.UTC)},
+		{"defined in winter", []string{"DTSTART;TZID=Custom Berlin:20261201T090000"}, time.Date(2026, 12, 1, 8, 0, 0, 0, time.UTC)},
+		{"quoted", []string{`DTSTART;TZID="Custom Berlin":20261201T090000`}, time.Date(2026, 12, 1, 8, 0, 0, 0, time.UTC)},
+		{"unknown", []string{"DTSTART;TZID=Nowhere:20260601T090000"}, time.Date(2026, 6, 1, 6, 0, 0, 0, time.UTC)},
+		{"all day", []string{"DTSTART;VALUE=DATE:20260601"}, time.Date(2026, 6, 1, AllDayHour-3, 0, 0, 0, time.UTC)},
+		{"alarm before", []string{"DTSTART:20260601T090000Z", "BEGIN:VALARM", "TRIGGER:-PT15M", "END:VALARM"}, time.Date(2026, 6, 1, 8, 45, 0, 0, time.UTC)},
+		{"alarm before end", []string{"DTSTART:20260601T090000Z", "DURATION:PT1H", "BEGIN:VALARM", "TRIGGER;RELATED=END:-PT5M", "END:VALARM"}, time.Date(2026, 6, 1, 9, 55, 0, 0, time.UTC)},
+		{"absolute alarm", []string{"DTSTART:20260601T090000Z", "BEGIN:VALARM", "TRIGGER;VALUE=DATE-TIME:20260531T200000Z", "END:VALARM"}, time.Date(2026, 5, 31, 20, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append(timezone, "BEGIN:VEVENT", "SUMMARY:test"), tt.lines...)
+			items, err := Import(context.Background(), strings.NewReader(calendar(append(lines, "END:VEVENT")...)), floating)
+			if err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+
+			times, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 1)
+			if err != nil || len(times) != 1 || !times[0].Equal(tt.expected) {
+				t.Errorf("expected %v got %v, %v", tt.expected, times, err)
+			}
+		})
+	}
+
+	t.Run("to-do", func(t *testing.T) {
+		items, err := Import(context.Background(), strings.NewReader(calendar("BEGIN:VTODO", "DTSTART:20260601T090000Z", "DUE:20260602T090000Z", "END:VTODO")), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		expected := time.Date(2026, 6, 2, 9, 0, 0, 0, time.UTC)
+		if times, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 1); err != nil || len(times) != 1 || !times[0].Equal(expected) {
+			t.Errorf("expected to be reminded when due at %v got %v, %v", expected, times, err)
+		}
+	})
+}
+
+func TestImport_limits(t *testing.T) {
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("daily time zone", func(t *testing.T) {
+		items, err := Import(context.Background(), strings.NewReader(calendar(
+			"BEGIN:VTIMEZONE", "TZID:X",
+			"BEGIN:STANDARD", "DTSTART:17000101T000000", "TZOFFSETFROM:+0100", "TZOFFSETTO:+0100", "RRULE:FREQ=DAILY", "END:STANDARD",
+			"END:VTIMEZONE",
+			"BEGIN:VEVENT", "SUMMARY:test", "DTSTART;TZID=X:19000101T090000", "RRULE:FREQ=DAILY", "END:VEVENT",
+		)), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		times, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 5)
+		if err != nil || len(times) != 5 || !times[0].Equal(time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected 5 occurrences from %v got %v, %v", after, times, err)
+		}
+	})
+
+	t.Run("start too early", func(t *testing.T) {
+		items, err := Import(context.Background(), strings.NewReader(calendar("BEGIN:VEVENT", "DTSTART:17000101", "RRULE:FREQ=DAILY", "END:VEVENT")), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if _, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 5); err != ErrStartTooEarly {
+			t.Errorf("expected %v got %v", ErrStartTooEarly, err)
+		}
+	})
+
+	t.Run("too complex", func(t *testing.T) {
+		items, err := Import(context.Background(), strings.NewReader(calendar("BEGIN:VEVENT", "DTSTART:19000101T090000Z", "RRULE:FREQ=DAILY", "END:VEVENT")), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		items[0].budget.periods = 1000
+		if _, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 5); err != ErrTooComplex {
+			t.Errorf("expected %v got %v", ErrTooComplex, err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		items, err := Import(ctx, strings.NewReader(calendar("BEGIN:VEVENT", "DTSTART:20260105T080000Z", "RRULE:FREQ=DAILY", "END:VEVENT")), time.UTC)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		cancel()
+		if _, err := items[0].Occurrences(after, after.AddDate(1, 0, 0), 5); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestImport_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{"empty", ""},
+		{"not a calendar", "BEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{"unterminated", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{"mismatched", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"},
+		{"invalid line", calendar("no colon")},
+		{"unterminated quote", calendar(`DTSTART;TZID="Berlin:20260101T000000`)},
+	}
+
+	for _, tt := range tests {
+		if _, err := Import(context.Background(), strings.NewReader(tt.ics), time.UTC); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	items, err := Import(context.Background(), strings.NewReader(calendar("BEGIN:VEVENT", "SUMMARY:no start", "END:VEVENT")), time.UTC)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if _, err := items[0].Occurrences(time.Time{}, time.Now(), 1); err != ErrNoStart {
+		t.Errorf("expected %v got %v", ErrNoStart, err)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	p, err := parseLine(`DESCRIPTION;ALTREP="cid:part1.0001@example.org";LANGUAGE=en,de:Fix \, the\; bug\nnow`)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	if p.name != "DESCRIPTION" || p.params["ALTREP"] != "cid:part1.0001@example.org" || p.params["LANGUAGE"] != "en,de" {
+		t.Errorf("unexpected property %+v", p)
+	}
+	if p.text() != "Fix , the; bug\nnow" {
+		t.Errorf("expected the unescaped text got %q", p.text())
+	}
+}
+
+func TestUnfold(t *testing.T) {
+	lines, err := unfold(strings.NewReader("SUMMARY:a long\r\n  summary\r\n\tcontinued\nUID:1\r\n"))
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	if len(lines) != 2 || lines[0].text != "SUMMARY:a long summarycontinued" || lines[1].number != 4 {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+}
@@ -0,0 +1,355 @@
+package ical
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPeriods bounds the days, weeks, months or years a rule is expanded
+	// over, so that rules matching nothing end.
+	maxPeriods = 100_000
+	// maxImportPeriods bounds the periods all rules of an import are
+	// expanded over, those of time zones included.
+	maxImportPeriods = 1_000_000
+)
+
+// budget is the work left for expanding the rules of an import, which
+// stops once it is used up or ctx is done.
+type budget struct {
+	ctx     context.Context
+	periods int
+	// err is why expanding stopped, nil until it did.
+	err error
+}
+
+func newBudget(ctx context.Context) *budget {
+	return &budget{ctx: ctx, periods: maxImportPeriods}
+}
+
+// take reports whether another period may be expanded.
+func (b *budget) take() bool {
+	if b.err == nil {
+		if err := b.ctx.Err(); err != nil {
+			b.err = err
+		} else if b.periods <= 0 {
+			b.err = ErrTooComplex
+		}
+	}
+	if b.err != nil {
+		return false
+	}
+
+	b.periods--
+
+	return true
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// weekdayNum is an entry of BYDAY, like -1SU for the last Sunday.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// rule is a recurrence rule. Of the parts of RFC 5545 it supports FREQ
+// from DAILY to YEARLY, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and
+// BYMONTH, which cover the rules calendar apps let users pick. Weeks
+// start on Mondays.
+type rule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	untilUTC   bool
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+}
+
+func parseRule(s string) (*rule, error) {
+	r := &rule{interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, r.freq) {
+				return nil, fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err == nil && r.interval < 1 {
+				err = fmt.Errorf("interval %d is not positive", r.interval)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err == nil && r.count < 1 {
+				err = fmt.Errorf("count %d is not positive", r.count)
+			}
+		case "UNTIL":
+			r.until, r.untilUTC, err = parseUntil(value)
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				v = strings.ToUpper(v)
+				if len(v) < 2 {
+					return nil, fmt.Errorf("invalid weekday %q", v)
+				}
+
+				day, ok := weekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid weekday %q", v)
+				}
+
+				n := 0
+				if prefix := v[:len(v)-2]; prefix != "" {
+					if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+						return nil, fmt.Errorf("invalid weekday %q", v)
+					}
+				}
+
+				r.byDay = append(r.byDay, weekdayNum{n: n, day: day})
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(v)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("invalid month day %q", v)
+				}
+
+				r.byMonthDay = append(r.byMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				month, err := strconv.Atoi(v)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("invalid month %q", v)
+				}
+
+				r.byMonth = append(r.byMonth, time.Month(month))
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("missing FREQ")
+	}
+
+	return r, nil
+}
+
+// parseUntil parses a date or a date-time, reporting whether it is in UTC.
+// A date is until the end of that day.
+func parseUntil(s string) (time.Time, bool, error) {
+	if len(s) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, s)
+
+		return t.Add(24*time.Hour - time.Second), false, err
+	}
+
+	if strings.HasSuffix(s, "Z") {
+		t, err := time.Parse(dateTimeLayout+"Z", s)
+
+		return t, true, err
+	}
+
+	t, err := time.Parse(dateTimeLayout, s)
+
+	return t, false, err
+}
+
+// expand calls fn with the occurrences of the rule starting at start in
+// order, as wall clock times and as the instants they are in z, until fn
+// returns false, the rule ends or the occurrences pass end. start is the
+// first occurrence, whether it matches the rule or not. Every period takes
+// from b.
+func (r *rule) expand(start time.Time, z zone, end time.Time, b *budget, fn func(wall, at time.Time) bool) {
+	count := 0
+	emit := func(wall time.Time) bool {
+		at := z.at(wall)
+		if (r.untilUTC && at.After(r.until)) || (!r.untilUTC && !r.until.IsZero() && wall.After(r.until)) {
+			return false
+		}
+
+		count++
+		if r.count > 0 && count > r.count {
+			return false
+		}
+
+		return !at.After(end) && fn(wall, at)
+	}
+
+	if !emit(start) {
+		return
+	}
+
+	// Wall clock times differ from instants by less than a day.
+	last := end.UTC().Add(24 * time.Hour)
+
+	for period := 0; period < maxPeriods && b.take(); period++ {
+		candidates, begin := r.candidates(start, period)
+		if begin.After(last) {
+			return
+		}
+
+		for _, wall := range candidates {
+			if wall.After(start) && !emit(wall) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the times in the period'th day, week, month or year of
+// the rule in order, and when the period begins.
+func (r *rule) candidates(start time.Time, period int) ([]time.Time, time.Time) {
+	step := period * r.interval
+
+	var days []time.Time
+	begin := start
+	switch r.freq {
+	case "DAILY":
+		begin = start.AddDate(0, 0, step)
+		days = []time.Time{begin}
+	case "WEEKLY":
+		begin = start.AddDate(0, 0, 7*step-weekdayIndex(start.Weekday()))
+		if len(r.byDay) == 0 {
+			days = []time.Time{begin.AddDate(0, 0, weekdayIndex(start.Weekday()))}
+		}
+		for _, wd := range r.byDay {
+			days = append(days, begin.AddDate(0, 0, weekdayIndex(wd.day)))
+		}
+	case "MONTHLY":
+		begin = firstOfMonth(start, start.Year(), start.Month()+time.Month(step))
+		days = r.monthDays(begin, start.Day())
+	case "YEARLY":
+		year := start.Year() + step
+		begin = firstOfMonth(start, year, time.January)
+
+		if len(r.byDay) > 0 && len(r.byMonth) == 0 && len(r.byMonthDay) == 0 {
+			days = weekdaysIn(begin, begin.AddDate(1, 0, 0), r.byDay)
+
+			break
+		}
+
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(firstOfMonth(start, year, month), start.Day())...)
+		}
+	}
+
+	// BYMONTH and BYDAY limit the days of shorter periods.
+	days = slices.DeleteFunc(days, func(day time.Time) bool {
+		if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, day.Month()) {
+			return true
+		}
+		if r.freq == "DAILY" && len(r.byDay) > 0 && !slices.ContainsFunc(r.byDay, func(wd weekdayNum) bool { return wd.day == day.Weekday() }) {
+			return true
+		}
+
+		return (r.freq == "DAILY" || r.freq == "WEEKLY") && len(r.byMonthDay) > 0 && !matchesMonthDay(day, r.byMonthDay)
+	})
+
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+
+	return slices.CompactFunc(days, time.Time.Equal), begin
+}
+
+// monthDays returns the days of the month starting at first matching
+// BYMONTHDAY and BYDAY, or day when there are neither.
+func (r *rule) monthDays(first time.Time, day int) []time.Time {
+	next := first.AddDate(0, 1, 0)
+
+	switch {
+	case len(r.byMonthDay) > 0:
+		var days []time.Time
+		for d := first; d.Before(next); d = d.AddDate(0, 0, 1) {
+			if !matchesMonthDay(d, r.byMonthDay) {
+				continue
+			}
+			if len(r.byDay) > 0 && !slices.ContainsFunc(r.byDay, func(wd weekdayNum) bool { return wd.day == d.Weekday() }) {
+				continue
+			}
+
+			days = append(days, d)
+		}
+
+		return days
+	case len(r.byDay) > 0:
+		return weekdaysIn(first, next, r.byDay)
+	case day <= daysIn(first):
+		return []time.Time{first.AddDate(0, 0, day-1)}
+	default:
+		return nil
+	}
+}
+
+// weekdaysIn returns the days from first to before next matching byDay,
+// where 2MO is the second and -1MO the last Monday between them.
+func weekdaysIn(first, next time.Time, byDay []weekdayNum) []time.Time {
+	var days []time.Time
+	for _, wd := range byDay {
+		var matching []time.Time
+		d := first.AddDate(0, 0, (int(wd.day)-int(first.Weekday())+7)%7)
+		for ; d.Before(next); d = d.AddDate(0, 0, 7) {
+			matching = append(matching, d)
+		}
+
+		switch {
+		case wd.n == 0:
+			days = append(days, matching...)
+		case wd.n > 0 && wd.n <= len(matching):
+			days = append(days, matching[wd.n-1])
+		case wd.n < 0 && -wd.n <= len(matching):
+			days = append(days, matching[len(matching)+wd.n])
+		}
+	}
+
+	return days
+}
+
+func matchesMonthDay(day time.Time, byMonthDay []int) bool {
+	n := daysIn(day)
+
+	return slices.ContainsFunc(byMonthDay, func(d int) bool {
+		return d == day.Day() || d == day.Day()-n-1
+	})
+}
+
+// firstOfMonth returns the first day of the month at the time of day of t.
+func firstOfMonth(t time.Time, year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// weekdayIndex counts days from Monday.
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,245 @@
+// Package idempotency lets clients retry mutating RPCs safely.
+//
+// A client sends a unique "idempotency-key" metadata entry with the request.
+// The first request with a key is executed and its response or error is
+// stored for a while; repeats of the same request get the stored outcome
+// instead of being executed again. Reusing a key for a different request
+// fails with FailedPrecondition.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+const MetadataKey = "idempotency-key"
+
+// pendingTimeout is how long a key stays reserved by a request which
+// neither completed nor released it, e.g. because the server crashed.
+const pendingTimeout = time.Minute
+
+// Methods are the RPCs covered by idempotency keys.
+var Methods = map[string]bool{
+	"SetUser":                 true,
+	"CreateReminder":          true,
+	"RemoveReminder":          true,
+	"CreateWebhook":           true,
+	"UpdateWebhook":           true,
+	"DeleteWebhook":           true,
+	"ReplayWebhookDeadLetter": true,
+	"RevokeHookToken":         true,
+	"ImportCalendar":          true,
+	"RevokeCalendarFeed":      true,
+	"DeleteUser":              true,
+	"CancelUserDeletion":      true,
+	"ShareReminder":           true,
+	"AcceptReminderShare":     true,
+	"RemoveReminderShare":     true,
+}
+
+type Record struct {
+	RequestHash []byte
+	Done        bool
+	// Response is a marshaled google.protobuf.Any, set on success.
+	Response []byte
+	// Status is a marshaled google.rpc.Status, set on failure.
+	Status []byte
+}
+
+type Store interface {
+	// Reserve creates a pending record for the key of the caller unless
+	// an unexpired one exists, which is returned instead.
+	Reserve(ctx context.Context, caller, key string, requestHash []byte, ttl time.Duration) (*Record, error)
+	// Complete stores the outcome of the request which reserved the key.
+	Complete(ctx context.Context, caller, key string, record Record, ttl time.Duration) error
+	// Release removes a pending record so the request can be retried.
+	Release(ctx context.Context, caller, key string) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type Interceptor struct {
+	store Store
+	ttl   time.Duration
+}
+
+// New keeps outcomes of requests in store for ttl.
+func New(store Store, ttl time.Duration) *Interceptor {
+	return &Interceptor{store: store, ttl: ttl}
+}
+
+func requestHash(fullMethod string, req any) ([]byte, error) {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(req.(proto.Message))
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(fullMethod))
+	hash.Write([]byte{0})
+	hash.Write(payload)
+
+	return hash.Sum(nil), nil
+}
+
+// retryable reports whether err is likely to go away on retry,
+// so that it must not be stored as the outcome of the request.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted,
+		codes.DeadlineExceeded, codes.Canceled, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Record) replay() (any, error) {
+	if len(r.Status) != 0 {
+		var st spb.Status
+		if err := proto.Unmarshal(r.Status, &st); err != nil {
+			return nil, status.Error(codes.Internal, "cannot decode stored error")
+		}
+
+		return nil, status.ErrorProto(&st)
+	}
+
+	var response anypb.Any
+	if err := proto.Unmarshal(r.Response, &response); err != nil {
+		return nil, status.Error(codes.Internal, "cannot decode stored response")
+	}
+
+	msg, err := response.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "cannot decode stored response")
+	}
+
+	return msg, nil
+}
+
+func outcome(requestHash []byte, resp any, err error) (Record, error) {
+	record := Record{RequestHash: requestHash, Done: true}
+
+	if err != nil {
+		st, marshalErr := proto.Marshal(status.Convert(err).Proto())
+		record.Status = st
+
+		return record, marshalErr
+	}
+
+	response, marshalErr := anypb.New(resp.(proto.Message))
+	if marshalErr != nil {
+		return record, marshalErr
+	}
+	record.Response, marshalErr = proto.Marshal(response)
+
+	return record, marshalErr
+}
+
+// namespace returns the namespace of the keys of caller. Callers acting on a
+// user are told apart by the user too, so that a stored response is never
+// replayed to a caller who may not act on its user.
+func namespace(caller auth.Caller) string {
+	if caller.IsService() {
+		return "service:" + caller.Name
+	}
+
+	return "user:" + strconv.FormatInt(*caller.UserID, 10) + ":" + caller.Name
+}
+
+// UnaryServerInterceptor must run after the auth interceptor,
+// keys are scoped by caller.
+func (i *Interceptor) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !Methods[path.Base(info.FullMethod)] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(MetadataKey)
+		if len(keys) == 0 || keys[0] == "" {
+			return handler(ctx, req)
+		}
+		key := keys[0]
+
+		caller, _ := auth.FromContext(ctx)
+		scope := namespace(caller)
+
+		hash, err := requestHash(info.FullMethod, req)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		existing, err := i.store.Reserve(ctx, scope, key, hash, pendingTimeout)
+		if err != nil {
+			log.Printf("Error reserving idempotency key %q: %v", key, err)
+
+			return nil, status.Error(codes.Unavailable, "cannot check idempotency key")
+		}
+
+		if existing != nil {
+			if !bytes.Equal(existing.RequestHash, hash) {
+				return nil, status.Error(codes.FailedPrecondition, "idempotency key was already used for another request")
+			}
+			if !existing.Done {
+				return nil, status.Error(codes.Aborted, "request with this idempotency key is in progress")
+			}
+
+			return existing.replay()
+		}
+
+		resp, err := handler(ctx, req)
+
+		// The outcome must be stored even when the client gave up waiting.
+		storeCtx := context.WithoutCancel(ctx)
+
+		if retryable(err) {
+			if releaseErr := i.store.Release(storeCtx, scope, key); releaseErr != nil {
+				log.Printf("Error releasing idempotency key %q: %v", key, releaseErr)
+			}
+
+			return resp, err
+		}
+
+		record, marshalErr := outcome(hash, resp, err)
+		if marshalErr == nil {
+			marshalErr = i.store.Complete(storeCtx, scope, key, record, i.ttl)
+		}
+		if marshalErr != nil {
+			log.Printf("Error storing outcome for idempotency key %q: %v", key, marshalErr)
+		}
+
+		return resp, err
+	}
+}
+
+// PurgeExpired deletes expired records every interval until ctx is done.
+func (i *Interceptor) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.store.DeleteExpired(ctx); err != nil {
+				log.Printf("Error deleting expired idempotency keys: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+const createReminder = "/todoservice.TodoService/CreateReminder"
+
+type handler struct {
+	calls int
+	err   error
+}
+
+func (h *handler) handle(context.Context, any) (any, error) {
+	h.calls++
+	if h.err != nil {
+		return nil, h.err
+	}
+
+	return &pb.ReminderId{Id: int32(h.calls)}, nil
+}
+
+func withKey(caller, key string) context.Context {
+	ctx := auth.NewContext(context.Background(), auth.Caller{Name: caller})
+
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, key))
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptor := New(NewMemoryStore(), time.Hour).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: createReminder}
+	reminder := &pb.Reminder{UserId: 1, ReminderText: "buy milk"}
+
+	call := func(ctx context.Context, h *handler, req proto.Message) (*pb.ReminderId, error) {
+		resp, err := interceptor(ctx, req, info, h.handle)
+		if err != nil {
+			return nil, err
+		}
+
+		return resp.(*pb.ReminderId), nil
+	}
+
+	t.Run("without key", func(t *testing.T) {
+		h := &handler{}
+		ctx := auth.NewContext(context.Background(), auth.Caller{Name: "bot"})
+
+		for i := 0; i < 2; i++ {
+			if _, err := call(ctx, h, reminder); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+
+		if h.calls != 2 {
+			t.Errorf("expected 2 calls of handler got %v", h.calls)
+		}
+	})
+
+	t.Run("repeated request", func(t *testing.T) {
+		h := &handler{}
+		ctx := withKey("bot", "repeated")
+
+		first, err := call(ctx, h, reminder)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		second, err := call(ctx, h, reminder)
+		if err != nil {
+			t.Fatalf("did not expect error on repeat got %v", err)
+		}
+
+		if h.calls != 1 {
+			t.Errorf("expected 1 call of handler got %v", h.calls)
+		}
+		if !proto.Equal(first, second) {
+			t.Errorf("expected replayed response %v got %v", first, second)
+		}
+	})
+
+	t.Run("reused key", func(t *testing.T) {
+		h := &handler{}
+		ctx := withKey("bot", "reused")
+
+		if _, err := call(ctx, h, reminder); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		other := &pb.Reminder{UserId: 1, ReminderText: "buy bread"}
+		if _, err := call(ctx, h, other); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition got %v", err)
+		}
+	})
+
+	t.Run("same key of other caller", func(t *testing.T) {
+		h := &handler{}
+
+		for _, caller := range []string{"bot", "web"} {
+			if _, err := call(withKey(caller, "shared"), h, reminder); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+
+		if h.calls != 2 {
+			t.Errorf("expected 2 calls of handler got %v", h.calls)
+		}
+	})
+
+	t.Run("same key of other user", func(t *testing.T) {
+		h := &handler{}
+
+		for _, userId := range []int64{1, 2} {
+			ctx := auth.NewContext(context.Background(), auth.Caller{Name: "web", UserID: &userId})
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, "shared"))
+			if _, err := call(ctx, h, &pb.Reminder{UserId: userId, ReminderText: "buy milk"}); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+
+		if h.calls != 2 {
+			t.Errorf("expected 2 calls of handler got %v", h.calls)
+		}
+	})
+
+	t.Run("stored error", func(t *testing.T) {
+		h := &handler{err: status.Error(codes.NotFound, "user 1: not found")}
+		ctx := withKey("bot", "stored error")
+
+		for i := 0; i < 2; i++ {
+			_, err := call(ctx, h, reminder)
+			if status.Code(err) != codes.NotFound || status.Convert(err).Message() != "user 1: not found" {
+				t.Errorf("expected NotFound got %v", err)
+			}
+		}
+
+		if h.calls != 1 {
+			t.Errorf("expected 1 call of handler got %v", h.calls)
+		}
+	})
+
+	t.Run("retryable error", func(t *testing.T) {
+		h := &handler{err: status.Error(codes.Unavailable, "database is down")}
+		ctx := withKey("bot", "retryable error")
+
+		if _, err := call(ctx, h, reminder); status.Code(err) != codes.Unavailable {
+			t.Fatalf("expected Unavailable got %v", err)
+		}
+
+		h.err = nil
+		if _, err := call(ctx, h, reminder); err != nil {
+			t.Errorf("did not expect error on retry got %v", err)
+		}
+
+		if h.calls != 2 {
+			t.Errorf("expected 2 calls of handler got %v", h.calls)
+		}
+	})
+
+	t.Run("request in progress", func(t *testing.T) {
+		ctx := withKey("bot", "in progress")
+
+		_, err := interceptor(ctx, reminder, info, func(ctx context.Context, req any) (any, error) {
+			return call(ctx, &handler{}, req.(proto.Message))
+		})
+
+		if status.Code(err) != codes.Aborted {
+			t.Errorf("expected Aborted got %v", err)
+		}
+	})
+
+	t.Run("method without idempotency", func(t *testing.T) {
+		h := &handler{}
+		ctx := withKey("bot", "get user")
+		getUser := &grpc.UnaryServerInfo{FullMethod: "/todoservice.TodoService/GetUser"}
+
+		for i := 0; i < 2; i++ {
+			if _, err := interceptor(ctx, &pb.UserId{Id: 1}, getUser, h.handle); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+
+		if h.calls != 2 {
+			t.Errorf("expected 2 calls of handler got %v", h.calls)
+		}
+	})
+}
+
+func TestMemoryStore_expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	ms := NewMemoryStore()
+	ms.now = func() time.Time { return now }
+
+	if existing, _ := ms.Reserve(ctx, "bot", "key", []byte("hash"), time.Minute); existing != nil {
+		t.Fatalf("did not expect existing record")
+	}
+	if existing, _ := ms.Reserve(ctx, "bot", "key", []byte("hash"), time.Minute); existing == nil {
+		t.Fatalf("expected pending record")
+	}
+
+	now = now.Add(time.Minute)
+	if existing, _ := ms.Reserve(ctx, "bot", "key", []byte("other"), time.Minute); existing != nil {
+		t.Errorf("expected expired record to be replaced")
+	}
+
+	now = now.Add(time.Minute)
+	_ = ms.DeleteExpired(ctx)
+	if len(ms.records) != 0 {
+		t.Errorf("expected expired records to be deleted, %d left", len(ms.records))
+	}
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryKey struct {
+	caller, key string
+}
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore keeps records in the memory of a single replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[memoryKey]memoryRecord
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[memoryKey]memoryRecord), now: time.Now}
+}
+
+func (ms *MemoryStore) Reserve(_ context.Context, caller, key string, requestHash []byte, ttl time.Duration) (*Record, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	k := memoryKey{caller: caller, key: key}
+	if existing, ok := ms.records[k]; ok && ms.now().Before(existing.expiresAt) {
+		record := existing.Record
+
+		return &record, nil
+	}
+
+	ms.records[k] = memoryRecord{
+		Record:    Record{RequestHash: requestHash},
+		expiresAt: ms.now().Add(ttl),
+	}
+
+	return nil, nil
+}
+
+func (ms *MemoryStore) Complete(_ context.Context, caller, key string, record Record, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.records[memoryKey{caller: caller, key: key}] = memoryRecord{Record: record, expiresAt: ms.now().Add(ttl)}
+
+	return nil
+}
+
+func (ms *MemoryStore) Release(_ context.Context, caller, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.records, memoryKey{caller: caller, key: key})
+
+	return nil
+}
+
+func (ms *MemoryStore) DeleteExpired(context.Context) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for k, record := range ms.records {
+		if !ms.now().Before(record.expiresAt) {
+			delete(ms.records, k)
+		}
+	}
+
+	return nil
+}
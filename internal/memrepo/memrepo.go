@@ -0,0 +1,186 @@
+// Package memrepo implements todoserviceserver.Repo in memory,
+// for local development and tests.
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+type user struct {
+	user            *pb.User
+	activeReminders int
+}
+
+type MemRepo struct {
+	mu                 sync.RWMutex
+	users              map[int64]*user
+	reminders          map[int32]*pb.Reminder
+	lastReminderId     int32
+	maxActiveReminders int
+}
+
+type Option func(*MemRepo)
+
+// WithMaxActiveReminders limits the number of reminders a user may have
+// which were not removed. Zero means no limit.
+func WithMaxActiveReminders(n int) Option {
+	return func(mr *MemRepo) {
+		mr.maxActiveReminders = n
+	}
+}
+
+func New(opts ...Option) *MemRepo {
+	mr := &MemRepo{
+		users:     make(map[int64]*user),
+		reminders: make(map[int32]*pb.Reminder),
+	}
+	for _, opt := range opts {
+		opt(mr)
+	}
+
+	return mr
+}
+
+func (mr *MemRepo) SetUser(_ context.Context, in *pb.User) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	u, ok := mr.users[in.GetId()]
+	if !ok {
+		u = &user{user: &pb.User{Id: in.GetId()}}
+		mr.users[in.GetId()] = u
+	}
+
+	if in.GetLanguageCode() != nil {
+		u.user.LanguageCode = proto.Clone(in.GetLanguageCode()).(*wrapperspb.StringValue)
+	}
+	if in.GetUtcOffset() != nil {
+		u.user.UtcOffset = proto.Clone(in.GetUtcOffset()).(*wrapperspb.Int32Value)
+	}
+	if in.GetNotificationChannels() != nil {
+		u.user.NotificationChannels = proto.Clone(in.GetNotificationChannels()).(*pb.NotificationChannels)
+	}
+
+	return nil
+}
+
+func (mr *MemRepo) GetUser(_ context.Context, id int64) (*pb.User, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	u, ok := mr.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	return proto.Clone(u.user).(*pb.User), nil
+}
+
+func (mr *MemRepo) CreateReminder(_ context.Context, in *pb.Reminder) (int32, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	u, ok := mr.users[in.GetUserId()]
+	if !ok {
+		return 0, fmt.Errorf("user %d: %w", in.GetUserId(), todoserviceserver.ErrNotFound)
+	}
+
+	if mr.maxActiveReminders > 0 && u.activeReminders >= mr.maxActiveReminders {
+		return 0, fmt.Errorf("user %d already has %d reminders: %w", in.GetUserId(), u.activeReminders, todoserviceserver.ErrQuotaExceeded)
+	}
+
+	mr.lastReminderId++
+	reminder := proto.Clone(in).(*pb.Reminder)
+	reminder.Id = mr.lastReminderId
+	mr.reminders[reminder.Id] = reminder
+	u.activeReminders++
+
+	return reminder.Id, nil
+}
+
+func (mr *MemRepo) CreateReminders(_ context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	u, ok := mr.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	if mr.maxActiveReminders > 0 && u.activeReminders+len(reminders) > mr.maxActiveReminders {
+		return nil, fmt.Errorf("user %d has no room for %d more reminders: %w", userId, len(reminders), todoserviceserver.ErrQuotaExceeded)
+	}
+
+	ids := make([]int32, 0, len(reminders))
+	for _, in := range reminders {
+		mr.lastReminderId++
+		reminder := proto.Clone(in).(*pb.Reminder)
+		reminder.Id, reminder.UserId = mr.lastReminderId, userId
+		mr.reminders[reminder.Id] = reminder
+		ids = append(ids, reminder.Id)
+	}
+	u.activeReminders += len(reminders)
+
+	return ids, nil
+}
+
+func (mr *MemRepo) GetReminder(_ context.Context, id int32) (*pb.Reminder, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	reminder, ok := mr.reminders[id]
+	if !ok {
+		return nil, fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	return proto.Clone(reminder).(*pb.Reminder), nil
+}
+
+func (mr *MemRepo) RemoveReminder(_ context.Context, id int32) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	reminder, ok := mr.reminders[id]
+	if !ok {
+		return fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	delete(mr.reminders, id)
+	mr.users[reminder.GetUserId()].activeReminders--
+
+	return nil
+}
+
+func (mr *MemRepo) GetRemindersByUserId(_ context.Context, userId int64) ([]*pb.Reminder, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	var reminders []*pb.Reminder
+	for _, reminder := range mr.reminders {
+		if reminder.GetUserId() == userId {
+			reminders = append(reminders, proto.Clone(reminder).(*pb.Reminder))
+		}
+	}
+
+	slices.SortFunc(reminders, compareReminders)
+
+	return reminders, nil
+}
+
+// compareReminders orders reminders by remind timestamp, then by id.
+func compareReminders(a, b *pb.Reminder) int {
+	if c := a.GetRemindTimestamp().AsTime().Compare(b.GetRemindTimestamp().AsTime()); c != 0 {
+		return c
+	}
+
+	return int(a.GetId()) - int(b.GetId())
+}
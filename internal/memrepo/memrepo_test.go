@@ -0,0 +1,14 @@
+package memrepo
+
+import (
+	"testing"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/repotest"
+)
+
+func TestMemRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, opts repotest.Options) todoserviceserver.Repo {
+		return New(WithMaxActiveReminders(opts.MaxActiveReminders))
+	})
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/retry"
+)
+
+// Route returns the channels a reminder of the user is delivered to, given
+// the channels the user set, nil when none.
+type Route func(userId int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel
+
+// Recipient of a fired reminder: its owner, or a user or group chat it is
+// shared with. Group chats have no channels, so they are routed to like
+// users without any.
+type Recipient struct {
+	ID       int64
+	Channels []*pb.NotificationChannel
+}
+
+// Target is a channel a fired reminder is delivered to, and the ID of the
+// recipient it was routed for.
+type Target struct {
+	RecipientID int64
+	Channel     *pb.NotificationChannel
+}
+
+// Fanout returns the channels route returns for every recipient, each
+// channel once, for the first recipient it was routed for.
+func Fanout(route Route, recipients []Recipient) []Target {
+	var targets []Target
+	seen := make(map[string]bool)
+	for _, recipient := range recipients {
+		for _, channel := range route(recipient.ID, recipient.Channels) {
+			key := channel.GetKind().String() + " " + channel.GetAddress()
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			targets = append(targets, Target{RecipientID: recipient.ID, Channel: channel})
+		}
+	}
+
+	return targets
+}
+
+// Delivery of a fired reminder to one channel.
+type Delivery struct {
+	ID       int64
+	Kind     pb.NotificationChannel_Kind
+	Address  string
+	Reminder *pb.Reminder
+	// Attempts made so far, the one the delivery was claimed for included.
+	Attempts int
+}
+
+type Store interface {
+	// FireDueReminders marks up to limit reminders due at now as fired and
+	// queues a delivery to every target Fanout returns for each of them,
+	// atomically: for its owner followed by the recipients who accepted it
+	// being shared with them. Deliveries keep the ID of their recipient, so
+	// that they are deleted with the user. It returns how many reminders
+	// were fired.
+	FireDueReminders(ctx context.Context, now time.Time, limit int, route Route) (int, error)
+	// ClaimDeliveries returns up to limit pending deliveries due at now,
+	// counting an attempt for each and hiding them from other claims until
+	// leaseUntil.
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error)
+	MarkDeliverySent(ctx context.Context, id int64) error
+	// MarkDeliveryFailed records the error of the last attempt. The delivery
+	// is attempted again at retryAt or, when retryAt is zero, given up.
+	MarkDeliveryFailed(ctx context.Context, id int64, lastError string, retryAt time.Time) error
+}
+
+type Dispatcher struct {
+	store    Store
+	registry *Registry
+	policy   retry.Policy
+}
+
+// NewDispatcher attempts deliveries up to 5 times, 30s after the first
+// failure and at most 1h apart, for 10s each. It fires 100 reminders and
+// attempts 100 deliveries at once.
+func NewDispatcher(store Store, registry *Registry, opts ...retry.Option) *Dispatcher {
+	policy := retry.Policy{
+		BatchSize:   100,
+		MaxAttempts: 5,
+		Backoff:     30 * time.Second,
+		MaxBackoff:  time.Hour,
+		Timeout:     10 * time.Second,
+		Now:         time.Now,
+	}
+
+	return &Dispatcher{store: store, registry: registry, policy: policy.With(opts...)}
+}
+
+// Route sends reminders to the channels the user set or, without any,
+// to the Telegram chat with the id of the user when Telegram is enabled.
+func (d *Dispatcher) Route(userId int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel {
+	if len(channels) > 0 || !d.registry.Enabled(pb.NotificationChannel_TELEGRAM) {
+		return channels
+	}
+
+	return []*pb.NotificationChannel{{
+		Kind:    pb.NotificationChannel_TELEGRAM,
+		Address: strconv.FormatInt(userId, 10),
+	}}
+}
+
+// Run calls Dispatch every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	retry.Run(ctx, interval, "dispatching reminders", d.Dispatch)
+}
+
+// Dispatch fires the due reminders and attempts the due deliveries,
+// in batches until none are left.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	for {
+		fired, err := d.store.FireDueReminders(ctx, d.policy.Now(), d.policy.BatchSize, d.Route)
+		if err != nil {
+			return err
+		}
+		if fired < d.policy.BatchSize {
+			break
+		}
+	}
+
+	return retry.Process(ctx, d.policy, d.store.ClaimDeliveries, d.deliver)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	err := d.policy.Attempt(ctx, func(ctx context.Context) error {
+		return d.registry.Notify(ctx, delivery.Kind, delivery.Address, delivery.Reminder)
+	})
+
+	if err == nil {
+		if err := d.store.MarkDeliverySent(ctx, delivery.ID); err != nil {
+			log.Printf("Error recording delivery %d as sent: %v", delivery.ID, err)
+		}
+
+		return
+	}
+
+	var retryAt time.Time
+	if !IsPermanent(err) {
+		retryAt = d.policy.RetryAt(delivery.Attempts)
+	}
+
+	log.Printf("Error in delivery %d of reminder %d to %s, attempt %d: %v",
+		delivery.ID, delivery.Reminder.GetId(), delivery.Kind, delivery.Attempts, err)
+
+	if err := d.store.MarkDeliveryFailed(ctx, delivery.ID, err.Error(), retryAt); err != nil {
+		log.Printf("Error recording failure of delivery %d: %v", delivery.ID, err)
+	}
+}
@@ -0,0 +1,195 @@
+package notify_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/notify/notifytest"
+	"github.com/awakair/awakair_todo_bot/internal/retry"
+	"github.com/awakair/awakair_todo_bot/internal/sqliterepo"
+)
+
+func testStore(t *testing.T) *sqliterepo.SqliteRepo {
+	t.Helper()
+
+	db, err := sqliterepo.Open(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := sqliterepo.New(db)
+	if err := repo.Migrate(context.Background()); err != nil {
+		t.Fatalf("cannot migrate database: %v", err)
+	}
+
+	return repo
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	api := notifytest.NewHTTPReceiver(t)
+	hook := notifytest.NewHTTPReceiver(t)
+	sink := notifytest.NewSMTPSink(t)
+
+	email, err := notify.NewEmail(sink.Addr, "", "", "bot@example.com")
+	if err != nil {
+		t.Fatalf("cannot create email notifier: %v", err)
+	}
+	registry := notify.NewRegistry()
+	registry.Register(pb.NotificationChannel_TELEGRAM, notify.NewTelegram(http.DefaultClient, api.URL, "token"))
+	registry.Register(pb.NotificationChannel_EMAIL, email)
+	registry.Register(pb.NotificationChannel_WEBHOOK, notify.NewWebhook(http.DefaultClient))
+
+	now := time.Now()
+	dispatcher := notify.NewDispatcher(store, registry,
+		retry.WithMaxAttempts(3),
+		retry.WithBackoff(time.Minute, 90*time.Second),
+		retry.WithClock(func() time.Time { return now }),
+	)
+
+	users := []*pb.User{
+		{Id: 1, NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+			{Kind: pb.NotificationChannel_EMAIL, Address: "alice@example.com"},
+			{Kind: pb.NotificationChannel_WEBHOOK, Address: hook.URL},
+		}}},
+		{Id: 2},
+	}
+	for _, user := range users {
+		if err := store.SetUser(ctx, user); err != nil {
+			t.Fatalf("cannot set user: %v", err)
+		}
+
+		_, err := store.CreateReminder(ctx, &pb.Reminder{
+			UserId:          user.GetId(),
+			ReminderText:    "stand up",
+			RemindTimestamp: timestamppb.New(now.Add(-time.Second)),
+		})
+		if err != nil {
+			t.Fatalf("cannot create reminder: %v", err)
+		}
+	}
+
+	dispatch := func() {
+		t.Helper()
+
+		if err := dispatcher.Dispatch(ctx); err != nil {
+			t.Fatalf("cannot dispatch: %v", err)
+		}
+	}
+
+	// The webhook fails until its last attempt.
+	hook.RespondWith(http.StatusServiceUnavailable, http.StatusBadGateway)
+	dispatch()
+
+	if mails := sink.Mails(); len(mails) != 1 || mails[0].To[0] != "alice@example.com" {
+		t.Errorf("expected a mail to alice@example.com got %+v", mails)
+	}
+	if requests := api.Requests(); len(requests) != 1 || requests[0].Path != "/bottoken/sendMessage" {
+		t.Errorf("expected user 2 without channels to get a Telegram message got %+v", requests)
+	}
+	if requests := hook.Requests(); len(requests) != 1 {
+		t.Errorf("expected 1 webhook attempt got %d", len(requests))
+	}
+
+	now = now.Add(59 * time.Second)
+	dispatch()
+	if requests := hook.Requests(); len(requests) != 1 {
+		t.Errorf("expected no retry before the backoff got %d attempts", len(requests))
+	}
+
+	now = now.Add(time.Second)
+	dispatch()
+	if requests := hook.Requests(); len(requests) != 2 {
+		t.Errorf("expected a retry after the backoff got %d attempts", len(requests))
+	}
+
+	now = now.Add(90 * time.Second)
+	dispatch()
+	now = now.Add(time.Hour)
+	dispatch()
+
+	if requests := hook.Requests(); len(requests) != 3 {
+		t.Errorf("expected the webhook to succeed on its third and last attempt got %d attempts", len(requests))
+	}
+	if mails, requests := sink.Mails(), api.Requests(); len(mails) != 1 || len(requests) != 1 {
+		t.Errorf("expected sent deliveries not to be repeated got %d mails and %d messages", len(mails), len(requests))
+	}
+}
+
+func TestDispatcher_giveUp(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+	gone := notifytest.NewHTTPReceiver(t)
+	down := notifytest.NewHTTPReceiver(t)
+
+	registry := notify.NewRegistry()
+	registry.Register(pb.NotificationChannel_WEBHOOK, notify.NewWebhook(http.DefaultClient))
+
+	now := time.Now()
+	dispatcher := notify.NewDispatcher(store, registry,
+		retry.WithMaxAttempts(2),
+		retry.WithBackoff(time.Minute, time.Minute),
+		retry.WithClock(func() time.Time { return now }),
+	)
+
+	err := store.SetUser(ctx, &pb.User{Id: 1, NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+		{Kind: pb.NotificationChannel_WEBHOOK, Address: gone.URL},
+		{Kind: pb.NotificationChannel_WEBHOOK, Address: down.URL},
+		{Kind: pb.NotificationChannel_EMAIL, Address: "alice@example.com"},
+	}}})
+	if err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+	if _, err := store.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "stand up", RemindTimestamp: timestamppb.New(now)}); err != nil {
+		t.Fatalf("cannot create reminder: %v", err)
+	}
+
+	// Email is not enabled, which is a permanent failure too.
+	gone.RespondWith(http.StatusGone, http.StatusGone)
+	down.RespondWith(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	for range 5 {
+		if err := dispatcher.Dispatch(ctx); err != nil {
+			t.Fatalf("cannot dispatch: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	if gone, down := len(gone.Requests()), len(down.Requests()); gone != 1 || down != 2 {
+		t.Errorf("expected 1 attempt for a permanent failure and 2 for temporary ones got %d and %d", gone, down)
+	}
+}
+
+func TestFanout(t *testing.T) {
+	registry := notify.NewRegistry()
+	registry.Register(pb.NotificationChannel_TELEGRAM, notify.NotifierFunc(func(context.Context, string, *pb.Reminder) error { return nil }))
+	d := notify.NewDispatcher(nil, registry)
+
+	email := &pb.NotificationChannel{Kind: pb.NotificationChannel_EMAIL, Address: "family@example.com"}
+	targets := notify.Fanout(d.Route, []notify.Recipient{
+		{ID: 1, Channels: []*pb.NotificationChannel{email}},
+		{ID: 2, Channels: []*pb.NotificationChannel{email}},
+		{ID: 3},
+		{ID: -100},
+	})
+
+	var got []string
+	for _, target := range targets {
+		got = append(got, fmt.Sprintf("%d %s %s", target.RecipientID, target.Channel.GetKind(), target.Channel.GetAddress()))
+	}
+	expected := []string{"1 EMAIL family@example.com", "3 TELEGRAM 3", "-100 TELEGRAM -100"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected targets %v got %v", expected, got)
+	}
+}
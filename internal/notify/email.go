@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Email sends reminders through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it. Addresses are email addresses.
+type Email struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+// NewEmail sends mail from the address from through the SMTP server at addr,
+// host:port, authenticating with PLAIN when username is not empty.
+func NewEmail(addr, username, password, from string) (*Email, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Email{addr: addr, host: host, from: from}
+	if username != "" {
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return e, nil
+}
+
+func (e *Email) Notify(ctx context.Context, address string, reminder *pb.Reminder) error {
+	err := e.send(ctx, address, e.message(address, reminder))
+
+	// 5xx replies, e.g. an unknown mailbox, are not worth retrying.
+	var replyErr *textproto.Error
+	if errors.As(err, &replyErr) && replyErr.Code >= 500 {
+		return Permanent(err)
+	}
+
+	return err
+}
+
+func (e *Email) send(ctx context.Context, to string, message []byte) error {
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("cannot reach SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if err := client.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// subjectLength is how many characters of the reminder text go to subjects.
+const subjectLength = 60
+
+func (e *Email) message(to string, reminder *pb.Reminder) []byte {
+	text := reminder.GetReminderText()
+
+	subject, _, _ := strings.Cut(text, "\n")
+	if runes := []rune(subject); len(runes) > subjectLength {
+		subject = string(runes[:subjectLength]) + "…"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Reminder: "+strings.TrimSpace(subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(text))
+	w.Close()
+
+	return buf.Bytes()
+}
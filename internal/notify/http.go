@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/awakair/awakair_todo_bot/internal/egress"
+)
+
+// maxErrorBody is how many bytes of the body of an error response end up
+// in the error, which is kept with the delivery.
+const maxErrorBody = 200
+
+// post sends body as JSON to target and returns the response body when the
+// status is 2xx. Client errors other than timeouts and rate limits are
+// permanent, as are targets in the network of the server. name stands for
+// target in errors, which may be secret.
+func post(ctx context.Context, client *http.Client, name, target string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid %s URL: %w", name, err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-service")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		err = fmt.Errorf("cannot reach %s: %w", name, err)
+		if errors.Is(err, egress.ErrForbiddenAddress) {
+			return nil, Permanent(err)
+		}
+
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response of %s: %w", name, err)
+	}
+
+	if resp.StatusCode/100 == 2 {
+		return respBody, nil
+	}
+
+	err = fmt.Errorf("%s answered %s", name, resp.Status)
+	if snippet := errorSnippet(respBody); snippet != "" {
+		err = fmt.Errorf("%w: %s", err, snippet)
+	}
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, Permanent(err)
+	}
+
+	return nil, err
+}
+
+// errorSnippet returns the start of the body of an error response.
+func errorSnippet(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) <= maxErrorBody {
+		return strings.ToValidUTF8(string(body), "")
+	}
+
+	return strings.ToValidUTF8(string(body[:maxErrorBody]), "") + "..."
+}
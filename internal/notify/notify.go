@@ -0,0 +1,83 @@
+// Package notify delivers fired reminders to the notification channels of
+// their users: Telegram chats, email addresses and webhooks.
+//
+// Firing a reminder queues one delivery per channel in the Store. The
+// Dispatcher then attempts every delivery on its own, retrying failed ones
+// with exponential backoff, so a broken channel delays no other. Deliveries
+// are at least once: a reminder sent by a replica which dies before
+// recording it is sent again.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Notifier sends reminders over one kind of channel.
+type Notifier interface {
+	// Notify sends reminder to address, whose format depends on the channel.
+	// Errors wrapped with Permanent are not retried.
+	Notify(ctx context.Context, address string, reminder *pb.Reminder) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, address string, reminder *pb.Reminder) error
+
+func (f NotifierFunc) Notify(ctx context.Context, address string, reminder *pb.Reminder) error {
+	return f(ctx, address, reminder)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as one which retrying would not fix,
+// e.g. a rejected address.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	return errors.As(err, new(permanentError))
+}
+
+// Registry holds the notifiers of the enabled channels.
+type Registry struct {
+	notifiers map[pb.NotificationChannel_Kind]Notifier
+}
+
+func NewRegistry() *Registry {
+	return &Registry{notifiers: make(map[pb.NotificationChannel_Kind]Notifier)}
+}
+
+// Register enables the channels of kind, replacing their previous notifier.
+func (r *Registry) Register(kind pb.NotificationChannel_Kind, notifier Notifier) {
+	r.notifiers[kind] = notifier
+}
+
+func (r *Registry) Enabled(kind pb.NotificationChannel_Kind) bool {
+	_, ok := r.notifiers[kind]
+
+	return ok
+}
+
+// Notify sends reminder over the channel of kind.
+func (r *Registry) Notify(ctx context.Context, kind pb.NotificationChannel_Kind, address string, reminder *pb.Reminder) error {
+	notifier, ok := r.notifiers[kind]
+	if !ok {
+		return Permanent(fmt.Errorf("%s channels are not enabled", kind))
+	}
+
+	return notifier.Notify(ctx, address, reminder)
+}
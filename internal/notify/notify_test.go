@@ -0,0 +1,185 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/egress"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/notify/notifytest"
+)
+
+var reminder = &pb.Reminder{
+	Id:              7,
+	UserId:          42,
+	ReminderText:    "Buy milk\nand bread",
+	RemindTimestamp: timestamppb.New(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)),
+}
+
+func TestTelegram(t *testing.T) {
+	ctx := context.Background()
+	api := notifytest.NewHTTPReceiver(t)
+	telegram := notify.NewTelegram(api.Client(), api.URL+"/", "123:secret")
+
+	if err := telegram.Notify(ctx, "42", reminder); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	requests := api.Requests()
+	if len(requests) != 1 || requests[0].Method != http.MethodPost || requests[0].Path != "/bot123:secret/sendMessage" {
+		t.Fatalf("expected a sendMessage call got %+v", requests)
+	}
+
+	var message struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}
+	if err := json.Unmarshal(requests[0].Body, &message); err != nil || message.ChatID != "42" || message.Text != reminder.GetReminderText() {
+		t.Errorf("expected reminder text sent to chat 42 got %s, %v", requests[0].Body, err)
+	}
+
+	for status, permanent := range map[int]bool{
+		http.StatusForbidden:           true,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+	} {
+		api.RespondWith(status)
+
+		err := telegram.Notify(ctx, "42", reminder)
+		if err == nil || notify.IsPermanent(err) != permanent {
+			t.Errorf("expected error permanent %v for status %d got %v", permanent, status, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "secret") {
+			t.Errorf("expected error not to reveal the bot token got %v", err)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	ctx := context.Background()
+	sink := notifytest.NewSMTPSink(t)
+
+	email, err := notify.NewEmail(sink.Addr, "bot", "secret", "bot@example.com")
+	if err != nil {
+		t.Fatalf("cannot create notifier: %v", err)
+	}
+
+	if err := email.Notify(ctx, "alice@example.com", reminder); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	mails := sink.Mails()
+	if len(mails) != 1 {
+		t.Fatalf("expected 1 mail got %+v", mails)
+	}
+
+	mail := mails[0]
+	if mail.Username != "bot" || mail.From != "bot@example.com" || len(mail.To) != 1 || mail.To[0] != "alice@example.com" {
+		t.Errorf("expected mail from bot@example.com to alice@example.com authenticated as bot got %+v", mail)
+	}
+	// The sink, like textproto, turns line endings into \n.
+	for _, want := range []string{"Subject: Reminder: Buy milk\n", "To: alice@example.com\n", "\n\nBuy milk\nand bread"} {
+		if !strings.Contains(mail.Data, want) {
+			t.Errorf("expected mail to contain %q got %q", want, mail.Data)
+		}
+	}
+
+	for code, permanent := range map[int]bool{550: true, 451: false} {
+		sink.RejectRecipients(code)
+
+		err := email.Notify(ctx, "alice@example.com", reminder)
+		if err == nil || notify.IsPermanent(err) != permanent {
+			t.Errorf("expected error permanent %v for reply %d got %v", permanent, code, err)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	ctx := context.Background()
+	hook := notifytest.NewHTTPReceiver(t)
+	webhook := notify.NewWebhook(hook.Client())
+
+	if err := webhook.Notify(ctx, hook.URL+"/hook", reminder); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	requests := hook.Requests()
+	if len(requests) != 1 || requests[0].Path != "/hook" || requests[0].Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON request to /hook got %+v", requests)
+	}
+
+	got := &pb.Reminder{}
+	if err := protojson.Unmarshal(requests[0].Body, got); err != nil || !proto.Equal(got, reminder) {
+		t.Errorf("expected reminder %v got %s, %v", reminder, requests[0].Body, err)
+	}
+	if !strings.Contains(string(requests[0].Body), "reminder_text") {
+		t.Errorf("expected proto field names got %s", requests[0].Body)
+	}
+
+	for status, permanent := range map[int]bool{
+		http.StatusGone:               true,
+		http.StatusRequestTimeout:     false,
+		http.StatusServiceUnavailable: false,
+	} {
+		hook.RespondWith(status)
+
+		err := webhook.Notify(ctx, hook.URL, reminder)
+		if err == nil || notify.IsPermanent(err) != permanent {
+			t.Errorf("expected error permanent %v for status %d got %v", permanent, status, err)
+		}
+	}
+
+	if err := webhook.Notify(ctx, "http://[::1]:namedport", reminder); !notify.IsPermanent(err) {
+		t.Errorf("expected permanent error for invalid URL got %v", err)
+	}
+
+	guarded := notify.NewWebhook(egress.NewClient(time.Second))
+	if err := guarded.Notify(ctx, hook.URL, reminder); !errors.Is(err, egress.ErrForbiddenAddress) || !notify.IsPermanent(err) {
+		t.Errorf("expected permanent error for a loopback URL got %v", err)
+	}
+}
+
+func TestWebhook_errorBody(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("ошибка ", 10_000)))
+	}))
+	defer hook.Close()
+
+	err := notify.NewWebhook(hook.Client()).Notify(context.Background(), hook.URL, reminder)
+	if err == nil || !strings.Contains(err.Error(), "500") || len(err.Error()) > 300 || !utf8.ValidString(err.Error()) {
+		t.Errorf("expected the status and the start of the body got %q", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := notify.NewRegistry()
+
+	var got string
+	registry.Register(pb.NotificationChannel_WEBHOOK, notify.NotifierFunc(func(_ context.Context, address string, _ *pb.Reminder) error {
+		got = address
+
+		return nil
+	}))
+
+	if err := registry.Notify(context.Background(), pb.NotificationChannel_WEBHOOK, "https://example.com", reminder); err != nil || got != "https://example.com" {
+		t.Errorf("expected the webhook notifier to be called got %q, %v", got, err)
+	}
+
+	err := registry.Notify(context.Background(), pb.NotificationChannel_EMAIL, "alice@example.com", reminder)
+	if !notify.IsPermanent(err) {
+		t.Errorf("expected permanent error for channels which are not enabled got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Package notifytest runs local stand-ins of the services reminders are
+// delivered through, so that every channel is tested without reaching out,
+// and checks that implementations of notify.Store behave alike.
+package notifytest
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Mail received by an SMTPSink.
+type Mail struct {
+	// Username the client authenticated as, empty without authentication.
+	Username string
+	From     string
+	To       []string
+	Data     string
+}
+
+// SMTPSink is an SMTP server on localhost keeping the mail it receives.
+// It offers PLAIN authentication, accepting any credentials, and no TLS.
+type SMTPSink struct {
+	Addr string
+
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []Mail
+	// rejectCode answers RCPT commands when not zero.
+	rejectCode int
+}
+
+// NewSMTPSink starts a sink stopped at the end of the test.
+func NewSMTPSink(t *testing.T) *SMTPSink {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start SMTP sink: %v", err)
+	}
+
+	s := &SMTPSink{Addr: listener.Addr().String(), listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return s
+}
+
+// Mails returns the mail received so far.
+func (s *SMTPSink) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Mail(nil), s.mails...)
+}
+
+// RejectRecipients makes the sink answer recipients with code,
+// e.g. 550 for an unknown mailbox or 451 for a temporary failure.
+// Zero accepts them again.
+func (s *SMTPSink) RejectRecipients(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejectCode = code
+}
+
+func (s *SMTPSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *SMTPSink) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	reply := func(format string, args ...any) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	var mail Mail
+	if !reply("220 localhost notifytest") {
+		return
+	}
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-localhost\r\n250-8BITMIME\r\n250 AUTH PLAIN")
+		case "HELO", "NOOP":
+			reply("250 OK")
+		case "RSET":
+			mail = Mail{}
+			reply("250 OK")
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			credentials, err := base64.StdEncoding.DecodeString(initial)
+			if err != nil {
+				reply("501 malformed credentials")
+
+				continue
+			}
+			// authorization identity \0 user name \0 password
+			fields := strings.Split(string(credentials), "\x00")
+			if len(fields) != 3 {
+				reply("501 malformed credentials")
+
+				continue
+			}
+			mail.Username = fields[1]
+			reply("235 authenticated")
+		case "MAIL":
+			mail.From = address(arg)
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			code := s.rejectCode
+			s.mu.Unlock()
+
+			if code != 0 {
+				reply("%d recipient rejected", code)
+
+				continue
+			}
+			mail.To = append(mail.To, address(arg))
+			reply("250 OK")
+		case "DATA":
+			if !reply("354 go ahead") {
+				return
+			}
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+
+			mail = Mail{Username: mail.Username}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+
+			return
+		default:
+			reply("502 %s is not implemented", verb)
+		}
+	}
+}
+
+// address extracts the address from arguments like "FROM:<a@example.com>".
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+
+	return strings.Trim(addr, "<>")
+}
+
+// Request received by an HTTPReceiver.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// HTTPReceiver is an HTTP server on localhost keeping the requests it
+// receives, standing in for webhooks and the Telegram Bot API. It answers
+// 200 with {"ok":true} unless told otherwise by RespondWith.
+type HTTPReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	statuses []int
+}
+
+// NewHTTPReceiver starts a receiver stopped at the end of the test.
+func NewHTTPReceiver(t *testing.T) *HTTPReceiver {
+	r := &HTTPReceiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	t.Cleanup(r.Server.Close)
+
+	return r
+}
+
+// Requests returns the requests received so far.
+func (r *HTTPReceiver) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Request(nil), r.requests...)
+}
+
+// RespondWith answers the next requests with statuses, in order.
+func (r *HTTPReceiver) RespondWith(statuses ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statuses = append(r.statuses, statuses...)
+}
+
+func (r *HTTPReceiver) handle(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.requests = append(r.requests, Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		io.WriteString(w, `{"ok":true}`)
+	} else {
+		io.WriteString(w, `{"ok":false,"description":"rejected by notifytest"}`)
+	}
+}
@@ -0,0 +1,249 @@
+package notifytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+)
+
+// StoreFactory returns an empty store and the repo writing to the same
+// database, whose users have at most maxActiveReminders active reminders.
+type StoreFactory func(t *testing.T, maxActiveReminders int) (notify.Store, todoserviceserver.Repo)
+
+// RunStore checks the implementation of notify.Store returned by newStore.
+func RunStore(t *testing.T, newStore StoreFactory) {
+	t.Run("FireDueReminders", func(t *testing.T) { testFireDueReminders(t, newStore) })
+	t.Run("quota", func(t *testing.T) { testFiredQuota(t, newStore) })
+	t.Run("ClaimDeliveries", func(t *testing.T) { testClaimDeliveries(t, newStore) })
+	t.Run("MarkDelivery", func(t *testing.T) { testMarkDelivery(t, newStore) })
+}
+
+var (
+	email = &pb.NotificationChannel{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"}
+	hook  = &pb.NotificationChannel{Kind: pb.NotificationChannel_WEBHOOK, Address: "https://example.com/hook"}
+)
+
+// fixture creates user 1 with the email and webhook channels and user 2
+// without channels, and returns their reminders: two due at now, one due
+// an hour later and one removed.
+func fixture(t *testing.T, repo todoserviceserver.Repo, now time.Time) []*pb.Reminder {
+	t.Helper()
+	ctx := context.Background()
+
+	users := []*pb.User{
+		{Id: 1, NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{email, hook}}},
+		{Id: 2},
+	}
+	for _, user := range users {
+		if err := repo.SetUser(ctx, user); err != nil {
+			t.Fatalf("cannot set user %+v: %v", user, err)
+		}
+	}
+
+	remindAt := func(d time.Duration) *timestamppb.Timestamp {
+		return timestamppb.New(now.Add(d).Truncate(time.Millisecond))
+	}
+	reminders := []*pb.Reminder{
+		{UserId: 1, ReminderText: "due", RemindTimestamp: remindAt(-time.Minute)},
+		{UserId: 2, ReminderText: "due too", RemindTimestamp: remindAt(-time.Second)},
+		{UserId: 1, ReminderText: "later", RemindTimestamp: remindAt(time.Hour)},
+		{UserId: 2, ReminderText: "removed", RemindTimestamp: remindAt(-time.Hour)},
+	}
+	for _, reminder := range reminders {
+		id, err := repo.CreateReminder(ctx, reminder)
+		if err != nil {
+			t.Fatalf("cannot create reminder %+v: %v", reminder, err)
+		}
+		reminder.Id = id
+	}
+	if err := repo.RemoveReminder(ctx, reminders[3].GetId()); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	return reminders
+}
+
+// route sends reminders of users without channels to the Telegram chat
+// with their id, as notify.Dispatcher.Route does.
+func route(userId int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel {
+	if len(channels) > 0 {
+		return channels
+	}
+
+	return []*pb.NotificationChannel{{Kind: pb.NotificationChannel_TELEGRAM, Address: "chat"}}
+}
+
+func mustFire(t *testing.T, store notify.Store, now time.Time, limit int) int {
+	t.Helper()
+
+	fired, err := store.FireDueReminders(context.Background(), now, limit, route)
+	if err != nil {
+		t.Fatalf("cannot fire reminders: %v", err)
+	}
+
+	return fired
+}
+
+func mustClaim(t *testing.T, store notify.Store, now time.Time, limit int) []notify.Delivery {
+	t.Helper()
+
+	deliveries, err := store.ClaimDeliveries(context.Background(), now, now.Add(time.Minute), limit)
+	if err != nil {
+		t.Fatalf("cannot claim deliveries: %v", err)
+	}
+
+	return deliveries
+}
+
+func testFireDueReminders(t *testing.T, newStore StoreFactory) {
+	store, repo := newStore(t, 0)
+	now := time.Now()
+	fixture(t, repo, now)
+
+	var routed [][]*pb.NotificationChannel
+	fired, err := store.FireDueReminders(context.Background(), now, 1, func(userId int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel {
+		routed = append(routed, channels)
+
+		return route(userId, channels)
+	})
+	if err != nil {
+		t.Fatalf("cannot fire reminders: %v", err)
+	}
+	if fired != 1 || len(routed) != 1 {
+		t.Fatalf("expected the limit of 1 reminder to be fired got %d routed %d times", fired, len(routed))
+	}
+	if len(routed[0]) != 2 || !proto.Equal(routed[0][0], email) || !proto.Equal(routed[0][1], hook) {
+		t.Errorf("expected the channels of user 1 to be routed first got %v", routed[0])
+	}
+
+	if fired := mustFire(t, store, now, 10); fired != 1 {
+		t.Errorf("expected the other due reminder to be fired got %d", fired)
+	}
+	if fired := mustFire(t, store, now, 10); fired != 0 {
+		t.Errorf("expected fired reminders not to fire again got %d", fired)
+	}
+	if fired := mustFire(t, store, now.Add(2*time.Hour), 10); fired != 1 {
+		t.Errorf("expected only the later reminder to fire later got %d", fired)
+	}
+
+	reminders, err := repo.GetRemindersByUserId(context.Background(), 1)
+	if err != nil || len(reminders) != 2 {
+		t.Errorf("expected fired reminders to stay listed got %v, %v", reminders, err)
+	}
+}
+
+func testFiredQuota(t *testing.T, newStore StoreFactory) {
+	store, repo := newStore(t, 1)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := repo.SetUser(ctx, &pb.User{Id: 1}); err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+	create := func(d time.Duration) (int32, error) {
+		return repo.CreateReminder(ctx, &pb.Reminder{
+			UserId:          1,
+			ReminderText:    "reminder",
+			RemindTimestamp: timestamppb.New(now.Add(d)),
+		})
+	}
+
+	firedId, err := create(-time.Minute)
+	if err != nil {
+		t.Fatalf("cannot create reminder: %v", err)
+	}
+	mustFire(t, store, now, 10)
+
+	if _, err := create(time.Hour); err != nil {
+		t.Fatalf("expected fired reminders not to count towards the quota got %v", err)
+	}
+
+	if err := repo.RemoveReminder(ctx, firedId); err != nil {
+		t.Fatalf("cannot remove fired reminder: %v", err)
+	}
+	if _, err := create(time.Hour); !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+		t.Errorf("expected removing a fired reminder not to free quota got %v", err)
+	}
+}
+
+func testClaimDeliveries(t *testing.T, newStore StoreFactory) {
+	store, repo := newStore(t, 0)
+	now := time.Now()
+	reminders := fixture(t, repo, now)
+	mustFire(t, store, now, 10)
+
+	deliveries := mustClaim(t, store, now, 2)
+	if len(deliveries) != 2 {
+		t.Fatalf("expected the limit of 2 deliveries got %+v", deliveries)
+	}
+	deliveries = append(deliveries, mustClaim(t, store, now, 10)...)
+
+	want := map[pb.NotificationChannel_Kind]struct {
+		address  string
+		reminder *pb.Reminder
+	}{
+		pb.NotificationChannel_EMAIL:    {email.GetAddress(), reminders[0]},
+		pb.NotificationChannel_WEBHOOK:  {hook.GetAddress(), reminders[0]},
+		pb.NotificationChannel_TELEGRAM: {"chat", reminders[1]},
+	}
+	if len(deliveries) != len(want) {
+		t.Fatalf("expected %d deliveries got %+v", len(want), deliveries)
+	}
+	for _, delivery := range deliveries {
+		w := want[delivery.Kind]
+		if delivery.Address != w.address || !proto.Equal(delivery.Reminder, w.reminder) || delivery.Attempts != 1 {
+			t.Errorf("expected first attempt of %s delivery to %s of %v got %+v", delivery.Kind, w.address, w.reminder, delivery)
+		}
+	}
+
+	if again := mustClaim(t, store, now, 10); len(again) != 0 {
+		t.Errorf("expected claimed deliveries to be hidden got %+v", again)
+	}
+
+	again := mustClaim(t, store, now.Add(2*time.Minute), 10)
+	if len(again) != len(want) || again[0].Attempts != 2 {
+		t.Errorf("expected deliveries to be claimed again for a second attempt once their lease ends got %+v", again)
+	}
+}
+
+func testMarkDelivery(t *testing.T, newStore StoreFactory) {
+	store, repo := newStore(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+	fixture(t, repo, now)
+	mustFire(t, store, now, 10)
+
+	deliveries := mustClaim(t, store, now, 10)
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 deliveries got %+v", deliveries)
+	}
+	sent, retried, failed := deliveries[0], deliveries[1], deliveries[2]
+
+	retryAt := now.Add(time.Hour)
+	for _, err := range []error{
+		store.MarkDeliverySent(ctx, sent.ID),
+		store.MarkDeliveryFailed(ctx, retried.ID, "temporary", retryAt),
+		store.MarkDeliveryFailed(ctx, failed.ID, "permanent", time.Time{}),
+	} {
+		if err != nil {
+			t.Fatalf("cannot mark delivery: %v", err)
+		}
+	}
+
+	if got := mustClaim(t, store, retryAt.Add(-time.Second), 10); len(got) != 0 {
+		t.Errorf("expected nothing to be claimed before the retry got %+v", got)
+	}
+
+	got := mustClaim(t, store, retryAt.Add(24*time.Hour), 10)
+	if len(got) != 1 || got[0].ID != retried.ID || got[0].Attempts != 2 {
+		t.Errorf("expected only delivery %d to be retried got %+v", retried.ID, got)
+	}
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Telegram sends reminders as messages of a bot through the Bot API.
+// Addresses are chat ids.
+type Telegram struct {
+	client *http.Client
+	// sendMessage is the URL of the sendMessage method, token included.
+	sendMessage string
+}
+
+// NewTelegram sends messages as the bot with botToken through the Bot API
+// served at apiURL, https://api.telegram.org for the real one.
+func NewTelegram(client *http.Client, apiURL, botToken string) *Telegram {
+	return &Telegram{
+		client:      client,
+		sendMessage: strings.TrimSuffix(apiURL, "/") + "/bot" + botToken + "/sendMessage",
+	}
+}
+
+func (t *Telegram) Notify(ctx context.Context, address string, reminder *pb.Reminder) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": address,
+		"text":    reminder.GetReminderText(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = post(ctx, t.client, "Telegram", t.sendMessage, body)
+
+	return err
+}
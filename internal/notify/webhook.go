@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Webhook posts reminders as JSON encoded todoservice.Reminder messages
+// with proto field names. Addresses are URLs.
+type Webhook struct {
+	client *http.Client
+}
+
+func NewWebhook(client *http.Client) *Webhook {
+	return &Webhook{client: client}
+}
+
+func (w *Webhook) Notify(ctx context.Context, address string, reminder *pb.Reminder) error {
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+
+	_, err = post(ctx, w.client, "webhook", address, body)
+
+	return err
+}
@@ -0,0 +1,116 @@
+// Package offset parses how long from now to remind, as hooks and todoctl
+// accept it.
+package offset
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxOffset is the furthest in the future Parse goes.
+const maxOffset = 10 * 365 * 24 * time.Hour
+
+var offsetUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// Parse parses how long from now to remind: either a Go duration such
+// as "1h30m" or amounts of units, optionally after "in" and separated by
+// commas or "and", as in "in 2 days", "an hour and 15 minutes" or "1d 12h".
+func Parse(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	offset, err := time.ParseDuration(s)
+	if err != nil {
+		if offset, err = parseAmounts(s); err != nil {
+			return 0, err
+		}
+	}
+
+	if offset <= 0 || offset > maxOffset {
+		return 0, fmt.Errorf("%q is not between now and %v from now", s, maxOffset)
+	}
+
+	return offset, nil
+}
+
+func parseAmounts(s string) (time.Duration, error) {
+	words := splitWords(strings.TrimPrefix(s, "in "))
+	if len(words) == 0 {
+		return 0, errors.New("offset must not be empty")
+	}
+
+	var offset time.Duration
+	for len(words) > 0 {
+		if len(words) == 1 {
+			return 0, fmt.Errorf("%q lacks a unit", words[0])
+		}
+
+		var amount int64
+		switch words[0] {
+		case "a", "an":
+			amount = 1
+		default:
+			var err error
+			if amount, err = strconv.ParseInt(words[0], 10, 64); err != nil {
+				return 0, fmt.Errorf("%q is not an amount", words[0])
+			}
+		}
+
+		unit, ok := offsetUnits[words[1]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q", words[1])
+		}
+		if amount > int64(maxOffset/unit) {
+			return 0, fmt.Errorf("%d %s is too far away", amount, words[1])
+		}
+
+		offset += time.Duration(amount) * unit
+		if offset > maxOffset {
+			return 0, fmt.Errorf("%q is too far away", s)
+		}
+		words = words[2:]
+	}
+
+	return offset, nil
+}
+
+// splitWords splits s into runs of digits and runs of letters, dropping
+// everything else and the word "and".
+func splitWords(s string) []string {
+	var words []string
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && s[start:end] != "and" {
+			words = append(words, s[start:end])
+		}
+		start = -1
+	}
+
+	var last rune
+	for i, r := range s {
+		switch {
+		case unicode.IsDigit(r) || unicode.IsLetter(r):
+			if start >= 0 && unicode.IsDigit(r) != unicode.IsDigit(last) {
+				flush(i)
+			}
+			if start < 0 {
+				start = i
+			}
+		default:
+			flush(i)
+		}
+		last = r
+	}
+	flush(len(s))
+
+	return words
+}
@@ -0,0 +1,29 @@
+package offset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"90m":                     90 * time.Minute,
+		"1h30m":                   90 * time.Minute,
+		"2 days":                  48 * time.Hour,
+		"in 10 minutes":           10 * time.Minute,
+		"In an hour":              time.Hour,
+		"1 hour and 30 minutes":   90 * time.Minute,
+		"1d12h":                   36 * time.Hour,
+		"a week, 2 days, 3 hours": 9*24*time.Hour + 3*time.Hour,
+	} {
+		if got, err := Parse(s); err != nil || got != expected {
+			t.Errorf("expected %v for %q got %v, %v", expected, s, got, err)
+		}
+	}
+
+	for _, s := range []string{"", "soon", "in", "2", "hours", "2 fortnights", "-1h", "0m", "11 years", "99999999999 weeks", "9223372036854775807 days"} {
+		if got, err := Parse(s); err == nil {
+			t.Errorf("expected error for %q got %v", s, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package postgresrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+// nonZero represents zero values as NULL, which filters take for any.
+func nonZero[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+
+	return &v
+}
+
+// maxRows represents no limit as NULL, which LIMIT takes for none.
+func maxRows(n int) *int32 {
+	if n <= 0 {
+		return nil
+	}
+
+	limit := int32(min(n, 1<<31-1))
+
+	return &limit
+}
+
+func (pr PostgresRepo) FindReminders(ctx context.Context, filter admin.ReminderFilter) ([]admin.Reminder, error) {
+	rows, err := pr.queries().FindReminders(ctx, db.FindRemindersParams{
+		UserID:        nonZero(filter.UserID),
+		Text:          nonZero(filter.Text),
+		CreatedAfter:  nonZero(filter.CreatedAfter),
+		CreatedBefore: nonZero(filter.CreatedBefore),
+		Pending:       filter.Pending,
+		MaxReminders:  maxRows(filter.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := make([]admin.Reminder, 0, len(rows))
+	for _, row := range rows {
+		reminders = append(reminders, admin.Reminder{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Text:      row.ReminderText,
+			RemindAt:  row.RemindAt,
+			CreatedAt: row.CreatedAt,
+			FiredAt:   row.FiredAt,
+		})
+	}
+
+	return reminders, nil
+}
+
+func (pr PostgresRepo) CancelReminders(ctx context.Context, ids []int32) (cancelled int64, err error) {
+	err = pr.audited(ctx, func(txRepo PostgresRepo) error {
+		rows, err := txRepo.queries().CancelReminders(ctx, ids)
+		if err != nil {
+			return err
+		}
+		cancelled = int64(len(rows))
+
+		changes := make([]audit.Change, 0, len(rows))
+		for _, row := range rows {
+			changes = append(changes, removedReminder(row.ID, row.UserID, row.ReminderText, row.RemindAt))
+		}
+
+		return txRepo.recordAudit(ctx, changes...)
+	})
+
+	return cancelled, err
+}
+
+func (pr PostgresRepo) FindFailedDeliveries(ctx context.Context, filter admin.DeliveryFilter) ([]admin.Delivery, error) {
+	rows, err := pr.queries().FindFailedDeliveries(ctx, db.FindFailedDeliveriesParams{
+		UserID:        nonZero(filter.UserID),
+		Channel:       nonZero(filter.Channel),
+		FailedAfter:   nonZero(filter.FailedAfter),
+		MaxDeliveries: maxRows(filter.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]admin.Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, admin.Delivery{
+			ID:         row.ID,
+			ReminderID: row.ReminderID,
+			UserID:     row.UserID,
+			Channel:    row.Channel,
+			Address:    row.Address,
+			Attempts:   row.Attempts,
+			LastError:  row.LastError,
+			FailedAt:   row.UpdatedAt,
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (pr PostgresRepo) RequeueDeliveries(ctx context.Context, ids []int64) (int64, error) {
+	return pr.queries().RequeueDeliveries(ctx, ids)
+}
+
+func (pr PostgresRepo) CountRemovedReminders(ctx context.Context, before time.Time) (int64, error) {
+	return pr.queries().CountRemovedReminders(ctx, &before)
+}
+
+func (pr PostgresRepo) PurgeRemovedReminders(ctx context.Context, before time.Time) (purged int64, err error) {
+	err = pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		if err := txRepo.queries().PurgeRemovedDeliveries(ctx, &before); err != nil {
+			return err
+		}
+		if err := txRepo.queries().PurgeRemovedShares(ctx, &before); err != nil {
+			return err
+		}
+
+		purged, err = txRepo.queries().PurgeRemovedReminders(ctx, &before)
+
+		return err
+	})
+
+	return purged, err
+}
+
+func (pr PostgresRepo) Stats(ctx context.Context) (admin.Stats, error) {
+	row, err := pr.queries().GetStats(ctx)
+	if err != nil {
+		return admin.Stats{}, err
+	}
+
+	counts, err := pr.queries().CountDeliveriesByStatus(ctx)
+	if err != nil {
+		return admin.Stats{}, err
+	}
+
+	stats := admin.Stats{
+		Users:            row.Users,
+		PendingReminders: row.PendingReminders,
+		FiredReminders:   row.FiredReminders,
+		RemovedReminders: row.RemovedReminders,
+		Deliveries:       make(map[string]int64, len(counts)),
+		SizeBytes:        row.SizeBytes,
+	}
+	for _, count := range counts {
+		stats.Deliveries[count.Status] = count.Count
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,54 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) CreateAPIKey(ctx context.Context, name string, userID *int64, hash []byte) (int64, error) {
+	return pr.queries().CreateAPIKey(ctx, db.CreateAPIKeyParams{Name: name, UserID: userID, KeyHash: hash})
+}
+
+func (pr PostgresRepo) RevokeAPIKey(ctx context.Context, id int64) error {
+	revoked, err := pr.queries().RevokeAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if revoked == 0 {
+		return auth.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) ListAPIKeys(ctx context.Context) ([]auth.APIKey, error) {
+	rows, err := pr.queries().ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]auth.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, auth.APIKey(row))
+	}
+
+	return keys, nil
+}
+
+func (pr PostgresRepo) FindAPIKey(ctx context.Context, hash []byte) (auth.Caller, error) {
+	row, err := pr.queries().FindAPIKey(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+	if err != nil {
+		return auth.Caller{}, err
+	}
+
+	return auth.Caller{Name: row.Name, UserID: row.UserID}, nil
+}
@@ -0,0 +1,77 @@
+package postgresrepo
+
+import (
+	"context"
+
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+// audited calls fn within a transaction when changes made in ctx are
+// audited, so that their events are recorded along with them, and
+// directly otherwise.
+func (pr PostgresRepo) audited(ctx context.Context, fn func(txRepo PostgresRepo) error) error {
+	if _, ok := audit.FromContext(ctx); !ok {
+		return fn(pr)
+	}
+
+	return pr.WithinTx(ctx, fn)
+}
+
+// recordAudit writes the events of changes made in ctx, if it has an
+// actor. Call it within the transaction making the changes.
+func (pr PostgresRepo) recordAudit(ctx context.Context, changes ...audit.Change) error {
+	events, err := audit.Events(ctx, changes...)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	rows := make([]db.CopyAuditEventsParams, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, db.CopyAuditEventsParams{
+			Actor:      event.Caller,
+			RequestID:  event.RequestID,
+			Method:     event.Method,
+			UserID:     event.UserID,
+			ReminderID: nonZero(event.ReminderID),
+			Before:     event.Before,
+			After:      event.After,
+		})
+	}
+
+	_, err = pr.queries().CopyAuditEvents(ctx, rows)
+
+	return err
+}
+
+func (pr PostgresRepo) AuditEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	rows, err := pr.queries().AuditEvents(ctx, db.AuditEventsParams{
+		UserID:     nonZero(filter.UserID),
+		ReminderID: nonZero(filter.ReminderID),
+		Actor:      nonZero(filter.Caller),
+		RequestID:  nonZero(filter.RequestID),
+		Method:     nonZero(filter.Method),
+		Since:      nonZero(filter.Since),
+		Until:      nonZero(filter.Until),
+		BeforeID:   nonZero(filter.BeforeID),
+		MaxEvents:  maxRows(filter.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]audit.Event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, audit.Event{
+			ID:         row.ID,
+			CreatedAt:  row.CreatedAt,
+			Actor:      audit.Actor{Caller: row.Actor, Method: row.Method, RequestID: row.RequestID},
+			UserID:     row.UserID,
+			ReminderID: row.ReminderID,
+			Before:     row.Before,
+			After:      row.After,
+		})
+	}
+
+	return events, nil
+}
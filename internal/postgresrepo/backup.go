@@ -0,0 +1,172 @@
+package postgresrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/backup"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) Backup(ctx context.Context, batchSize int, users func([]backup.User) error, reminders func([]backup.Reminder) error) error {
+	// Repeatable read sees one snapshot throughout. The transaction is not
+	// retried like WithinTx does, users and reminders were called already.
+	snapshot := pr
+	snapshot.txIsoLevel = pgx.RepeatableRead
+
+	return snapshot.runTx(ctx, func(txRepo PostgresRepo) error {
+		q := txRepo.queries()
+
+		for after := int64(0); ; {
+			rows, err := q.BackupUsers(ctx, db.BackupUsersParams{After: after, MaxUsers: int32(batchSize)})
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			batch := make([]backup.User, 0, len(rows))
+			for _, row := range rows {
+				batch = append(batch, backup.User{
+					ID:                   row.ID,
+					LanguageCode:         row.LanguageCode,
+					UTCOffset:            row.UtcOffset,
+					NotificationChannels: row.NotificationChannels,
+				})
+			}
+			if err := users(batch); err != nil {
+				return err
+			}
+
+			after = rows[len(rows)-1].ID
+		}
+
+		for after := int32(0); ; {
+			rows, err := q.BackupReminders(ctx, db.BackupRemindersParams{After: after, MaxReminders: int32(batchSize)})
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			batch := make([]backup.Reminder, 0, len(rows))
+			for _, row := range rows {
+				batch = append(batch, backup.Reminder{
+					ID:        row.ID,
+					UserID:    row.UserID,
+					Text:      row.ReminderText,
+					RemindAt:  row.RemindAt,
+					CreatedAt: row.CreatedAt,
+					FiredAt:   row.FiredAt,
+					RemovedAt: row.DeletedAt,
+				})
+			}
+			if err := reminders(batch); err != nil {
+				return err
+			}
+
+			after = rows[len(rows)-1].ID
+		}
+
+		return nil
+	})
+}
+
+func (pr PostgresRepo) BeginRestore(ctx context.Context, checksum string) (resumed bool, err error) {
+	err = pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		q := txRepo.queries()
+
+		if resumed, err = q.IsRestoreStarted(ctx, checksum); err != nil || resumed {
+			return err
+		}
+
+		hasUsers, err := q.HasUsers(ctx)
+		if err != nil {
+			return err
+		}
+		if hasUsers {
+			return backup.ErrNotEmpty
+		}
+
+		return q.InsertRestore(ctx, checksum)
+	})
+
+	return resumed, err
+}
+
+func (pr PostgresRepo) RestoreUsers(ctx context.Context, users []backup.User) error {
+	return pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		for _, user := range users {
+			err := txRepo.queries().RestoreUser(ctx, db.RestoreUserParams{
+				ID:                   user.ID,
+				LanguageCode:         user.LanguageCode,
+				UtcOffset:            user.UTCOffset,
+				NotificationChannels: user.NotificationChannels,
+			})
+			if err != nil {
+				return fmt.Errorf("user %d: %w", user.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (pr PostgresRepo) RestoreReminders(ctx context.Context, checksum string, reminders []backup.Reminder) (ids []int32, err error) {
+	err = pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		ids = make([]int32, 0, len(reminders))
+		for _, reminder := range reminders {
+			id, err := txRepo.queries().RestoreReminder(ctx, db.RestoreReminderParams{
+				UserID:       reminder.UserID,
+				ReminderText: reminder.Text,
+				RemindAt:     reminder.RemindAt,
+				CreatedAt:    reminder.CreatedAt,
+				FiredAt:      reminder.FiredAt,
+				DeletedAt:    reminder.RemovedAt,
+				Checksum:     checksum,
+				SourceID:     reminder.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("reminder %d: %w", reminder.ID, err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+
+	return ids, err
+}
+
+func (pr PostgresRepo) RestoredReminders(ctx context.Context, checksum string) (map[int32]int32, error) {
+	rows, err := pr.queries().RestoredReminders(ctx, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int32]int32, len(rows))
+	for _, row := range rows {
+		ids[row.SourceID] = row.ReminderID
+	}
+
+	return ids, nil
+}
+
+func (pr PostgresRepo) FinishRestore(ctx context.Context, checksum string) error {
+	finished, err := pr.queries().FinishRestore(ctx, checksum)
+	if err != nil {
+		return err
+	}
+
+	if finished == 0 {
+		return fmt.Errorf("restore %s: %w", checksum, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
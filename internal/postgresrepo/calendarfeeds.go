@@ -0,0 +1,79 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) CreateCalendarFeed(ctx context.Context, userId int64, hash []byte) error {
+	row, err := pr.queries().CreateCalendarFeed(ctx, db.CreateCalendarFeedParams{UserID: userId, SecretHash: hash})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !row.UserExists:
+		return fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	case !row.Created:
+		return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrAlreadyExists)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) RotateCalendarFeed(ctx context.Context, userId int64, hash []byte) error {
+	rotated, err := pr.queries().RotateCalendarFeed(ctx, db.RotateCalendarFeedParams{UserID: userId, SecretHash: hash})
+	if err != nil {
+		return err
+	}
+
+	if rotated == 0 {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) DeleteCalendarFeed(ctx context.Context, userId int64) error {
+	deleted, err := pr.queries().DeleteCalendarFeed(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) FindCalendarFeed(ctx context.Context, hash []byte) (int64, todoserviceserver.CalendarFeedVersion, error) {
+	row, err := pr.queries().FindCalendarFeed(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, todoserviceserver.CalendarFeedVersion{}, fmt.Errorf("calendar feed: %w", todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return 0, todoserviceserver.CalendarFeedVersion{}, err
+	}
+
+	version := todoserviceserver.CalendarFeedVersion{ETag: row.Etag}
+	if row.ModifiedAt != nil {
+		version.ModifiedAt = *row.ModifiedAt
+	}
+
+	return row.UserID, version, nil
+}
+
+func (pr PostgresRepo) SetCalendarFeedVersion(ctx context.Context, userId int64, version todoserviceserver.CalendarFeedVersion) error {
+	return pr.queries().SetCalendarFeedVersion(ctx, db.SetCalendarFeedVersionParams{
+		UserID:     userId,
+		Etag:       version.ETag,
+		ModifiedAt: nonZero(version.ModifiedAt),
+	})
+}
@@ -0,0 +1,326 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: admin.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const cancelReminders = `-- name: CancelReminders :many
+WITH removed AS (
+    UPDATE reminders
+    SET deleted_at = now()
+    WHERE reminders.id = ANY($1::integer[]) AND deleted_at IS NULL
+    RETURNING id, user_id, reminder_text, remind_at, fired_at
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - per_user.pending_count
+    FROM (
+        SELECT removed.user_id, count(*)::integer AS pending_count
+        FROM removed
+        WHERE removed.fired_at IS NULL
+        GROUP BY removed.user_id
+    ) AS per_user
+    WHERE users.id = per_user.user_id
+)
+SELECT id, user_id, reminder_text, remind_at
+FROM removed
+`
+
+type CancelRemindersRow struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+}
+
+// Like RemoveReminder for many reminders at once.
+func (q *Queries) CancelReminders(ctx context.Context, ids []int32) ([]CancelRemindersRow, error) {
+	rows, err := q.db.Query(ctx, cancelReminders, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CancelRemindersRow
+	for rows.Next() {
+		var i CancelRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countDeliveriesByStatus = `-- name: CountDeliveriesByStatus :many
+SELECT status, count(*)
+FROM deliveries
+GROUP BY status
+`
+
+type CountDeliveriesByStatusRow struct {
+	Status string
+	Count  int64
+}
+
+func (q *Queries) CountDeliveriesByStatus(ctx context.Context) ([]CountDeliveriesByStatusRow, error) {
+	rows, err := q.db.Query(ctx, countDeliveriesByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountDeliveriesByStatusRow
+	for rows.Next() {
+		var i CountDeliveriesByStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countRemovedReminders = `-- name: CountRemovedReminders :one
+SELECT count(*)
+FROM reminders
+WHERE deleted_at < $1
+`
+
+func (q *Queries) CountRemovedReminders(ctx context.Context, before *time.Time) (int64, error) {
+	row := q.db.QueryRow(ctx, countRemovedReminders, before)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const findFailedDeliveries = `-- name: FindFailedDeliveries :many
+SELECT deliveries.id, deliveries.reminder_id, reminders.user_id, deliveries.channel, deliveries.address,
+    deliveries.attempts, coalesce(deliveries.last_error, '')::text AS last_error, deliveries.updated_at
+FROM deliveries
+JOIN reminders ON reminders.id = deliveries.reminder_id
+WHERE deliveries.status = 'failed' AND reminders.deleted_at IS NULL
+    AND ($1::bigint IS NULL OR reminders.user_id = $1)
+    AND ($2::text IS NULL OR deliveries.channel = $2)
+    AND ($3::timestamptz IS NULL OR deliveries.updated_at >= $3)
+ORDER BY deliveries.id
+LIMIT $4
+`
+
+type FindFailedDeliveriesParams struct {
+	UserID        *int64
+	Channel       *string
+	FailedAfter   *time.Time
+	MaxDeliveries *int32
+}
+
+type FindFailedDeliveriesRow struct {
+	ID         int64
+	ReminderID int32
+	UserID     int64
+	Channel    string
+	Address    string
+	Attempts   int32
+	LastError  string
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) FindFailedDeliveries(ctx context.Context, arg FindFailedDeliveriesParams) ([]FindFailedDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, findFailedDeliveries,
+		arg.UserID,
+		arg.Channel,
+		arg.FailedAfter,
+		arg.MaxDeliveries,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindFailedDeliveriesRow
+	for rows.Next() {
+		var i FindFailedDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReminderID,
+			&i.UserID,
+			&i.Channel,
+			&i.Address,
+			&i.Attempts,
+			&i.LastError,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findReminders = `-- name: FindReminders :many
+SELECT id, user_id, reminder_text, remind_at, created_at, fired_at
+FROM reminders
+WHERE deleted_at IS NULL
+    AND ($1::bigint IS NULL OR user_id = $1)
+    AND ($2::text IS NULL OR strpos(lower(reminder_text), lower($2)) > 0)
+    AND ($3::timestamptz IS NULL OR created_at >= $3)
+    AND ($4::timestamptz IS NULL OR created_at < $4)
+    AND (NOT $5::boolean OR fired_at IS NULL)
+ORDER BY id
+LIMIT $6
+`
+
+type FindRemindersParams struct {
+	UserID        *int64
+	Text          *string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Pending       bool
+	MaxReminders  *int32
+}
+
+type FindRemindersRow struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+	CreatedAt    time.Time
+	FiredAt      *time.Time
+}
+
+func (q *Queries) FindReminders(ctx context.Context, arg FindRemindersParams) ([]FindRemindersRow, error) {
+	rows, err := q.db.Query(ctx, findReminders,
+		arg.UserID,
+		arg.Text,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Pending,
+		arg.MaxReminders,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindRemindersRow
+	for rows.Next() {
+		var i FindRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+			&i.CreatedAt,
+			&i.FiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStats = `-- name: GetStats :one
+SELECT
+    (SELECT count(*) FROM users) AS users,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NULL) AS pending_reminders,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NOT NULL) AS fired_reminders,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NOT NULL) AS removed_reminders,
+    pg_database_size(current_database()) AS size_bytes
+`
+
+type GetStatsRow struct {
+	Users            int64
+	PendingReminders int64
+	FiredReminders   int64
+	RemovedReminders int64
+	SizeBytes        int64
+}
+
+func (q *Queries) GetStats(ctx context.Context) (GetStatsRow, error) {
+	row := q.db.QueryRow(ctx, getStats)
+	var i GetStatsRow
+	err := row.Scan(
+		&i.Users,
+		&i.PendingReminders,
+		&i.FiredReminders,
+		&i.RemovedReminders,
+		&i.SizeBytes,
+	)
+	return i, err
+}
+
+const purgeRemovedDeliveries = `-- name: PurgeRemovedDeliveries :exec
+DELETE FROM deliveries
+WHERE reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.deleted_at < $1
+)
+`
+
+func (q *Queries) PurgeRemovedDeliveries(ctx context.Context, before *time.Time) error {
+	_, err := q.db.Exec(ctx, purgeRemovedDeliveries, before)
+	return err
+}
+
+const purgeRemovedReminders = `-- name: PurgeRemovedReminders :execrows
+DELETE FROM reminders
+WHERE deleted_at < $1
+`
+
+// Run after PurgeRemovedDeliveries and PurgeRemovedShares, in the same
+// transaction.
+func (q *Queries) PurgeRemovedReminders(ctx context.Context, before *time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeRemovedReminders, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeRemovedShares = `-- name: PurgeRemovedShares :exec
+DELETE FROM reminder_shares
+WHERE reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.deleted_at < $1
+)
+`
+
+func (q *Queries) PurgeRemovedShares(ctx context.Context, before *time.Time) error {
+	_, err := q.db.Exec(ctx, purgeRemovedShares, before)
+	return err
+}
+
+const requeueDeliveries = `-- name: RequeueDeliveries :execrows
+UPDATE deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+WHERE id = ANY($1::bigint[]) AND status = 'failed'
+`
+
+func (q *Queries) RequeueDeliveries(ctx context.Context, ids []int64) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueDeliveries, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,102 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: api_keys.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (name, user_id, key_hash)
+VALUES ($1, $2, $3)
+RETURNING id
+`
+
+type CreateAPIKeyParams struct {
+	Name    string
+	UserID  *int64
+	KeyHash []byte
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createAPIKey, arg.Name, arg.UserID, arg.KeyHash)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const findAPIKey = `-- name: FindAPIKey :one
+SELECT name, user_id
+FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL
+`
+
+type FindAPIKeyRow struct {
+	Name   string
+	UserID *int64
+}
+
+func (q *Queries) FindAPIKey(ctx context.Context, keyHash []byte) (FindAPIKeyRow, error) {
+	row := q.db.QueryRow(ctx, findAPIKey, keyHash)
+	var i FindAPIKeyRow
+	err := row.Scan(&i.Name, &i.UserID)
+	return i, err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, name, user_id, created_at, revoked_at
+FROM api_keys
+ORDER BY id
+`
+
+type ListAPIKeysRow struct {
+	ID        int64
+	Name      string
+	UserID    *int64
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (q *Queries) ListAPIKeys(ctx context.Context) ([]ListAPIKeysRow, error) {
+	rows, err := q.db.Query(ctx, listAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAPIKeysRow
+	for rows.Next() {
+		var i ListAPIKeysRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeAPIKey, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,100 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: audit_events.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const auditEvents = `-- name: AuditEvents :many
+SELECT id, created_at, actor, request_id, method, user_id, coalesce(reminder_id, 0)::integer AS reminder_id, before, after
+FROM audit_events
+WHERE ($1::bigint IS NULL OR user_id = $1)
+    AND ($2::integer IS NULL OR reminder_id = $2)
+    AND ($3::text IS NULL OR actor = $3)
+    AND ($4::text IS NULL OR request_id = $4)
+    AND ($5::text IS NULL OR method = $5)
+    AND ($6::timestamptz IS NULL OR created_at >= $6)
+    AND ($7::timestamptz IS NULL OR created_at < $7)
+    AND ($8::bigint IS NULL OR id < $8)
+ORDER BY id DESC
+LIMIT $9
+`
+
+type AuditEventsParams struct {
+	UserID     *int64
+	ReminderID *int32
+	Actor      *string
+	RequestID  *string
+	Method     *string
+	Since      *time.Time
+	Until      *time.Time
+	BeforeID   *int64
+	MaxEvents  *int32
+}
+
+type AuditEventsRow struct {
+	ID         int64
+	CreatedAt  time.Time
+	Actor      string
+	RequestID  string
+	Method     string
+	UserID     int64
+	ReminderID int32
+	Before     []byte
+	After      []byte
+}
+
+func (q *Queries) AuditEvents(ctx context.Context, arg AuditEventsParams) ([]AuditEventsRow, error) {
+	rows, err := q.db.Query(ctx, auditEvents,
+		arg.UserID,
+		arg.ReminderID,
+		arg.Actor,
+		arg.RequestID,
+		arg.Method,
+		arg.Since,
+		arg.Until,
+		arg.BeforeID,
+		arg.MaxEvents,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEventsRow
+	for rows.Next() {
+		var i AuditEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Actor,
+			&i.RequestID,
+			&i.Method,
+			&i.UserID,
+			&i.ReminderID,
+			&i.Before,
+			&i.After,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type CopyAuditEventsParams struct {
+	Actor      string
+	RequestID  string
+	Method     string
+	UserID     int64
+	ReminderID *int32
+	Before     []byte
+	After      []byte
+}
@@ -0,0 +1,252 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: backup.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const backupReminders = `-- name: BackupReminders :many
+SELECT id, user_id, reminder_text, remind_at, created_at, fired_at, deleted_at
+FROM reminders
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type BackupRemindersParams struct {
+	After        int32
+	MaxReminders int32
+}
+
+type BackupRemindersRow struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+	CreatedAt    time.Time
+	FiredAt      *time.Time
+	DeletedAt    *time.Time
+}
+
+func (q *Queries) BackupReminders(ctx context.Context, arg BackupRemindersParams) ([]BackupRemindersRow, error) {
+	rows, err := q.db.Query(ctx, backupReminders, arg.After, arg.MaxReminders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BackupRemindersRow
+	for rows.Next() {
+		var i BackupRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+			&i.CreatedAt,
+			&i.FiredAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const backupUsers = `-- name: BackupUsers :many
+SELECT id, language_code, utc_offset, notification_channels
+FROM users
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type BackupUsersParams struct {
+	After    int64
+	MaxUsers int32
+}
+
+type BackupUsersRow struct {
+	ID                   int64
+	LanguageCode         *string
+	UtcOffset            *int32
+	NotificationChannels []byte
+}
+
+func (q *Queries) BackupUsers(ctx context.Context, arg BackupUsersParams) ([]BackupUsersRow, error) {
+	rows, err := q.db.Query(ctx, backupUsers, arg.After, arg.MaxUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BackupUsersRow
+	for rows.Next() {
+		var i BackupUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LanguageCode,
+			&i.UtcOffset,
+			&i.NotificationChannels,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const finishRestore = `-- name: FinishRestore :execrows
+UPDATE restores
+SET finished_at = COALESCE(finished_at, now())
+WHERE checksum = $1
+`
+
+func (q *Queries) FinishRestore(ctx context.Context, checksum string) (int64, error) {
+	result, err := q.db.Exec(ctx, finishRestore, checksum)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const hasUsers = `-- name: HasUsers :one
+SELECT EXISTS (SELECT 1 FROM users)
+`
+
+func (q *Queries) HasUsers(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, hasUsers)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const insertRestore = `-- name: InsertRestore :exec
+INSERT INTO restores (checksum) VALUES ($1)
+`
+
+func (q *Queries) InsertRestore(ctx context.Context, checksum string) error {
+	_, err := q.db.Exec(ctx, insertRestore, checksum)
+	return err
+}
+
+const isRestoreStarted = `-- name: IsRestoreStarted :one
+SELECT EXISTS (SELECT 1 FROM restores WHERE checksum = $1)
+`
+
+func (q *Queries) IsRestoreStarted(ctx context.Context, checksum string) (bool, error) {
+	row := q.db.QueryRow(ctx, isRestoreStarted, checksum)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const restoreReminder = `-- name: RestoreReminder :one
+WITH restored AS (
+    INSERT INTO reminders (user_id, reminder_text, remind_at, created_at, fired_at, deleted_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, user_id, fired_at, deleted_at
+), recorded AS (
+    INSERT INTO restored_reminders (checksum, source_id, reminder_id)
+    SELECT $7, $8, restored.id FROM restored
+), counted AS (
+    UPDATE users
+    SET active_reminders = active_reminders + 1
+    FROM restored
+    WHERE users.id = restored.user_id AND restored.fired_at IS NULL AND restored.deleted_at IS NULL
+)
+SELECT id FROM restored
+`
+
+type RestoreReminderParams struct {
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+	CreatedAt    time.Time
+	FiredAt      *time.Time
+	DeletedAt    *time.Time
+	Checksum     string
+	SourceID     int32
+}
+
+// Restored reminders which are neither fired nor removed count against
+// the quota of their users like created ones, however many that makes.
+func (q *Queries) RestoreReminder(ctx context.Context, arg RestoreReminderParams) (int32, error) {
+	row := q.db.QueryRow(ctx, restoreReminder,
+		arg.UserID,
+		arg.ReminderText,
+		arg.RemindAt,
+		arg.CreatedAt,
+		arg.FiredAt,
+		arg.DeletedAt,
+		arg.Checksum,
+		arg.SourceID,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const restoreUser = `-- name: RestoreUser :exec
+INSERT INTO users (id, language_code, utc_offset, notification_channels)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING
+`
+
+type RestoreUserParams struct {
+	ID                   int64
+	LanguageCode         *string
+	UtcOffset            *int32
+	NotificationChannels []byte
+}
+
+func (q *Queries) RestoreUser(ctx context.Context, arg RestoreUserParams) error {
+	_, err := q.db.Exec(ctx, restoreUser,
+		arg.ID,
+		arg.LanguageCode,
+		arg.UtcOffset,
+		arg.NotificationChannels,
+	)
+	return err
+}
+
+const restoredReminders = `-- name: RestoredReminders :many
+SELECT source_id, reminder_id
+FROM restored_reminders
+WHERE checksum = $1
+`
+
+type RestoredRemindersRow struct {
+	SourceID   int32
+	ReminderID int32
+}
+
+func (q *Queries) RestoredReminders(ctx context.Context, checksum string) ([]RestoredRemindersRow, error) {
+	rows, err := q.db.Query(ctx, restoredReminders, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RestoredRemindersRow
+	for rows.Next() {
+		var i RestoredRemindersRow
+		if err := rows.Scan(&i.SourceID, &i.ReminderID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,110 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: calendar_feeds.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createCalendarFeed = `-- name: CreateCalendarFeed :one
+WITH created AS (
+    INSERT INTO calendar_feeds (user_id, secret_hash)
+    SELECT users.id, $2
+    FROM users
+    WHERE users.id = $1
+    ON CONFLICT (user_id) DO NOTHING
+    RETURNING user_id
+)
+SELECT
+    EXISTS (SELECT 1 FROM users AS u WHERE u.id = $1) AS user_exists,
+    EXISTS (SELECT 1 FROM created) AS created
+`
+
+type CreateCalendarFeedParams struct {
+	UserID     int64
+	SecretHash []byte
+}
+
+type CreateCalendarFeedRow struct {
+	UserExists bool
+	Created    bool
+}
+
+func (q *Queries) CreateCalendarFeed(ctx context.Context, arg CreateCalendarFeedParams) (CreateCalendarFeedRow, error) {
+	row := q.db.QueryRow(ctx, createCalendarFeed, arg.UserID, arg.SecretHash)
+	var i CreateCalendarFeedRow
+	err := row.Scan(&i.UserExists, &i.Created)
+	return i, err
+}
+
+const deleteCalendarFeed = `-- name: DeleteCalendarFeed :execrows
+DELETE FROM calendar_feeds
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteCalendarFeed(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCalendarFeed, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findCalendarFeed = `-- name: FindCalendarFeed :one
+SELECT user_id, etag, modified_at
+FROM calendar_feeds
+WHERE secret_hash = $1
+`
+
+type FindCalendarFeedRow struct {
+	UserID     int64
+	Etag       string
+	ModifiedAt *time.Time
+}
+
+func (q *Queries) FindCalendarFeed(ctx context.Context, secretHash []byte) (FindCalendarFeedRow, error) {
+	row := q.db.QueryRow(ctx, findCalendarFeed, secretHash)
+	var i FindCalendarFeedRow
+	err := row.Scan(&i.UserID, &i.Etag, &i.ModifiedAt)
+	return i, err
+}
+
+const rotateCalendarFeed = `-- name: RotateCalendarFeed :execrows
+UPDATE calendar_feeds
+SET secret_hash = $1, created_at = now()
+WHERE user_id = $2
+`
+
+type RotateCalendarFeedParams struct {
+	SecretHash []byte
+	UserID     int64
+}
+
+func (q *Queries) RotateCalendarFeed(ctx context.Context, arg RotateCalendarFeedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, rotateCalendarFeed, arg.SecretHash, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setCalendarFeedVersion = `-- name: SetCalendarFeedVersion :exec
+UPDATE calendar_feeds
+SET etag = $1, modified_at = $2
+WHERE user_id = $3
+`
+
+type SetCalendarFeedVersionParams struct {
+	Etag       string
+	ModifiedAt *time.Time
+	UserID     int64
+}
+
+func (q *Queries) SetCalendarFeedVersion(ctx context.Context, arg SetCalendarFeedVersionParams) error {
+	_, err := q.db.Exec(ctx, setCalendarFeedVersion, arg.Etag, arg.ModifiedAt, arg.UserID)
+	return err
+}
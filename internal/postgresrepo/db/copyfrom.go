@@ -0,0 +1,83 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: copyfrom.go
+
+package db
+
+import (
+	"context"
+)
+
+// iteratorForCopyAuditEvents implements pgx.CopyFromSource.
+type iteratorForCopyAuditEvents struct {
+	rows                 []CopyAuditEventsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForCopyAuditEvents) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForCopyAuditEvents) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].Actor,
+		r.rows[0].RequestID,
+		r.rows[0].Method,
+		r.rows[0].UserID,
+		r.rows[0].ReminderID,
+		r.rows[0].Before,
+		r.rows[0].After,
+	}, nil
+}
+
+func (r iteratorForCopyAuditEvents) Err() error {
+	return nil
+}
+
+func (q *Queries) CopyAuditEvents(ctx context.Context, arg []CopyAuditEventsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"audit_events"}, []string{"actor", "request_id", "method", "user_id", "reminder_id", "before", "after"}, &iteratorForCopyAuditEvents{rows: arg})
+}
+
+// iteratorForCopyReminders implements pgx.CopyFromSource.
+type iteratorForCopyReminders struct {
+	rows                 []CopyRemindersParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForCopyReminders) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForCopyReminders) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].ID,
+		r.rows[0].UserID,
+		r.rows[0].ReminderText,
+		r.rows[0].RemindAt,
+	}, nil
+}
+
+func (r iteratorForCopyReminders) Err() error {
+	return nil
+}
+
+func (q *Queries) CopyReminders(ctx context.Context, arg []CopyRemindersParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"reminders"}, []string{"id", "user_id", "reminder_text", "remind_at"}, &iteratorForCopyReminders{rows: arg})
+}
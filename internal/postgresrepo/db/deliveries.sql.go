@@ -0,0 +1,196 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: deliveries.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const claimDeliveries = `-- name: ClaimDeliveries :many
+WITH claimed AS (
+    UPDATE deliveries
+    SET attempts = attempts + 1, next_attempt_at = $1, updated_at = now()
+    WHERE deliveries.id IN (
+        SELECT due.id
+        FROM deliveries AS due
+        WHERE due.status = 'pending' AND due.next_attempt_at <= $2
+        ORDER BY due.next_attempt_at, due.id
+        LIMIT $3
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING deliveries.id, deliveries.reminder_id, deliveries.channel, deliveries.address, deliveries.attempts
+)
+SELECT claimed.id, claimed.channel, claimed.address, claimed.attempts,
+    reminders.id AS reminder_id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+FROM claimed
+JOIN reminders ON reminders.id = claimed.reminder_id
+ORDER BY claimed.id
+`
+
+type ClaimDeliveriesParams struct {
+	LeaseUntil    time.Time
+	Now           time.Time
+	MaxDeliveries int32
+}
+
+type ClaimDeliveriesRow struct {
+	ID           int64
+	Channel      string
+	Address      string
+	Attempts     int32
+	ReminderID   int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+}
+
+func (q *Queries) ClaimDeliveries(ctx context.Context, arg ClaimDeliveriesParams) ([]ClaimDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimDeliveries, arg.LeaseUntil, arg.Now, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDeliveriesRow
+	for rows.Next() {
+		var i ClaimDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Channel,
+			&i.Address,
+			&i.Attempts,
+			&i.ReminderID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createDelivery = `-- name: CreateDelivery :exec
+INSERT INTO deliveries (reminder_id, recipient_id, channel, address, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateDeliveryParams struct {
+	ReminderID    int32
+	RecipientID   *int64
+	Channel       string
+	Address       string
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) CreateDelivery(ctx context.Context, arg CreateDeliveryParams) error {
+	_, err := q.db.Exec(ctx, createDelivery,
+		arg.ReminderID,
+		arg.RecipientID,
+		arg.Channel,
+		arg.Address,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const fireDueReminders = `-- name: FireDueReminders :many
+WITH fired AS (
+    UPDATE reminders
+    SET fired_at = now()
+    WHERE reminders.id IN (
+        SELECT due.id
+        FROM reminders AS due
+        WHERE due.deleted_at IS NULL AND due.fired_at IS NULL AND due.remind_at <= $1
+        ORDER BY due.remind_at, due.id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING reminders.id, reminders.user_id
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - per_user.fired_count
+    FROM (
+        SELECT fired.user_id, count(*)::integer AS fired_count
+        FROM fired
+        GROUP BY fired.user_id
+    ) AS per_user
+    WHERE users.id = per_user.user_id
+)
+SELECT fired.id, fired.user_id, users.notification_channels
+FROM fired
+JOIN users ON users.id = fired.user_id
+ORDER BY fired.id
+`
+
+type FireDueRemindersParams struct {
+	Now          time.Time
+	MaxReminders int32
+}
+
+type FireDueRemindersRow struct {
+	ID                   int32
+	UserID               int64
+	NotificationChannels []byte
+}
+
+// Due reminders locked by another replica firing them are skipped.
+// Fired reminders release their quota.
+func (q *Queries) FireDueReminders(ctx context.Context, arg FireDueRemindersParams) ([]FireDueRemindersRow, error) {
+	rows, err := q.db.Query(ctx, fireDueReminders, arg.Now, arg.MaxReminders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FireDueRemindersRow
+	for rows.Next() {
+		var i FireDueRemindersRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.NotificationChannels); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markDeliveryFailed = `-- name: MarkDeliveryFailed :exec
+UPDATE deliveries
+SET status = CASE WHEN $1::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+    next_attempt_at = COALESCE($1::timestamptz, next_attempt_at),
+    last_error = $2,
+    updated_at = now()
+WHERE id = $3
+`
+
+type MarkDeliveryFailedParams struct {
+	RetryAt   *time.Time
+	LastError *string
+	ID        int64
+}
+
+// The delivery is given up when retry_at is NULL.
+func (q *Queries) MarkDeliveryFailed(ctx context.Context, arg MarkDeliveryFailedParams) error {
+	_, err := q.db.Exec(ctx, markDeliveryFailed, arg.RetryAt, arg.LastError, arg.ID)
+	return err
+}
+
+const markDeliverySent = `-- name: MarkDeliverySent :exec
+UPDATE deliveries
+SET status = 'sent', last_error = NULL, updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkDeliverySent(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markDeliverySent, id)
+	return err
+}
@@ -0,0 +1,58 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: hook_tokens.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deleteHookToken = `-- name: DeleteHookToken :execrows
+DELETE FROM hook_tokens
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteHookToken(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteHookToken, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findHookToken = `-- name: FindHookToken :one
+SELECT user_id
+FROM hook_tokens
+WHERE token_hash = $1
+`
+
+func (q *Queries) FindHookToken(ctx context.Context, tokenHash []byte) (int64, error) {
+	row := q.db.QueryRow(ctx, findHookToken, tokenHash)
+	var user_id int64
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const setHookToken = `-- name: SetHookToken :execrows
+INSERT INTO hook_tokens (user_id, token_hash)
+SELECT users.id, $1
+FROM users
+WHERE users.id = $2
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = excluded.token_hash, created_at = now()
+`
+
+type SetHookTokenParams struct {
+	TokenHash []byte
+	UserID    int64
+}
+
+func (q *Queries) SetHookToken(ctx context.Context, arg SetHookTokenParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setHookToken, arg.TokenHash, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,175 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+
+package db
+
+import (
+	"time"
+)
+
+type APIKey struct {
+	ID        int64
+	Name      string
+	KeyHash   []byte
+	UserID    *int64
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type AuditEvent struct {
+	ID         int64
+	CreatedAt  time.Time
+	Actor      string
+	RequestID  string
+	Method     string
+	UserID     int64
+	ReminderID *int32
+	Before     []byte
+	After      []byte
+}
+
+type CalendarFeed struct {
+	UserID     int64
+	SecretHash []byte
+	Etag       string
+	ModifiedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type Delivery struct {
+	ID            int64
+	ReminderID    int32
+	Channel       string
+	Address       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	RecipientID   *int64
+}
+
+type HookToken struct {
+	UserID    int64
+	TokenHash []byte
+	CreatedAt time.Time
+}
+
+type IdempotencyKey struct {
+	Caller      string
+	Key         string
+	RequestHash []byte
+	Done        bool
+	Response    []byte
+	Status      []byte
+	ExpiresAt   time.Time
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+	FullAt    time.Time
+}
+
+type Reminder struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+	CreatedAt    time.Time
+	DeletedAt    *time.Time
+	FiredAt      *time.Time
+}
+
+type ReminderShare struct {
+	ID         int64
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	AcceptedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type Restore struct {
+	Checksum   string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+type RestoredReminder struct {
+	Checksum   string
+	SourceID   int32
+	ReminderID int32
+}
+
+type User struct {
+	ID                   int64
+	LanguageCode         *string
+	UtcOffset            *int32
+	ActiveReminders      int32
+	NotificationChannels []byte
+}
+
+type UserDeletion struct {
+	UserID      int64
+	RequestedAt time.Time
+	DeleteAt    time.Time
+}
+
+type UserDeletionAudit struct {
+	ID          int64
+	RequestedAt time.Time
+	DeletedAt   time.Time
+	Reminders   int64
+	Deliveries  int64
+	Webhooks    int64
+}
+
+type UserEvent struct {
+	Seq       int64
+	UserID    int64
+	Kind      string
+	Data      []byte
+	CreatedAt time.Time
+}
+
+type Webhook struct {
+	ID                  int64
+	UserID              int64
+	URL                 string
+	Secret              string
+	Events              []string
+	Enabled             bool
+	ConsecutiveFailures int32
+	DisabledReason      *string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type WebhookDeadLetter struct {
+	ID             int64
+	WebhookID      int64
+	EventSeq       int64
+	EventKind      string
+	EventData      []byte
+	EventCreatedAt time.Time
+	Attempts       int32
+	LastError      string
+	FailedAt       time.Time
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	EventSeq       int64
+	EventKind      string
+	EventData      []byte
+	EventCreatedAt time.Time
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastError      *string
+}
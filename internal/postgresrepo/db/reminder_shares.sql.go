@@ -0,0 +1,280 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: reminder_shares.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const acceptReminderShare = `-- name: AcceptReminderShare :execrows
+UPDATE reminder_shares
+SET accepted_at = coalesce(reminder_shares.accepted_at, now())
+FROM reminders
+WHERE reminders.id = reminder_shares.reminder_id AND reminders.deleted_at IS NULL
+    AND reminder_shares.reminder_id = $1
+    AND reminder_shares.user_id IS NOT DISTINCT FROM $2
+    AND reminder_shares.chat_id IS NOT DISTINCT FROM $3
+`
+
+type AcceptReminderShareParams struct {
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+}
+
+func (q *Queries) AcceptReminderShare(ctx context.Context, arg AcceptReminderShareParams) (int64, error) {
+	result, err := q.db.Exec(ctx, acceptReminderShare, arg.ReminderID, arg.UserID, arg.ChatID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getReminderInvitations = `-- name: GetReminderInvitations :many
+SELECT reminder_shares.reminder_id, reminder_shares.user_id, reminder_shares.chat_id, reminder_shares.role,
+    (reminder_shares.accepted_at IS NOT NULL)::boolean AS accepted, reminder_shares.created_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminder_shares.user_id IS NOT DISTINCT FROM $1
+    AND reminder_shares.chat_id IS NOT DISTINCT FROM $2
+    AND reminder_shares.accepted_at IS NULL AND reminders.deleted_at IS NULL
+ORDER BY reminder_shares.id
+`
+
+type GetReminderInvitationsParams struct {
+	UserID *int64
+	ChatID *int64
+}
+
+type GetReminderInvitationsRow struct {
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	Accepted   bool
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetReminderInvitations(ctx context.Context, arg GetReminderInvitationsParams) ([]GetReminderInvitationsRow, error) {
+	rows, err := q.db.Query(ctx, getReminderInvitations, arg.UserID, arg.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReminderInvitationsRow
+	for rows.Next() {
+		var i GetReminderInvitationsRow
+		if err := rows.Scan(
+			&i.ReminderID,
+			&i.UserID,
+			&i.ChatID,
+			&i.Role,
+			&i.Accepted,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReminderShares = `-- name: GetReminderShares :many
+SELECT reminder_id, user_id, chat_id, role, (accepted_at IS NOT NULL)::boolean AS accepted, created_at
+FROM reminder_shares
+WHERE reminder_id = $1
+ORDER BY id
+`
+
+type GetReminderSharesRow struct {
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	Accepted   bool
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetReminderShares(ctx context.Context, reminderID int32) ([]GetReminderSharesRow, error) {
+	rows, err := q.db.Query(ctx, getReminderShares, reminderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReminderSharesRow
+	for rows.Next() {
+		var i GetReminderSharesRow
+		if err := rows.Scan(
+			&i.ReminderID,
+			&i.UserID,
+			&i.ChatID,
+			&i.Role,
+			&i.Accepted,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getShareRecipients = `-- name: GetShareRecipients :many
+SELECT reminder_shares.reminder_id, coalesce(reminder_shares.user_id, reminder_shares.chat_id)::bigint AS recipient_id,
+    users.notification_channels
+FROM reminder_shares
+LEFT JOIN users ON users.id = reminder_shares.user_id
+WHERE reminder_shares.reminder_id = ANY($1::integer[]) AND reminder_shares.accepted_at IS NOT NULL
+ORDER BY reminder_shares.id
+`
+
+type GetShareRecipientsRow struct {
+	ReminderID           int32
+	RecipientID          int64
+	NotificationChannels []byte
+}
+
+// The recipients who accepted the reminders being shared with them, see
+// FireDueReminders.
+func (q *Queries) GetShareRecipients(ctx context.Context, reminderIds []int32) ([]GetShareRecipientsRow, error) {
+	rows, err := q.db.Query(ctx, getShareRecipients, reminderIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetShareRecipientsRow
+	for rows.Next() {
+		var i GetShareRecipientsRow
+		if err := rows.Scan(&i.ReminderID, &i.RecipientID, &i.NotificationChannels); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSharedReminders = `-- name: GetSharedReminders :many
+SELECT reminders.id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminder_shares.user_id = $1::bigint AND reminder_shares.accepted_at IS NOT NULL AND reminders.deleted_at IS NULL
+ORDER BY reminders.remind_at, reminders.id
+`
+
+type GetSharedRemindersRow struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+}
+
+func (q *Queries) GetSharedReminders(ctx context.Context, userID int64) ([]GetSharedRemindersRow, error) {
+	rows, err := q.db.Query(ctx, getSharedReminders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSharedRemindersRow
+	for rows.Next() {
+		var i GetSharedRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeReminderShare = `-- name: RemoveReminderShare :execrows
+DELETE FROM reminder_shares
+WHERE reminder_id = $1
+    AND user_id IS NOT DISTINCT FROM $2
+    AND chat_id IS NOT DISTINCT FROM $3
+`
+
+type RemoveReminderShareParams struct {
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+}
+
+func (q *Queries) RemoveReminderShare(ctx context.Context, arg RemoveReminderShareParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeReminderShare, arg.ReminderID, arg.UserID, arg.ChatID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const shareReminder = `-- name: ShareReminder :one
+INSERT INTO reminder_shares (reminder_id, user_id, chat_id, role)
+SELECT reminders.id, $1, $2, $3
+FROM reminders
+WHERE reminders.id = $4 AND reminders.deleted_at IS NULL
+ON CONFLICT (reminder_id, (coalesce(user_id, 0)), (coalesce(chat_id, 0))) DO UPDATE
+SET role = excluded.role,
+    accepted_at = CASE
+        WHEN reminder_shares.role = 'VIEWER' AND excluded.role = 'EDITOR' THEN NULL
+        ELSE reminder_shares.accepted_at
+    END
+RETURNING reminder_id, user_id, chat_id, role, (accepted_at IS NOT NULL)::boolean AS accepted, created_at
+`
+
+type ShareReminderParams struct {
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	ReminderID int32
+}
+
+type ShareReminderRow struct {
+	ReminderID int32
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	Accepted   bool
+	CreatedAt  time.Time
+}
+
+// Returns no row when the reminder does not exist or was removed, fails
+// with a foreign key violation when the user does not exist. Raising a
+// viewer to an editor invites the recipient again.
+func (q *Queries) ShareReminder(ctx context.Context, arg ShareReminderParams) (ShareReminderRow, error) {
+	row := q.db.QueryRow(ctx, shareReminder,
+		arg.UserID,
+		arg.ChatID,
+		arg.Role,
+		arg.ReminderID,
+	)
+	var i ShareReminderRow
+	err := row.Scan(
+		&i.ReminderID,
+		&i.UserID,
+		&i.ChatID,
+		&i.Role,
+		&i.Accepted,
+		&i.CreatedAt,
+	)
+	return i, err
+}
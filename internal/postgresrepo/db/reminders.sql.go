@@ -0,0 +1,191 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: reminders.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type CopyRemindersParams struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+}
+
+const createReminder = `-- name: CreateReminder :one
+WITH counted AS (
+    UPDATE users
+    SET active_reminders = active_reminders + 1
+    WHERE users.id = $3 AND active_reminders < $4::integer
+    RETURNING users.id
+)
+INSERT INTO reminders (user_id, reminder_text, remind_at)
+SELECT id, $1::text, $2::timestamptz FROM counted
+RETURNING id
+`
+
+type CreateReminderParams struct {
+	ReminderText       string
+	RemindAt           time.Time
+	UserID             int64
+	MaxActiveReminders int32
+}
+
+// The quota is checked and taken by a single row update, so concurrent
+// inserts for the same user cannot exceed it. No row is returned when the
+// user does not exist or has no quota left.
+func (q *Queries) CreateReminder(ctx context.Context, arg CreateReminderParams) (int32, error) {
+	row := q.db.QueryRow(ctx, createReminder,
+		arg.ReminderText,
+		arg.RemindAt,
+		arg.UserID,
+		arg.MaxActiveReminders,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getReminder = `-- name: GetReminder :one
+SELECT id, user_id, reminder_text, remind_at, created_at, deleted_at, fired_at
+FROM reminders
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetReminder(ctx context.Context, id int32) (Reminder, error) {
+	row := q.db.QueryRow(ctx, getReminder, id)
+	var i Reminder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ReminderText,
+		&i.RemindAt,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.FiredAt,
+	)
+	return i, err
+}
+
+const getRemindersByUserId = `-- name: GetRemindersByUserId :many
+SELECT id, user_id, reminder_text, remind_at, created_at, deleted_at, fired_at
+FROM reminders
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY remind_at, id
+`
+
+func (q *Queries) GetRemindersByUserId(ctx context.Context, userID int64) ([]Reminder, error) {
+	rows, err := q.db.Query(ctx, getRemindersByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reminder
+	for rows.Next() {
+		var i Reminder
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ReminderText,
+			&i.RemindAt,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.FiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const nextReminderIds = `-- name: NextReminderIds :many
+SELECT nextval(pg_get_serial_sequence('reminders', 'id'))::integer AS id
+FROM generate_series(1, $1::integer)
+`
+
+// Takes n ids for CopyReminders, which does not return the ids it inserts.
+func (q *Queries) NextReminderIds(ctx context.Context, n int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, nextReminderIds, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeReminder = `-- name: RemoveReminder :one
+WITH removed AS (
+    UPDATE reminders
+    SET deleted_at = now()
+    WHERE reminders.id = $1 AND deleted_at IS NULL
+    RETURNING id, user_id, reminder_text, remind_at, fired_at
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - 1
+    FROM removed
+    WHERE users.id = removed.user_id AND removed.fired_at IS NULL
+)
+SELECT id, user_id, reminder_text, remind_at
+FROM removed
+`
+
+type RemoveReminderRow struct {
+	ID           int32
+	UserID       int64
+	ReminderText string
+	RemindAt     time.Time
+}
+
+// Fired reminders no longer count as active, removing them frees nothing.
+func (q *Queries) RemoveReminder(ctx context.Context, id int32) (RemoveReminderRow, error) {
+	row := q.db.QueryRow(ctx, removeReminder, id)
+	var i RemoveReminderRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ReminderText,
+		&i.RemindAt,
+	)
+	return i, err
+}
+
+const takeQuota = `-- name: TakeQuota :execrows
+UPDATE users
+SET active_reminders = active_reminders + $1::integer
+WHERE id = $2 AND active_reminders + $1::integer <= $3::integer
+`
+
+type TakeQuotaParams struct {
+	N                  int32
+	UserID             int64
+	MaxActiveReminders int32
+}
+
+// Takes n of the quota of a user at once, see CreateReminder.
+func (q *Queries) TakeQuota(ctx context.Context, arg TakeQuotaParams) (int64, error) {
+	result, err := q.db.Exec(ctx, takeQuota, arg.N, arg.UserID, arg.MaxActiveReminders)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
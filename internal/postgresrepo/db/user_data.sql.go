@@ -0,0 +1,496 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: user_data.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+DELETE FROM user_deletions
+WHERE user_id = $1
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelUserDeletion, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteAuditEventsOfUser = `-- name: DeleteAuditEventsOfUser :exec
+DELETE FROM audit_events
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteAuditEventsOfUser(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteAuditEventsOfUser, userID)
+	return err
+}
+
+const deleteDeliveriesOfUser = `-- name: DeleteDeliveriesOfUser :execrows
+DELETE FROM deliveries
+WHERE deliveries.recipient_id = $1::bigint OR reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.user_id = $1
+)
+`
+
+// Deliveries of the reminders of the user and to the user of reminders
+// shared with them.
+func (q *Queries) DeleteDeliveriesOfUser(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeliveriesOfUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteEventsOfUser = `-- name: DeleteEventsOfUser :exec
+DELETE FROM user_events
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteEventsOfUser(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteEventsOfUser, userID)
+	return err
+}
+
+const deleteRemindersOfUser = `-- name: DeleteRemindersOfUser :execrows
+DELETE FROM reminders
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteRemindersOfUser(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRemindersOfUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteSecretsOfUser = `-- name: DeleteSecretsOfUser :exec
+WITH hook_token AS (
+    DELETE FROM hook_tokens WHERE hook_tokens.user_id = $1::bigint
+), calendar_feed AS (
+    DELETE FROM calendar_feeds WHERE calendar_feeds.user_id = $1
+), idempotency_key AS (
+    DELETE FROM idempotency_keys
+    WHERE caller LIKE 'user:' || $1::bigint || ':%'
+), rate_limit_bucket AS (
+    DELETE FROM rate_limit_buckets
+    WHERE key LIKE 'user:' || $1::bigint || ':%'
+)
+DELETE FROM api_keys
+WHERE api_keys.user_id = $1::bigint
+`
+
+func (q *Queries) DeleteSecretsOfUser(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteSecretsOfUser, userID)
+	return err
+}
+
+const deleteSharesOfUser = `-- name: DeleteSharesOfUser :exec
+DELETE FROM reminder_shares
+WHERE reminder_shares.user_id = $1::bigint
+    OR reminder_shares.reminder_id IN (
+        SELECT reminders.id
+        FROM reminders
+        WHERE reminders.user_id = $1
+    )
+`
+
+// Shares with the user and of their reminders.
+func (q *Queries) DeleteSharesOfUser(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteSharesOfUser, userID)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteUser, userID)
+	return err
+}
+
+const deleteWebhooksOfUser = `-- name: DeleteWebhooksOfUser :execrows
+DELETE FROM webhooks
+WHERE user_id = $1
+`
+
+// Their queued deliveries and dead letters cascade.
+func (q *Queries) DeleteWebhooksOfUser(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhooksOfUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const dueUserDeletions = `-- name: DueUserDeletions :many
+SELECT user_id
+FROM user_deletions
+WHERE delete_at <= $1
+ORDER BY delete_at
+LIMIT $2
+`
+
+type DueUserDeletionsParams struct {
+	Now      time.Time
+	MaxUsers int32
+}
+
+func (q *Queries) DueUserDeletions(ctx context.Context, arg DueUserDeletionsParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, dueUserDeletions, arg.Now, arg.MaxUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var user_id int64
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDataAPIKeys = `-- name: GetUserDataAPIKeys :many
+SELECT name, created_at, revoked_at
+FROM api_keys
+WHERE user_id = $1::bigint
+ORDER BY id
+`
+
+type GetUserDataAPIKeysRow struct {
+	Name      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (q *Queries) GetUserDataAPIKeys(ctx context.Context, userID int64) ([]GetUserDataAPIKeysRow, error) {
+	rows, err := q.db.Query(ctx, getUserDataAPIKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserDataAPIKeysRow
+	for rows.Next() {
+		var i GetUserDataAPIKeysRow
+		if err := rows.Scan(&i.Name, &i.CreatedAt, &i.RevokedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDataDeliveries = `-- name: GetUserDataDeliveries :many
+SELECT
+    deliveries.id,
+    deliveries.reminder_id,
+    deliveries.channel,
+    deliveries.address,
+    deliveries.status,
+    deliveries.attempts,
+    deliveries.last_error,
+    deliveries.created_at,
+    deliveries.updated_at
+FROM deliveries
+JOIN reminders ON reminders.id = deliveries.reminder_id
+WHERE reminders.user_id = $1 OR deliveries.recipient_id = $1
+ORDER BY deliveries.id
+`
+
+type GetUserDataDeliveriesRow struct {
+	ID         int64
+	ReminderID int32
+	Channel    string
+	Address    string
+	Status     string
+	Attempts   int32
+	LastError  *string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) GetUserDataDeliveries(ctx context.Context, userID int64) ([]GetUserDataDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, getUserDataDeliveries, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserDataDeliveriesRow
+	for rows.Next() {
+		var i GetUserDataDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReminderID,
+			&i.Channel,
+			&i.Address,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDataReminders = `-- name: GetUserDataReminders :many
+SELECT id, reminder_text, remind_at, created_at, fired_at, deleted_at
+FROM reminders
+WHERE user_id = $1
+ORDER BY id
+`
+
+type GetUserDataRemindersRow struct {
+	ID           int32
+	ReminderText string
+	RemindAt     time.Time
+	CreatedAt    time.Time
+	FiredAt      *time.Time
+	DeletedAt    *time.Time
+}
+
+func (q *Queries) GetUserDataReminders(ctx context.Context, userID int64) ([]GetUserDataRemindersRow, error) {
+	rows, err := q.db.Query(ctx, getUserDataReminders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserDataRemindersRow
+	for rows.Next() {
+		var i GetUserDataRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReminderText,
+			&i.RemindAt,
+			&i.CreatedAt,
+			&i.FiredAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDataSettings = `-- name: GetUserDataSettings :one
+SELECT
+    hook_tokens.created_at AS hook_token_created_at,
+    calendar_feeds.created_at AS calendar_feed_created_at,
+    user_deletions.delete_at
+FROM users
+LEFT JOIN hook_tokens ON hook_tokens.user_id = users.id
+LEFT JOIN calendar_feeds ON calendar_feeds.user_id = users.id
+LEFT JOIN user_deletions ON user_deletions.user_id = users.id
+WHERE users.id = $1
+`
+
+type GetUserDataSettingsRow struct {
+	HookTokenCreatedAt    *time.Time
+	CalendarFeedCreatedAt *time.Time
+	DeleteAt              *time.Time
+}
+
+func (q *Queries) GetUserDataSettings(ctx context.Context, userID int64) (GetUserDataSettingsRow, error) {
+	row := q.db.QueryRow(ctx, getUserDataSettings, userID)
+	var i GetUserDataSettingsRow
+	err := row.Scan(&i.HookTokenCreatedAt, &i.CalendarFeedCreatedAt, &i.DeleteAt)
+	return i, err
+}
+
+const getUserDataShares = `-- name: GetUserDataShares :many
+SELECT
+    reminder_shares.reminder_id,
+    reminders.user_id AS owner_id,
+    reminder_shares.user_id,
+    reminder_shares.chat_id,
+    reminder_shares.role,
+    reminder_shares.accepted_at,
+    reminder_shares.created_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminders.user_id = $1 OR reminder_shares.user_id = $1
+ORDER BY reminder_shares.id
+`
+
+type GetUserDataSharesRow struct {
+	ReminderID int32
+	OwnerID    int64
+	UserID     *int64
+	ChatID     *int64
+	Role       string
+	AcceptedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// Shares of the reminders of the user and with the user.
+func (q *Queries) GetUserDataShares(ctx context.Context, userID int64) ([]GetUserDataSharesRow, error) {
+	rows, err := q.db.Query(ctx, getUserDataShares, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserDataSharesRow
+	for rows.Next() {
+		var i GetUserDataSharesRow
+		if err := rows.Scan(
+			&i.ReminderID,
+			&i.OwnerID,
+			&i.UserID,
+			&i.ChatID,
+			&i.Role,
+			&i.AcceptedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDataWebhooks = `-- name: GetUserDataWebhooks :many
+SELECT id, url, events, enabled, disabled_reason, created_at
+FROM webhooks
+WHERE user_id = $1
+ORDER BY id
+`
+
+type GetUserDataWebhooksRow struct {
+	ID             int64
+	URL            string
+	Events         []string
+	Enabled        bool
+	DisabledReason *string
+	CreatedAt      time.Time
+}
+
+func (q *Queries) GetUserDataWebhooks(ctx context.Context, userID int64) ([]GetUserDataWebhooksRow, error) {
+	rows, err := q.db.Query(ctx, getUserDataWebhooks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserDataWebhooksRow
+	for rows.Next() {
+		var i GetUserDataWebhooksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.URL,
+			&i.Events,
+			&i.Enabled,
+			&i.DisabledReason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertUserDeletionAudit = `-- name: InsertUserDeletionAudit :exec
+INSERT INTO user_deletion_audit (requested_at, reminders, deliveries, webhooks)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertUserDeletionAuditParams struct {
+	RequestedAt time.Time
+	Reminders   int64
+	Deliveries  int64
+	Webhooks    int64
+}
+
+func (q *Queries) InsertUserDeletionAudit(ctx context.Context, arg InsertUserDeletionAuditParams) error {
+	_, err := q.db.Exec(ctx, insertUserDeletionAudit,
+		arg.RequestedAt,
+		arg.Reminders,
+		arg.Deliveries,
+		arg.Webhooks,
+	)
+	return err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+WITH scheduled AS (
+    INSERT INTO user_deletions (user_id, delete_at)
+    SELECT users.id, $1
+    FROM users
+    WHERE users.id = $2
+    ON CONFLICT (user_id) DO NOTHING
+    RETURNING delete_at
+)
+SELECT delete_at FROM scheduled
+UNION ALL
+SELECT d.delete_at FROM user_deletions AS d WHERE d.user_id = $2
+`
+
+type ScheduleUserDeletionParams struct {
+	DeleteAt time.Time
+	UserID   int64
+}
+
+// Returns no row when the user does not exist.
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, scheduleUserDeletion, arg.DeleteAt, arg.UserID)
+	var delete_at time.Time
+	err := row.Scan(&delete_at)
+	return delete_at, err
+}
+
+const takeDueUserDeletion = `-- name: TakeDueUserDeletion :one
+DELETE FROM user_deletions
+WHERE user_id = $1 AND delete_at <= $2
+RETURNING requested_at
+`
+
+type TakeDueUserDeletionParams struct {
+	UserID int64
+	Now    time.Time
+}
+
+// Starts DeleteUser, which the other deletions follow in the same
+// transaction.
+func (q *Queries) TakeDueUserDeletion(ctx context.Context, arg TakeDueUserDeletionParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, takeDueUserDeletion, arg.UserID, arg.Now)
+	var requested_at time.Time
+	err := row.Scan(&requested_at)
+	return requested_at, err
+}
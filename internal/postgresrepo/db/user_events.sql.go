@@ -0,0 +1,83 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: user_events.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteUserEvents = `-- name: DeleteUserEvents :execrows
+DELETE FROM user_events
+WHERE user_events.created_at < $1
+    AND user_events.seq < (SELECT max(seq) FROM user_events)
+`
+
+// The latest event is kept to tell how far clients may resume from.
+func (q *Queries) DeleteUserEvents(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserEvents, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userEventSeqRange = `-- name: UserEventSeqRange :one
+SELECT coalesce(min(seq), 0)::bigint AS oldest, coalesce(max(seq), 0)::bigint AS latest
+FROM user_events
+`
+
+type UserEventSeqRangeRow struct {
+	Oldest int64
+	Latest int64
+}
+
+func (q *Queries) UserEventSeqRange(ctx context.Context) (UserEventSeqRangeRow, error) {
+	row := q.db.QueryRow(ctx, userEventSeqRange)
+	var i UserEventSeqRangeRow
+	err := row.Scan(&i.Oldest, &i.Latest)
+	return i, err
+}
+
+const userEvents = `-- name: UserEvents :many
+SELECT seq, user_id, kind, data, created_at
+FROM user_events
+WHERE user_id = $1 AND seq > $2
+ORDER BY seq
+LIMIT $3
+`
+
+type UserEventsParams struct {
+	UserID    int64
+	AfterSeq  int64
+	MaxEvents int32
+}
+
+func (q *Queries) UserEvents(ctx context.Context, arg UserEventsParams) ([]UserEvent, error) {
+	rows, err := q.db.Query(ctx, userEvents, arg.UserID, arg.AfterSeq, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserEvent
+	for rows.Next() {
+		var i UserEvent
+		if err := rows.Scan(
+			&i.Seq,
+			&i.UserID,
+			&i.Kind,
+			&i.Data,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,72 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: users.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getUser = `-- name: GetUser :one
+SELECT language_code, utc_offset, notification_channels
+FROM users
+WHERE id = $1
+`
+
+type GetUserRow struct {
+	LanguageCode         *string
+	UtcOffset            *int32
+	NotificationChannels []byte
+}
+
+func (q *Queries) GetUser(ctx context.Context, id int64) (GetUserRow, error) {
+	row := q.db.QueryRow(ctx, getUser, id)
+	var i GetUserRow
+	err := row.Scan(&i.LanguageCode, &i.UtcOffset, &i.NotificationChannels)
+	return i, err
+}
+
+const lockUser = `-- name: LockUser :execrows
+SELECT 1
+FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+// Keeps the user from changing until the end of the transaction.
+func (q *Queries) LockUser(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, lockUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUser = `-- name: SetUser :exec
+INSERT INTO users (id, language_code, utc_offset, notification_channels)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE
+SET language_code = COALESCE(excluded.language_code, users.language_code),
+    utc_offset = COALESCE(excluded.utc_offset, users.utc_offset),
+    notification_channels = COALESCE(excluded.notification_channels, users.notification_channels)
+`
+
+type SetUserParams struct {
+	ID                   int64
+	LanguageCode         *string
+	UtcOffset            *int32
+	NotificationChannels []byte
+}
+
+// Fields passed as NULL keep their stored values.
+func (q *Queries) SetUser(ctx context.Context, arg SetUserParams) error {
+	_, err := q.db.Exec(ctx, setUser,
+		arg.ID,
+		arg.LanguageCode,
+		arg.UtcOffset,
+		arg.NotificationChannels,
+	)
+	return err
+}
@@ -0,0 +1,433 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.26.0
+// source: webhooks.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+WITH claimed AS (
+    UPDATE webhook_deliveries
+    SET attempts = attempts + 1, next_attempt_at = $1
+    WHERE webhook_deliveries.id IN (
+        SELECT due.id
+        FROM webhook_deliveries AS due
+        JOIN webhooks ON webhooks.id = due.webhook_id
+        WHERE webhooks.enabled AND due.next_attempt_at <= $2
+        ORDER BY due.next_attempt_at, due.id
+        LIMIT $3
+        FOR UPDATE OF due SKIP LOCKED
+    )
+    RETURNING webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event_seq,
+        webhook_deliveries.event_kind, webhook_deliveries.event_data, webhook_deliveries.event_created_at,
+        webhook_deliveries.attempts
+)
+SELECT claimed.id, claimed.webhook_id, claimed.event_seq, claimed.event_kind, claimed.event_data,
+    claimed.event_created_at, claimed.attempts, webhooks.user_id, webhooks.url, webhooks.secret
+FROM claimed
+JOIN webhooks ON webhooks.id = claimed.webhook_id
+ORDER BY claimed.id
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseUntil    time.Time
+	Now           time.Time
+	MaxDeliveries int32
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	ID             int64
+	WebhookID      int64
+	EventSeq       int64
+	EventKind      string
+	EventData      []byte
+	EventCreatedAt time.Time
+	Attempts       int32
+	UserID         int64
+	URL            string
+	Secret         string
+}
+
+// Deliveries of disabled webhooks wait until they are enabled again.
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.LeaseUntil, arg.Now, arg.MaxDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.EventSeq,
+			&i.EventKind,
+			&i.EventData,
+			&i.EventCreatedAt,
+			&i.Attempts,
+			&i.UserID,
+			&i.URL,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countWebhookFailure = `-- name: CountWebhookFailure :exec
+UPDATE webhooks
+SET consecutive_failures = consecutive_failures + 1,
+    enabled = enabled AND consecutive_failures + 1 < $1::integer,
+    disabled_reason = CASE
+        WHEN enabled AND consecutive_failures + 1 >= $1::integer THEN $2::text
+        ELSE disabled_reason
+    END,
+    updated_at = now()
+WHERE id = $3
+`
+
+type CountWebhookFailureParams struct {
+	DisableAfter   int32
+	DisabledReason string
+	ID             int64
+}
+
+// The webhook is disabled with disabled_reason by its disable_after-th
+// failure in a row.
+func (q *Queries) CountWebhookFailure(ctx context.Context, arg CountWebhookFailureParams) error {
+	_, err := q.db.Exec(ctx, countWebhookFailure, arg.DisableAfter, arg.DisabledReason, arg.ID)
+	return err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, secret, events)
+VALUES ($1, $2, $3, $4::text[])
+RETURNING id
+`
+
+type CreateWebhookParams struct {
+	UserID int64
+	URL    string
+	Secret string
+	Events []string
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createWebhook,
+		arg.UserID,
+		arg.URL,
+		arg.Secret,
+		arg.Events,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deadLetterWebhookDelivery = `-- name: DeadLetterWebhookDelivery :one
+WITH failed AS (
+    DELETE FROM webhook_deliveries
+    WHERE webhook_deliveries.id = $2
+    RETURNING webhook_deliveries.webhook_id, webhook_deliveries.event_seq, webhook_deliveries.event_kind,
+        webhook_deliveries.event_data, webhook_deliveries.event_created_at, webhook_deliveries.attempts
+)
+INSERT INTO webhook_dead_letters (webhook_id, event_seq, event_kind, event_data, event_created_at, attempts, last_error)
+SELECT failed.webhook_id, failed.event_seq, failed.event_kind, failed.event_data, failed.event_created_at,
+    failed.attempts, $1::text
+FROM failed
+RETURNING webhook_id
+`
+
+type DeadLetterWebhookDeliveryParams struct {
+	LastError string
+	ID        int64
+}
+
+func (q *Queries) DeadLetterWebhookDelivery(ctx context.Context, arg DeadLetterWebhookDeliveryParams) (int64, error) {
+	row := q.db.QueryRow(ctx, deadLetterWebhookDelivery, arg.LastError, arg.ID)
+	var webhook_id int64
+	err := row.Scan(&webhook_id)
+	return webhook_id, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhook, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, user_id, url, events, enabled, disabled_reason
+FROM webhooks
+WHERE id = $1
+`
+
+type GetWebhookRow struct {
+	ID             int64
+	UserID         int64
+	URL            string
+	Events         []string
+	Enabled        bool
+	DisabledReason *string
+}
+
+func (q *Queries) GetWebhook(ctx context.Context, id int64) (GetWebhookRow, error) {
+	row := q.db.QueryRow(ctx, getWebhook, id)
+	var i GetWebhookRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.URL,
+		&i.Events,
+		&i.Enabled,
+		&i.DisabledReason,
+	)
+	return i, err
+}
+
+const getWebhookDeadLetter = `-- name: GetWebhookDeadLetter :one
+SELECT webhook_dead_letters.id, webhook_dead_letters.webhook_id, webhooks.user_id,
+    webhook_dead_letters.event_seq, webhook_dead_letters.event_kind, webhook_dead_letters.event_data,
+    webhook_dead_letters.event_created_at, webhook_dead_letters.attempts, webhook_dead_letters.last_error,
+    webhook_dead_letters.failed_at
+FROM webhook_dead_letters
+JOIN webhooks ON webhooks.id = webhook_dead_letters.webhook_id
+WHERE webhook_dead_letters.id = $1
+`
+
+type GetWebhookDeadLetterRow struct {
+	ID             int64
+	WebhookID      int64
+	UserID         int64
+	EventSeq       int64
+	EventKind      string
+	EventData      []byte
+	EventCreatedAt time.Time
+	Attempts       int32
+	LastError      string
+	FailedAt       time.Time
+}
+
+func (q *Queries) GetWebhookDeadLetter(ctx context.Context, id int64) (GetWebhookDeadLetterRow, error) {
+	row := q.db.QueryRow(ctx, getWebhookDeadLetter, id)
+	var i GetWebhookDeadLetterRow
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.UserID,
+		&i.EventSeq,
+		&i.EventKind,
+		&i.EventData,
+		&i.EventCreatedAt,
+		&i.Attempts,
+		&i.LastError,
+		&i.FailedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeadLettersByUserId = `-- name: GetWebhookDeadLettersByUserId :many
+SELECT webhook_dead_letters.id, webhook_dead_letters.webhook_id, webhooks.user_id,
+    webhook_dead_letters.event_seq, webhook_dead_letters.event_kind, webhook_dead_letters.event_data,
+    webhook_dead_letters.event_created_at, webhook_dead_letters.attempts, webhook_dead_letters.last_error,
+    webhook_dead_letters.failed_at
+FROM webhook_dead_letters
+JOIN webhooks ON webhooks.id = webhook_dead_letters.webhook_id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_dead_letters.id
+`
+
+type GetWebhookDeadLettersByUserIdRow struct {
+	ID             int64
+	WebhookID      int64
+	UserID         int64
+	EventSeq       int64
+	EventKind      string
+	EventData      []byte
+	EventCreatedAt time.Time
+	Attempts       int32
+	LastError      string
+	FailedAt       time.Time
+}
+
+func (q *Queries) GetWebhookDeadLettersByUserId(ctx context.Context, userID int64) ([]GetWebhookDeadLettersByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getWebhookDeadLettersByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhookDeadLettersByUserIdRow
+	for rows.Next() {
+		var i GetWebhookDeadLettersByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.UserID,
+			&i.EventSeq,
+			&i.EventKind,
+			&i.EventData,
+			&i.EventCreatedAt,
+			&i.Attempts,
+			&i.LastError,
+			&i.FailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksByUserId = `-- name: GetWebhooksByUserId :many
+SELECT id, user_id, url, events, enabled, disabled_reason
+FROM webhooks
+WHERE user_id = $1
+ORDER BY id
+`
+
+type GetWebhooksByUserIdRow struct {
+	ID             int64
+	UserID         int64
+	URL            string
+	Events         []string
+	Enabled        bool
+	DisabledReason *string
+}
+
+func (q *Queries) GetWebhooksByUserId(ctx context.Context, userID int64) ([]GetWebhooksByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getWebhooksByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhooksByUserIdRow
+	for rows.Next() {
+		var i GetWebhooksByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.URL,
+			&i.Events,
+			&i.Enabled,
+			&i.DisabledReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const replayWebhookDeadLetter = `-- name: ReplayWebhookDeadLetter :execrows
+WITH replayed AS (
+    DELETE FROM webhook_dead_letters
+    WHERE webhook_dead_letters.id = $1
+    RETURNING webhook_dead_letters.webhook_id, webhook_dead_letters.event_seq, webhook_dead_letters.event_kind,
+        webhook_dead_letters.event_data, webhook_dead_letters.event_created_at
+)
+INSERT INTO webhook_deliveries (webhook_id, event_seq, event_kind, event_data, event_created_at)
+SELECT replayed.webhook_id, replayed.event_seq, replayed.event_kind, replayed.event_data, replayed.event_created_at
+FROM replayed
+`
+
+func (q *Queries) ReplayWebhookDeadLetter(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, replayWebhookDeadLetter, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const retryWebhookDelivery = `-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries
+SET next_attempt_at = $1, last_error = $2::text
+WHERE id = $3
+RETURNING webhook_id
+`
+
+type RetryWebhookDeliveryParams struct {
+	RetryAt   time.Time
+	LastError string
+	ID        int64
+}
+
+func (q *Queries) RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) (int64, error) {
+	row := q.db.QueryRow(ctx, retryWebhookDelivery, arg.RetryAt, arg.LastError, arg.ID)
+	var webhook_id int64
+	err := row.Scan(&webhook_id)
+	return webhook_id, err
+}
+
+const updateWebhook = `-- name: UpdateWebhook :execrows
+UPDATE webhooks
+SET url = $1,
+    events = $2::text[],
+    enabled = coalesce($3::boolean, enabled),
+    consecutive_failures = CASE WHEN $3::boolean THEN 0 ELSE consecutive_failures END,
+    disabled_reason = CASE WHEN $3::boolean THEN NULL ELSE disabled_reason END,
+    updated_at = now()
+WHERE id = $4
+`
+
+type UpdateWebhookParams struct {
+	URL     string
+	Events  []string
+	Enabled *bool
+	ID      int64
+}
+
+// Enabling a webhook forgets its failures and why it was disabled. A null
+// enabled leaves the webhook as it is.
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateWebhook,
+		arg.URL,
+		arg.Events,
+		arg.Enabled,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const webhookDelivered = `-- name: WebhookDelivered :exec
+WITH delivered AS (
+    DELETE FROM webhook_deliveries
+    WHERE webhook_deliveries.id = $1
+    RETURNING webhook_deliveries.webhook_id
+)
+UPDATE webhooks
+SET consecutive_failures = 0
+FROM delivered
+WHERE webhooks.id = delivered.webhook_id AND webhooks.consecutive_failures <> 0
+`
+
+func (q *Queries) WebhookDelivered(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, webhookDelivered, id)
+	return err
+}
@@ -0,0 +1,128 @@
+package postgresrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) FireDueReminders(ctx context.Context, now time.Time, limit int, route notify.Route) (int, error) {
+	var fired int
+	err := pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		rows, err := txRepo.queries().FireDueReminders(ctx, db.FireDueRemindersParams{
+			Now:          now,
+			MaxReminders: int32(limit),
+		})
+		if err != nil {
+			return err
+		}
+
+		reminderIds := make([]int32, 0, len(rows))
+		for _, row := range rows {
+			reminderIds = append(reminderIds, row.ID)
+		}
+		shared, err := txRepo.shareRecipients(ctx, reminderIds)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			channels, err := decodeChannels(row.NotificationChannels)
+			if err != nil {
+				return fmt.Errorf("cannot decode channels of user %d: %w", row.UserID, err)
+			}
+			recipients := append([]notify.Recipient{{ID: row.UserID, Channels: channels.GetChannels()}}, shared[row.ID]...)
+
+			for _, target := range notify.Fanout(route, recipients) {
+				err := txRepo.queries().CreateDelivery(ctx, db.CreateDeliveryParams{
+					ReminderID:    row.ID,
+					RecipientID:   &target.RecipientID,
+					Channel:       target.Channel.GetKind().String(),
+					Address:       target.Channel.GetAddress(),
+					NextAttemptAt: now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		fired = len(rows)
+
+		return nil
+	})
+
+	return fired, err
+}
+
+func (pr PostgresRepo) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]notify.Delivery, error) {
+	rows, err := pr.queries().ClaimDeliveries(ctx, db.ClaimDeliveriesParams{
+		LeaseUntil:    leaseUntil,
+		Now:           now,
+		MaxDeliveries: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]notify.Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, notify.Delivery{
+			ID:      row.ID,
+			Kind:    pb.NotificationChannel_Kind(pb.NotificationChannel_Kind_value[row.Channel]),
+			Address: row.Address,
+			Reminder: &pb.Reminder{
+				Id:              row.ReminderID,
+				UserId:          row.UserID,
+				ReminderText:    row.ReminderText,
+				RemindTimestamp: timestamppb.New(row.RemindAt),
+			},
+			Attempts: int(row.Attempts),
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (pr PostgresRepo) MarkDeliverySent(ctx context.Context, id int64) error {
+	return pr.queries().MarkDeliverySent(ctx, id)
+}
+
+func (pr PostgresRepo) MarkDeliveryFailed(ctx context.Context, id int64, lastError string, retryAt time.Time) error {
+	params := db.MarkDeliveryFailedParams{ID: id, LastError: &lastError}
+	if !retryAt.IsZero() {
+		params.RetryAt = &retryAt
+	}
+
+	return pr.queries().MarkDeliveryFailed(ctx, params)
+}
+
+// encodeChannels returns the stored form of channels, nil for nil.
+func encodeChannels(channels *pb.NotificationChannels) ([]byte, error) {
+	if channels == nil {
+		return nil, nil
+	}
+
+	return protojson.Marshal(channels)
+}
+
+// decodeChannels returns nil for NULL.
+func decodeChannels(data []byte) (*pb.NotificationChannels, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	channels := &pb.NotificationChannels{}
+	if err := protojson.Unmarshal(data, channels); err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
@@ -0,0 +1,114 @@
+package postgresrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+// UserEventsChannel receives the id of a user whenever an event of the user
+// is recorded, see migrations/0007_user_events.sql.
+const UserEventsChannel = "user_events"
+
+func (pr PostgresRepo) UserEventSeqRange(ctx context.Context) (oldest, latest int64, err error) {
+	row, err := pr.queries().UserEventSeqRange(ctx)
+
+	return row.Oldest, row.Latest, err
+}
+
+func (pr PostgresRepo) UserEvents(ctx context.Context, userId, afterSeq int64, limit int) ([]*pb.UserEvent, error) {
+	rows, err := pr.queries().UserEvents(ctx, db.UserEventsParams{
+		UserID:    userId,
+		AfterSeq:  afterSeq,
+		MaxEvents: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*pb.UserEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := userEventFromRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode event %d: %w", row.Seq, err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (pr PostgresRepo) DeleteUserEvents(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return pr.queries().DeleteUserEvents(ctx, createdBefore)
+}
+
+// eventData is the union of the data recorded for every event kind.
+type eventData struct {
+	LanguageCode *string `json:"language_code"`
+	UtcOffset    *int32  `json:"utc_offset"`
+	// NotificationChannels is protojson, decoded by decodeChannels.
+	NotificationChannels json.RawMessage `json:"notification_channels"`
+	ID                   int32           `json:"id"`
+	ReminderText         string          `json:"reminder_text"`
+	RemindAt             int64           `json:"remind_at"`
+}
+
+func userEventFromRow(row db.UserEvent) (*pb.UserEvent, error) {
+	var data eventData
+	if err := json.Unmarshal(row.Data, &data); err != nil {
+		return nil, err
+	}
+
+	event := &pb.UserEvent{
+		Seq:    row.Seq,
+		UserId: row.UserID,
+		Time:   timestamppb.New(row.CreatedAt),
+	}
+
+	reminder := &pb.Reminder{
+		Id:              data.ID,
+		UserId:          row.UserID,
+		ReminderText:    data.ReminderText,
+		RemindTimestamp: timestamppb.New(time.UnixMicro(data.RemindAt)),
+	}
+
+	switch row.Kind {
+	case "profile_changed":
+		user := &pb.User{Id: row.UserID}
+		if data.LanguageCode != nil {
+			user.LanguageCode = wrapperspb.String(*data.LanguageCode)
+		}
+		if data.UtcOffset != nil {
+			user.UtcOffset = wrapperspb.Int32(*data.UtcOffset)
+		}
+		// JSON null decodes into the literal null rather than nil.
+		if len(data.NotificationChannels) > 0 && string(data.NotificationChannels) != "null" {
+			channels, err := decodeChannels(data.NotificationChannels)
+			if err != nil {
+				return nil, err
+			}
+			user.NotificationChannels = channels
+		}
+		event.Event = &pb.UserEvent_ProfileChanged{ProfileChanged: user}
+	case "reminder_created":
+		event.Event = &pb.UserEvent_ReminderCreated{ReminderCreated: reminder}
+	case "reminder_updated":
+		event.Event = &pb.UserEvent_ReminderUpdated{ReminderUpdated: reminder}
+	case "reminder_removed":
+		event.Event = &pb.UserEvent_ReminderRemoved{ReminderRemoved: reminder}
+	case "reminder_fired":
+		event.Event = &pb.UserEvent_ReminderFired{ReminderFired: reminder}
+	default:
+		return nil, fmt.Errorf("unknown kind %q", row.Kind)
+	}
+
+	return event, nil
+}
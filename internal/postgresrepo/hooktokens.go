@@ -0,0 +1,47 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) SetHookToken(ctx context.Context, userId int64, hash []byte) error {
+	set, err := pr.queries().SetHookToken(ctx, db.SetHookTokenParams{UserID: userId, TokenHash: hash})
+	if err != nil {
+		return err
+	}
+
+	if set == 0 {
+		return fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) DeleteHookToken(ctx context.Context, userId int64) error {
+	deleted, err := pr.queries().DeleteHookToken(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return fmt.Errorf("hook token of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) FindHookToken(ctx context.Context, hash []byte) (int64, error) {
+	userId, err := pr.queries().FindHookToken(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("hook token: %w", todoserviceserver.ErrNotFound)
+	}
+
+	return userId, err
+}
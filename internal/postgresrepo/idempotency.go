@@ -0,0 +1,75 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
+)
+
+// Reserve implements idempotency.Store.
+func (pr PostgresRepo) Reserve(ctx context.Context, caller, key string, requestHash []byte, ttl time.Duration) (*idempotency.Record, error) {
+	const (
+		queryReserve = `INSERT INTO idempotency_keys AS k (caller, key, request_hash, expires_at)
+	VALUES ($1, $2, $3, now() + $4::interval)
+	ON CONFLICT (caller, key) DO UPDATE
+	SET request_hash = excluded.request_hash,
+		done = false,
+		response = NULL,
+		status = NULL,
+		expires_at = excluded.expires_at
+	WHERE k.expires_at <= now()
+	RETURNING caller`
+
+		queryExisting = `SELECT request_hash, done, response, status
+	FROM idempotency_keys
+	WHERE caller = $1 AND key = $2`
+	)
+
+	var reserved string
+	err := pr.dbDriver.QueryRow(ctx, queryReserve, caller, key, requestHash, ttl).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var record idempotency.Record
+	err = pr.dbDriver.QueryRow(ctx, queryExisting, caller, key).Scan(
+		&record.RequestHash, &record.Done, &record.Response, &record.Status,
+	)
+
+	return &record, err
+}
+
+func (pr PostgresRepo) Complete(ctx context.Context, caller, key string, record idempotency.Record, ttl time.Duration) error {
+	const query = `UPDATE idempotency_keys
+	SET done = true, response = $3, status = $4, expires_at = now() + $5::interval
+	WHERE caller = $1 AND key = $2`
+
+	_, err := pr.dbDriver.Exec(ctx, query, caller, key, record.Response, record.Status, ttl)
+
+	return err
+}
+
+func (pr PostgresRepo) Release(ctx context.Context, caller, key string) error {
+	const query = `DELETE FROM idempotency_keys
+	WHERE caller = $1 AND key = $2 AND NOT done`
+
+	_, err := pr.dbDriver.Exec(ctx, query, caller, key)
+
+	return err
+}
+
+func (pr PostgresRepo) DeleteExpired(ctx context.Context) error {
+	const query = `DELETE FROM idempotency_keys
+	WHERE expires_at <= now()`
+
+	_, err := pr.dbDriver.Exec(ctx, query)
+
+	return err
+}
@@ -0,0 +1,61 @@
+package postgresrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CacheInvalidationChannel receives a notification whenever a user or
+// reminders of a user change, see migrations/0006_cache_invalidation.sql.
+const CacheInvalidationChannel = "cache_invalidation"
+
+const listenRetryDelay = 5 * time.Second
+
+// Listen holds a connection of pool listening on channel and calls handle
+// with the payload of every notification until ctx is done. Notifications
+// sent while the connection is down are lost, so reset is called every time
+// listening starts, including the first one.
+func Listen(ctx context.Context, pool *pgxpool.Pool, channel string, reset func(), handle func(payload string)) {
+	for {
+		err := listen(ctx, pool, channel, reset, handle)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Error listening on %s, retrying in %v: %v", channel, listenRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func listen(ctx context.Context, pool *pgxpool.Pool, channel string, reset func(), handle func(payload string)) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is left listening, so it must not return to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	reset()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle(notification.Payload)
+	}
+}
@@ -0,0 +1,62 @@
+package postgresrepo
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the migrations which were not applied yet, in the order
+// of their file names. Each migration runs in its own transaction.
+func (pr PostgresRepo) Migrate(ctx context.Context) error {
+	const (
+		queryCreateTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version text PRIMARY KEY,
+	applied_at timestamptz NOT NULL DEFAULT now()
+	)`
+
+		queryApplied = `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`
+	)
+
+	if _, err := pr.dbDriver.Exec(ctx, queryCreateTable); err != nil {
+		return fmt.Errorf("cannot create schema_migrations: %w", err)
+	}
+
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		version := entry.Name()
+
+		var applied bool
+		if err := pr.dbDriver.QueryRow(ctx, queryApplied, version).Scan(&applied); err != nil {
+			return fmt.Errorf("cannot check migration %s: %w", version, err)
+		}
+		if applied {
+			continue
+		}
+
+		migration, err := migrations.ReadFile("migrations/" + version)
+		if err != nil {
+			return err
+		}
+
+		// Without arguments the statements are sent in one simple query,
+		// which Postgres runs as a single transaction.
+		sql := fmt.Sprintf("%s;\nINSERT INTO schema_migrations (version) VALUES ('%s');", migration, version)
+		if _, err := pr.dbDriver.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("cannot apply migration %s: %w", version, err)
+		}
+
+		log.Printf("Applied migration %s", version)
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+-- IF NOT EXISTS keeps databases set up by hand before migrations existed.
+CREATE TABLE IF NOT EXISTS users (
+    id bigint PRIMARY KEY,
+    language_code text,
+    utc_offset integer
+);
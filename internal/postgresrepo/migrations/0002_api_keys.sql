@@ -0,0 +1,9 @@
+CREATE TABLE api_keys (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    key_hash bytea NOT NULL UNIQUE,
+    -- NULL for service accounts, which may act on any user.
+    user_id bigint,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    revoked_at timestamptz
+);
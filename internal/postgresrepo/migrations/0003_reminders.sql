@@ -0,0 +1,15 @@
+-- Maintained by the repository to enforce the active reminders quota
+-- without counting reminders on every insert.
+ALTER TABLE users ADD COLUMN active_reminders integer NOT NULL DEFAULT 0;
+
+CREATE TABLE reminders (
+    id serial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id),
+    reminder_text text NOT NULL,
+    remind_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    deleted_at timestamptz
+);
+
+CREATE INDEX reminders_user_id_remind_at_idx ON reminders (user_id, remind_at)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+-- Token buckets shared by all server replicas, see PostgresRepo.Take.
+CREATE TABLE rate_limit_buckets (
+    key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL
+);
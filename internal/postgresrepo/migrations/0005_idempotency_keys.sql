@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    caller text NOT NULL,
+    key text NOT NULL,
+    request_hash bytea NOT NULL,
+    done boolean NOT NULL DEFAULT false,
+    -- google.protobuf.Any with the response, or google.rpc.Status with the error.
+    response bytea,
+    status bytea,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (caller, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
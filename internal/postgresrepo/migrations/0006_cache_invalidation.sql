@@ -0,0 +1,28 @@
+-- Tells caches of every server replica which entries went stale,
+-- see postgresrepo.Listen and cachedrepo.CachedRepo.Invalidate.
+-- Payloads are "user:ID" and "reminders:USER_ID".
+CREATE FUNCTION notify_user_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('cache_invalidation', 'user:' || NEW.id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_cache_invalidation
+    AFTER INSERT OR UPDATE OF language_code, utc_offset ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_changed();
+
+CREATE FUNCTION notify_reminders_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('cache_invalidation', 'reminders:' || OLD.user_id);
+    ELSE
+        PERFORM pg_notify('cache_invalidation', 'reminders:' || NEW.user_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reminders_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON reminders
+    FOR EACH ROW EXECUTE FUNCTION notify_reminders_changed();
@@ -0,0 +1,67 @@
+-- Changes of users and reminders, streamed by WatchUserEvents.
+-- data holds the changed row: language_code and utc_offset for
+-- profile_changed, id, reminder_text and remind_at (microseconds since
+-- the Unix epoch) for reminder events.
+CREATE TABLE user_events (
+    seq bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    kind text NOT NULL,
+    data jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_events_user_id_seq_idx ON user_events (user_id, seq);
+CREATE INDEX user_events_created_at_idx ON user_events (created_at);
+
+-- Events of a user are inserted under a lock on the user held until commit,
+-- so they become visible in the order of their seq and a client which saw
+-- seq N never misses an event of that user with a smaller seq.
+CREATE FUNCTION insert_user_event(event_user_id bigint, event_kind text, event_data jsonb) RETURNS void AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(event_user_id);
+    INSERT INTO user_events (user_id, kind, data) VALUES (event_user_id, event_kind, event_data);
+    PERFORM pg_notify('user_events', event_user_id::text);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION record_user_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM insert_user_event(NEW.id, 'profile_changed', jsonb_build_object(
+        'language_code', NEW.language_code,
+        'utc_offset', NEW.utc_offset
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_user_events
+    AFTER INSERT OR UPDATE OF language_code, utc_offset ON users
+    FOR EACH ROW EXECUTE FUNCTION record_user_event();
+
+CREATE FUNCTION record_reminder_event() RETURNS trigger AS $$
+DECLARE
+    kind text;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'reminder_created';
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        kind := 'reminder_removed';
+    ELSIF NEW.reminder_text IS DISTINCT FROM OLD.reminder_text
+        OR NEW.remind_at IS DISTINCT FROM OLD.remind_at THEN
+        kind := 'reminder_updated';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    PERFORM insert_user_event(NEW.user_id, kind, jsonb_build_object(
+        'id', NEW.id,
+        'reminder_text', NEW.reminder_text,
+        'remind_at', (extract(epoch FROM NEW.remind_at) * 1000000)::bigint
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reminders_user_events
+    AFTER INSERT OR UPDATE ON reminders
+    FOR EACH ROW EXECUTE FUNCTION record_reminder_event();
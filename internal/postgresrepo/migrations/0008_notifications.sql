@@ -0,0 +1,76 @@
+-- Delivery of fired reminders, see package notify.
+-- notification_channels holds the protojson encoding of
+-- todoservice.NotificationChannels, NULL when the user set none.
+ALTER TABLE users ADD COLUMN notification_channels jsonb;
+
+-- Fired reminders stay listed until removed but no longer count as active.
+ALTER TABLE reminders ADD COLUMN fired_at timestamptz;
+
+CREATE INDEX reminders_due_idx ON reminders (remind_at)
+    WHERE deleted_at IS NULL AND fired_at IS NULL;
+
+-- One row per channel a fired reminder is sent to. Pending deliveries are
+-- attempted once next_attempt_at passes; a claimed one has next_attempt_at
+-- moved ahead, so it is retried if its sender dies.
+CREATE TABLE deliveries (
+    id bigserial PRIMARY KEY,
+    reminder_id integer NOT NULL REFERENCES reminders (id),
+    channel text NOT NULL,
+    address text NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX deliveries_reminder_id_idx ON deliveries (reminder_id);
+CREATE INDEX deliveries_pending_idx ON deliveries (next_attempt_at) WHERE status = 'pending';
+
+DROP TRIGGER users_cache_invalidation ON users;
+CREATE TRIGGER users_cache_invalidation
+    AFTER INSERT OR UPDATE OF language_code, utc_offset, notification_channels ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_changed();
+
+CREATE OR REPLACE FUNCTION record_user_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM insert_user_event(NEW.id, 'profile_changed', jsonb_build_object(
+        'language_code', NEW.language_code,
+        'utc_offset', NEW.utc_offset,
+        'notification_channels', NEW.notification_channels
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER users_user_events ON users;
+CREATE TRIGGER users_user_events
+    AFTER INSERT OR UPDATE OF language_code, utc_offset, notification_channels ON users
+    FOR EACH ROW EXECUTE FUNCTION record_user_event();
+
+CREATE OR REPLACE FUNCTION record_reminder_event() RETURNS trigger AS $$
+DECLARE
+    kind text;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'reminder_created';
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        kind := 'reminder_removed';
+    ELSIF NEW.fired_at IS NOT NULL AND OLD.fired_at IS NULL THEN
+        kind := 'reminder_fired';
+    ELSIF NEW.reminder_text IS DISTINCT FROM OLD.reminder_text
+        OR NEW.remind_at IS DISTINCT FROM OLD.remind_at THEN
+        kind := 'reminder_updated';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    PERFORM insert_user_event(NEW.user_id, kind, jsonb_build_object(
+        'id', NEW.id,
+        'reminder_text', NEW.reminder_text,
+        'remind_at', (extract(epoch FROM NEW.remind_at) * 1000000)::bigint
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,69 @@
+-- Webhooks registered by users, see package webhooks.
+CREATE TABLE webhooks (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id),
+    url text NOT NULL,
+    secret text NOT NULL,
+    -- Kinds of user_events sent, all of them when empty.
+    events text[] NOT NULL DEFAULT '{}',
+    enabled boolean NOT NULL DEFAULT true,
+    -- Failed attempts since the last successful one, the webhook is disabled
+    -- once there are too many.
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    disabled_reason text,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+-- Events waiting to be sent to a webhook. They are copied from user_events,
+-- which are purged after events.retention, and deleted once sent.
+CREATE TABLE webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_seq bigint NOT NULL,
+    event_kind text NOT NULL,
+    event_data jsonb NOT NULL,
+    event_created_at timestamptz NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text
+);
+
+CREATE INDEX webhook_deliveries_next_attempt_at_idx ON webhook_deliveries (next_attempt_at);
+
+-- Deliveries given up, until replayed by ReplayWebhookDeadLetter.
+CREATE TABLE webhook_dead_letters (
+    id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_seq bigint NOT NULL,
+    event_kind text NOT NULL,
+    event_data jsonb NOT NULL,
+    event_created_at timestamptz NOT NULL,
+    attempts integer NOT NULL,
+    last_error text NOT NULL,
+    failed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_dead_letters_webhook_id_idx ON webhook_dead_letters (webhook_id);
+
+-- Events are queued for the enabled webhooks of their user within the
+-- transaction recording them.
+CREATE OR REPLACE FUNCTION insert_user_event(event_user_id bigint, event_kind text, event_data jsonb) RETURNS void AS $$
+DECLARE
+    event user_events;
+BEGIN
+    PERFORM pg_advisory_xact_lock(event_user_id);
+    INSERT INTO user_events (user_id, kind, data) VALUES (event_user_id, event_kind, event_data)
+        RETURNING * INTO event;
+    PERFORM pg_notify('user_events', event_user_id::text);
+
+    INSERT INTO webhook_deliveries (webhook_id, event_seq, event_kind, event_data, event_created_at)
+    SELECT webhooks.id, event.seq, event.kind, event.data, event.created_at
+    FROM webhooks
+    WHERE webhooks.user_id = event_user_id
+        AND webhooks.enabled
+        AND (cardinality(webhooks.events) = 0 OR event_kind = ANY (webhooks.events));
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,7 @@
+-- Secrets of the HTTP hooks creating reminders, at most one per user.
+-- Only hashes are kept, like for api_keys.
+CREATE TABLE hook_tokens (
+    user_id bigint PRIMARY KEY REFERENCES users (id),
+    token_hash bytea NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
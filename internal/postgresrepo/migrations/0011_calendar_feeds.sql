@@ -0,0 +1,11 @@
+-- Secrets of the calendar feeds of users, at most one per user.
+-- Only hashes are kept, like for hook_tokens. etag and modified_at are of
+-- the content served last, so that polling calendar apps can be told it
+-- is unchanged.
+CREATE TABLE calendar_feeds (
+    user_id bigint PRIMARY KEY REFERENCES users (id),
+    secret_hash bytea NOT NULL UNIQUE,
+    etag text NOT NULL DEFAULT '',
+    modified_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
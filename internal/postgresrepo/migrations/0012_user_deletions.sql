@@ -0,0 +1,37 @@
+-- Deletions of users scheduled by DeleteUser, carried out by
+-- userdata.Deleter once delete_at passes unless cancelled before.
+CREATE TABLE user_deletions (
+    user_id bigint PRIMARY KEY REFERENCES users (id),
+    requested_at timestamptz NOT NULL DEFAULT now(),
+    delete_at timestamptz NOT NULL
+);
+
+CREATE INDEX user_deletions_delete_at_idx ON user_deletions (delete_at);
+
+-- Audit log of carried out deletions. It keeps how much was deleted but
+-- nothing identifying the user.
+CREATE TABLE user_deletion_audit (
+    id bigserial PRIMARY KEY,
+    requested_at timestamptz NOT NULL,
+    deleted_at timestamptz NOT NULL DEFAULT now(),
+    reminders bigint NOT NULL,
+    deliveries bigint NOT NULL,
+    webhooks bigint NOT NULL
+);
+
+-- Caches of every server replica drop deleted users too. Deleting
+-- reminders already tells them.
+CREATE OR REPLACE FUNCTION notify_user_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('cache_invalidation', 'user:' || OLD.id);
+    ELSE
+        PERFORM pg_notify('cache_invalidation', 'user:' || NEW.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_cache_invalidation_delete
+    AFTER DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_user_changed();
@@ -0,0 +1,18 @@
+-- Restores of backups, see package backup, by the hex SHA-256 of the
+-- backup. A restore without finished_at was interrupted and is resumed by
+-- running it again.
+CREATE TABLE restores (
+    checksum text PRIMARY KEY,
+    started_at timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz
+);
+
+-- The IDs reminders restored from a backup got. reminder_id references
+-- nothing, so that restored reminders may be purged and deleted with
+-- their users like any other.
+CREATE TABLE restored_reminders (
+    checksum text NOT NULL REFERENCES restores (checksum),
+    source_id integer NOT NULL,
+    reminder_id integer NOT NULL,
+    PRIMARY KEY (checksum, source_id)
+);
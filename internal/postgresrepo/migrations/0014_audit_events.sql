@@ -0,0 +1,31 @@
+-- Changes of users and reminders made through the API, see package audit,
+-- written in the transaction making them. before and after hold the
+-- changed user or reminder as JSON, NULL before it was created and after
+-- it was removed. reminder_id is NULL for changes of the user itself.
+CREATE TABLE audit_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    actor text NOT NULL,
+    request_id text NOT NULL,
+    method text NOT NULL,
+    user_id bigint NOT NULL,
+    reminder_id integer,
+    before jsonb,
+    after jsonb
+);
+
+CREATE INDEX audit_events_user_id_id_idx ON audit_events (user_id, id);
+CREATE INDEX audit_events_reminder_id_idx ON audit_events (reminder_id) WHERE reminder_id IS NOT NULL;
+CREATE INDEX audit_events_request_id_idx ON audit_events (request_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- Events are never changed. They are deleted only along with their user.
+CREATE FUNCTION reject_audit_event_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_event_update();
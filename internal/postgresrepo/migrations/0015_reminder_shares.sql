@@ -0,0 +1,17 @@
+-- Users and Telegram group chats reminders are shared with, exactly one of
+-- user_id and chat_id per share. Shares are invitations until accepted_at
+-- is set. role is the name of a pb.ReminderShare_Role.
+CREATE TABLE reminder_shares (
+    id bigserial PRIMARY KEY,
+    reminder_id integer NOT NULL REFERENCES reminders (id),
+    user_id bigint REFERENCES users (id),
+    chat_id bigint,
+    role text NOT NULL CHECK (role IN ('VIEWER', 'EDITOR')),
+    accepted_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((user_id IS NULL) <> (chat_id IS NULL))
+);
+
+CREATE UNIQUE INDEX reminder_shares_recipient_idx ON reminder_shares (reminder_id, (coalesce(user_id, 0)), (coalesce(chat_id, 0)));
+CREATE INDEX reminder_shares_user_id_idx ON reminder_shares (user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX reminder_shares_chat_id_idx ON reminder_shares (chat_id) WHERE chat_id IS NOT NULL;
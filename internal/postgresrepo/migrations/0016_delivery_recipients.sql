@@ -0,0 +1,5 @@
+-- Who a delivery was routed for: the owner of the reminder, or a user or
+-- group chat it is shared with. NULL for deliveries queued before.
+ALTER TABLE deliveries ADD COLUMN recipient_id bigint;
+
+CREATE INDEX deliveries_recipient_id_idx ON deliveries (recipient_id) WHERE recipient_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- When a bucket has refilled completely, so that it behaves exactly like a
+-- missing one and PostgresRepo.DeleteFullBuckets deletes it. The rate of
+-- existing buckets is unknown, they are kept for a day.
+ALTER TABLE rate_limit_buckets ADD COLUMN full_at timestamptz;
+UPDATE rate_limit_buckets SET full_at = updated_at + interval '1 day';
+ALTER TABLE rate_limit_buckets ALTER COLUMN full_at SET NOT NULL;
+
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
@@ -3,76 +3,105 @@ package postgresrepo
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 
 	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
 )
 
 type DbDriver interface {
-	// Query(ctx context.Context, sql string, optionsAndArgs ...interface{}) (pgx.Rows, error)
-	// QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, optionsAndArgs ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 }
 
 type PostgresRepo struct {
-	dbDriver DbDriver
+	dbDriver           DbDriver
+	maxActiveReminders int
+	txIsoLevel         pgx.TxIsoLevel
+	// inTx is set on repos passed to WithinTx callbacks.
+	inTx bool
 }
 
-func New(dbDriver DbDriver) *PostgresRepo {
-	return &PostgresRepo{dbDriver: dbDriver}
+type Option func(*PostgresRepo)
+
+// WithMaxActiveReminders limits the number of reminders a user may have
+// which were neither removed nor fired. Zero means no limit.
+func WithMaxActiveReminders(n int) Option {
+	return func(pr *PostgresRepo) {
+		pr.maxActiveReminders = n
+	}
 }
 
-func (pr PostgresRepo) SetUser(ctx context.Context, user *pb.User) error {
-	const (
-		queryEmptyUser = `INSERT INTO users (id)
-	VALUES ($1::bigint);
-	ON CONFLICT (id)
-	DO NOTHING;`
-
-		queryFullUser = `INSERT INTO users (id, language_code, utc_offset)
-	VALUES ($1::bigint, $2, $3);
-	ON CONFLICT (id)
-	DO UPDATE SET language_code = $2, utc_offset = $3;`
-
-		queryLanguageCode = `INSERT INTO users (id, language_code)
-	VALUES ($1::bigint, $2);
-	ON CONFLICT (id)
-	DO UPDATE SET language_code = $2;`
-
-		queryUtcOffset = `INSERT INTO users (id, utc_offset)
-	VALUES ($1::bigint, $2);
-	ON CONFLICT (id)
-	DO UPDATE SET utc_offset = $2;`
-	)
-
-	if user.GetLanguageCode() == nil && user.GetUtcOffset() == nil {
-		_, err := pr.dbDriver.Exec(ctx, queryEmptyUser, user.GetId())
+// WithTxIsoLevel sets the isolation level of transactions started by
+// WithinTx, e.g. pgx.Serializable.
+func WithTxIsoLevel(level pgx.TxIsoLevel) Option {
+	return func(pr *PostgresRepo) {
+		pr.txIsoLevel = level
+	}
+}
 
-		return err
+func New(dbDriver DbDriver, opts ...Option) *PostgresRepo {
+	pr := &PostgresRepo{dbDriver: dbDriver}
+	for _, opt := range opts {
+		opt(pr)
 	}
 
-	if user.GetLanguageCode() != nil && user.GetUtcOffset() != nil {
-		_, err := pr.dbDriver.Exec(
-			ctx, queryFullUser, user.GetId(),
-			user.GetLanguageCode().GetValue(), user.GetUtcOffset().GetValue(),
-		)
+	return pr
+}
 
-		return err
-	}
+// queries runs the generated queries on the driver of pr,
+// within the transaction of pr if any.
+func (pr PostgresRepo) queries() *db.Queries {
+	return db.New(pr.dbDriver)
+}
 
+func (pr PostgresRepo) SetUser(ctx context.Context, user *pb.User) error {
+	params := db.SetUserParams{ID: user.GetId()}
 	if user.GetLanguageCode() != nil {
-		_, err := pr.dbDriver.Exec(
-			ctx, queryLanguageCode, user.GetId(), user.GetLanguageCode().GetValue(),
-		)
+		params.LanguageCode = &user.LanguageCode.Value
+	}
+	if user.GetUtcOffset() != nil {
+		params.UtcOffset = &user.UtcOffset.Value
+	}
 
+	channels, err := encodeChannels(user.GetNotificationChannels())
+	if err != nil {
 		return err
 	}
+	params.NotificationChannels = channels
 
-	_, err := pr.dbDriver.Exec(
-		ctx, queryUtcOffset,
-		user.GetId(),
-		user.GetUtcOffset().GetValue(),
-	)
+	if _, ok := audit.FromContext(ctx); !ok {
+		return pr.queries().SetUser(ctx, params)
+	}
 
-	return err
+	// The audit event holds the user as it was before and is after the
+	// change, which fields passed as NULL keep.
+	return pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		var before *pb.User
+		locked, err := txRepo.queries().LockUser(ctx, user.GetId())
+		if err != nil {
+			return err
+		}
+		if locked > 0 {
+			if before, err = txRepo.GetUser(ctx, user.GetId()); err != nil {
+				return err
+			}
+		}
+
+		if err := txRepo.queries().SetUser(ctx, params); err != nil {
+			return err
+		}
+
+		after, err := txRepo.GetUser(ctx, user.GetId())
+		if err != nil {
+			return err
+		}
+
+		return txRepo.recordAudit(ctx, audit.Change{UserID: user.GetId(), Before: before, After: after})
+	})
 }
@@ -0,0 +1,489 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/notify/notifytest"
+	"github.com/awakair/awakair_todo_bot/internal/repotest"
+)
+
+// testPool connects to the database in TEST_DATABASE_URL and migrates it.
+// Tests using it are skipped when the variable is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("cannot connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := New(pool).Migrate(ctx); err != nil {
+		t.Fatalf("cannot migrate test database: %v", err)
+	}
+
+	return pool
+}
+
+func TestPostgresRepo(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.Run(t, func(t *testing.T, opts repotest.Options) todoserviceserver.Repo {
+		const query = `TRUNCATE users, reminders RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool, WithMaxActiveReminders(opts.MaxActiveReminders))
+	})
+}
+
+func TestPostgresRepo_notify(t *testing.T) {
+	pool := testPool(t)
+
+	notifytest.RunStore(t, func(t *testing.T, maxActiveReminders int) (notify.Store, todoserviceserver.Repo) {
+		const query = `TRUNCATE users, reminders, deliveries RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		repo := New(pool, WithMaxActiveReminders(maxActiveReminders))
+
+		return repo, repo
+	})
+}
+
+func TestPostgresRepo_admin(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunAdmin(t, func(t *testing.T, maxActiveReminders int) repotest.AdminStore {
+		const query = `TRUNCATE users, reminders, deliveries RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool, WithMaxActiveReminders(maxActiveReminders))
+	})
+}
+
+func TestPostgresRepo_hookTokens(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunHookTokens(t, func(t *testing.T) (todoserviceserver.HookTokenStore, todoserviceserver.Repo) {
+		const query = `TRUNCATE users, hook_tokens RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		repo := New(pool)
+
+		return repo, repo
+	})
+}
+
+func TestPostgresRepo_calendarFeeds(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunCalendarFeeds(t, func(t *testing.T) (todoserviceserver.CalendarFeedStore, todoserviceserver.Repo) {
+		const query = `TRUNCATE users, calendar_feeds RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		repo := New(pool)
+
+		return repo, repo
+	})
+}
+
+func TestPostgresRepo_userData(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunUserData(t, func(t *testing.T) repotest.UserDataStore {
+		const query = `TRUNCATE users, reminders, deliveries, hook_tokens, calendar_feeds, user_deletions,
+			user_deletion_audit RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool)
+	})
+}
+
+func TestPostgresRepo_backup(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunBackup(t, func(t *testing.T, maxActiveReminders int) repotest.BackupStore {
+		const query = `TRUNCATE users, reminders, deliveries, restores, restored_reminders RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool, WithMaxActiveReminders(maxActiveReminders))
+	})
+}
+
+func TestPostgresRepo_audit(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunAudit(t, func(t *testing.T) repotest.AuditStore {
+		const query = `TRUNCATE users, reminders, deliveries, user_deletions, user_deletion_audit, audit_events
+			RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool)
+	})
+}
+
+func TestPostgresRepo_shares(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunShares(t, func(t *testing.T) repotest.ShareStore {
+		const query = `TRUNCATE users, reminders, deliveries, reminder_shares, user_deletions, user_deletion_audit
+			RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool)
+	})
+}
+
+func TestPostgresRepo_webhooks(t *testing.T) {
+	pool := testPool(t)
+
+	repotest.RunWebhooks(t, func(t *testing.T) repotest.WebhookStore {
+		const query = `TRUNCATE users, reminders, deliveries, user_events, webhooks, webhook_deliveries, webhook_dead_letters
+			RESTART IDENTITY CASCADE`
+
+		if _, err := pool.Exec(context.Background(), query); err != nil {
+			t.Fatalf("cannot clean test database: %v", err)
+		}
+
+		return New(pool)
+	})
+}
+
+func TestPostgresRepo_DeleteFullBuckets(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx, `TRUNCATE rate_limit_buckets`); err != nil {
+		t.Fatalf("cannot clean test database: %v", err)
+	}
+
+	repo := New(pool)
+	for key, rate := range map[string]config.Rate{
+		"fast": {PerSecond: 1000, Burst: 1},
+		"slow": {PerSecond: 0.001, Burst: 1},
+	} {
+		if ok, _, err := repo.Take(ctx, key, rate); !ok || err != nil {
+			t.Fatalf("expected a token of %s got %v, %v", key, ok, err)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := repo.DeleteFullBuckets(ctx); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	rows, _ := pool.Query(ctx, `SELECT key FROM rate_limit_buckets`)
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || !slices.Equal(keys, []string{"slow"}) {
+		t.Errorf("expected only the slow bucket left got %v, %v", keys, err)
+	}
+}
+
+func newMock(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("cannot create mock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	return mock
+}
+
+func TestPostgresRepo_WithinTx(t *testing.T) {
+	ctx := context.Background()
+	const query = "UPDATE users SET active_reminders = 0"
+
+	exec := func(txRepo PostgresRepo) error {
+		_, err := txRepo.dbDriver.Exec(ctx, query)
+
+		return err
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable})
+		mock.ExpectExec(query).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		if err := New(mock, WithTxIsoLevel(pgx.Serializable)).WithinTx(ctx, exec); err != nil {
+			t.Errorf("did not expect error got %v", err)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec(query).WillReturnError(errors.New("disk is full"))
+		mock.ExpectRollback()
+
+		if err := New(mock).WithinTx(ctx, exec); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		mock := newMock(t)
+		for _, code := range []string{"40001", "40P01"} {
+			mock.ExpectBeginTx(pgx.TxOptions{})
+			mock.ExpectExec(query).WillReturnError(&pgconn.PgError{Code: code})
+			mock.ExpectRollback()
+		}
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec(query).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		if err := New(mock).WithinTx(ctx, exec); err != nil {
+			t.Errorf("did not expect error got %v", err)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		mock := newMock(t)
+		for i := 0; i < maxTxAttempts; i++ {
+			mock.ExpectBeginTx(pgx.TxOptions{})
+			mock.ExpectExec(query).WillReturnError(&pgconn.PgError{Code: "40001"})
+			mock.ExpectRollback()
+		}
+
+		// Canceled during the last attempt, WithinTx does not wait for
+		// another one.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		attempts := 0
+		err := New(mock).WithinTx(ctx, func(txRepo PostgresRepo) error {
+			if attempts++; attempts == maxTxAttempts {
+				cancel()
+			}
+
+			return exec(txRepo)
+		})
+		if !retryableTx(err) || attempts != maxTxAttempts {
+			t.Errorf("expected serialization failure after %d attempts got %v after %d", maxTxAttempts, err, attempts)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec(query).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(query).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		err := New(mock).WithinTx(ctx, func(txRepo PostgresRepo) error {
+			if err := exec(txRepo); err != nil {
+				return err
+			}
+
+			return txRepo.WithinTx(ctx, exec)
+		})
+		if err != nil {
+			t.Errorf("did not expect error got %v", err)
+		}
+	})
+}
+
+func TestPostgresRepo_CreateReminder(t *testing.T) {
+	ctx := context.Background()
+	reminder := &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: timestamppb.Now()}
+
+	t.Run("created", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectQuery("INSERT INTO reminders").
+			WithArgs("buy milk", reminder.GetRemindTimestamp().AsTime(), int64(1), int32(3)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(7)))
+		mock.ExpectCommit()
+
+		id, err := New(mock, WithMaxActiveReminders(3)).CreateReminder(ctx, reminder)
+		if err != nil || id != 7 {
+			t.Errorf("expected id 7 got %v, %v", id, err)
+		}
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectQuery("INSERT INTO reminders").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT language_code, utc_offset").
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"language_code", "utc_offset", "notification_channels"}).AddRow(nil, nil, nil))
+		mock.ExpectRollback()
+
+		_, err := New(mock, WithMaxActiveReminders(3)).CreateReminder(ctx, reminder)
+		if !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+			t.Errorf("expected ErrQuotaExceeded got %v", err)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mock := newMock(t)
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectQuery("INSERT INTO reminders").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT language_code, utc_offset").
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"language_code", "utc_offset", "notification_channels"}))
+		mock.ExpectRollback()
+
+		_, err := New(mock).CreateReminder(ctx, reminder)
+		if !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound got %v", err)
+		}
+	})
+}
+
+func TestPostgresRepo_SetUser(t *testing.T) {
+	languageCode := "en"
+	channels := &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+		{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"},
+	}}
+
+	for name, user := range map[string]*pb.User{
+		"empty":                 {Id: 1},
+		"language code":         {Id: 1, LanguageCode: wrapperspb.String(languageCode)},
+		"notification channels": {Id: 1, NotificationChannels: channels},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wantLanguageCode *string
+			if user.GetLanguageCode() != nil {
+				wantLanguageCode = &languageCode
+			}
+			var wantChannels []byte
+			if user.GetNotificationChannels() != nil {
+				wantChannels, _ = protojson.Marshal(channels)
+			}
+
+			mock := newMock(t)
+			mock.ExpectExec("INSERT INTO users").
+				WithArgs(int64(1), wantLanguageCode, (*int32)(nil), wantChannels).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+			if err := New(mock).SetUser(context.Background(), user); err != nil {
+				t.Errorf("did not expect error got %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_integration(t *testing.T) {
+	ctx := context.Background()
+	pool := testPool(t)
+	repo := New(pool)
+
+	t.Run("migrate twice", func(t *testing.T) {
+		if err := repo.Migrate(ctx); err != nil {
+			t.Errorf("expected applied migrations to be skipped got %v", err)
+		}
+	})
+
+	t.Run("user events", func(t *testing.T) {
+		const userId = -4242
+
+		_, latest, err := repo.UserEventSeqRange(ctx)
+		if err != nil {
+			t.Fatalf("cannot get event seqs: %v", err)
+		}
+
+		if err := repo.SetUser(ctx, &pb.User{Id: userId, LanguageCode: wrapperspb.String("en")}); err != nil {
+			t.Fatalf("cannot set user: %v", err)
+		}
+		id, err := repo.CreateReminder(ctx, &pb.Reminder{UserId: userId, ReminderText: "buy milk", RemindTimestamp: timestamppb.Now()})
+		if err != nil {
+			t.Fatalf("cannot create reminder: %v", err)
+		}
+		if err := repo.RemoveReminder(ctx, id); err != nil {
+			t.Fatalf("cannot remove reminder: %v", err)
+		}
+
+		events, err := repo.UserEvents(ctx, userId, latest, 10)
+		if err != nil {
+			t.Fatalf("cannot get events: %v", err)
+		}
+		if len(events) != 3 ||
+			events[0].GetProfileChanged().GetLanguageCode().GetValue() != "en" ||
+			events[1].GetReminderCreated().GetId() != id ||
+			events[2].GetReminderRemoved().GetReminderText() != "buy milk" {
+			t.Errorf("expected profile changed, reminder created and removed events got %+v", events)
+		}
+	})
+
+	t.Run("api keys", func(t *testing.T) {
+		hash, userId := []byte(t.Name()), int64(42)
+
+		id, err := repo.CreateAPIKey(ctx, "alice", &userId, hash)
+		if err != nil {
+			t.Fatalf("cannot create key: %v", err)
+		}
+
+		caller, err := repo.FindAPIKey(ctx, hash)
+		if err != nil || caller.Name != "alice" || caller.UserID == nil || *caller.UserID != userId {
+			t.Errorf("expected caller alice of user %d got %+v, %v", userId, caller, err)
+		}
+
+		if err := repo.RevokeAPIKey(ctx, id); err != nil {
+			t.Fatalf("cannot revoke key: %v", err)
+		}
+		if _, err := repo.FindAPIKey(ctx, hash); !errors.Is(err, auth.ErrKeyNotFound) {
+			t.Errorf("expected ErrKeyNotFound for revoked key got %v", err)
+		}
+	})
+}
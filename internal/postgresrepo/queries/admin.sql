@@ -0,0 +1,89 @@
+-- name: FindReminders :many
+SELECT id, user_id, reminder_text, remind_at, created_at, fired_at
+FROM reminders
+WHERE deleted_at IS NULL
+    AND (sqlc.narg(user_id)::bigint IS NULL OR user_id = sqlc.narg(user_id))
+    AND (sqlc.narg(text)::text IS NULL OR strpos(lower(reminder_text), lower(sqlc.narg(text))) > 0)
+    AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+    AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+    AND (NOT @pending::boolean OR fired_at IS NULL)
+ORDER BY id
+LIMIT sqlc.narg(max_reminders);
+
+-- name: CancelReminders :many
+-- Like RemoveReminder for many reminders at once.
+WITH removed AS (
+    UPDATE reminders
+    SET deleted_at = now()
+    WHERE reminders.id = ANY(@ids::integer[]) AND deleted_at IS NULL
+    RETURNING id, user_id, reminder_text, remind_at, fired_at
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - per_user.pending_count
+    FROM (
+        SELECT removed.user_id, count(*)::integer AS pending_count
+        FROM removed
+        WHERE removed.fired_at IS NULL
+        GROUP BY removed.user_id
+    ) AS per_user
+    WHERE users.id = per_user.user_id
+)
+SELECT id, user_id, reminder_text, remind_at
+FROM removed;
+
+-- name: FindFailedDeliveries :many
+SELECT deliveries.id, deliveries.reminder_id, reminders.user_id, deliveries.channel, deliveries.address,
+    deliveries.attempts, coalesce(deliveries.last_error, '')::text AS last_error, deliveries.updated_at
+FROM deliveries
+JOIN reminders ON reminders.id = deliveries.reminder_id
+WHERE deliveries.status = 'failed' AND reminders.deleted_at IS NULL
+    AND (sqlc.narg(user_id)::bigint IS NULL OR reminders.user_id = sqlc.narg(user_id))
+    AND (sqlc.narg(channel)::text IS NULL OR deliveries.channel = sqlc.narg(channel))
+    AND (sqlc.narg(failed_after)::timestamptz IS NULL OR deliveries.updated_at >= sqlc.narg(failed_after))
+ORDER BY deliveries.id
+LIMIT sqlc.narg(max_deliveries);
+
+-- name: RequeueDeliveries :execrows
+UPDATE deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+WHERE id = ANY(@ids::bigint[]) AND status = 'failed';
+
+-- name: CountRemovedReminders :one
+SELECT count(*)
+FROM reminders
+WHERE deleted_at < @before;
+
+-- name: PurgeRemovedDeliveries :exec
+DELETE FROM deliveries
+WHERE reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.deleted_at < @before
+);
+
+-- name: PurgeRemovedShares :exec
+DELETE FROM reminder_shares
+WHERE reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.deleted_at < @before
+);
+
+-- name: PurgeRemovedReminders :execrows
+-- Run after PurgeRemovedDeliveries and PurgeRemovedShares, in the same
+-- transaction.
+DELETE FROM reminders
+WHERE deleted_at < @before;
+
+-- name: GetStats :one
+SELECT
+    (SELECT count(*) FROM users) AS users,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NULL) AS pending_reminders,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NOT NULL) AS fired_reminders,
+    (SELECT count(*) FROM reminders WHERE deleted_at IS NOT NULL) AS removed_reminders,
+    pg_database_size(current_database()) AS size_bytes;
+
+-- name: CountDeliveriesByStatus :many
+SELECT status, count(*)
+FROM deliveries
+GROUP BY status;
@@ -0,0 +1,19 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (name, user_id, key_hash)
+VALUES (@name, sqlc.narg(user_id), @key_hash)
+RETURNING id;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = @id AND revoked_at IS NULL;
+
+-- name: ListAPIKeys :many
+SELECT id, name, user_id, created_at, revoked_at
+FROM api_keys
+ORDER BY id;
+
+-- name: FindAPIKey :one
+SELECT name, user_id
+FROM api_keys
+WHERE key_hash = @key_hash AND revoked_at IS NULL;
@@ -0,0 +1,17 @@
+-- name: CopyAuditEvents :copyfrom
+INSERT INTO audit_events (actor, request_id, method, user_id, reminder_id, before, after)
+VALUES (@actor, @request_id, @method, @user_id, @reminder_id, @before, @after);
+
+-- name: AuditEvents :many
+SELECT id, created_at, actor, request_id, method, user_id, coalesce(reminder_id, 0)::integer AS reminder_id, before, after
+FROM audit_events
+WHERE (sqlc.narg(user_id)::bigint IS NULL OR user_id = sqlc.narg(user_id))
+    AND (sqlc.narg(reminder_id)::integer IS NULL OR reminder_id = sqlc.narg(reminder_id))
+    AND (sqlc.narg(actor)::text IS NULL OR actor = sqlc.narg(actor))
+    AND (sqlc.narg(request_id)::text IS NULL OR request_id = sqlc.narg(request_id))
+    AND (sqlc.narg(method)::text IS NULL OR method = sqlc.narg(method))
+    AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+    AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+    AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.narg(max_events);
@@ -0,0 +1,55 @@
+-- name: BackupUsers :many
+SELECT id, language_code, utc_offset, notification_channels
+FROM users
+WHERE id > @after
+ORDER BY id
+LIMIT @max_users;
+
+-- name: BackupReminders :many
+SELECT id, user_id, reminder_text, remind_at, created_at, fired_at, deleted_at
+FROM reminders
+WHERE id > @after
+ORDER BY id
+LIMIT @max_reminders;
+
+-- name: IsRestoreStarted :one
+SELECT EXISTS (SELECT 1 FROM restores WHERE checksum = @checksum);
+
+-- name: HasUsers :one
+SELECT EXISTS (SELECT 1 FROM users);
+
+-- name: InsertRestore :exec
+INSERT INTO restores (checksum) VALUES (@checksum);
+
+-- name: RestoreUser :exec
+INSERT INTO users (id, language_code, utc_offset, notification_channels)
+VALUES (@id, sqlc.narg(language_code), sqlc.narg(utc_offset), sqlc.narg(notification_channels))
+ON CONFLICT (id) DO NOTHING;
+
+-- name: RestoreReminder :one
+-- Restored reminders which are neither fired nor removed count against
+-- the quota of their users like created ones, however many that makes.
+WITH restored AS (
+    INSERT INTO reminders (user_id, reminder_text, remind_at, created_at, fired_at, deleted_at)
+    VALUES (@user_id, @reminder_text, @remind_at, @created_at, sqlc.narg(fired_at), sqlc.narg(deleted_at))
+    RETURNING id, user_id, fired_at, deleted_at
+), recorded AS (
+    INSERT INTO restored_reminders (checksum, source_id, reminder_id)
+    SELECT @checksum, @source_id, restored.id FROM restored
+), counted AS (
+    UPDATE users
+    SET active_reminders = active_reminders + 1
+    FROM restored
+    WHERE users.id = restored.user_id AND restored.fired_at IS NULL AND restored.deleted_at IS NULL
+)
+SELECT id FROM restored;
+
+-- name: RestoredReminders :many
+SELECT source_id, reminder_id
+FROM restored_reminders
+WHERE checksum = @checksum;
+
+-- name: FinishRestore :execrows
+UPDATE restores
+SET finished_at = COALESCE(finished_at, now())
+WHERE checksum = @checksum;
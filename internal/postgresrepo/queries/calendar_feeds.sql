@@ -0,0 +1,31 @@
+-- name: CreateCalendarFeed :one
+WITH created AS (
+    INSERT INTO calendar_feeds (user_id, secret_hash)
+    SELECT users.id, @secret_hash
+    FROM users
+    WHERE users.id = @user_id
+    ON CONFLICT (user_id) DO NOTHING
+    RETURNING user_id
+)
+SELECT
+    EXISTS (SELECT 1 FROM users AS u WHERE u.id = @user_id) AS user_exists,
+    EXISTS (SELECT 1 FROM created) AS created;
+
+-- name: RotateCalendarFeed :execrows
+UPDATE calendar_feeds
+SET secret_hash = @secret_hash, created_at = now()
+WHERE user_id = @user_id;
+
+-- name: DeleteCalendarFeed :execrows
+DELETE FROM calendar_feeds
+WHERE user_id = @user_id;
+
+-- name: FindCalendarFeed :one
+SELECT user_id, etag, modified_at
+FROM calendar_feeds
+WHERE secret_hash = @secret_hash;
+
+-- name: SetCalendarFeedVersion :exec
+UPDATE calendar_feeds
+SET etag = @etag, modified_at = @modified_at
+WHERE user_id = @user_id;
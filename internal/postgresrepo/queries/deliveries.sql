@@ -0,0 +1,67 @@
+-- name: FireDueReminders :many
+-- Due reminders locked by another replica firing them are skipped.
+-- Fired reminders release their quota.
+WITH fired AS (
+    UPDATE reminders
+    SET fired_at = now()
+    WHERE reminders.id IN (
+        SELECT due.id
+        FROM reminders AS due
+        WHERE due.deleted_at IS NULL AND due.fired_at IS NULL AND due.remind_at <= @now
+        ORDER BY due.remind_at, due.id
+        LIMIT @max_reminders
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING reminders.id, reminders.user_id
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - per_user.fired_count
+    FROM (
+        SELECT fired.user_id, count(*)::integer AS fired_count
+        FROM fired
+        GROUP BY fired.user_id
+    ) AS per_user
+    WHERE users.id = per_user.user_id
+)
+SELECT fired.id, fired.user_id, users.notification_channels
+FROM fired
+JOIN users ON users.id = fired.user_id
+ORDER BY fired.id;
+
+-- name: CreateDelivery :exec
+INSERT INTO deliveries (reminder_id, recipient_id, channel, address, next_attempt_at)
+VALUES (@reminder_id, @recipient_id, @channel, @address, @next_attempt_at);
+
+-- name: ClaimDeliveries :many
+WITH claimed AS (
+    UPDATE deliveries
+    SET attempts = attempts + 1, next_attempt_at = @lease_until, updated_at = now()
+    WHERE deliveries.id IN (
+        SELECT due.id
+        FROM deliveries AS due
+        WHERE due.status = 'pending' AND due.next_attempt_at <= @now
+        ORDER BY due.next_attempt_at, due.id
+        LIMIT @max_deliveries
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING deliveries.id, deliveries.reminder_id, deliveries.channel, deliveries.address, deliveries.attempts
+)
+SELECT claimed.id, claimed.channel, claimed.address, claimed.attempts,
+    reminders.id AS reminder_id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+FROM claimed
+JOIN reminders ON reminders.id = claimed.reminder_id
+ORDER BY claimed.id;
+
+-- name: MarkDeliverySent :exec
+UPDATE deliveries
+SET status = 'sent', last_error = NULL, updated_at = now()
+WHERE id = @id;
+
+-- name: MarkDeliveryFailed :exec
+-- The delivery is given up when retry_at is NULL.
+UPDATE deliveries
+SET status = CASE WHEN sqlc.narg(retry_at)::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+    next_attempt_at = COALESCE(sqlc.narg(retry_at)::timestamptz, next_attempt_at),
+    last_error = @last_error,
+    updated_at = now()
+WHERE id = @id;
@@ -0,0 +1,16 @@
+-- name: SetHookToken :execrows
+INSERT INTO hook_tokens (user_id, token_hash)
+SELECT users.id, @token_hash
+FROM users
+WHERE users.id = @user_id
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = excluded.token_hash, created_at = now();
+
+-- name: DeleteHookToken :execrows
+DELETE FROM hook_tokens
+WHERE user_id = @user_id;
+
+-- name: FindHookToken :one
+SELECT user_id
+FROM hook_tokens
+WHERE token_hash = @token_hash;
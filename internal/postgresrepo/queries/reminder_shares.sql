@@ -0,0 +1,63 @@
+-- name: ShareReminder :one
+-- Returns no row when the reminder does not exist or was removed, fails
+-- with a foreign key violation when the user does not exist. Raising a
+-- viewer to an editor invites the recipient again.
+INSERT INTO reminder_shares (reminder_id, user_id, chat_id, role)
+SELECT reminders.id, sqlc.narg(user_id), sqlc.narg(chat_id), @role
+FROM reminders
+WHERE reminders.id = @reminder_id AND reminders.deleted_at IS NULL
+ON CONFLICT (reminder_id, (coalesce(user_id, 0)), (coalesce(chat_id, 0))) DO UPDATE
+SET role = excluded.role,
+    accepted_at = CASE
+        WHEN reminder_shares.role = 'VIEWER' AND excluded.role = 'EDITOR' THEN NULL
+        ELSE reminder_shares.accepted_at
+    END
+RETURNING reminder_id, user_id, chat_id, role, (accepted_at IS NOT NULL)::boolean AS accepted, created_at;
+
+-- name: AcceptReminderShare :execrows
+UPDATE reminder_shares
+SET accepted_at = coalesce(reminder_shares.accepted_at, now())
+FROM reminders
+WHERE reminders.id = reminder_shares.reminder_id AND reminders.deleted_at IS NULL
+    AND reminder_shares.reminder_id = @reminder_id
+    AND reminder_shares.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+    AND reminder_shares.chat_id IS NOT DISTINCT FROM sqlc.narg(chat_id);
+
+-- name: RemoveReminderShare :execrows
+DELETE FROM reminder_shares
+WHERE reminder_id = @reminder_id
+    AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+    AND chat_id IS NOT DISTINCT FROM sqlc.narg(chat_id);
+
+-- name: GetReminderShares :many
+SELECT reminder_id, user_id, chat_id, role, (accepted_at IS NOT NULL)::boolean AS accepted, created_at
+FROM reminder_shares
+WHERE reminder_id = @reminder_id
+ORDER BY id;
+
+-- name: GetReminderInvitations :many
+SELECT reminder_shares.reminder_id, reminder_shares.user_id, reminder_shares.chat_id, reminder_shares.role,
+    (reminder_shares.accepted_at IS NOT NULL)::boolean AS accepted, reminder_shares.created_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminder_shares.user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+    AND reminder_shares.chat_id IS NOT DISTINCT FROM sqlc.narg(chat_id)
+    AND reminder_shares.accepted_at IS NULL AND reminders.deleted_at IS NULL
+ORDER BY reminder_shares.id;
+
+-- name: GetSharedReminders :many
+SELECT reminders.id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminder_shares.user_id = @user_id::bigint AND reminder_shares.accepted_at IS NOT NULL AND reminders.deleted_at IS NULL
+ORDER BY reminders.remind_at, reminders.id;
+
+-- name: GetShareRecipients :many
+-- The recipients who accepted the reminders being shared with them, see
+-- FireDueReminders.
+SELECT reminder_shares.reminder_id, coalesce(reminder_shares.user_id, reminder_shares.chat_id)::bigint AS recipient_id,
+    users.notification_channels
+FROM reminder_shares
+LEFT JOIN users ON users.id = reminder_shares.user_id
+WHERE reminder_shares.reminder_id = ANY(@reminder_ids::integer[]) AND reminder_shares.accepted_at IS NOT NULL
+ORDER BY reminder_shares.id;
@@ -0,0 +1,54 @@
+-- name: CreateReminder :one
+-- The quota is checked and taken by a single row update, so concurrent
+-- inserts for the same user cannot exceed it. No row is returned when the
+-- user does not exist or has no quota left.
+WITH counted AS (
+    UPDATE users
+    SET active_reminders = active_reminders + 1
+    WHERE users.id = @user_id AND active_reminders < @max_active_reminders::integer
+    RETURNING users.id
+)
+INSERT INTO reminders (user_id, reminder_text, remind_at)
+SELECT id, @reminder_text::text, @remind_at::timestamptz FROM counted
+RETURNING id;
+
+-- name: GetReminder :one
+SELECT id, user_id, reminder_text, remind_at, created_at, deleted_at, fired_at
+FROM reminders
+WHERE id = @id AND deleted_at IS NULL;
+
+-- name: RemoveReminder :one
+-- Fired reminders no longer count as active, removing them frees nothing.
+WITH removed AS (
+    UPDATE reminders
+    SET deleted_at = now()
+    WHERE reminders.id = @id AND deleted_at IS NULL
+    RETURNING id, user_id, reminder_text, remind_at, fired_at
+), released AS (
+    UPDATE users
+    SET active_reminders = active_reminders - 1
+    FROM removed
+    WHERE users.id = removed.user_id AND removed.fired_at IS NULL
+)
+SELECT id, user_id, reminder_text, remind_at
+FROM removed;
+
+-- name: GetRemindersByUserId :many
+SELECT id, user_id, reminder_text, remind_at, created_at, deleted_at, fired_at
+FROM reminders
+WHERE user_id = @user_id AND deleted_at IS NULL
+ORDER BY remind_at, id;
+
+-- name: TakeQuota :execrows
+-- Takes n of the quota of a user at once, see CreateReminder.
+UPDATE users
+SET active_reminders = active_reminders + @n::integer
+WHERE id = @user_id AND active_reminders + @n::integer <= @max_active_reminders::integer;
+
+-- name: NextReminderIds :many
+-- Takes n ids for CopyReminders, which does not return the ids it inserts.
+SELECT nextval(pg_get_serial_sequence('reminders', 'id'))::integer AS id
+FROM generate_series(1, @n::integer);
+
+-- name: CopyReminders :copyfrom
+INSERT INTO reminders (id, user_id, reminder_text, remind_at) VALUES (@id, @user_id, @reminder_text, @remind_at);
@@ -0,0 +1,151 @@
+-- name: GetUserDataReminders :many
+SELECT id, reminder_text, remind_at, created_at, fired_at, deleted_at
+FROM reminders
+WHERE user_id = @user_id
+ORDER BY id;
+
+-- name: GetUserDataDeliveries :many
+SELECT
+    deliveries.id,
+    deliveries.reminder_id,
+    deliveries.channel,
+    deliveries.address,
+    deliveries.status,
+    deliveries.attempts,
+    deliveries.last_error,
+    deliveries.created_at,
+    deliveries.updated_at
+FROM deliveries
+JOIN reminders ON reminders.id = deliveries.reminder_id
+WHERE reminders.user_id = @user_id OR deliveries.recipient_id = @user_id
+ORDER BY deliveries.id;
+
+-- name: GetUserDataShares :many
+-- Shares of the reminders of the user and with the user.
+SELECT
+    reminder_shares.reminder_id,
+    reminders.user_id AS owner_id,
+    reminder_shares.user_id,
+    reminder_shares.chat_id,
+    reminder_shares.role,
+    reminder_shares.accepted_at,
+    reminder_shares.created_at
+FROM reminder_shares
+JOIN reminders ON reminders.id = reminder_shares.reminder_id
+WHERE reminders.user_id = @user_id OR reminder_shares.user_id = @user_id
+ORDER BY reminder_shares.id;
+
+-- name: GetUserDataWebhooks :many
+SELECT id, url, events, enabled, disabled_reason, created_at
+FROM webhooks
+WHERE user_id = @user_id
+ORDER BY id;
+
+-- name: GetUserDataAPIKeys :many
+SELECT name, created_at, revoked_at
+FROM api_keys
+WHERE user_id = @user_id::bigint
+ORDER BY id;
+
+-- name: GetUserDataSettings :one
+SELECT
+    hook_tokens.created_at AS hook_token_created_at,
+    calendar_feeds.created_at AS calendar_feed_created_at,
+    user_deletions.delete_at
+FROM users
+LEFT JOIN hook_tokens ON hook_tokens.user_id = users.id
+LEFT JOIN calendar_feeds ON calendar_feeds.user_id = users.id
+LEFT JOIN user_deletions ON user_deletions.user_id = users.id
+WHERE users.id = @user_id;
+
+-- name: ScheduleUserDeletion :one
+-- Returns no row when the user does not exist.
+WITH scheduled AS (
+    INSERT INTO user_deletions (user_id, delete_at)
+    SELECT users.id, @delete_at
+    FROM users
+    WHERE users.id = @user_id
+    ON CONFLICT (user_id) DO NOTHING
+    RETURNING delete_at
+)
+SELECT delete_at FROM scheduled
+UNION ALL
+SELECT d.delete_at FROM user_deletions AS d WHERE d.user_id = @user_id;
+
+-- name: CancelUserDeletion :execrows
+DELETE FROM user_deletions
+WHERE user_id = @user_id;
+
+-- name: DueUserDeletions :many
+SELECT user_id
+FROM user_deletions
+WHERE delete_at <= @now
+ORDER BY delete_at
+LIMIT @max_users;
+
+-- name: TakeDueUserDeletion :one
+-- Starts DeleteUser, which the other deletions follow in the same
+-- transaction.
+DELETE FROM user_deletions
+WHERE user_id = @user_id AND delete_at <= @now
+RETURNING requested_at;
+
+-- name: DeleteDeliveriesOfUser :execrows
+-- Deliveries of the reminders of the user and to the user of reminders
+-- shared with them.
+DELETE FROM deliveries
+WHERE deliveries.recipient_id = @user_id::bigint OR reminder_id IN (
+    SELECT reminders.id
+    FROM reminders
+    WHERE reminders.user_id = @user_id
+);
+
+-- name: DeleteSharesOfUser :exec
+-- Shares with the user and of their reminders.
+DELETE FROM reminder_shares
+WHERE reminder_shares.user_id = @user_id::bigint
+    OR reminder_shares.reminder_id IN (
+        SELECT reminders.id
+        FROM reminders
+        WHERE reminders.user_id = @user_id
+    );
+
+-- name: DeleteRemindersOfUser :execrows
+DELETE FROM reminders
+WHERE user_id = @user_id;
+
+-- name: DeleteWebhooksOfUser :execrows
+-- Their queued deliveries and dead letters cascade.
+DELETE FROM webhooks
+WHERE user_id = @user_id;
+
+-- name: DeleteEventsOfUser :exec
+DELETE FROM user_events
+WHERE user_id = @user_id;
+
+-- name: DeleteAuditEventsOfUser :exec
+DELETE FROM audit_events
+WHERE user_id = @user_id;
+
+-- name: DeleteSecretsOfUser :exec
+WITH hook_token AS (
+    DELETE FROM hook_tokens WHERE hook_tokens.user_id = @user_id::bigint
+), calendar_feed AS (
+    DELETE FROM calendar_feeds WHERE calendar_feeds.user_id = @user_id
+), idempotency_key AS (
+    DELETE FROM idempotency_keys
+    WHERE caller LIKE 'user:' || @user_id::bigint || ':%'
+), rate_limit_bucket AS (
+    DELETE FROM rate_limit_buckets
+    WHERE key LIKE 'user:' || @user_id::bigint || ':%'
+)
+DELETE FROM api_keys
+WHERE api_keys.user_id = @user_id::bigint;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = @user_id;
+
+-- name: InsertUserDeletionAudit :exec
+INSERT INTO user_deletion_audit (requested_at, reminders, deliveries, webhooks)
+VALUES (@requested_at, @reminders, @deliveries, @webhooks);
@@ -0,0 +1,16 @@
+-- name: UserEvents :many
+SELECT seq, user_id, kind, data, created_at
+FROM user_events
+WHERE user_id = @user_id AND seq > @after_seq
+ORDER BY seq
+LIMIT @max_events;
+
+-- name: UserEventSeqRange :one
+SELECT coalesce(min(seq), 0)::bigint AS oldest, coalesce(max(seq), 0)::bigint AS latest
+FROM user_events;
+
+-- name: DeleteUserEvents :execrows
+-- The latest event is kept to tell how far clients may resume from.
+DELETE FROM user_events
+WHERE user_events.created_at < @created_before
+    AND user_events.seq < (SELECT max(seq) FROM user_events);
@@ -0,0 +1,20 @@
+-- name: SetUser :exec
+-- Fields passed as NULL keep their stored values.
+INSERT INTO users (id, language_code, utc_offset, notification_channels)
+VALUES (@id, sqlc.narg(language_code), sqlc.narg(utc_offset), sqlc.narg(notification_channels))
+ON CONFLICT (id) DO UPDATE
+SET language_code = COALESCE(excluded.language_code, users.language_code),
+    utc_offset = COALESCE(excluded.utc_offset, users.utc_offset),
+    notification_channels = COALESCE(excluded.notification_channels, users.notification_channels);
+
+-- name: GetUser :one
+SELECT language_code, utc_offset, notification_channels
+FROM users
+WHERE id = @id;
+
+-- name: LockUser :execrows
+-- Keeps the user from changing until the end of the transaction.
+SELECT 1
+FROM users
+WHERE id = @id
+FOR UPDATE;
@@ -0,0 +1,128 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, secret, events)
+VALUES (@user_id, @url, @secret, @events::text[])
+RETURNING id;
+
+-- name: GetWebhook :one
+SELECT id, user_id, url, events, enabled, disabled_reason
+FROM webhooks
+WHERE id = @id;
+
+-- name: GetWebhooksByUserId :many
+SELECT id, user_id, url, events, enabled, disabled_reason
+FROM webhooks
+WHERE user_id = @user_id
+ORDER BY id;
+
+-- name: UpdateWebhook :execrows
+-- Enabling a webhook forgets its failures and why it was disabled. A null
+-- enabled leaves the webhook as it is.
+UPDATE webhooks
+SET url = @url,
+    events = @events::text[],
+    enabled = coalesce(sqlc.narg(enabled)::boolean, enabled),
+    consecutive_failures = CASE WHEN sqlc.narg(enabled)::boolean THEN 0 ELSE consecutive_failures END,
+    disabled_reason = CASE WHEN sqlc.narg(enabled)::boolean THEN NULL ELSE disabled_reason END,
+    updated_at = now()
+WHERE id = @id;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = @id;
+
+-- name: ClaimWebhookDeliveries :many
+-- Deliveries of disabled webhooks wait until they are enabled again.
+WITH claimed AS (
+    UPDATE webhook_deliveries
+    SET attempts = attempts + 1, next_attempt_at = @lease_until
+    WHERE webhook_deliveries.id IN (
+        SELECT due.id
+        FROM webhook_deliveries AS due
+        JOIN webhooks ON webhooks.id = due.webhook_id
+        WHERE webhooks.enabled AND due.next_attempt_at <= @now
+        ORDER BY due.next_attempt_at, due.id
+        LIMIT @max_deliveries
+        FOR UPDATE OF due SKIP LOCKED
+    )
+    RETURNING webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event_seq,
+        webhook_deliveries.event_kind, webhook_deliveries.event_data, webhook_deliveries.event_created_at,
+        webhook_deliveries.attempts
+)
+SELECT claimed.id, claimed.webhook_id, claimed.event_seq, claimed.event_kind, claimed.event_data,
+    claimed.event_created_at, claimed.attempts, webhooks.user_id, webhooks.url, webhooks.secret
+FROM claimed
+JOIN webhooks ON webhooks.id = claimed.webhook_id
+ORDER BY claimed.id;
+
+-- name: WebhookDelivered :exec
+WITH delivered AS (
+    DELETE FROM webhook_deliveries
+    WHERE webhook_deliveries.id = @id
+    RETURNING webhook_deliveries.webhook_id
+)
+UPDATE webhooks
+SET consecutive_failures = 0
+FROM delivered
+WHERE webhooks.id = delivered.webhook_id AND webhooks.consecutive_failures <> 0;
+
+-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries
+SET next_attempt_at = @retry_at, last_error = @last_error::text
+WHERE id = @id
+RETURNING webhook_id;
+
+-- name: DeadLetterWebhookDelivery :one
+WITH failed AS (
+    DELETE FROM webhook_deliveries
+    WHERE webhook_deliveries.id = @id
+    RETURNING webhook_deliveries.webhook_id, webhook_deliveries.event_seq, webhook_deliveries.event_kind,
+        webhook_deliveries.event_data, webhook_deliveries.event_created_at, webhook_deliveries.attempts
+)
+INSERT INTO webhook_dead_letters (webhook_id, event_seq, event_kind, event_data, event_created_at, attempts, last_error)
+SELECT failed.webhook_id, failed.event_seq, failed.event_kind, failed.event_data, failed.event_created_at,
+    failed.attempts, @last_error::text
+FROM failed
+RETURNING webhook_id;
+
+-- name: CountWebhookFailure :exec
+-- The webhook is disabled with disabled_reason by its disable_after-th
+-- failure in a row.
+UPDATE webhooks
+SET consecutive_failures = consecutive_failures + 1,
+    enabled = enabled AND consecutive_failures + 1 < @disable_after::integer,
+    disabled_reason = CASE
+        WHEN enabled AND consecutive_failures + 1 >= @disable_after::integer THEN @disabled_reason::text
+        ELSE disabled_reason
+    END,
+    updated_at = now()
+WHERE id = @id;
+
+-- name: GetWebhookDeadLetter :one
+SELECT webhook_dead_letters.id, webhook_dead_letters.webhook_id, webhooks.user_id,
+    webhook_dead_letters.event_seq, webhook_dead_letters.event_kind, webhook_dead_letters.event_data,
+    webhook_dead_letters.event_created_at, webhook_dead_letters.attempts, webhook_dead_letters.last_error,
+    webhook_dead_letters.failed_at
+FROM webhook_dead_letters
+JOIN webhooks ON webhooks.id = webhook_dead_letters.webhook_id
+WHERE webhook_dead_letters.id = @id;
+
+-- name: GetWebhookDeadLettersByUserId :many
+SELECT webhook_dead_letters.id, webhook_dead_letters.webhook_id, webhooks.user_id,
+    webhook_dead_letters.event_seq, webhook_dead_letters.event_kind, webhook_dead_letters.event_data,
+    webhook_dead_letters.event_created_at, webhook_dead_letters.attempts, webhook_dead_letters.last_error,
+    webhook_dead_letters.failed_at
+FROM webhook_dead_letters
+JOIN webhooks ON webhooks.id = webhook_dead_letters.webhook_id
+WHERE webhooks.user_id = @user_id
+ORDER BY webhook_dead_letters.id;
+
+-- name: ReplayWebhookDeadLetter :execrows
+WITH replayed AS (
+    DELETE FROM webhook_dead_letters
+    WHERE webhook_dead_letters.id = @id
+    RETURNING webhook_dead_letters.webhook_id, webhook_dead_letters.event_seq, webhook_dead_letters.event_kind,
+        webhook_dead_letters.event_data, webhook_dead_letters.event_created_at
+)
+INSERT INTO webhook_deliveries (webhook_id, event_seq, event_kind, event_data, event_created_at)
+SELECT replayed.webhook_id, replayed.event_seq, replayed.event_kind, replayed.event_data, replayed.event_created_at
+FROM replayed;
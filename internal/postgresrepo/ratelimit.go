@@ -0,0 +1,56 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// Take implements ratelimit.Store with buckets shared by all replicas.
+func (pr PostgresRepo) Take(ctx context.Context, key string, rate config.Rate) (bool, time.Duration, error) {
+	const (
+		queryTake = `INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, full_at)
+	VALUES ($1, $3::double precision - 1, now(), now() + make_interval(secs => 1 / $2::double precision))
+	ON CONFLICT (key) DO UPDATE
+	SET tokens = LEAST($3, b.tokens + extract(epoch FROM now() - b.updated_at)::double precision * $2::double precision) - 1,
+		updated_at = now(),
+		full_at = now() + make_interval(secs => ($3 - LEAST($3, b.tokens + extract(epoch FROM now() - b.updated_at)::double precision * $2) + 1) / $2)
+	WHERE LEAST($3, b.tokens + extract(epoch FROM now() - b.updated_at)::double precision * $2) >= 1
+	RETURNING tokens`
+
+		queryTokens = `SELECT LEAST($3, tokens + extract(epoch FROM now() - updated_at)::double precision * $2::double precision)
+	FROM rate_limit_buckets
+	WHERE key = $1`
+	)
+
+	perSecond, burst := rate.PerSecond, float64(rate.Burst)
+
+	var tokens float64
+	err := pr.dbDriver.QueryRow(ctx, queryTake, key, perSecond, burst).Scan(&tokens)
+	if err == nil {
+		return true, 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, 0, err
+	}
+
+	if err := pr.dbDriver.QueryRow(ctx, queryTokens, key, perSecond, burst).Scan(&tokens); err != nil {
+		return false, 0, err
+	}
+
+	return false, time.Duration((1 - tokens) / perSecond * float64(time.Second)), nil
+}
+
+// DeleteFullBuckets implements ratelimit.Purger.
+func (pr PostgresRepo) DeleteFullBuckets(ctx context.Context) error {
+	const query = `DELETE FROM rate_limit_buckets
+	WHERE full_at <= now()`
+
+	_, err := pr.dbDriver.Exec(ctx, query)
+
+	return err
+}
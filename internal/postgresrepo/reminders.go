@@ -0,0 +1,196 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+func (pr PostgresRepo) GetUser(ctx context.Context, id int64) (*pb.User, error) {
+	row, err := pr.queries().GetUser(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := &pb.User{Id: id}
+	if row.LanguageCode != nil {
+		user.LanguageCode = wrapperspb.String(*row.LanguageCode)
+	}
+	if row.UtcOffset != nil {
+		user.UtcOffset = wrapperspb.Int32(*row.UtcOffset)
+	}
+
+	user.NotificationChannels, err = decodeChannels(row.NotificationChannels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode channels of user %d: %w", id, err)
+	}
+
+	return user, nil
+}
+
+func (pr PostgresRepo) CreateReminder(ctx context.Context, reminder *pb.Reminder) (int32, error) {
+	limit := pr.maxActiveReminders
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	// Telling a missing user from an exhausted quota takes a second query,
+	// which must see the same users row.
+	var id int32
+	err := pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		var err error
+		id, err = txRepo.queries().CreateReminder(ctx, db.CreateReminderParams{
+			UserID:             reminder.GetUserId(),
+			ReminderText:       reminder.GetReminderText(),
+			RemindAt:           reminder.GetRemindTimestamp().AsTime(),
+			MaxActiveReminders: int32(limit),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := txRepo.GetUser(ctx, reminder.GetUserId()); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("user %d already has %d reminders: %w", reminder.GetUserId(), limit, todoserviceserver.ErrQuotaExceeded)
+		}
+		if err != nil {
+			return err
+		}
+
+		return txRepo.recordAudit(ctx, createdReminder(id, reminder.GetUserId(), reminder))
+	})
+
+	return id, err
+}
+
+// createdReminder is the audit.Change of creating reminder with id.
+func createdReminder(id int32, userId int64, reminder *pb.Reminder) audit.Change {
+	after := &pb.Reminder{
+		Id:              id,
+		UserId:          userId,
+		ReminderText:    reminder.GetReminderText(),
+		RemindTimestamp: reminder.GetRemindTimestamp(),
+	}
+
+	return audit.Change{UserID: userId, ReminderID: id, After: after}
+}
+
+func (pr PostgresRepo) CreateReminders(ctx context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error) {
+	limit := pr.maxActiveReminders
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	var ids []int32
+	err := pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		q := txRepo.queries()
+
+		taken, err := q.TakeQuota(ctx, db.TakeQuotaParams{N: int32(len(reminders)), UserID: userId, MaxActiveReminders: int32(limit)})
+		if err != nil {
+			return err
+		}
+		if taken == 0 {
+			if _, err := txRepo.GetUser(ctx, userId); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("user %d has no room for %d more reminders: %w", userId, len(reminders), todoserviceserver.ErrQuotaExceeded)
+		}
+
+		if ids, err = q.NextReminderIds(ctx, int32(len(reminders))); err != nil {
+			return err
+		}
+
+		rows := make([]db.CopyRemindersParams, 0, len(reminders))
+		changes := make([]audit.Change, 0, len(reminders))
+		for i, reminder := range reminders {
+			rows = append(rows, db.CopyRemindersParams{
+				ID:           ids[i],
+				UserID:       userId,
+				ReminderText: reminder.GetReminderText(),
+				RemindAt:     reminder.GetRemindTimestamp().AsTime(),
+			})
+			changes = append(changes, createdReminder(ids[i], userId, reminder))
+		}
+
+		if _, err := q.CopyReminders(ctx, rows); err != nil {
+			return err
+		}
+
+		return txRepo.recordAudit(ctx, changes...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (pr PostgresRepo) GetReminder(ctx context.Context, id int32) (*pb.Reminder, error) {
+	row, err := pr.queries().GetReminder(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reminderFromRow(row), nil
+}
+
+func (pr PostgresRepo) RemoveReminder(ctx context.Context, id int32) error {
+	return pr.audited(ctx, func(txRepo PostgresRepo) error {
+		row, err := txRepo.queries().RemoveReminder(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		return txRepo.recordAudit(ctx, removedReminder(row.ID, row.UserID, row.ReminderText, row.RemindAt))
+	})
+}
+
+// removedReminder is the audit.Change of removing a reminder.
+func removedReminder(id int32, userId int64, text string, remindAt time.Time) audit.Change {
+	before := &pb.Reminder{Id: id, UserId: userId, ReminderText: text, RemindTimestamp: timestamppb.New(remindAt)}
+
+	return audit.Change{UserID: userId, ReminderID: id, Before: before}
+}
+
+func (pr PostgresRepo) GetRemindersByUserId(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	rows, err := pr.queries().GetRemindersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := make([]*pb.Reminder, 0, len(rows))
+	for _, row := range rows {
+		reminders = append(reminders, reminderFromRow(row))
+	}
+
+	return reminders, nil
+}
+
+func reminderFromRow(row db.Reminder) *pb.Reminder {
+	return &pb.Reminder{
+		Id:              row.ID,
+		UserId:          row.UserID,
+		ReminderText:    row.ReminderText,
+		RemindTimestamp: timestamppb.New(row.RemindAt),
+	}
+}
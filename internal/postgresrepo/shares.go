@@ -0,0 +1,174 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+)
+
+// recipientIds returns the user_id and chat_id columns of recipient.
+func recipientIds(recipient *pb.ShareRecipient) (userId, chatId *int64) {
+	switch recipient := recipient.GetRecipient().(type) {
+	case *pb.ShareRecipient_UserId:
+		return &recipient.UserId, nil
+	case *pb.ShareRecipient_ChatId:
+		return nil, &recipient.ChatId
+	default:
+		return nil, nil
+	}
+}
+
+func shareFromRow(row db.GetReminderSharesRow) *pb.ReminderShare {
+	share := &pb.ReminderShare{
+		ReminderId: row.ReminderID,
+		Role:       pb.ReminderShare_Role(pb.ReminderShare_Role_value[row.Role]),
+		Accepted:   row.Accepted,
+		CreatedAt:  timestamppb.New(row.CreatedAt),
+	}
+	if row.UserID != nil {
+		share.Recipient = &pb.ShareRecipient{Recipient: &pb.ShareRecipient_UserId{UserId: *row.UserID}}
+	} else if row.ChatID != nil {
+		share.Recipient = &pb.ShareRecipient{Recipient: &pb.ShareRecipient_ChatId{ChatId: *row.ChatID}}
+	}
+
+	return share
+}
+
+func (pr PostgresRepo) ShareReminder(ctx context.Context, share *pb.ReminderShare) (*pb.ReminderShare, error) {
+	userId, chatId := recipientIds(share.GetRecipient())
+	row, err := pr.queries().ShareReminder(ctx, db.ShareReminderParams{
+		UserID:     userId,
+		ChatID:     chatId,
+		Role:       share.GetRole().String(),
+		ReminderID: share.GetReminderId(),
+	})
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("reminder %d: %w", share.GetReminderId(), todoserviceserver.ErrNotFound)
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return nil, fmt.Errorf("user %d: %w", share.GetRecipient().GetUserId(), todoserviceserver.ErrNotFound)
+	case err != nil:
+		return nil, err
+	}
+
+	return shareFromRow(db.GetReminderSharesRow(row)), nil
+}
+
+func (pr PostgresRepo) AcceptReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	userId, chatId := recipientIds(recipient)
+	accepted, err := pr.queries().AcceptReminderShare(ctx, db.AcceptReminderShareParams{
+		ReminderID: reminderId,
+		UserID:     userId,
+		ChatID:     chatId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if accepted == 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) RemoveReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	userId, chatId := recipientIds(recipient)
+	removed, err := pr.queries().RemoveReminderShare(ctx, db.RemoveReminderShareParams{
+		ReminderID: reminderId,
+		UserID:     userId,
+		ChatID:     chatId,
+	})
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) GetReminderShares(ctx context.Context, reminderId int32) ([]*pb.ReminderShare, error) {
+	rows, err := pr.queries().GetReminderShares(ctx, reminderId)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*pb.ReminderShare, 0, len(rows))
+	for _, row := range rows {
+		shares = append(shares, shareFromRow(row))
+	}
+
+	return shares, nil
+}
+
+func (pr PostgresRepo) GetReminderInvitations(ctx context.Context, recipient *pb.ShareRecipient) ([]*pb.ReminderShare, error) {
+	userId, chatId := recipientIds(recipient)
+	rows, err := pr.queries().GetReminderInvitations(ctx, db.GetReminderInvitationsParams{UserID: userId, ChatID: chatId})
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*pb.ReminderShare, 0, len(rows))
+	for _, row := range rows {
+		shares = append(shares, shareFromRow(db.GetReminderSharesRow(row)))
+	}
+
+	return shares, nil
+}
+
+func (pr PostgresRepo) GetSharedReminders(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	rows, err := pr.queries().GetSharedReminders(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := make([]*pb.Reminder, 0, len(rows))
+	for _, row := range rows {
+		reminders = append(reminders, &pb.Reminder{
+			Id:              row.ID,
+			UserId:          row.UserID,
+			ReminderText:    row.ReminderText,
+			RemindTimestamp: timestamppb.New(row.RemindAt),
+		})
+	}
+
+	return reminders, nil
+}
+
+// shareRecipients returns the recipients who accepted the reminders being
+// shared with them, by reminder.
+func (pr PostgresRepo) shareRecipients(ctx context.Context, reminderIds []int32) (map[int32][]notify.Recipient, error) {
+	rows, err := pr.queries().GetShareRecipients(ctx, reminderIds)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[int32][]notify.Recipient)
+	for _, row := range rows {
+		channels, err := decodeChannels(row.NotificationChannels)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode channels of user %d: %w", row.RecipientID, err)
+		}
+
+		recipients[row.ReminderID] = append(recipients[row.ReminderID], notify.Recipient{
+			ID:       row.RecipientID,
+			Channels: channels.GetChannels(),
+		})
+	}
+
+	return recipients, nil
+}
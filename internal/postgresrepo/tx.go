@@ -0,0 +1,92 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// maxTxAttempts is how many times WithinTx runs a transaction
+	// which keeps failing with a retryable error.
+	maxTxAttempts = 5
+	txRetryDelay  = 10 * time.Millisecond
+)
+
+// txDriver runs the statements of a repo within a transaction.
+type txDriver struct {
+	pgx.Tx
+}
+
+func (td txDriver) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return td.Tx.Begin(ctx)
+}
+
+// retryableTx reports whether err aborted a transaction which may succeed
+// when run again: a serialization failure or a deadlock.
+func retryableTx(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// WithinTx calls fn with a repo whose methods run in one transaction,
+// committed when fn returns nil and rolled back otherwise. Its isolation
+// level is the database default unless set with WithTxIsoLevel.
+// Transactions failing on serialization or deadlocks are retried with fn
+// called again, so fn must not have side effects outside of the repo.
+//
+// Calling WithinTx on a repo passed to fn joins the outer transaction.
+func (pr PostgresRepo) WithinTx(ctx context.Context, fn func(txRepo PostgresRepo) error) error {
+	if pr.inTx {
+		return fn(pr)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := pr.runTx(ctx, fn)
+		if !retryableTx(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			return fmt.Errorf("transaction failed %d times: %w", maxTxAttempts, err)
+		}
+
+		log.Printf("Retrying transaction after attempt %d: %v", attempt, err)
+
+		delay := txRetryDelay<<(attempt-1) + rand.N(txRetryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (pr PostgresRepo) runTx(ctx context.Context, fn func(txRepo PostgresRepo) error) error {
+	tx, err := pr.dbDriver.BeginTx(ctx, pgx.TxOptions{IsoLevel: pr.txIsoLevel})
+	if err != nil {
+		return err
+	}
+	txRepo := pr
+	txRepo.dbDriver = txDriver{tx}
+	txRepo.inTx = true
+
+	if err := fn(txRepo); err != nil {
+		if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+			log.Printf("Error rolling back transaction: %v", rollbackErr)
+		}
+
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
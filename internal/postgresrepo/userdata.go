@@ -0,0 +1,207 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+func (pr PostgresRepo) GetUserData(ctx context.Context, userId int64) (data *userdata.Data, err error) {
+	err = pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		data, err = txRepo.getUserData(ctx, userId)
+
+		return err
+	})
+
+	return data, err
+}
+
+func (pr PostgresRepo) getUserData(ctx context.Context, userId int64) (*userdata.Data, error) {
+	user, err := pr.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data := &userdata.Data{Profile: userdata.ProfileOf(user)}
+
+	reminders, err := pr.queries().GetUserDataReminders(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Reminders = make([]userdata.Reminder, 0, len(reminders))
+	for _, row := range reminders {
+		data.Reminders = append(data.Reminders, userdata.Reminder{
+			ID:        row.ID,
+			Text:      row.ReminderText,
+			RemindAt:  row.RemindAt,
+			CreatedAt: row.CreatedAt,
+			FiredAt:   row.FiredAt,
+			RemovedAt: row.DeletedAt,
+		})
+	}
+
+	deliveries, err := pr.queries().GetUserDataDeliveries(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Deliveries = make([]userdata.Delivery, 0, len(deliveries))
+	for _, row := range deliveries {
+		delivery := userdata.Delivery{
+			ID:         row.ID,
+			ReminderID: row.ReminderID,
+			Channel:    row.Channel,
+			Address:    row.Address,
+			Status:     row.Status,
+			Attempts:   row.Attempts,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+		}
+		if row.LastError != nil {
+			delivery.LastError = *row.LastError
+		}
+		data.Deliveries = append(data.Deliveries, delivery)
+	}
+
+	shares, err := pr.queries().GetUserDataShares(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Shares = make([]userdata.Share, 0, len(shares))
+	for _, row := range shares {
+		data.Shares = append(data.Shares, userdata.Share{
+			ReminderID: row.ReminderID,
+			OwnerID:    row.OwnerID,
+			UserID:     row.UserID,
+			ChatID:     row.ChatID,
+			Role:       row.Role,
+			AcceptedAt: row.AcceptedAt,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+
+	webhooks, err := pr.queries().GetUserDataWebhooks(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Settings.Webhooks = make([]userdata.Webhook, 0, len(webhooks))
+	for _, row := range webhooks {
+		webhook := userdata.Webhook{
+			ID:        row.ID,
+			URL:       row.URL,
+			Events:    row.Events,
+			Enabled:   row.Enabled,
+			CreatedAt: row.CreatedAt,
+		}
+		if row.DisabledReason != nil {
+			webhook.DisabledReason = *row.DisabledReason
+		}
+		data.Settings.Webhooks = append(data.Settings.Webhooks, webhook)
+	}
+
+	keys, err := pr.queries().GetUserDataAPIKeys(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Settings.APIKeys = make([]userdata.APIKey, 0, len(keys))
+	for _, row := range keys {
+		data.Settings.APIKeys = append(data.Settings.APIKeys, userdata.APIKey{
+			Name:      row.Name,
+			CreatedAt: row.CreatedAt,
+			RevokedAt: row.RevokedAt,
+		})
+	}
+
+	settings, err := pr.queries().GetUserDataSettings(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Settings.HookTokenCreatedAt = settings.HookTokenCreatedAt
+	data.Settings.CalendarFeedCreatedAt = settings.CalendarFeedCreatedAt
+	data.Settings.DeleteAt = settings.DeleteAt
+
+	return data, nil
+}
+
+func (pr PostgresRepo) ScheduleUserDeletion(ctx context.Context, userId int64, deleteAt time.Time) (time.Time, error) {
+	scheduled, err := pr.queries().ScheduleUserDeletion(ctx, db.ScheduleUserDeletionParams{UserID: userId, DeleteAt: deleteAt})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return scheduled, err
+}
+
+func (pr PostgresRepo) CancelUserDeletion(ctx context.Context, userId int64) error {
+	cancelled, err := pr.queries().CancelUserDeletion(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if cancelled == 0 {
+		return fmt.Errorf("deletion of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) DueUserDeletions(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	return pr.queries().DueUserDeletions(ctx, db.DueUserDeletionsParams{Now: now, MaxUsers: int32(limit)})
+}
+
+func (pr PostgresRepo) DeleteUser(ctx context.Context, userId int64, now time.Time) (deletion userdata.Deletion, ok bool, err error) {
+	err = pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		q := txRepo.queries()
+		deletion, ok = userdata.Deletion{}, false
+
+		requestedAt, err := q.TakeDueUserDeletion(ctx, db.TakeDueUserDeletionParams{UserID: userId, Now: now})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		deletion.RequestedAt = requestedAt
+
+		if deletion.Deliveries, err = q.DeleteDeliveriesOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := q.DeleteSharesOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if deletion.Reminders, err = q.DeleteRemindersOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if deletion.Webhooks, err = q.DeleteWebhooksOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := q.DeleteEventsOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := q.DeleteAuditEventsOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := q.DeleteSecretsOfUser(ctx, userId); err != nil {
+			return err
+		}
+		if err := q.DeleteUser(ctx, userId); err != nil {
+			return err
+		}
+
+		ok = true
+
+		return q.InsertUserDeletionAudit(ctx, db.InsertUserDeletionAuditParams{
+			RequestedAt: deletion.RequestedAt,
+			Reminders:   deletion.Reminders,
+			Deliveries:  deletion.Deliveries,
+			Webhooks:    deletion.Webhooks,
+		})
+	})
+
+	return deletion, ok, err
+}
@@ -0,0 +1,244 @@
+package postgresrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/postgresrepo/db"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+func (pr PostgresRepo) CreateWebhook(ctx context.Context, webhook *pb.Webhook) (int64, error) {
+	id, err := pr.queries().CreateWebhook(ctx, db.CreateWebhookParams{
+		UserID: webhook.GetUserId(),
+		URL:    webhook.GetUrl(),
+		Secret: webhook.GetSecret(),
+		Events: append([]string{}, webhook.GetEvents()...),
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return 0, fmt.Errorf("user %d: %w", webhook.GetUserId(), todoserviceserver.ErrNotFound)
+	}
+
+	return id, err
+}
+
+func (pr PostgresRepo) GetWebhook(ctx context.Context, id int64) (*pb.Webhook, error) {
+	row, err := pr.queries().GetWebhook(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("webhook %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return webhookFromRow(db.GetWebhooksByUserIdRow(row)), nil
+}
+
+func (pr PostgresRepo) GetWebhooksByUserId(ctx context.Context, userId int64) ([]*pb.Webhook, error) {
+	rows, err := pr.queries().GetWebhooksByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := make([]*pb.Webhook, 0, len(rows))
+	for _, row := range rows {
+		hooks = append(hooks, webhookFromRow(row))
+	}
+
+	return hooks, nil
+}
+
+func webhookFromRow(row db.GetWebhooksByUserIdRow) *pb.Webhook {
+	webhook := &pb.Webhook{
+		Id:      row.ID,
+		UserId:  row.UserID,
+		Url:     row.URL,
+		Events:  row.Events,
+		Enabled: &row.Enabled,
+	}
+	if row.DisabledReason != nil {
+		webhook.DisabledReason = *row.DisabledReason
+	}
+
+	return webhook
+}
+
+func (pr PostgresRepo) UpdateWebhook(ctx context.Context, webhook *pb.Webhook) error {
+	updated, err := pr.queries().UpdateWebhook(ctx, db.UpdateWebhookParams{
+		ID:      webhook.GetId(),
+		URL:     webhook.GetUrl(),
+		Events:  append([]string{}, webhook.GetEvents()...),
+		Enabled: webhook.Enabled,
+	})
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		return fmt.Errorf("webhook %d: %w", webhook.GetId(), todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) DeleteWebhook(ctx context.Context, id int64) error {
+	deleted, err := pr.queries().DeleteWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return fmt.Errorf("webhook %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) GetWebhookDeadLetter(ctx context.Context, id int64) (*pb.WebhookDeadLetter, error) {
+	row, err := pr.queries().GetWebhookDeadLetter(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("dead letter %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetterFromRow(db.GetWebhookDeadLettersByUserIdRow(row))
+}
+
+func (pr PostgresRepo) GetWebhookDeadLettersByUserId(ctx context.Context, userId int64) ([]*pb.WebhookDeadLetter, error) {
+	rows, err := pr.queries().GetWebhookDeadLettersByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*pb.WebhookDeadLetter, 0, len(rows))
+	for _, row := range rows {
+		deadLetter, err := deadLetterFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+func deadLetterFromRow(row db.GetWebhookDeadLettersByUserIdRow) (*pb.WebhookDeadLetter, error) {
+	event, err := userEventFromRow(db.UserEvent{
+		Seq:       row.EventSeq,
+		UserID:    row.UserID,
+		Kind:      row.EventKind,
+		Data:      row.EventData,
+		CreatedAt: row.EventCreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode event of dead letter %d: %w", row.ID, err)
+	}
+
+	return &pb.WebhookDeadLetter{
+		Id:        row.ID,
+		WebhookId: row.WebhookID,
+		UserId:    row.UserID,
+		Event:     event,
+		Attempts:  row.Attempts,
+		LastError: row.LastError,
+		FailedAt:  timestamppb.New(row.FailedAt),
+	}, nil
+}
+
+// ReplayWebhookDeadLetter queues the event of the dead letter again for its
+// webhook, with no attempts made.
+func (pr PostgresRepo) ReplayWebhookDeadLetter(ctx context.Context, id int64) error {
+	replayed, err := pr.queries().ReplayWebhookDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if replayed == 0 {
+		return fmt.Errorf("dead letter %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]webhooks.Delivery, error) {
+	rows, err := pr.queries().ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil:    leaseUntil,
+		Now:           now,
+		MaxDeliveries: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]webhooks.Delivery, 0, len(rows))
+	for _, row := range rows {
+		event, err := userEventFromRow(db.UserEvent{
+			Seq:       row.EventSeq,
+			UserID:    row.UserID,
+			Kind:      row.EventKind,
+			Data:      row.EventData,
+			CreatedAt: row.EventCreatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode event of webhook delivery %d: %w", row.ID, err)
+		}
+
+		deliveries = append(deliveries, webhooks.Delivery{
+			ID:        row.ID,
+			WebhookID: row.WebhookID,
+			URL:       row.URL,
+			Secret:    row.Secret,
+			Event:     event,
+			Attempts:  int(row.Attempts),
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (pr PostgresRepo) WebhookDelivered(ctx context.Context, id int64) error {
+	return pr.queries().WebhookDelivered(ctx, id)
+}
+
+func (pr PostgresRepo) WebhookDeliveryFailed(ctx context.Context, id int64, lastError string, retryAt time.Time, disableAfter int) error {
+	return pr.WithinTx(ctx, func(txRepo PostgresRepo) error {
+		var (
+			webhookId int64
+			err       error
+		)
+		if retryAt.IsZero() {
+			webhookId, err = txRepo.queries().DeadLetterWebhookDelivery(ctx, db.DeadLetterWebhookDeliveryParams{
+				ID:        id,
+				LastError: lastError,
+			})
+		} else {
+			webhookId, err = txRepo.queries().RetryWebhookDelivery(ctx, db.RetryWebhookDeliveryParams{
+				ID:        id,
+				RetryAt:   retryAt,
+				LastError: lastError,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		return txRepo.queries().CountWebhookFailure(ctx, db.CountWebhookFailureParams{
+			ID:             webhookId,
+			DisableAfter:   int32(disableAfter),
+			DisabledReason: fmt.Sprintf("%d failed attempts in a row, the last one with: %s", disableAfter, lastError),
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// sweepEvery is the number of Take calls between removals of full buckets,
+// which behave exactly like missing ones.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rate      config.Rate
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = math.Min(float64(b.rate.Burst), b.tokens+elapsed*b.rate.PerSecond)
+	b.updatedAt = now
+}
+
+// MemoryStore keeps buckets in the memory of a single replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (ms *MemoryStore) Take(_ context.Context, key string, rate config.Rate) (bool, time.Duration, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := ms.now()
+
+	ms.calls++
+	if ms.calls%sweepEvery == 0 {
+		ms.sweep(now)
+	}
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Burst), updatedAt: now}
+		ms.buckets[key] = b
+	}
+	b.rate = rate
+	b.refill(now)
+
+	if b.tokens < 1 {
+		retryAfter := time.Duration((1 - b.tokens) / rate.PerSecond * float64(time.Second))
+
+		return false, retryAfter, nil
+	}
+
+	b.tokens--
+
+	return true, 0, nil
+}
+
+func (ms *MemoryStore) sweep(now time.Time) {
+	for key, b := range ms.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.rate.Burst) {
+			delete(ms.buckets, key)
+		}
+	}
+}
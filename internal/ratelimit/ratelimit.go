@@ -0,0 +1,207 @@
+// Package ratelimit limits how often callers and users may call each RPC
+// using token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// Store keeps token buckets.
+type Store interface {
+	// Take removes a token from the bucket named key, creating a full one
+	// if it does not exist. When the bucket is empty it reports
+	// how long to wait for the next token instead.
+	Take(ctx context.Context, key string, rate config.Rate) (ok bool, retryAfter time.Duration, err error)
+}
+
+// Purger is implemented by stores which keep full buckets, which behave
+// exactly like missing ones, until they are deleted.
+type Purger interface {
+	DeleteFullBuckets(ctx context.Context) error
+}
+
+type Limiter struct {
+	limits config.RateLimits
+	store  Store
+}
+
+func New(limits config.RateLimits, store Store) (*Limiter, error) {
+	for method := range limits.Methods {
+		if !isTodoServiceMethod(method) {
+			return nil, fmt.Errorf("rate limits for unknown method %q", method)
+		}
+	}
+
+	return &Limiter{limits: limits, store: store}, nil
+}
+
+func isTodoServiceMethod(name string) bool {
+	for _, method := range pb.TodoService_ServiceDesc.Methods {
+		if method.MethodName == name {
+			return true
+		}
+	}
+	for _, stream := range pb.TodoService_ServiceDesc.Streams {
+		if stream.StreamName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userId returns the user a request acts on, when it is known from the request alone.
+func userId(req any) (int64, bool) {
+	switch req := req.(type) {
+	case *pb.User:
+		return req.GetId(), true
+	case *pb.UserId:
+		return req.GetId(), true
+	case *pb.Reminder:
+		return req.GetUserId(), true
+	case *pb.Webhook:
+		return req.GetUserId(), true
+	case *pb.ExportCalendarRequest:
+		return req.GetUserId(), true
+	case *pb.ImportCalendarRequest:
+		return req.GetUserId(), true
+	case *pb.ExportUserDataRequest:
+		return req.GetUserId(), true
+	case *pb.ImportRemindersRequest:
+		if req.GetOptions() == nil {
+			return 0, false
+		}
+
+		return req.GetOptions().GetUserId(), true
+	default:
+		return 0, false
+	}
+}
+
+// Allow takes a token from every bucket the request falls into and returns
+// a ResourceExhausted status error with RetryInfo when one of them is empty.
+func (l *Limiter) Allow(ctx context.Context, fullMethod string, req any) error {
+	method := path.Base(fullMethod)
+	limits := l.limits.For(method)
+
+	if caller, ok := auth.FromContext(ctx); ok {
+		if err := l.take(ctx, "caller:"+caller.Name+":"+method, limits.PerCaller); err != nil {
+			return err
+		}
+	}
+
+	if id, ok := userId(req); ok {
+		if err := l.take(ctx, "user:"+strconv.FormatInt(id, 10)+":"+method, limits.PerUser); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AllowKey takes a token from the bucket named key, for limits which are
+// not about RPCs, and returns the same errors as Allow.
+func (l *Limiter) AllowKey(ctx context.Context, key string, rate config.Rate) error {
+	return l.take(ctx, key, rate)
+}
+
+func (l *Limiter) take(ctx context.Context, key string, rate config.Rate) error {
+	if rate.PerSecond == 0 {
+		return nil
+	}
+
+	ok, retryAfter, err := l.store.Take(ctx, key, rate)
+	if err != nil {
+		// Failing open keeps the service usable when the shared store is down.
+		log.Printf("Error taking rate limit token for %s: %v", key, err)
+
+		return nil
+	}
+	if ok {
+		return nil
+	}
+
+	st := status.Newf(codes.ResourceExhausted, "rate limit exceeded, retry in %v", retryAfter)
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// UnaryServerInterceptor must run after the auth interceptor to limit per caller.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.Allow(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor limits server streams on their request message.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, limiter: l, method: info.FullMethod})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	limiter *Limiter
+	method  string
+	checked bool
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.checked {
+		return nil
+	}
+	s.checked = true
+
+	return s.limiter.Allow(s.Context(), s.method, m)
+}
+
+// PurgeFull deletes full buckets every interval until ctx is done, unless
+// the store of l removes them on its own.
+func (l *Limiter) PurgeFull(ctx context.Context, interval time.Duration) {
+	purger, ok := l.store.(Purger)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := purger.DeleteFullBuckets(ctx); err != nil {
+				log.Printf("Error deleting full rate limit buckets: %v", err)
+			}
+		}
+	}
+}
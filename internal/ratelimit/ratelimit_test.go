@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{now: time.Now()}
+	ms := NewMemoryStore()
+	ms.now = c.Now
+	rate := config.Rate{PerSecond: 2, Burst: 3}
+
+	for i := 0; i < rate.Burst; i++ {
+		if ok, _, _ := ms.Take(ctx, "key", rate); !ok {
+			t.Fatalf("expected token %d of burst to be available", i+1)
+		}
+	}
+
+	ok, retryAfter, _ := ms.Take(ctx, "key", rate)
+	if ok {
+		t.Fatalf("expected bucket to be empty after burst")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("expected retry after 500ms got %v", retryAfter)
+	}
+
+	if ok, _, _ := ms.Take(ctx, "other key", rate); !ok {
+		t.Errorf("expected buckets to be independent")
+	}
+
+	c.now = c.now.Add(retryAfter)
+	if ok, _, _ := ms.Take(ctx, "key", rate); !ok {
+		t.Errorf("expected token after waiting %v", retryAfter)
+	}
+
+	c.now = c.now.Add(time.Hour)
+	ms.sweep(c.now)
+	if len(ms.buckets) != 0 {
+		t.Errorf("expected full buckets to be swept, %d left", len(ms.buckets))
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	limits := config.RateLimits{
+		Default: config.MethodLimits{
+			PerCaller: config.Rate{PerSecond: 1, Burst: 3},
+			PerUser:   config.Rate{PerSecond: 1, Burst: 1},
+		},
+		Methods: map[string]config.MethodLimits{
+			"GetUser": {PerUser: config.Rate{PerSecond: 1, Burst: 2}},
+		},
+	}
+
+	l, err := New(limits, NewMemoryStore())
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	ctx := auth.NewContext(context.Background(), auth.Caller{Name: "bot"})
+	const (
+		setUser = "/todoservice.TodoService/SetUser"
+		getUser = "/todoservice.TodoService/GetUser"
+	)
+
+	t.Run("per user", func(t *testing.T) {
+		if err := l.Allow(ctx, setUser, &pb.User{Id: 1}); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		err := l.Allow(ctx, setUser, &pb.User{Id: 1})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("expected ResourceExhausted got %v", err)
+		}
+
+		var retryInfo *errdetails.RetryInfo
+		for _, detail := range status.Convert(err).Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok {
+				retryInfo = info
+			}
+		}
+		if retryInfo == nil || retryInfo.GetRetryDelay().AsDuration() <= 0 {
+			t.Errorf("expected RetryInfo with positive delay got %v", status.Convert(err).Details())
+		}
+
+		if err := l.Allow(ctx, setUser, &pb.User{Id: 2}); err != nil {
+			t.Errorf("did not expect error for another user got %v", err)
+		}
+	})
+
+	t.Run("per method override", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := l.Allow(ctx, getUser, &pb.UserId{Id: 3}); err != nil {
+				t.Fatalf("did not expect error on call %d got %v", i+1, err)
+			}
+		}
+	})
+
+	t.Run("per caller", func(t *testing.T) {
+		if err := l.Allow(ctx, getUser, &pb.UserId{Id: 4}); err != nil {
+			t.Fatalf("did not expect error on last token of caller burst got %v", err)
+		}
+
+		err := l.Allow(ctx, getUser, &pb.UserId{Id: 5})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected ResourceExhausted once caller burst is used got %v", err)
+		}
+
+		other := auth.NewContext(context.Background(), auth.Caller{Name: "web"})
+		if err := l.Allow(other, getUser, &pb.UserId{Id: 5}); err != nil {
+			t.Errorf("did not expect error for another caller got %v", err)
+		}
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		limits.Methods = map[string]config.MethodLimits{"AddReminder": {}}
+		if _, err := New(limits, NewMemoryStore()); err == nil {
+			t.Errorf("expected error for unknown method")
+		}
+	})
+}
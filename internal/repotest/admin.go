@@ -0,0 +1,181 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+)
+
+// AdminStore is a storage backend as a whole, which admin.Store inspects.
+type AdminStore interface {
+	admin.Store
+	notify.Store
+	todoserviceserver.Repo
+}
+
+// AdminFactory returns an empty backend limiting users to
+// maxActiveReminders reminders.
+type AdminFactory func(t *testing.T, maxActiveReminders int) AdminStore
+
+// RunAdmin checks an implementation of admin.Store.
+func RunAdmin(t *testing.T, newStore AdminFactory) {
+	ctx := context.Background()
+	store := newStore(t, 2)
+	now := time.Now()
+
+	mustSetUser(t, store, &pb.User{Id: 1, NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+		{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"},
+	}}})
+	mustSetUser(t, store, &pb.User{Id: 2})
+
+	fired := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "Water the plants", RemindTimestamp: timestamppb.New(now.Add(-time.Minute))})
+	pending := mustCreateReminder(t, store, &pb.Reminder{UserId: 2, ReminderText: "water them again", RemindTimestamp: at(1)})
+	later := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "call mom", RemindTimestamp: at(2)})
+	removed := mustCreateReminder(t, store, &pb.Reminder{UserId: 2, ReminderText: "removed", RemindTimestamp: at(3)})
+	if err := store.RemoveReminder(ctx, removed); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	route := func(_ int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel { return channels }
+	if _, err := store.FireDueReminders(ctx, now, 10, route); err != nil {
+		t.Fatalf("cannot fire reminders: %v", err)
+	}
+	claimed, err := store.ClaimDeliveries(ctx, now, now.Add(time.Minute), 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected one delivery got %+v, %v", claimed, err)
+	}
+	if err := store.MarkDeliveryFailed(ctx, claimed[0].ID, "mailbox full", time.Time{}); err != nil {
+		t.Fatalf("cannot fail delivery: %v", err)
+	}
+
+	t.Run("FindReminders", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   admin.ReminderFilter
+			expected []int32
+		}{
+			{"any", admin.ReminderFilter{}, []int32{fired, pending, later}},
+			{"user", admin.ReminderFilter{UserID: 1}, []int32{fired, later}},
+			{"text", admin.ReminderFilter{Text: "WATER"}, []int32{fired, pending}},
+			{"pending", admin.ReminderFilter{Text: "water", Pending: true}, []int32{pending}},
+			{"created after", admin.ReminderFilter{CreatedAfter: now.Add(time.Minute)}, nil},
+			{"created before", admin.ReminderFilter{CreatedBefore: now.Add(-time.Minute)}, nil},
+			{"created between", admin.ReminderFilter{CreatedAfter: now.Add(-time.Minute), CreatedBefore: now.Add(time.Minute)}, []int32{fired, pending, later}},
+			{"limit", admin.ReminderFilter{Limit: 1}, []int32{fired}},
+		}
+
+		for _, tt := range tests {
+			reminders, err := store.FindReminders(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: did not expect error got %v", tt.name, err)
+			}
+
+			var ids []int32
+			for _, reminder := range reminders {
+				ids = append(ids, reminder.ID)
+			}
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("%s: expected reminders %v got %v", tt.name, tt.expected, ids)
+			}
+		}
+
+		reminders, _ := store.FindReminders(ctx, admin.ReminderFilter{UserID: 1, Limit: 1})
+		if len(reminders) != 1 || reminders[0].Text != "Water the plants" || reminders[0].FiredAt == nil || reminders[0].CreatedAt.IsZero() {
+			t.Errorf("expected the fired reminder got %+v", reminders)
+		}
+	})
+
+	t.Run("FindFailedDeliveries", func(t *testing.T) {
+		deliveries, err := store.FindFailedDeliveries(ctx, admin.DeliveryFilter{UserID: 1, Channel: "EMAIL", FailedAfter: now.Add(-time.Minute)})
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("expected one failed delivery got %+v, %v", deliveries, err)
+		}
+
+		got := deliveries[0]
+		if got.ID != claimed[0].ID || got.ReminderID != fired || got.Address != "user@example.com" || got.Attempts != 1 || got.LastError != "mailbox full" {
+			t.Errorf("expected the failed delivery of reminder %d got %+v", fired, got)
+		}
+
+		for _, filter := range []admin.DeliveryFilter{{UserID: 2}, {Channel: "TELEGRAM"}, {FailedAfter: now.Add(time.Minute)}} {
+			if deliveries, err := store.FindFailedDeliveries(ctx, filter); err != nil || len(deliveries) != 0 {
+				t.Errorf("expected no delivery for %+v got %+v, %v", filter, deliveries, err)
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stats, err := store.Stats(ctx)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if stats.Users != 2 || stats.PendingReminders != 2 || stats.FiredReminders != 1 || stats.RemovedReminders != 1 ||
+			stats.Deliveries["failed"] != 1 || stats.Deliveries["pending"] != 0 || stats.SizeBytes <= 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("RequeueDeliveries", func(t *testing.T) {
+		if n, err := store.RequeueDeliveries(ctx, []int64{claimed[0].ID, claimed[0].ID + 100}); err != nil || n != 1 {
+			t.Fatalf("expected one delivery requeued got %d, %v", n, err)
+		}
+		if n, err := store.RequeueDeliveries(ctx, []int64{claimed[0].ID}); err != nil || n != 0 {
+			t.Errorf("expected a pending delivery to be skipped got %d, %v", n, err)
+		}
+
+		got, err := store.ClaimDeliveries(ctx, time.Now(), time.Now().Add(time.Minute), 10)
+		if err != nil || len(got) != 1 || got[0].ID != claimed[0].ID || got[0].Attempts != 1 {
+			t.Errorf("expected delivery %d claimed for a first attempt again got %+v, %v", claimed[0].ID, got, err)
+		}
+	})
+
+	t.Run("CancelReminders", func(t *testing.T) {
+		if n, err := store.CancelReminders(ctx, []int32{pending, fired, removed}); err != nil || n != 2 {
+			t.Fatalf("expected two reminders cancelled got %d, %v", n, err)
+		}
+
+		reminders, err := store.FindReminders(ctx, admin.ReminderFilter{})
+		if err != nil || len(reminders) != 1 || reminders[0].ID != later {
+			t.Errorf("expected only reminder %d left got %+v, %v", later, reminders, err)
+		}
+
+		// Cancelling the pending reminder gave its quota back, cancelling
+		// the fired one gave nothing back.
+		for userId, free := range map[int64]int{1: 1, 2: 2} {
+			for i := 0; i < free; i++ {
+				mustCreateReminder(t, store, &pb.Reminder{UserId: userId, ReminderText: "after cancel", RemindTimestamp: at(1)})
+			}
+			if _, err := store.CreateReminder(ctx, &pb.Reminder{UserId: userId, ReminderText: "over quota", RemindTimestamp: at(1)}); !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+				t.Errorf("expected user %d to have %d reminders left got %v", userId, free, err)
+			}
+		}
+	})
+
+	t.Run("PurgeRemovedReminders", func(t *testing.T) {
+		if n, err := store.CountRemovedReminders(ctx, now.Add(-time.Minute)); err != nil || n != 0 {
+			t.Errorf("expected no reminder removed a minute ago got %d, %v", n, err)
+		}
+
+		before := time.Now().Add(time.Minute)
+		if n, err := store.CountRemovedReminders(ctx, before); err != nil || n != 3 {
+			t.Errorf("expected 3 removed reminders got %d, %v", n, err)
+		}
+		if n, err := store.PurgeRemovedReminders(ctx, before); err != nil || n != 3 {
+			t.Fatalf("expected 3 reminders purged got %d, %v", n, err)
+		}
+
+		stats, err := store.Stats(ctx)
+		if err != nil || stats.RemovedReminders != 0 || stats.Deliveries["pending"] != 0 || stats.Deliveries["failed"] != 0 {
+			t.Errorf("expected removed reminders and their deliveries gone got %+v, %v", stats, err)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package repotest
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// AuditStore is a storage backend as a whole, which records audit events
+// of the changes made through it.
+type AuditStore interface {
+	audit.Store
+	admin.Store
+	todoserviceserver.Repo
+	todoserviceserver.UserDataStore
+	userdata.Store
+}
+
+// AuditFactory returns an empty backend.
+type AuditFactory func(t *testing.T) AuditStore
+
+// RunAudit checks that a backend records changes made with an actor and
+// implements audit.Store.
+func RunAudit(t *testing.T, newStore AuditFactory) {
+	ctx := context.Background()
+	store := newStore(t)
+	start := time.Now().Add(-time.Second)
+
+	actor := func(requestId, method string) context.Context {
+		return audit.NewContext(ctx, audit.Actor{Caller: `service "bot"`, Method: method, RequestID: requestId})
+	}
+
+	// Changes made without an actor are not recorded.
+	mustSetUser(t, store, &pb.User{Id: 3})
+	mustCreateReminder(t, store, &pb.Reminder{UserId: 3, ReminderText: "unaudited", RemindTimestamp: at(1)})
+
+	if err := store.SetUser(actor("set-1", "SetUser"), &pb.User{Id: 1}); err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+	if err := store.SetUser(actor("set-2", "SetUser"), &pb.User{Id: 1, LanguageCode: wrapperspb.String("en")}); err != nil {
+		t.Fatalf("cannot update user: %v", err)
+	}
+	if err := store.SetUser(actor("set-3", "SetUser"), &pb.User{Id: 2}); err != nil {
+		t.Fatalf("cannot set user: %v", err)
+	}
+
+	created, err := store.CreateReminder(actor("create", "CreateReminder"), &pb.Reminder{UserId: 1, ReminderText: "water the plants", RemindTimestamp: at(1)})
+	if err != nil {
+		t.Fatalf("cannot create reminder: %v", err)
+	}
+	imported := []*pb.Reminder{
+		{UserId: 2, ReminderText: "call mom", RemindTimestamp: at(2)},
+		{UserId: 2, ReminderText: "buy milk", RemindTimestamp: at(3)},
+	}
+	if _, err := store.CreateReminders(actor("import", "ImportReminders"), 2, imported); err != nil {
+		t.Fatalf("cannot create reminders: %v", err)
+	}
+	if err := store.RemoveReminder(actor("remove", "RemoveReminder"), created); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	all, err := store.AuditEvents(ctx, audit.Filter{})
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	var requestIds []string
+	for _, event := range all {
+		requestIds = append(requestIds, event.RequestID)
+	}
+	if expected := []string{"remove", "import", "import", "create", "set-3", "set-2", "set-1"}; !slices.Equal(requestIds, expected) {
+		t.Fatalf("expected events of requests %v got %v", expected, requestIds)
+	}
+
+	imports, err := store.AuditEvents(ctx, audit.Filter{RequestID: "import"})
+	if err != nil || len(imports) != 2 {
+		t.Fatalf("expected two events of the import got %+v, %v", imports, err)
+	}
+
+	t.Run("Events", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			event         audit.Event
+			userId        int64
+			reminderId    int32
+			changedFields []string
+		}{
+			{"created user", all[6], 1, 0, []string{"id"}},
+			{"updated user", all[5], 1, 0, []string{"language_code"}},
+			{"created reminder", all[3], 1, created, []string{"id", "remind_timestamp", "reminder_text", "user_id"}},
+			{"removed reminder", all[0], 1, created, []string{"id", "remind_timestamp", "reminder_text", "user_id"}},
+		}
+
+		for _, tt := range tests {
+			event, err := tt.event.Proto()
+			if err != nil {
+				t.Fatalf("%s: did not expect error got %v", tt.name, err)
+			}
+
+			if event.GetActor() != `service "bot"` || event.GetUserId() != tt.userId || event.GetReminderId() != tt.reminderId ||
+				event.GetTime().AsTime().Before(start) {
+				t.Errorf("%s: unexpected event %v", tt.name, event)
+			}
+			if !slices.Equal(event.GetChangedFields(), tt.changedFields) {
+				t.Errorf("%s: expected changed fields %v got %v", tt.name, tt.changedFields, event.GetChangedFields())
+			}
+		}
+
+		if all[6].Before != nil || all[3].Before != nil || all[0].After != nil {
+			t.Errorf("expected no state before creations and after removals got %+v", all)
+		}
+
+		event, _ := all[0].Proto()
+		if text := event.GetBefore().GetFields()["reminder_text"].GetStringValue(); text != "water the plants" {
+			t.Errorf("expected the removed reminder recorded got %q", text)
+		}
+	})
+
+	t.Run("CancelReminders", func(t *testing.T) {
+		ids := []int32{imports[0].ReminderID, imports[1].ReminderID, created}
+		if n, err := store.CancelReminders(actor("cancel", "admin reminders cancel"), ids); err != nil || n != 2 {
+			t.Fatalf("expected two reminders cancelled got %d, %v", n, err)
+		}
+
+		events, err := store.AuditEvents(ctx, audit.Filter{RequestID: "cancel"})
+		if err != nil || len(events) != 2 {
+			t.Fatalf("expected two events of the cancellation got %+v, %v", events, err)
+		}
+		for _, event := range events {
+			if event.UserID != 2 || event.Method != "admin reminders cancel" || event.Before == nil || event.After != nil {
+				t.Errorf("expected the cancellation of a reminder of user 2 got %+v", event)
+			}
+		}
+	})
+
+	t.Run("AuditEvents", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   audit.Filter
+			expected []string
+		}{
+			{"user", audit.Filter{UserID: 1}, []string{"remove", "create", "set-2", "set-1"}},
+			{"reminder", audit.Filter{ReminderID: created}, []string{"remove", "create"}},
+			{"method", audit.Filter{Method: "SetUser"}, []string{"set-3", "set-2", "set-1"}},
+			{"actor", audit.Filter{Caller: `user 1`}, nil},
+			{"before id", audit.Filter{UserID: 1, BeforeID: all[3].ID}, []string{"set-2", "set-1"}},
+			{"limit", audit.Filter{UserID: 1, Limit: 2}, []string{"remove", "create"}},
+			{"since", audit.Filter{Since: time.Now().Add(time.Minute)}, nil},
+			{"until", audit.Filter{Until: start}, nil},
+			{"between", audit.Filter{UserID: 2, Since: start, Until: time.Now().Add(time.Minute)}, []string{"cancel", "cancel", "import", "import", "set-3"}},
+		}
+
+		for _, tt := range tests {
+			events, err := store.AuditEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: did not expect error got %v", tt.name, err)
+			}
+
+			var requestIds []string
+			for _, event := range events {
+				requestIds = append(requestIds, event.RequestID)
+			}
+			if !slices.Equal(requestIds, tt.expected) {
+				t.Errorf("%s: expected events of requests %v got %v", tt.name, tt.expected, requestIds)
+			}
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		deleteAt := time.Now()
+		if _, err := store.ScheduleUserDeletion(ctx, 1, deleteAt); err != nil {
+			t.Fatalf("cannot schedule deletion: %v", err)
+		}
+		if _, ok, err := store.DeleteUser(ctx, 1, deleteAt); err != nil || !ok {
+			t.Fatalf("expected user deleted got %v, %v", ok, err)
+		}
+
+		if events, err := store.AuditEvents(ctx, audit.Filter{UserID: 1}); err != nil || len(events) != 0 {
+			t.Errorf("expected events of the deleted user gone got %+v, %v", events, err)
+		}
+		if events, err := store.AuditEvents(ctx, audit.Filter{UserID: 2}); err != nil || len(events) != 5 {
+			t.Errorf("expected events of user 2 kept got %+v, %v", events, err)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/backup"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+)
+
+// BackupStore is a storage backend as a whole, which backups are taken of
+// and restored into.
+type BackupStore interface {
+	backup.Source
+	backup.Target
+	todoserviceserver.Repo
+	notify.Store
+}
+
+// BackupFactory returns an empty backend limiting users to
+// maxActiveReminders reminders, zero for no limit. Backends returned before
+// are not used anymore.
+type BackupFactory func(t *testing.T, maxActiveReminders int) BackupStore
+
+// RunBackup checks an implementation of backup.Source and backup.Target by
+// restoring the backup of one backend into others.
+func RunBackup(t *testing.T, newStore BackupFactory) {
+	ctx := context.Background()
+	source := newStore(t, 0)
+	now := time.Now()
+
+	mustSetUser(t, source, &pb.User{Id: 1, LanguageCode: wrapperspb.String("en"), UtcOffset: wrapperspb.Int32(2),
+		NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+			{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"},
+		}}})
+	mustSetUser(t, source, &pb.User{Id: 2})
+
+	fired := mustCreateReminder(t, source, &pb.Reminder{UserId: 1, ReminderText: "water the plants", RemindTimestamp: timestamppb.New(now.Add(-time.Minute))})
+	pending := mustCreateReminder(t, source, &pb.Reminder{UserId: 1, ReminderText: "call mom", RemindTimestamp: at(1)})
+	removed := mustCreateReminder(t, source, &pb.Reminder{UserId: 1, ReminderText: "removed", RemindTimestamp: at(2)})
+	other := mustCreateReminder(t, source, &pb.Reminder{UserId: 2, ReminderText: "someone else's", RemindTimestamp: at(3)})
+	if err := source.RemoveReminder(ctx, removed); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	route := func(_ int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel { return channels }
+	if _, err := source.FireDueReminders(ctx, now, 10, route); err != nil {
+		t.Fatalf("cannot fire reminders: %v", err)
+	}
+
+	var (
+		buf       bytes.Buffer
+		users     []backup.User
+		reminders []backup.Reminder
+	)
+	summary, err := backup.Write(ctx, &buf, source, 3, now)
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	if summary.Users != 2 || summary.Reminders != 4 {
+		t.Errorf("expected 2 users and 4 reminders got %+v", summary)
+	}
+
+	err = source.Backup(ctx, 10, func(batch []backup.User) error {
+		users = append(users, batch...)
+
+		return nil
+	}, func(batch []backup.Reminder) error {
+		reminders = append(reminders, batch...)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	if verified, err := backup.Verify(bytes.NewReader(buf.Bytes())); err != nil || verified != summary {
+		t.Fatalf("expected backup %+v verified got %+v, %v", summary, verified, err)
+	}
+
+	restore := func(target backup.Target) (backup.Result, error) {
+		return backup.Restore(ctx, bytes.NewReader(buf.Bytes()), target, summary.Checksum, 2)
+	}
+
+	t.Run("Restore", func(t *testing.T) {
+		target := newStore(t, 2)
+
+		result, err := restore(target)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if result.Resumed || result.Users != 2 || result.Reminders != 4 || len(result.IDs) != 4 {
+			t.Errorf("expected 2 users and 4 reminders restored got %+v", result)
+		}
+
+		user, err := target.GetUser(ctx, 1)
+		if err != nil || user.GetLanguageCode().GetValue() != "en" || user.GetUtcOffset().GetValue() != 2 ||
+			len(user.GetNotificationChannels().GetChannels()) != 1 {
+			t.Errorf("expected the profile of user 1 got %v, %v", user, err)
+		}
+
+		listed, err := target.GetRemindersByUserId(ctx, 1)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		var ids []int32
+		for _, reminder := range listed {
+			ids = append(ids, reminder.GetId())
+		}
+		if expected := []int32{result.IDs[fired], result.IDs[pending]}; !slices.Equal(ids, expected) {
+			t.Errorf("expected reminders %v listed got %v", expected, ids)
+		}
+
+		if n, err := target.FireDueReminders(ctx, time.Now(), 10, route); err != nil || n != 0 {
+			t.Errorf("expected fired reminders not to fire again got %d, %v", n, err)
+		}
+
+		// The pending reminder takes one of the two of the quota.
+		mustCreateReminder(t, target, &pb.Reminder{UserId: 1, ReminderText: "after restore", RemindTimestamp: at(1)})
+		if _, err := target.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "over quota", RemindTimestamp: at(1)}); !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+			t.Errorf("expected ErrQuotaExceeded got %v", err)
+		}
+
+		again, err := restore(target)
+		if err != nil || !again.Resumed || again.Skipped != 4 || !maps.Equal(again.IDs, result.IDs) {
+			t.Errorf("expected every reminder skipped restoring again got %+v, %v", again, err)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		target := newStore(t, 0)
+
+		// Interrupted after restoring the last reminder, which took the first
+		// ID of target.
+		if resumed, err := target.BeginRestore(ctx, summary.Checksum); err != nil || resumed {
+			t.Fatalf("expected restore begun got %v, %v", resumed, err)
+		}
+		if err := target.RestoreUsers(ctx, users); err != nil {
+			t.Fatalf("cannot restore users: %v", err)
+		}
+		restored, err := target.RestoreReminders(ctx, summary.Checksum, reminders[len(reminders)-1:])
+		if err != nil || len(restored) != 1 {
+			t.Fatalf("cannot restore reminder: %v, %v", restored, err)
+		}
+
+		result, err := restore(target)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if !result.Resumed || result.Skipped != 1 || len(result.IDs) != 4 || result.IDs[other] != restored[0] {
+			t.Errorf("expected reminder %d restored before as %d got %+v", other, restored[0], result)
+		}
+
+		if listed, err := target.GetRemindersByUserId(ctx, 2); err != nil || len(listed) != 1 {
+			t.Errorf("expected reminder %d restored once got %v, %v", other, listed, err)
+		}
+		if reminder, err := target.GetReminder(ctx, result.IDs[pending]); err != nil || reminder.GetReminderText() != "call mom" {
+			t.Errorf("expected reminder %d restored as %d got %v, %v", pending, result.IDs[pending], reminder, err)
+		}
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+		target := newStore(t, 0)
+		mustSetUser(t, target, &pb.User{Id: 3})
+
+		if _, err := restore(target); !errors.Is(err, backup.ErrNotEmpty) {
+			t.Errorf("expected ErrNotEmpty got %v", err)
+		}
+	})
+}
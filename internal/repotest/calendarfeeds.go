@@ -0,0 +1,72 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+// CalendarFeedFactory returns an empty store and a repo sharing its users.
+type CalendarFeedFactory func(t *testing.T) (todoserviceserver.CalendarFeedStore, todoserviceserver.Repo)
+
+// RunCalendarFeeds checks an implementation of
+// todoserviceserver.CalendarFeedStore.
+func RunCalendarFeeds(t *testing.T, newStore CalendarFeedFactory) {
+	ctx := context.Background()
+	store, repo := newStore(t)
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	mustSetUser(t, repo, &pb.User{Id: 2})
+
+	if err := store.CreateCalendarFeed(ctx, 3, []byte("unknown user")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown user got %v", err)
+	}
+	if err := store.RotateCalendarFeed(ctx, 1, []byte("no feed")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound rotating a missing feed got %v", err)
+	}
+	if err := store.DeleteCalendarFeed(ctx, 1); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a user without feed got %v", err)
+	}
+
+	for userId, hash := range map[int64]string{1: "first", 2: "second"} {
+		if err := store.CreateCalendarFeed(ctx, userId, []byte(hash)); err != nil {
+			t.Fatalf("cannot create calendar feed of user %d: %v", userId, err)
+		}
+	}
+	if err := store.CreateCalendarFeed(ctx, 1, []byte("again")); !errors.Is(err, todoserviceserver.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists for a second feed got %v", err)
+	}
+
+	if userId, version, err := store.FindCalendarFeed(ctx, []byte("first")); err != nil || userId != 1 || version != (todoserviceserver.CalendarFeedVersion{}) {
+		t.Errorf("expected the unversioned feed of user 1 got %d, %+v, %v", userId, version, err)
+	}
+
+	version := todoserviceserver.CalendarFeedVersion{ETag: `"abc"`, ModifiedAt: time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)}
+	if err := store.SetCalendarFeedVersion(ctx, 1, version); err != nil {
+		t.Fatalf("cannot set calendar feed version: %v", err)
+	}
+	if err := store.RotateCalendarFeed(ctx, 1, []byte("rotated")); err != nil {
+		t.Fatalf("cannot rotate calendar feed: %v", err)
+	}
+
+	if userId, got, err := store.FindCalendarFeed(ctx, []byte("rotated")); err != nil || userId != 1 || got.ETag != version.ETag || !got.ModifiedAt.Equal(version.ModifiedAt) {
+		t.Errorf("expected feed of user 1 at version %+v got %d, %+v, %v", version, userId, got, err)
+	}
+	if _, _, err := store.FindCalendarFeed(ctx, []byte("first")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a rotated secret got %v", err)
+	}
+
+	if err := store.DeleteCalendarFeed(ctx, 2); err != nil {
+		t.Fatalf("cannot delete calendar feed: %v", err)
+	}
+	if _, _, err := store.FindCalendarFeed(ctx, []byte("second")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted feed got %v", err)
+	}
+	if err := store.CreateCalendarFeed(ctx, 2, []byte("second again")); err != nil {
+		t.Errorf("expected a new feed after deleting one got %v", err)
+	}
+}
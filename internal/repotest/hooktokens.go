@@ -0,0 +1,54 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+// HookTokenFactory returns an empty store and a repo sharing its users.
+type HookTokenFactory func(t *testing.T) (todoserviceserver.HookTokenStore, todoserviceserver.Repo)
+
+// RunHookTokens checks an implementation of todoserviceserver.HookTokenStore.
+func RunHookTokens(t *testing.T, newStore HookTokenFactory) {
+	ctx := context.Background()
+	store, repo := newStore(t)
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	mustSetUser(t, repo, &pb.User{Id: 2})
+
+	if err := store.SetHookToken(ctx, 3, []byte("unknown user")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown user got %v", err)
+	}
+	if err := store.DeleteHookToken(ctx, 1); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a user without token got %v", err)
+	}
+
+	for userId, hash := range map[int64]string{1: "first", 2: "second"} {
+		if err := store.SetHookToken(ctx, userId, []byte(hash)); err != nil {
+			t.Fatalf("cannot set hook token of user %d: %v", userId, err)
+		}
+	}
+	if err := store.SetHookToken(ctx, 1, []byte("rotated")); err != nil {
+		t.Fatalf("cannot rotate hook token: %v", err)
+	}
+
+	for hash, expected := range map[string]int64{"rotated": 1, "second": 2} {
+		if userId, err := store.FindHookToken(ctx, []byte(hash)); err != nil || userId != expected {
+			t.Errorf("expected token %q of user %d got %d, %v", hash, expected, userId, err)
+		}
+	}
+	if _, err := store.FindHookToken(ctx, []byte("first")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a rotated token got %v", err)
+	}
+
+	if err := store.DeleteHookToken(ctx, 2); err != nil {
+		t.Fatalf("cannot delete hook token: %v", err)
+	}
+	if _, err := store.FindHookToken(ctx, []byte("second")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted token got %v", err)
+	}
+}
@@ -0,0 +1,393 @@
+// Package repotest checks that an implementation of todoserviceserver.Repo
+// behaves like the others. Every implementation runs Run from its tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+type Options struct {
+	MaxActiveReminders int
+}
+
+// Factory returns an empty repo configured with opts.
+type Factory func(t *testing.T, opts Options) todoserviceserver.Repo
+
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("SetUser", func(t *testing.T) { testSetUser(t, newRepo(t, Options{})) })
+	t.Run("GetUser", func(t *testing.T) { testGetUser(t, newRepo(t, Options{})) })
+	t.Run("CreateReminder", func(t *testing.T) { testCreateReminder(t, newRepo(t, Options{})) })
+	t.Run("CreateReminders", func(t *testing.T) { testCreateReminders(t, newRepo(t, Options{MaxActiveReminders: 3})) })
+	t.Run("RemoveReminder", func(t *testing.T) { testRemoveReminder(t, newRepo(t, Options{})) })
+	t.Run("GetRemindersByUserId", func(t *testing.T) { testGetRemindersByUserId(t, newRepo(t, Options{})) })
+	t.Run("quota", func(t *testing.T) { testQuota(t, newRepo(t, Options{MaxActiveReminders: 3})) })
+	t.Run("concurrency", func(t *testing.T) { testConcurrency(t, newRepo(t, Options{MaxActiveReminders: 10})) })
+}
+
+// at returns a timestamp the given number of hours from now, with the
+// precision every backend keeps.
+func at(hours int) *timestamppb.Timestamp {
+	return timestamppb.New(time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Millisecond))
+}
+
+func mustSetUser(t *testing.T, repo todoserviceserver.Repo, user *pb.User) {
+	t.Helper()
+
+	if err := repo.SetUser(context.Background(), user); err != nil {
+		t.Fatalf("cannot set user %+v: %v", user, err)
+	}
+}
+
+func mustCreateReminder(t *testing.T, repo todoserviceserver.Repo, reminder *pb.Reminder) int32 {
+	t.Helper()
+
+	id, err := repo.CreateReminder(context.Background(), reminder)
+	if err != nil {
+		t.Fatalf("cannot create reminder %+v: %v", reminder, err)
+	}
+
+	return id
+}
+
+func testSetUser(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+	channels := &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+		{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"},
+		{Kind: pb.NotificationChannel_TELEGRAM, Address: "-100"},
+	}}
+
+	steps := []struct {
+		name string
+		set  *pb.User
+		want *pb.User
+	}{
+		{
+			"empty user",
+			&pb.User{Id: 1},
+			&pb.User{Id: 1},
+		},
+		{
+			"language code only",
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("en")},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("en")},
+		},
+		{
+			"utc offset keeps language code",
+			&pb.User{Id: 1, UtcOffset: wrapperspb.Int32(3)},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("en"), UtcOffset: wrapperspb.Int32(3)},
+		},
+		{
+			"empty update keeps everything",
+			&pb.User{Id: 1},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("en"), UtcOffset: wrapperspb.Int32(3)},
+		},
+		{
+			"full update",
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(-5)},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(-5)},
+		},
+		{
+			"zero values are values",
+			&pb.User{Id: 1, UtcOffset: wrapperspb.Int32(0)},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(0)},
+		},
+		{
+			"notification channels",
+			&pb.User{Id: 1, NotificationChannels: channels},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(0), NotificationChannels: channels},
+		},
+		{
+			"other fields keep notification channels",
+			&pb.User{Id: 1, UtcOffset: wrapperspb.Int32(2)},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(2), NotificationChannels: channels},
+		},
+		{
+			"no notification channels are channels",
+			&pb.User{Id: 1, NotificationChannels: &pb.NotificationChannels{}},
+			&pb.User{Id: 1, LanguageCode: wrapperspb.String("ru"), UtcOffset: wrapperspb.Int32(2), NotificationChannels: &pb.NotificationChannels{}},
+		},
+	}
+
+	for _, step := range steps {
+		mustSetUser(t, repo, step.set)
+
+		got, err := repo.GetUser(ctx, step.set.GetId())
+		if err != nil {
+			t.Fatalf("%s: cannot get user: %v", step.name, err)
+		}
+		if !proto.Equal(got, step.want) {
+			t.Errorf("%s: expected %+v got %+v", step.name, step.want, got)
+		}
+	}
+
+	mustSetUser(t, repo, &pb.User{Id: 2, UtcOffset: wrapperspb.Int32(14)})
+	got, err := repo.GetUser(ctx, 1)
+	if err != nil || got.GetUtcOffset().GetValue() != 2 {
+		t.Errorf("expected other users to be untouched got %+v, %v", got, err)
+	}
+}
+
+func testGetUser(t *testing.T, repo todoserviceserver.Repo) {
+	_, err := repo.GetUser(context.Background(), 404)
+	if !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound got %v", err)
+	}
+
+	user := &pb.User{Id: -7, LanguageCode: wrapperspb.String("de")}
+	mustSetUser(t, repo, user)
+
+	got, err := repo.GetUser(context.Background(), -7)
+	if err != nil {
+		t.Fatalf("cannot get user: %v", err)
+	}
+	if !proto.Equal(got, user) {
+		t.Errorf("expected %+v got %+v", user, got)
+	}
+
+	got.LanguageCode = wrapperspb.String("fr")
+	again, _ := repo.GetUser(context.Background(), -7)
+	if again.GetLanguageCode().GetValue() != "de" {
+		t.Errorf("expected returned users not to alias stored ones")
+	}
+}
+
+func testCreateReminder(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+
+	_, err := repo.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "orphan", RemindTimestamp: at(1)})
+	if !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for unknown user got %v", err)
+	}
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+
+	reminder := &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: at(1), Id: 1000}
+	first := mustCreateReminder(t, repo, reminder)
+	second := mustCreateReminder(t, repo, reminder)
+
+	if first == second {
+		t.Errorf("expected distinct ids got %v twice", first)
+	}
+
+	got, err := repo.GetReminder(ctx, first)
+	if err != nil {
+		t.Fatalf("cannot get reminder: %v", err)
+	}
+
+	want := proto.Clone(reminder).(*pb.Reminder)
+	want.Id = first
+	if !proto.Equal(got, want) {
+		t.Errorf("expected %+v got %+v", want, got)
+	}
+
+	if _, err := repo.GetReminder(ctx, second+1000); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for unknown reminder got %v", err)
+	}
+}
+
+// testCreateReminders expects a repo allowing 3 active reminders per user.
+func testCreateReminders(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+
+	orphans := []*pb.Reminder{{ReminderText: "orphan", RemindTimestamp: at(1)}}
+	if _, err := repo.CreateReminders(ctx, 1, orphans); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for unknown user got %v", err)
+	}
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "first", RemindTimestamp: at(3)})
+
+	reminders := []*pb.Reminder{
+		{UserId: 1, ReminderText: "buy milk", RemindTimestamp: at(2)},
+		{UserId: 1, ReminderText: "call mom", RemindTimestamp: at(1)},
+		{UserId: 1, ReminderText: "over quota", RemindTimestamp: at(1)},
+	}
+	if _, err := repo.CreateReminders(ctx, 1, reminders); !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded got %v", err)
+	}
+	if got, err := repo.GetRemindersByUserId(ctx, 1); err != nil || len(got) != 1 {
+		t.Errorf("expected none of the reminders over quota created got %v, %v", got, err)
+	}
+
+	ids, err := repo.CreateReminders(ctx, 1, reminders[:2])
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+	for i, id := range ids {
+		if got, err := repo.GetReminder(ctx, id); err != nil || got.GetReminderText() != reminders[i].GetReminderText() {
+			t.Errorf("expected reminder %d to be %q got %v, %v", id, reminders[i].GetReminderText(), got, err)
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected 2 ids got %v", ids)
+	}
+
+	got, err := repo.GetRemindersByUserId(ctx, 1)
+	if err != nil {
+		t.Fatalf("cannot get reminders: %v", err)
+	}
+	var texts []string
+	for _, reminder := range got {
+		texts = append(texts, reminder.GetReminderText())
+	}
+	if expected := []string{"call mom", "buy milk", "first"}; !slices.Equal(texts, expected) {
+		t.Errorf("expected reminders %v got %v", expected, texts)
+	}
+
+	if _, err := repo.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "over quota", RemindTimestamp: at(1)}); !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+		t.Errorf("expected the created reminders to count against the quota got %v", err)
+	}
+}
+
+func testRemoveReminder(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	removed := mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "removed", RemindTimestamp: at(1)})
+	kept := mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "kept", RemindTimestamp: at(2)})
+
+	if err := repo.RemoveReminder(ctx, removed); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	if _, err := repo.GetReminder(ctx, removed); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for removed reminder got %v", err)
+	}
+	if err := repo.RemoveReminder(ctx, removed); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound removing reminder twice got %v", err)
+	}
+	if err := repo.RemoveReminder(ctx, kept+1000); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound removing unknown reminder got %v", err)
+	}
+
+	reminders, err := repo.GetRemindersByUserId(ctx, 1)
+	if err != nil {
+		t.Fatalf("cannot get reminders: %v", err)
+	}
+	if len(reminders) != 1 || reminders[0].GetId() != kept {
+		t.Errorf("expected only reminder %v to be left got %+v", kept, reminders)
+	}
+}
+
+func testGetRemindersByUserId(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	mustSetUser(t, repo, &pb.User{Id: 2})
+
+	reminders, err := repo.GetRemindersByUserId(ctx, 1)
+	if err != nil || len(reminders) != 0 {
+		t.Errorf("expected no reminders got %+v, %v", reminders, err)
+	}
+
+	later := mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "later", RemindTimestamp: at(3)})
+	mustCreateReminder(t, repo, &pb.Reminder{UserId: 2, ReminderText: "other user", RemindTimestamp: at(1)})
+	sooner := mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "sooner", RemindTimestamp: at(1)})
+	sameTime := mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "same time", RemindTimestamp: at(3)})
+
+	reminders, err = repo.GetRemindersByUserId(ctx, 1)
+	if err != nil {
+		t.Fatalf("cannot get reminders: %v", err)
+	}
+
+	want := []int32{sooner, later, sameTime}
+	if len(reminders) != len(want) {
+		t.Fatalf("expected %v reminders got %+v", len(want), reminders)
+	}
+	for i, reminder := range reminders {
+		if reminder.GetId() != want[i] {
+			t.Errorf("expected reminder %v at position %v got %v", want[i], i, reminder.GetId())
+		}
+		if reminder.GetUserId() != 1 {
+			t.Errorf("expected reminders of user 1 only got %+v", reminder)
+		}
+	}
+}
+
+func testQuota(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+	mustSetUser(t, repo, &pb.User{Id: 2})
+
+	var ids []int32
+	for i := 0; i < 3; i++ {
+		ids = append(ids, mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "quota", RemindTimestamp: at(1)}))
+	}
+
+	_, err := repo.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "over quota", RemindTimestamp: at(1)})
+	if !errors.Is(err, todoserviceserver.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded got %v", err)
+	}
+
+	mustCreateReminder(t, repo, &pb.Reminder{UserId: 2, ReminderText: "other user", RemindTimestamp: at(1)})
+
+	if err := repo.RemoveReminder(ctx, ids[0]); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+	mustCreateReminder(t, repo, &pb.Reminder{UserId: 1, ReminderText: "after removal", RemindTimestamp: at(1)})
+}
+
+// testConcurrency expects a repo allowing 10 active reminders per user.
+func testConcurrency(t *testing.T, repo todoserviceserver.Repo) {
+	ctx := context.Background()
+	const workers = 25
+
+	mustSetUser(t, repo, &pb.User{Id: 1})
+
+	var (
+		wg      sync.WaitGroup
+		created atomic.Int32
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := repo.CreateReminder(ctx, &pb.Reminder{UserId: 1, ReminderText: "concurrent", RemindTimestamp: at(i + 1)})
+			switch {
+			case err == nil:
+				created.Add(1)
+			case !errors.Is(err, todoserviceserver.ErrQuotaExceeded):
+				t.Errorf("unexpected error creating reminder: %v", err)
+			}
+
+			if i%2 == 0 {
+				err = repo.SetUser(ctx, &pb.User{Id: 1, LanguageCode: wrapperspb.String("en")})
+			} else {
+				err = repo.SetUser(ctx, &pb.User{Id: 1, UtcOffset: wrapperspb.Int32(int32(i % 14))})
+			}
+			if err != nil {
+				t.Errorf("unexpected error setting user: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if created.Load() != 10 {
+		t.Errorf("expected exactly 10 reminders within quota got %v", created.Load())
+	}
+
+	reminders, err := repo.GetRemindersByUserId(ctx, 1)
+	if err != nil || len(reminders) != 10 {
+		t.Errorf("expected 10 stored reminders got %v, %v", len(reminders), err)
+	}
+
+	user, err := repo.GetUser(ctx, 1)
+	if err != nil || user.GetLanguageCode() == nil || user.GetUtcOffset() == nil {
+		t.Errorf("expected concurrent partial updates to both be kept got %+v, %v", user, err)
+	}
+}
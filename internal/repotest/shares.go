@@ -0,0 +1,310 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// ShareStore is a storage backend as a whole, which shares, deliveries and
+// deletions cover.
+type ShareStore interface {
+	todoserviceserver.ShareStore
+	todoserviceserver.Repo
+	todoserviceserver.UserDataStore
+	userdata.Store
+	notify.Store
+	admin.Store
+}
+
+// ShareFactory returns an empty backend.
+type ShareFactory func(t *testing.T) ShareStore
+
+func userRecipient(userId int64) *pb.ShareRecipient {
+	return &pb.ShareRecipient{Recipient: &pb.ShareRecipient_UserId{UserId: userId}}
+}
+
+func chatRecipient(chatId int64) *pb.ShareRecipient {
+	return &pb.ShareRecipient{Recipient: &pb.ShareRecipient_ChatId{ChatId: chatId}}
+}
+
+// recipientString formats recipient for comparisons, like user:2.
+func recipientString(recipient *pb.ShareRecipient) string {
+	if chatId := recipient.GetChatId(); chatId != 0 {
+		return "chat:" + strconv.FormatInt(chatId, 10)
+	}
+
+	return "user:" + strconv.FormatInt(recipient.GetUserId(), 10)
+}
+
+// RunShares checks an implementation of todoserviceserver.ShareStore and
+// that shared reminders are delivered to every recipient who accepted.
+func RunShares(t *testing.T, newStore ShareFactory) {
+	ctx := context.Background()
+	store := newStore(t)
+	now := time.Now()
+
+	mustSetUser(t, store, &pb.User{Id: 1, NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+		{Kind: pb.NotificationChannel_EMAIL, Address: "family@example.com"},
+	}}})
+	mustSetUser(t, store, &pb.User{Id: 2})
+	mustSetUser(t, store, &pb.User{Id: 3})
+
+	due := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "water the plants", RemindTimestamp: timestamppb.New(now.Add(-time.Minute))})
+	later := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "call grandma", RemindTimestamp: at(1)})
+	removed := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "removed", RemindTimestamp: at(2)})
+
+	share := func(reminderId int32, recipient *pb.ShareRecipient, role pb.ReminderShare_Role) (*pb.ReminderShare, error) {
+		return store.ShareReminder(ctx, &pb.ReminderShare{ReminderId: reminderId, Recipient: recipient, Role: role})
+	}
+
+	recipients := func(shares []*pb.ReminderShare) []string {
+		var got []string
+		for _, share := range shares {
+			got = append(got, recipientString(share.GetRecipient()))
+		}
+
+		return got
+	}
+
+	t.Run("ShareReminder", func(t *testing.T) {
+		if _, err := share(100, userRecipient(2), pb.ReminderShare_VIEWER); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a missing reminder got %v", todoserviceserver.ErrNotFound, err)
+		}
+		if _, err := share(due, userRecipient(100), pb.ReminderShare_VIEWER); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a missing user got %v", todoserviceserver.ErrNotFound, err)
+		}
+
+		got, err := share(due, userRecipient(2), pb.ReminderShare_VIEWER)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if got.GetReminderId() != due || got.GetRecipient().GetUserId() != 2 || got.GetRole() != pb.ReminderShare_VIEWER ||
+			got.GetAccepted() || got.GetCreatedAt().AsTime().Before(now.Add(-time.Second)) {
+			t.Errorf("unexpected share %v", got)
+		}
+
+		for _, recipient := range []*pb.ShareRecipient{chatRecipient(-100), userRecipient(3)} {
+			if _, err := share(due, recipient, pb.ReminderShare_VIEWER); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+		for _, recipient := range []*pb.ShareRecipient{userRecipient(2), userRecipient(3)} {
+			if _, err := share(removed, recipient, pb.ReminderShare_VIEWER); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+		if got, err := share(due, userRecipient(3), pb.ReminderShare_EDITOR); err != nil || got.GetRole() != pb.ReminderShare_EDITOR {
+			t.Errorf("expected the role of user 3 changed got %v, %v", got, err)
+		}
+
+		shares, err := store.GetReminderShares(ctx, due)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if expected := []string{"user:2", "chat:-100", "user:3"}; !slices.Equal(recipients(shares), expected) {
+			t.Errorf("expected shares with %v got %v", expected, recipients(shares))
+		}
+		if shares[2].GetRole() != pb.ReminderShare_EDITOR {
+			t.Errorf("expected user 3 to be an editor got %v", shares[2])
+		}
+	})
+
+	t.Run("AcceptReminderShare", func(t *testing.T) {
+		if err := store.AcceptReminderShare(ctx, later, userRecipient(2)); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a missing share got %v", todoserviceserver.ErrNotFound, err)
+		}
+
+		invitations, err := store.GetReminderInvitations(ctx, userRecipient(2))
+		if err != nil || len(invitations) != 2 || invitations[0].GetReminderId() != due || invitations[1].GetReminderId() != removed {
+			t.Fatalf("expected two invitations of user 2 got %v, %v", invitations, err)
+		}
+		if shared, err := store.GetSharedReminders(ctx, 2); err != nil || len(shared) != 0 {
+			t.Errorf("expected no reminders shared with user 2 before accepting got %v, %v", shared, err)
+		}
+
+		for _, recipient := range []*pb.ShareRecipient{userRecipient(2), chatRecipient(-100), userRecipient(2)} {
+			if err := store.AcceptReminderShare(ctx, due, recipient); err != nil {
+				t.Fatalf("did not expect error got %v", err)
+			}
+		}
+		if err := store.AcceptReminderShare(ctx, removed, userRecipient(2)); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		// Raising a viewer to an editor asks for a new acceptance, lowering
+		// it does not.
+		if got, err := share(due, userRecipient(2), pb.ReminderShare_VIEWER); err != nil || !got.GetAccepted() {
+			t.Errorf("expected the share of user 2 still accepted got %v, %v", got, err)
+		}
+		if got, err := share(due, userRecipient(2), pb.ReminderShare_EDITOR); err != nil || got.GetAccepted() {
+			t.Errorf("expected the raised share of user 2 not accepted got %v, %v", got, err)
+		}
+		if invitations, err := store.GetReminderInvitations(ctx, userRecipient(2)); err != nil || len(invitations) != 1 || invitations[0].GetReminderId() != due {
+			t.Errorf("expected user 2 invited to reminder %d again got %v, %v", due, invitations, err)
+		}
+		if err := store.AcceptReminderShare(ctx, due, userRecipient(2)); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if got, err := share(due, userRecipient(2), pb.ReminderShare_VIEWER); err != nil || !got.GetAccepted() {
+			t.Errorf("expected the lowered share of user 2 still accepted got %v, %v", got, err)
+		}
+
+		if invitations, err := store.GetReminderInvitations(ctx, chatRecipient(-100)); err != nil || len(invitations) != 0 {
+			t.Errorf("expected no invitations of the chat left got %v, %v", invitations, err)
+		}
+		if invitations, err := store.GetReminderInvitations(ctx, userRecipient(3)); err != nil || len(invitations) != 2 {
+			t.Errorf("expected the invitations of user 3 left got %v, %v", invitations, err)
+		}
+	})
+
+	t.Run("removed reminders", func(t *testing.T) {
+		if err := store.RemoveReminder(ctx, removed); err != nil {
+			t.Fatalf("cannot remove reminder: %v", err)
+		}
+
+		if _, err := share(removed, userRecipient(2), pb.ReminderShare_VIEWER); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a removed reminder got %v", todoserviceserver.ErrNotFound, err)
+		}
+		if err := store.AcceptReminderShare(ctx, removed, userRecipient(3)); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a removed reminder got %v", todoserviceserver.ErrNotFound, err)
+		}
+
+		invitations, err := store.GetReminderInvitations(ctx, userRecipient(3))
+		if err != nil || len(invitations) != 1 || invitations[0].GetReminderId() != due {
+			t.Errorf("expected only the invitation of user 3 to reminder %d got %v, %v", due, invitations, err)
+		}
+
+		shared, err := store.GetSharedReminders(ctx, 2)
+		if err != nil || len(shared) != 1 || shared[0].GetId() != due || shared[0].GetUserId() != 1 || shared[0].GetReminderText() != "water the plants" {
+			t.Errorf("expected only reminder %d shared with user 2 got %v, %v", due, shared, err)
+		}
+	})
+
+	t.Run("FireDueReminders", func(t *testing.T) {
+		route := func(userId int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel {
+			if len(channels) > 0 {
+				return channels
+			}
+
+			return []*pb.NotificationChannel{{Kind: pb.NotificationChannel_TELEGRAM, Address: strconv.FormatInt(userId, 10)}}
+		}
+		if fired, err := store.FireDueReminders(ctx, now, 10, route); err != nil || fired != 1 {
+			t.Fatalf("expected one reminder fired got %d, %v", fired, err)
+		}
+
+		deliveries, err := store.ClaimDeliveries(ctx, now, now.Add(time.Minute), 10)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		var addresses []string
+		for _, delivery := range deliveries {
+			if delivery.Reminder.GetId() != due {
+				t.Errorf("expected a delivery of reminder %d got %+v", due, delivery)
+			}
+			addresses = append(addresses, delivery.Kind.String()+" "+delivery.Address)
+		}
+		slices.Sort(addresses)
+
+		// User 3 did not accept.
+		if expected := []string{"EMAIL family@example.com", "TELEGRAM -100", "TELEGRAM 2"}; !slices.Equal(addresses, expected) {
+			t.Errorf("expected deliveries to %v got %v", expected, addresses)
+		}
+	})
+
+	t.Run("RemoveReminderShare", func(t *testing.T) {
+		if err := store.RemoveReminderShare(ctx, due, chatRecipient(-100)); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if err := store.RemoveReminderShare(ctx, due, chatRecipient(-100)); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected %v for a removed share got %v", todoserviceserver.ErrNotFound, err)
+		}
+
+		shares, err := store.GetReminderShares(ctx, due)
+		if expected := []string{"user:2", "user:3"}; err != nil || !slices.Equal(recipients(shares), expected) {
+			t.Errorf("expected shares with %v got %v, %v", expected, recipients(shares), err)
+		}
+	})
+
+	t.Run("PurgeRemovedReminders", func(t *testing.T) {
+		if n, err := store.PurgeRemovedReminders(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+			t.Fatalf("expected one reminder purged got %d, %v", n, err)
+		}
+	})
+
+	addresses := func(deliveries []userdata.Delivery) []string {
+		var got []string
+		for _, delivery := range deliveries {
+			got = append(got, delivery.Channel+" "+delivery.Address)
+		}
+		slices.Sort(got)
+
+		return got
+	}
+
+	t.Run("GetUserData", func(t *testing.T) {
+		data, err := store.GetUserData(ctx, 2)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if len(data.Shares) != 1 || data.Shares[0].ReminderID != due || data.Shares[0].OwnerID != 1 ||
+			*data.Shares[0].UserID != 2 || data.Shares[0].Role != "VIEWER" || data.Shares[0].AcceptedAt == nil {
+			t.Errorf("expected the share of reminder %d with user 2 got %+v", due, data.Shares)
+		}
+		if expected := []string{"TELEGRAM 2"}; !slices.Equal(addresses(data.Deliveries), expected) {
+			t.Errorf("expected deliveries to %v got %v", expected, addresses(data.Deliveries))
+		}
+
+		data, err = store.GetUserData(ctx, 1)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if len(data.Shares) != 2 || *data.Shares[0].UserID != 2 || *data.Shares[1].UserID != 3 || data.Shares[1].AcceptedAt != nil {
+			t.Errorf("expected the shares of reminder %d with users 2 and 3 got %+v", due, data.Shares)
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		deleteAt := time.Now()
+		deleteUser := func(userId int64) {
+			t.Helper()
+
+			if _, err := store.ScheduleUserDeletion(ctx, userId, deleteAt); err != nil {
+				t.Fatalf("cannot schedule deletion: %v", err)
+			}
+			if _, ok, err := store.DeleteUser(ctx, userId, deleteAt); err != nil || !ok {
+				t.Fatalf("expected user %d deleted got %v, %v", userId, ok, err)
+			}
+		}
+
+		deleteUser(2)
+		data, err := store.GetUserData(ctx, 1)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if expected := []string{"EMAIL family@example.com", "TELEGRAM -100"}; !slices.Equal(addresses(data.Deliveries), expected) {
+			t.Errorf("expected the deliveries to user 2 deleted got %v", addresses(data.Deliveries))
+		}
+		if len(data.Shares) != 1 || *data.Shares[0].UserID != 3 {
+			t.Errorf("expected the share with user 2 deleted got %+v", data.Shares)
+		}
+
+		deleteUser(1)
+
+		if invitations, err := store.GetReminderInvitations(ctx, userRecipient(3)); err != nil || len(invitations) != 0 {
+			t.Errorf("expected the shares of deleted reminders gone got %v, %v", invitations, err)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// UserDataStore is a storage backend as a whole, which user data exports
+// and deletions cover.
+type UserDataStore interface {
+	todoserviceserver.UserDataStore
+	userdata.Store
+	todoserviceserver.Repo
+	todoserviceserver.HookTokenStore
+	todoserviceserver.CalendarFeedStore
+	notify.Store
+}
+
+// UserDataFactory returns an empty backend.
+type UserDataFactory func(t *testing.T) UserDataStore
+
+// RunUserData checks an implementation of todoserviceserver.UserDataStore
+// and userdata.Store.
+func RunUserData(t *testing.T, newStore UserDataFactory) {
+	ctx := context.Background()
+	store := newStore(t)
+	now := time.Now()
+
+	mustSetUser(t, store, &pb.User{Id: 1, LanguageCode: wrapperspb.String("en"), UtcOffset: wrapperspb.Int32(2),
+		NotificationChannels: &pb.NotificationChannels{Channels: []*pb.NotificationChannel{
+			{Kind: pb.NotificationChannel_EMAIL, Address: "user@example.com"},
+		}}})
+	mustSetUser(t, store, &pb.User{Id: 2})
+
+	fired := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "water the plants", RemindTimestamp: timestamppb.New(now.Add(-time.Minute))})
+	pending := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "call mom", RemindTimestamp: at(1)})
+	removed := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "removed", RemindTimestamp: at(2)})
+	other := mustCreateReminder(t, store, &pb.Reminder{UserId: 2, ReminderText: "someone else's", RemindTimestamp: at(3)})
+	if err := store.RemoveReminder(ctx, removed); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	route := func(_ int64, channels []*pb.NotificationChannel) []*pb.NotificationChannel { return channels }
+	if _, err := store.FireDueReminders(ctx, now, 10, route); err != nil {
+		t.Fatalf("cannot fire reminders: %v", err)
+	}
+
+	for userId, hash := range map[int64]string{1: "first", 2: "second"} {
+		if err := store.SetHookToken(ctx, userId, []byte(hash)); err != nil {
+			t.Fatalf("cannot set hook token: %v", err)
+		}
+	}
+	if err := store.CreateCalendarFeed(ctx, 1, []byte("feed")); err != nil {
+		t.Fatalf("cannot create calendar feed: %v", err)
+	}
+
+	t.Run("GetUserData", func(t *testing.T) {
+		if _, err := store.GetUserData(ctx, 3); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for an unknown user got %v", err)
+		}
+
+		data, err := store.GetUserData(ctx, 1)
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		profile := data.Profile
+		if profile.ID != 1 || profile.LanguageCode == nil || *profile.LanguageCode != "en" || profile.UTCOffset == nil || *profile.UTCOffset != 2 {
+			t.Errorf("expected the profile of user 1 got %+v", profile)
+		}
+		if expected := []userdata.Channel{{Kind: "EMAIL", Address: "user@example.com"}}; !slices.Equal(profile.NotificationChannels, expected) {
+			t.Errorf("expected channels %v got %v", expected, profile.NotificationChannels)
+		}
+
+		var ids []int32
+		for _, reminder := range data.Reminders {
+			ids = append(ids, reminder.ID)
+		}
+		if expected := []int32{fired, pending, removed}; !slices.Equal(ids, expected) {
+			t.Fatalf("expected reminders %v got %v", expected, ids)
+		}
+		if data.Reminders[0].FiredAt == nil || data.Reminders[1].FiredAt != nil || data.Reminders[2].RemovedAt == nil {
+			t.Errorf("expected fired and removed reminders got %+v", data.Reminders)
+		}
+
+		if len(data.Deliveries) != 1 || data.Deliveries[0].ReminderID != fired || data.Deliveries[0].Address != "user@example.com" {
+			t.Errorf("expected the delivery of reminder %d got %+v", fired, data.Deliveries)
+		}
+
+		settings := data.Settings
+		if settings.HookTokenCreatedAt == nil || settings.CalendarFeedCreatedAt == nil || settings.DeleteAt != nil {
+			t.Errorf("expected a hook token and a calendar feed got %+v", settings)
+		}
+		if settings.Webhooks == nil || settings.APIKeys == nil {
+			t.Errorf("expected empty webhooks and API keys got %+v", settings)
+		}
+	})
+
+	deleteAt := now.Add(time.Hour).Truncate(time.Microsecond)
+
+	t.Run("ScheduleUserDeletion", func(t *testing.T) {
+		if _, err := store.ScheduleUserDeletion(ctx, 3, deleteAt); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for an unknown user got %v", err)
+		}
+		if err := store.CancelUserDeletion(ctx, 1); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound cancelling an unscheduled deletion got %v", err)
+		}
+
+		for _, at := range []time.Time{deleteAt, deleteAt.Add(time.Hour)} {
+			scheduled, err := store.ScheduleUserDeletion(ctx, 1, at)
+			if err != nil || !scheduled.Equal(deleteAt) {
+				t.Errorf("expected deletion at %v got %v, %v", deleteAt, scheduled, err)
+			}
+		}
+
+		data, err := store.GetUserData(ctx, 1)
+		if err != nil || data.Settings.DeleteAt == nil || !data.Settings.DeleteAt.Equal(deleteAt) {
+			t.Errorf("expected deletion at %v in the export got %+v, %v", deleteAt, data, err)
+		}
+
+		if due, err := store.DueUserDeletions(ctx, now, 10); err != nil || len(due) != 0 {
+			t.Errorf("expected no due deletions got %v, %v", due, err)
+		}
+		if due, err := store.DueUserDeletions(ctx, deleteAt, 10); err != nil || !slices.Equal(due, []int64{1}) {
+			t.Errorf("expected the deletion of user 1 due got %v, %v", due, err)
+		}
+		if _, ok, err := store.DeleteUser(ctx, 1, now); err != nil || ok {
+			t.Errorf("expected no deletion before it is due got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("CancelUserDeletion", func(t *testing.T) {
+		if err := store.CancelUserDeletion(ctx, 1); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		if due, err := store.DueUserDeletions(ctx, deleteAt, 10); err != nil || len(due) != 0 {
+			t.Errorf("expected no due deletions got %v, %v", due, err)
+		}
+		if _, ok, err := store.DeleteUser(ctx, 1, deleteAt); err != nil || ok {
+			t.Errorf("expected no deletion once cancelled got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		if _, err := store.ScheduleUserDeletion(ctx, 1, deleteAt); err != nil {
+			t.Fatalf("cannot schedule deletion: %v", err)
+		}
+
+		deletion, ok, err := store.DeleteUser(ctx, 1, deleteAt)
+		if err != nil || !ok {
+			t.Fatalf("expected user 1 deleted got %v, %v", ok, err)
+		}
+		if deletion.Reminders != 3 || deletion.Deliveries != 1 || deletion.RequestedAt.IsZero() {
+			t.Errorf("expected 3 reminders and 1 delivery deleted got %+v", deletion)
+		}
+
+		if _, err := store.GetUser(ctx, 1); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for the deleted user got %v", err)
+		}
+		if reminders, err := store.GetRemindersByUserId(ctx, 1); err != nil || len(reminders) != 0 {
+			t.Errorf("expected no reminders of the deleted user got %v, %v", reminders, err)
+		}
+		if _, err := store.GetReminder(ctx, fired); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a reminder of the deleted user got %v", err)
+		}
+		if _, err := store.FindHookToken(ctx, []byte("first")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for the hook token of the deleted user got %v", err)
+		}
+		if _, _, err := store.FindCalendarFeed(ctx, []byte("feed")); !errors.Is(err, todoserviceserver.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for the calendar feed of the deleted user got %v", err)
+		}
+		if due, err := store.DueUserDeletions(ctx, deleteAt, 10); err != nil || len(due) != 0 {
+			t.Errorf("expected no due deletions got %v, %v", due, err)
+		}
+
+		if _, err := store.GetReminder(ctx, other); err != nil {
+			t.Errorf("expected the reminders of other users kept got %v", err)
+		}
+		if userId, err := store.FindHookToken(ctx, []byte("second")); err != nil || userId != 2 {
+			t.Errorf("expected the hook token of user 2 kept got %d, %v", userId, err)
+		}
+
+		// A deleted user may sign up again.
+		mustSetUser(t, store, &pb.User{Id: 1})
+	})
+}
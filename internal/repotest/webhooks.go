@@ -0,0 +1,183 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+// WebhookStore is a storage backend queueing the events of users for their
+// webhooks.
+type WebhookStore interface {
+	todoserviceserver.WebhookStore
+	todoserviceserver.Repo
+	webhooks.Store
+}
+
+// WebhookFactory returns an empty backend.
+type WebhookFactory func(t *testing.T) WebhookStore
+
+// RunWebhooks checks an implementation of todoserviceserver.WebhookStore
+// and of webhooks.Store.
+func RunWebhooks(t *testing.T, newStore WebhookFactory) {
+	ctx := context.Background()
+	store := newStore(t)
+	now := time.Now()
+
+	mustSetUser(t, store, &pb.User{Id: 1})
+	mustSetUser(t, store, &pb.User{Id: 2})
+
+	if _, err := store.CreateWebhook(ctx, &pb.Webhook{UserId: 3, Url: "https://example.com", Secret: "secret"}); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown user got %v", err)
+	}
+
+	create := func(webhook *pb.Webhook) int64 {
+		t.Helper()
+
+		id, err := store.CreateWebhook(ctx, webhook)
+		if err != nil {
+			t.Fatalf("cannot create webhook: %v", err)
+		}
+
+		return id
+	}
+	all := create(&pb.Webhook{UserId: 1, Url: "https://example.com/all", Secret: "all secret"})
+	created := create(&pb.Webhook{UserId: 1, Url: "https://example.com/created", Secret: "created secret", Events: []string{"reminder_created"}})
+	other := create(&pb.Webhook{UserId: 2, Url: "https://example.com/other", Secret: "other secret"})
+
+	got, err := store.GetWebhook(ctx, all)
+	if err != nil || got.GetUrl() != "https://example.com/all" || !got.GetEnabled() || got.GetSecret() != "" {
+		t.Errorf("expected enabled webhook without its secret got %+v, %v", got, err)
+	}
+	if _, err := store.GetWebhook(ctx, other+100); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown webhook got %v", err)
+	}
+	if hooks, err := store.GetWebhooksByUserId(ctx, 1); err != nil || len(hooks) != 2 || hooks[0].GetId() != all || hooks[1].GetId() != created {
+		t.Errorf("expected webhooks %d and %d of user 1 got %+v, %v", all, created, hooks, err)
+	}
+
+	reminder := mustCreateReminder(t, store, &pb.Reminder{UserId: 1, ReminderText: "buy milk", RemindTimestamp: at(1)})
+	if err := store.RemoveReminder(ctx, reminder); err != nil {
+		t.Fatalf("cannot remove reminder: %v", err)
+	}
+
+	claim := func(now time.Time) []webhooks.Delivery {
+		t.Helper()
+
+		deliveries, err := store.ClaimWebhookDeliveries(ctx, now, now.Add(time.Minute), 10)
+		if err != nil {
+			t.Fatalf("cannot claim webhook deliveries: %v", err)
+		}
+
+		return deliveries
+	}
+
+	// The creation goes to both webhooks of user 1, the removal to one.
+	deliveries := claim(now.Add(time.Minute))
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 deliveries got %+v", deliveries)
+	}
+	var toAll, toCreated []webhooks.Delivery
+	for _, delivery := range deliveries {
+		switch {
+		case delivery.WebhookID == all && delivery.Secret == "all secret":
+			toAll = append(toAll, delivery)
+		case delivery.WebhookID == created && delivery.URL == "https://example.com/created":
+			toCreated = append(toCreated, delivery)
+		}
+		if delivery.Attempts != 1 || delivery.Event.GetUserId() != 1 {
+			t.Errorf("expected a first attempt of an event of user 1 got %+v", delivery)
+		}
+	}
+	if len(toAll) != 2 || len(toCreated) != 1 || toCreated[0].Event.GetReminderCreated().GetId() != reminder {
+		t.Fatalf("expected 2 deliveries to webhook %d and the creation to webhook %d got %+v", all, created, deliveries)
+	}
+
+	if leased := claim(now.Add(time.Minute)); len(leased) != 0 {
+		t.Errorf("expected claimed deliveries to be leased got %+v", leased)
+	}
+
+	if err := store.WebhookDelivered(ctx, toAll[0].ID); err != nil {
+		t.Fatalf("cannot record delivery: %v", err)
+	}
+	if err := store.WebhookDeliveryFailed(ctx, toAll[1].ID, "503", now.Add(time.Hour), 5); err != nil {
+		t.Fatalf("cannot record failure: %v", err)
+	}
+	if err := store.WebhookDeliveryFailed(ctx, toCreated[0].ID, "gone", time.Time{}, 1); err != nil {
+		t.Fatalf("cannot record failure: %v", err)
+	}
+
+	// The failed delivery is retried, the one given up became a dead letter
+	// and disabled its webhook.
+	if retried := claim(now.Add(2 * time.Hour)); len(retried) != 1 || retried[0].ID != toAll[1].ID || retried[0].Attempts != 2 {
+		t.Errorf("expected delivery %d retried got %+v", toAll[1].ID, retried)
+	}
+
+	deadLetters, err := store.GetWebhookDeadLettersByUserId(ctx, 1)
+	if err != nil || len(deadLetters) != 1 {
+		t.Fatalf("expected a dead letter got %+v, %v", deadLetters, err)
+	}
+	if deadLetter := deadLetters[0]; deadLetter.GetWebhookId() != created || deadLetter.GetLastError() != "gone" || deadLetter.GetAttempts() != 1 ||
+		deadLetter.GetEvent().GetReminderCreated().GetId() != reminder {
+		t.Errorf("expected the creation to webhook %d given up got %+v", created, deadLetter)
+	}
+	if _, err := store.GetWebhookDeadLetter(ctx, deadLetters[0].GetId()); err != nil {
+		t.Errorf("cannot get dead letter: %v", err)
+	}
+	if deadLetters, err := store.GetWebhookDeadLettersByUserId(ctx, 2); err != nil || len(deadLetters) != 0 {
+		t.Errorf("expected no dead letters of user 2 got %+v, %v", deadLetters, err)
+	}
+
+	got, err = store.GetWebhook(ctx, created)
+	if err != nil || got.GetEnabled() || !strings.Contains(got.GetDisabledReason(), "gone") {
+		t.Errorf("expected webhook disabled for its failure got %+v, %v", got, err)
+	}
+
+	update := &pb.Webhook{Id: created, Url: "https://example.com/updated", Events: []string{"reminder_created"}}
+	if err := store.UpdateWebhook(ctx, update); err != nil {
+		t.Fatalf("cannot update webhook: %v", err)
+	}
+	if got, err := store.GetWebhook(ctx, created); err != nil || got.GetUrl() != update.GetUrl() || got.GetEnabled() {
+		t.Errorf("expected updated webhook left disabled got %+v, %v", got, err)
+	}
+
+	update.Enabled = proto.Bool(true)
+	if err := store.UpdateWebhook(ctx, update); err != nil {
+		t.Fatalf("cannot enable webhook: %v", err)
+	}
+	if got, err := store.GetWebhook(ctx, created); err != nil || !got.GetEnabled() || got.GetDisabledReason() != "" {
+		t.Errorf("expected enabled webhook got %+v, %v", got, err)
+	}
+	if err := store.UpdateWebhook(ctx, &pb.Webhook{Id: other + 100, Url: "https://example.com"}); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown webhook got %v", err)
+	}
+
+	if err := store.ReplayWebhookDeadLetter(ctx, deadLetters[0].GetId()); err != nil {
+		t.Fatalf("cannot replay dead letter: %v", err)
+	}
+	if err := store.ReplayWebhookDeadLetter(ctx, deadLetters[0].GetId()); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a replayed dead letter got %v", err)
+	}
+	if replayed := claim(now.Add(3 * time.Hour)); len(replayed) != 1 || replayed[0].WebhookID != created || replayed[0].Attempts != 1 ||
+		replayed[0].URL != update.GetUrl() {
+		t.Errorf("expected the dead letter queued again got %+v", replayed)
+	}
+
+	if err := store.DeleteWebhook(ctx, all); err != nil {
+		t.Fatalf("cannot delete webhook: %v", err)
+	}
+	if _, err := store.GetWebhook(ctx, all); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted webhook got %v", err)
+	}
+	if err := store.DeleteWebhook(ctx, all); !errors.Is(err, todoserviceserver.ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting a deleted webhook got %v", err)
+	}
+}
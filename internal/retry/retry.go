@@ -0,0 +1,156 @@
+// Package retry attempts queued deliveries, such as those of fired
+// reminders and of webhook events: it claims them in batches, attempts the
+// deliveries of a batch concurrently, each under a timeout, and retries
+// failed ones with exponential backoff.
+package retry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Policy of attempting deliveries.
+type Policy struct {
+	// BatchSize is how many deliveries are claimed at once.
+	BatchSize int
+	// MaxAttempts is how many times a delivery is attempted before it is
+	// given up.
+	MaxAttempts int
+	// Backoff is the delay before the first retry of a delivery, doubled
+	// for every next one up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout limits every attempt.
+	Timeout time.Duration
+	Now     func() time.Time
+}
+
+type Option func(*Policy)
+
+// WithMaxAttempts sets how many times a delivery is attempted before it is
+// given up.
+func WithMaxAttempts(n int) Option {
+	return func(p *Policy) {
+		p.MaxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a delivery, doubled
+// for every next one up to max.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(p *Policy) {
+		p.Backoff = initial
+		p.MaxBackoff = max
+	}
+}
+
+// WithTimeout limits every attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Policy) {
+		p.Timeout = timeout
+	}
+}
+
+// WithBatchSize sets how many deliveries are claimed at once.
+func WithBatchSize(n int) Option {
+	return func(p *Policy) {
+		p.BatchSize = n
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(p *Policy) {
+		p.Now = now
+	}
+}
+
+// With returns p changed by opts.
+func (p Policy) With(opts ...Option) Policy {
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// Delay returns the delay after the given failed attempt.
+func (p Policy) Delay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempts && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxBackoff)
+}
+
+// RetryAt returns when to attempt a delivery again after the given failed
+// attempt, or zero when it is given up.
+func (p Policy) RetryAt(attempts int) time.Time {
+	if attempts >= p.MaxAttempts {
+		return time.Time{}
+	}
+
+	return p.Now().Add(p.Delay(attempts))
+}
+
+// Attempt calls attempt with a context limited by the timeout of p.
+func (p Policy) Attempt(ctx context.Context, attempt func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	return attempt(ctx)
+}
+
+// ClaimFunc returns up to limit deliveries due at now, counting an attempt
+// for each and hiding them from other claims until leaseUntil.
+type ClaimFunc[T any] func(ctx context.Context, now, leaseUntil time.Time, limit int) ([]T, error)
+
+// Process claims deliveries in batches and calls deliver for each delivery
+// of a batch concurrently, until a batch is not full.
+func Process[T any](ctx context.Context, p Policy, claim ClaimFunc[T], deliver func(context.Context, T)) error {
+	for {
+		now := p.Now()
+		// A delivery whose attempt outlives its lease may be claimed again,
+		// so the lease leaves room for recording the outcome.
+		deliveries, err := claim(ctx, now, now.Add(2*p.Timeout), p.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				deliver(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < p.BatchSize {
+			return nil
+		}
+	}
+}
+
+// Run calls work every interval until ctx is done, logging its errors as
+// errors of what.
+func Run(ctx context.Context, interval time.Duration, what string, work func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := work(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error %s: %v", what, err)
+			}
+		}
+	}
+}
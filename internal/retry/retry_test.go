@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPolicy_RetryAt(t *testing.T) {
+	now := time.Now()
+	p := Policy{MaxAttempts: 5, Backoff: time.Minute, MaxBackoff: 5 * time.Minute}.With(WithClock(func() time.Time { return now }))
+
+	for _, tt := range []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+	} {
+		if retryAt := p.RetryAt(tt.attempts); !retryAt.Equal(now.Add(tt.delay)) {
+			t.Errorf("expected retry %v after attempt %d got %v", tt.delay, tt.attempts, retryAt.Sub(now))
+		}
+	}
+
+	if retryAt := p.RetryAt(5); !retryAt.IsZero() {
+		t.Errorf("expected delivery given up after the last attempt got %v", retryAt)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	now := time.Now()
+	p := Policy{BatchSize: 2, Timeout: time.Second, Now: func() time.Time { return now }}
+	queue := []int{1, 2, 3, 4, 5}
+
+	claim := func(_ context.Context, claimedAt, leaseUntil time.Time, limit int) ([]int, error) {
+		if !claimedAt.Equal(now) || !leaseUntil.Equal(now.Add(2*time.Second)) {
+			t.Errorf("expected a lease of 2s from now got %v to %v", claimedAt, leaseUntil)
+		}
+
+		batch := queue[:min(limit, len(queue))]
+		queue = queue[len(batch):]
+
+		return batch, nil
+	}
+
+	var (
+		mu        sync.Mutex
+		delivered int
+	)
+	err := Process(context.Background(), p, claim, func(context.Context, int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		delivered++
+	})
+	if err != nil || delivered != 5 {
+		t.Errorf("expected 5 deliveries got %d, %v", delivered, err)
+	}
+
+	claimErr := errors.New("database is down")
+	err = Process(context.Background(), p, func(context.Context, time.Time, time.Time, int) ([]int, error) {
+		return nil, claimErr
+	}, func(context.Context, int) {})
+	if !errors.Is(err, claimErr) {
+		t.Errorf("expected %v got %v", claimErr, err)
+	}
+}
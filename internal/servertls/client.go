@@ -0,0 +1,70 @@
+package servertls
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Client describes the frontend calling the server, as stated by its
+// verified client certificate.
+type Client struct {
+	// Subject is the RFC 2253 form of the certificate subject.
+	Subject    string
+	CommonName string
+}
+
+type clientKey struct{}
+
+// ClientFromContext returns the client put into ctx by the interceptors.
+// It is false when mutual TLS is not used on the connection.
+func ClientFromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(clientKey{}).(Client)
+
+	return client, ok
+}
+
+func withClient(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, clientKey{}, clientFromCertificate(info.State.VerifiedChains[0][0]))
+}
+
+func clientFromCertificate(cert *x509.Certificate) Client {
+	return Client{
+		Subject:    cert.Subject.String(),
+		CommonName: cert.Subject.CommonName,
+	}
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClient(ctx), req)
+	}
+}
+
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withClient(ss.Context())})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
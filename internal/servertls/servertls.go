@@ -0,0 +1,178 @@
+// Package servertls provides TLS credentials for the gRPC server whose
+// certificates can be replaced without a restart, and exposes the verified
+// client certificate of mutual TLS connections to handlers.
+package servertls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// Reloader holds the current server certificate and client CA pool.
+// A failed reload keeps serving the previously loaded files.
+type Reloader struct {
+	certFile, keyFile, clientCAFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func New(cfg config.TLS) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     cfg.CertFile,
+		keyFile:      cfg.KeyFile,
+		clientCAFile: cfg.ClientCAFile,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload reads the certificate, key and client CA files again.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load server certificate: %w", err)
+	}
+
+	var clientCA *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read client CA bundle: %w", err)
+		}
+
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(pem) {
+			return errors.New("client CA bundle contains no certificates")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.clientCA = clientCA
+	r.modTimes = modTimes
+
+	return nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+
+	return files
+}
+
+func (r *Reloader) statFiles() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func (r *Reloader) changed() bool {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		// Files are likely being replaced right now, check again later.
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for file, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Watch reloads the files every time their modification time changes,
+// checking every interval until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				log.Printf("Error reloading TLS certificates: %v", err)
+			} else {
+				log.Printf("TLS certificates were reloaded")
+			}
+		}
+	}
+}
+
+// TLSConfig returns a configuration which picks up the latest
+// loaded certificates on every handshake.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return r.config(true, []string{"h2"})
+}
+
+// HTTPTLSConfig returns a configuration like TLSConfig for HTTP servers,
+// which never asks for client certificates.
+func (r *Reloader) HTTPTLSConfig() *tls.Config {
+	return r.config(false, []string{"h2", "http/1.1"})
+}
+
+func (r *Reloader) config(clientAuth bool, nextProtos []string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				NextProtos:   nextProtos,
+			}
+
+			if clientAuth && r.clientCA != nil {
+				cfg.ClientCAs = r.clientCA
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return cfg, nil
+		},
+	}
+}
@@ -0,0 +1,287 @@
+package servertls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+type certificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func (c certificate) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c certificate) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.pem, c.keyPEM(t))
+	if err != nil {
+		t.Fatalf("cannot build key pair: %v", err)
+	}
+
+	return cert
+}
+
+// newCertificate creates a certificate signed by parent, or a self-signed CA when parent is nil.
+func newCertificate(t *testing.T, commonName string, parent *certificate) certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"awakair"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return certificate{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("cannot write %s: %v", path, err)
+	}
+}
+
+func server(t *testing.T, r *Reloader) (*bufconn.Listener, <-chan Client) {
+	lis := bufconn.Listen(1024 * 1024)
+	clients := make(chan Client, 1)
+
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(r.TLSConfig())),
+		grpc.ChainUnaryInterceptor(
+			UnaryServerInterceptor(),
+			func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if client, ok := ClientFromContext(ctx); ok {
+					clients <- client
+				}
+
+				return handler(ctx, req)
+			},
+		),
+	)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis, clients
+}
+
+func call(ctx context.Context, lis *bufconn.Listener, cfg *tls.Config) error {
+	conn, err := grpc.DialContext(ctx, "localhost",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}), grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+
+	return err
+}
+
+func TestReloader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := config.TLS{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+
+	ca := newCertificate(t, "test ca", nil)
+	serverCert := newCertificate(t, "server", &ca)
+	writeFile(t, cfg.CertFile, serverCert.pem)
+	writeFile(t, cfg.KeyFile, serverCert.keyPEM(t))
+	writeFile(t, cfg.ClientCAFile, ca.pem)
+
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("did not expect error loading certificates got %v", err)
+	}
+
+	lis, clients := server(t, r)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	t.Run("client with certificate", func(t *testing.T) {
+		clientCert := newCertificate(t, "telegram-frontend", &ca)
+
+		err := call(ctx, lis, &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert.tlsCertificate(t)},
+		})
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		client := <-clients
+		if client.CommonName != "telegram-frontend" || client.Subject != "CN=telegram-frontend,O=awakair" {
+			t.Errorf("unexpected client %+v", client)
+		}
+	})
+
+	t.Run("client without certificate", func(t *testing.T) {
+		err := call(ctx, lis, &tls.Config{RootCAs: roots})
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("expected Unavailable got %v", err)
+		}
+	})
+
+	t.Run("client with certificate of another CA", func(t *testing.T) {
+		otherCA := newCertificate(t, "other ca", nil)
+		clientCert := newCertificate(t, "intruder", &otherCA)
+
+		err := call(ctx, lis, &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert.tlsCertificate(t)},
+		})
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("expected Unavailable got %v", err)
+		}
+	})
+
+	t.Run("http client without certificate", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		srv.TLS = r.HTTPTLSConfig()
+		srv.StartTLS()
+		defer srv.Close()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		resp, err := client.Get("https://localhost:" + srv.URL[strings.LastIndex(srv.URL, ":")+1:])
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected %d got %d", http.StatusNoContent, resp.StatusCode)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		newCA := newCertificate(t, "new ca", nil)
+		newServerCert := newCertificate(t, "new server", &newCA)
+		writeFile(t, cfg.CertFile, newServerCert.pem)
+		writeFile(t, cfg.KeyFile, newServerCert.keyPEM(t))
+		writeFile(t, cfg.ClientCAFile, newCA.pem)
+
+		if err := r.Reload(); err != nil {
+			t.Fatalf("did not expect error reloading got %v", err)
+		}
+
+		newRoots := x509.NewCertPool()
+		newRoots.AddCert(newCA.cert)
+		clientCert := newCertificate(t, "web-frontend", &newCA)
+
+		err := call(ctx, lis, &tls.Config{
+			RootCAs:      newRoots,
+			Certificates: []tls.Certificate{clientCert.tlsCertificate(t)},
+		})
+		if err != nil {
+			t.Fatalf("did not expect error after reload got %v", err)
+		}
+		if client := <-clients; client.CommonName != "web-frontend" {
+			t.Errorf("unexpected client %+v", client)
+		}
+	})
+
+	t.Run("broken files keep previous certificates", func(t *testing.T) {
+		writeFile(t, cfg.KeyFile, []byte("not a key"))
+
+		if err := r.Reload(); err == nil {
+			t.Errorf("expected error reloading broken key")
+		}
+		if r.cert == nil {
+			t.Errorf("expected previous certificate to be kept")
+		}
+	})
+}
+
+func TestReloader_changed(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.TLS{
+		CertFile: filepath.Join(dir, "server.pem"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	}
+
+	ca := newCertificate(t, "test ca", nil)
+	writeFile(t, cfg.CertFile, ca.pem)
+	writeFile(t, cfg.KeyFile, ca.keyPEM(t))
+
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("did not expect error loading certificates got %v", err)
+	}
+
+	if r.changed() {
+		t.Errorf("did not expect change right after loading")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(cfg.CertFile, later, later); err != nil {
+		t.Fatalf("cannot touch certificate: %v", err)
+	}
+
+	if !r.changed() {
+		t.Errorf("expected change after certificate was touched")
+	}
+}
@@ -0,0 +1,304 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/admin"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+)
+
+// nullMicros represents the zero time as NULL, which filters take for any.
+func nullMicros(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+
+	us := toMicros(t)
+
+	return &us
+}
+
+// limit represents no limit as -1, which LIMIT takes for none.
+func limit(n int) int {
+	if n <= 0 {
+		return -1
+	}
+
+	return n
+}
+
+// placeholders returns "?,?,..." for n arguments of an IN list.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func (sr SqliteRepo) FindReminders(ctx context.Context, filter admin.ReminderFilter) ([]admin.Reminder, error) {
+	const query = `SELECT id, user_id, reminder_text, remind_at, created_at, fired_at
+	FROM reminders
+	WHERE deleted_at IS NULL
+		AND (?1 = 0 OR user_id = ?1)
+		AND (?2 = '' OR instr(lower(reminder_text), lower(?2)) > 0)
+		AND (?3 IS NULL OR created_at >= ?3)
+		AND (?4 IS NULL OR created_at < ?4)
+		AND (NOT ?5 OR fired_at IS NULL)
+	ORDER BY id
+	LIMIT ?6`
+
+	rows, err := sr.db.QueryContext(ctx, query, filter.UserID, filter.Text, nullMicros(filter.CreatedAfter),
+		nullMicros(filter.CreatedBefore), filter.Pending, limit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []admin.Reminder
+	for rows.Next() {
+		var (
+			reminder            admin.Reminder
+			remindAt, createdAt int64
+			firedAt             sql.NullInt64
+		)
+		if err := rows.Scan(&reminder.ID, &reminder.UserID, &reminder.Text, &remindAt, &createdAt, &firedAt); err != nil {
+			return nil, err
+		}
+
+		reminder.RemindAt, reminder.CreatedAt = fromMicros(remindAt), fromMicros(createdAt)
+		if firedAt.Valid {
+			t := fromMicros(firedAt.Int64)
+			reminder.FiredAt = &t
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+func (sr SqliteRepo) CancelReminders(ctx context.Context, ids []int32) (cancelled int64, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	queryRemove := `UPDATE reminders
+	SET deleted_at = ?
+	WHERE deleted_at IS NULL AND id IN (` + placeholders(len(ids)) + `)
+	RETURNING id, user_id, reminder_text, remind_at, fired_at IS NOT NULL`
+
+	// Fired reminders no longer count as active.
+	const queryFreeQuota = `UPDATE users
+	SET active_reminders = active_reminders - ?2
+	WHERE id = ?1`
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	args := []any{toMicros(time.Now())}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := tx.QueryContext(ctx, queryRemove, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make(map[int64]int)
+	var changes []audit.Change
+	for rows.Next() {
+		var (
+			id       int32
+			userId   int64
+			text     string
+			remindAt int64
+			fired    bool
+		)
+		if err = rows.Scan(&id, &userId, &text, &remindAt, &fired); err != nil {
+			rows.Close()
+
+			return 0, err
+		}
+
+		cancelled++
+		if !fired {
+			pending[userId]++
+		}
+		changes = append(changes, removedReminder(id, userId, text, remindAt))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for userId, n := range pending {
+		if _, err = tx.ExecContext(ctx, queryFreeQuota, userId, n); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = recordAudit(ctx, tx, changes...); err != nil {
+		return 0, err
+	}
+
+	return cancelled, tx.Commit()
+}
+
+func (sr SqliteRepo) FindFailedDeliveries(ctx context.Context, filter admin.DeliveryFilter) ([]admin.Delivery, error) {
+	const query = `SELECT deliveries.id, deliveries.reminder_id, reminders.user_id, deliveries.channel, deliveries.address,
+		deliveries.attempts, coalesce(deliveries.last_error, ''), deliveries.updated_at
+	FROM deliveries
+	JOIN reminders ON reminders.id = deliveries.reminder_id
+	WHERE deliveries.status = 'failed' AND reminders.deleted_at IS NULL
+		AND (?1 = 0 OR reminders.user_id = ?1)
+		AND (?2 = '' OR deliveries.channel = ?2)
+		AND (?3 IS NULL OR deliveries.updated_at >= ?3)
+	ORDER BY deliveries.id
+	LIMIT ?4`
+
+	rows, err := sr.db.QueryContext(ctx, query, filter.UserID, filter.Channel, nullMicros(filter.FailedAfter), limit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []admin.Delivery
+	for rows.Next() {
+		var (
+			delivery admin.Delivery
+			failedAt int64
+		)
+		if err := rows.Scan(&delivery.ID, &delivery.ReminderID, &delivery.UserID, &delivery.Channel, &delivery.Address,
+			&delivery.Attempts, &delivery.LastError, &failedAt); err != nil {
+			return nil, err
+		}
+		delivery.FailedAt = fromMicros(failedAt)
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (sr SqliteRepo) RequeueDeliveries(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query := `UPDATE deliveries
+	SET status = 'pending', attempts = 0, next_attempt_at = ?, updated_at = ?
+	WHERE status = 'failed' AND id IN (` + placeholders(len(ids)) + `)`
+
+	now := toMicros(time.Now())
+	args := []any{now, now}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	result, err := sr.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (sr SqliteRepo) CountRemovedReminders(ctx context.Context, before time.Time) (int64, error) {
+	const query = `SELECT count(*)
+	FROM reminders
+	WHERE deleted_at < ?1`
+
+	var n int64
+	err := sr.db.QueryRowContext(ctx, query, toMicros(before)).Scan(&n)
+
+	return n, err
+}
+
+func (sr SqliteRepo) PurgeRemovedReminders(ctx context.Context, before time.Time) (purged int64, err error) {
+	const (
+		queryDeleteDeliveries = `DELETE FROM deliveries
+	WHERE reminder_id IN (SELECT id FROM reminders WHERE deleted_at < ?1)`
+
+		queryDeleteShares = `DELETE FROM reminder_shares
+	WHERE reminder_id IN (SELECT id FROM reminders WHERE deleted_at < ?1)`
+
+		queryDeleteReminders = `DELETE FROM reminders
+	WHERE deleted_at < ?1`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, query := range []string{queryDeleteDeliveries, queryDeleteShares} {
+		if _, err = tx.ExecContext(ctx, query, toMicros(before)); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, queryDeleteReminders, toMicros(before))
+	if err != nil {
+		return 0, err
+	}
+	if purged, err = result.RowsAffected(); err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
+
+func (sr SqliteRepo) Stats(ctx context.Context) (admin.Stats, error) {
+	const (
+		queryCounts = `SELECT
+		(SELECT count(*) FROM users),
+		(SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NULL),
+		(SELECT count(*) FROM reminders WHERE deleted_at IS NULL AND fired_at IS NOT NULL),
+		(SELECT count(*) FROM reminders WHERE deleted_at IS NOT NULL),
+		(SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size())`
+
+		queryDeliveries = `SELECT status, count(*)
+	FROM deliveries
+	GROUP BY status`
+	)
+
+	stats := admin.Stats{Deliveries: make(map[string]int64)}
+	err := sr.db.QueryRowContext(ctx, queryCounts).Scan(&stats.Users, &stats.PendingReminders, &stats.FiredReminders,
+		&stats.RemovedReminders, &stats.SizeBytes)
+	if err != nil {
+		return admin.Stats{}, err
+	}
+
+	rows, err := sr.db.QueryContext(ctx, queryDeliveries)
+	if err != nil {
+		return admin.Stats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return admin.Stats{}, err
+		}
+
+		stats.Deliveries[status] = n
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,90 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+)
+
+func (sr SqliteRepo) CreateAPIKey(ctx context.Context, name string, userID *int64, hash []byte) (int64, error) {
+	const query = `INSERT INTO api_keys (name, user_id, key_hash, created_at)
+	VALUES (?1, ?2, ?3, ?4)
+	RETURNING id`
+
+	var id int64
+	err := sr.db.QueryRowContext(ctx, query, name, userID, hash, toMicros(time.Now())).Scan(&id)
+
+	return id, err
+}
+
+func (sr SqliteRepo) RevokeAPIKey(ctx context.Context, id int64) error {
+	const query = `UPDATE api_keys
+	SET revoked_at = ?2
+	WHERE id = ?1 AND revoked_at IS NULL`
+
+	result, err := sr.db.ExecContext(ctx, query, id, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return auth.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) ListAPIKeys(ctx context.Context) ([]auth.APIKey, error) {
+	const query = `SELECT id, name, user_id, created_at, revoked_at
+	FROM api_keys
+	ORDER BY id`
+
+	rows, err := sr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []auth.APIKey
+	for rows.Next() {
+		var (
+			key       auth.APIKey
+			createdAt int64
+			revokedAt sql.NullInt64
+		)
+
+		if err := rows.Scan(&key.ID, &key.Name, &key.UserID, &createdAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		key.CreatedAt = fromMicros(createdAt)
+		if revokedAt.Valid {
+			t := fromMicros(revokedAt.Int64)
+			key.RevokedAt = &t
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (sr SqliteRepo) FindAPIKey(ctx context.Context, hash []byte) (auth.Caller, error) {
+	const query = `SELECT name, user_id
+	FROM api_keys
+	WHERE key_hash = ?1 AND revoked_at IS NULL`
+
+	var caller auth.Caller
+	err := sr.db.QueryRowContext(ctx, query, hash).Scan(&caller.Name, &caller.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+
+	return caller, err
+}
@@ -0,0 +1,102 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+)
+
+// textOrNil stores JSON as TEXT, nil as NULL.
+func textOrNil(b []byte) *string {
+	if b == nil {
+		return nil
+	}
+
+	s := string(b)
+
+	return &s
+}
+
+// recordAudit writes the events of changes made in ctx within tx, if ctx
+// has an actor.
+func recordAudit(ctx context.Context, tx *sql.Tx, changes ...audit.Change) error {
+	const query = `INSERT INTO audit_events (created_at, actor, request_id, method, user_id, reminder_id, before, after)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`
+
+	events, err := audit.Events(ctx, changes...)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	insert, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	now := toMicros(time.Now())
+	for _, event := range events {
+		var reminderId *int32
+		if event.ReminderID != 0 {
+			reminderId = &event.ReminderID
+		}
+
+		_, err := insert.ExecContext(ctx, now, event.Caller, event.RequestID, event.Method, event.UserID, reminderId,
+			textOrNil(event.Before), textOrNil(event.After))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) AuditEvents(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	const query = `SELECT id, created_at, actor, request_id, method, user_id, coalesce(reminder_id, 0), before, after
+	FROM audit_events
+	WHERE (?1 = 0 OR user_id = ?1)
+		AND (?2 = 0 OR reminder_id = ?2)
+		AND (?3 = '' OR actor = ?3)
+		AND (?4 = '' OR request_id = ?4)
+		AND (?5 = '' OR method = ?5)
+		AND (?6 IS NULL OR created_at >= ?6)
+		AND (?7 IS NULL OR created_at < ?7)
+		AND (?8 = 0 OR id < ?8)
+	ORDER BY id DESC
+	LIMIT ?9`
+
+	rows, err := sr.db.QueryContext(ctx, query, filter.UserID, filter.ReminderID, filter.Caller, filter.RequestID,
+		filter.Method, nullMicros(filter.Since), nullMicros(filter.Until), filter.BeforeID, limit(filter.Limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var (
+			event         audit.Event
+			createdAt     int64
+			before, after sql.NullString
+		)
+		err := rows.Scan(&event.ID, &createdAt, &event.Caller, &event.RequestID, &event.Method, &event.UserID,
+			&event.ReminderID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+
+		event.CreatedAt = fromMicros(createdAt)
+		if before.Valid {
+			event.Before = []byte(before.String)
+		}
+		if after.Valid {
+			event.After = []byte(after.String)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
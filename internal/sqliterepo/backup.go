@@ -0,0 +1,276 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/backup"
+)
+
+// microsOrNil returns the microseconds of t, nil when t is.
+func microsOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+
+	us := toMicros(*t)
+
+	return &us
+}
+
+func (sr SqliteRepo) Backup(ctx context.Context, batchSize int, users func([]backup.User) error, reminders func([]backup.Reminder) error) error {
+	// A read transaction sees one snapshot of the database throughout.
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for after := int64(0); ; {
+		batch, err := backupUsers(ctx, tx, after, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := users(batch); err != nil {
+			return err
+		}
+
+		after = batch[len(batch)-1].ID
+	}
+
+	for after := int32(0); ; {
+		batch, err := backupReminders(ctx, tx, after, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := reminders(batch); err != nil {
+			return err
+		}
+
+		after = batch[len(batch)-1].ID
+	}
+
+	return nil
+}
+
+func backupUsers(ctx context.Context, tx *sql.Tx, after int64, limit int) ([]backup.User, error) {
+	const query = `SELECT id, language_code, utc_offset, notification_channels
+	FROM users
+	WHERE id > ?1
+	ORDER BY id
+	LIMIT ?2`
+
+	rows, err := tx.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []backup.User
+	for rows.Next() {
+		var (
+			user     backup.User
+			channels sql.NullString
+		)
+		if err := rows.Scan(&user.ID, &user.LanguageCode, &user.UTCOffset, &channels); err != nil {
+			return nil, err
+		}
+		if channels.Valid {
+			user.NotificationChannels = json.RawMessage(channels.String)
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func backupReminders(ctx context.Context, tx *sql.Tx, after int32, limit int) ([]backup.Reminder, error) {
+	const query = `SELECT id, user_id, reminder_text, remind_at, created_at, fired_at, deleted_at
+	FROM reminders
+	WHERE id > ?1
+	ORDER BY id
+	LIMIT ?2`
+
+	rows, err := tx.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []backup.Reminder
+	for rows.Next() {
+		var (
+			reminder            backup.Reminder
+			remindAt, createdAt int64
+			firedAt, deletedAt  sql.NullInt64
+		)
+		if err := rows.Scan(&reminder.ID, &reminder.UserID, &reminder.Text, &remindAt, &createdAt, &firedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+
+		reminder.RemindAt, reminder.CreatedAt = fromMicros(remindAt), fromMicros(createdAt)
+		reminder.FiredAt, reminder.RemovedAt = timeOrNil(firedAt), timeOrNil(deletedAt)
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+func (sr SqliteRepo) BeginRestore(ctx context.Context, checksum string) (bool, error) {
+	const (
+		queryStarted = `SELECT EXISTS (SELECT 1 FROM restores WHERE checksum = ?1)`
+		queryEmpty   = `SELECT NOT EXISTS (SELECT 1 FROM users)`
+		queryInsert  = `INSERT INTO restores (checksum, started_at) VALUES (?1, ?2)`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var started, empty bool
+	if err := tx.QueryRowContext(ctx, queryStarted, checksum).Scan(&started); err != nil {
+		return false, err
+	}
+	if started {
+		return true, nil
+	}
+
+	if err := tx.QueryRowContext(ctx, queryEmpty).Scan(&empty); err != nil {
+		return false, err
+	}
+	if !empty {
+		return false, backup.ErrNotEmpty
+	}
+
+	if _, err := tx.ExecContext(ctx, queryInsert, checksum, toMicros(time.Now())); err != nil {
+		return false, err
+	}
+
+	return false, tx.Commit()
+}
+
+func (sr SqliteRepo) RestoreUsers(ctx context.Context, users []backup.User) error {
+	const query = `INSERT INTO users (id, language_code, utc_offset, notification_channels)
+	VALUES (?1, ?2, ?3, ?4)
+	ON CONFLICT (id) DO NOTHING`
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		var channels *string
+		if user.NotificationChannels != nil {
+			s := string(user.NotificationChannels)
+			channels = &s
+		}
+
+		if _, err := tx.ExecContext(ctx, query, user.ID, user.LanguageCode, user.UTCOffset, channels); err != nil {
+			return fmt.Errorf("user %d: %w", user.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (sr SqliteRepo) RestoreReminders(ctx context.Context, checksum string, reminders []backup.Reminder) ([]int32, error) {
+	const (
+		queryInsert = `INSERT INTO reminders (user_id, reminder_text, remind_at, created_at, fired_at, deleted_at)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	RETURNING id`
+		queryRecord = `INSERT INTO restored_reminders (checksum, source_id, reminder_id) VALUES (?1, ?2, ?3)`
+		// Restored reminders count against the quota of their users like
+		// created ones, however many that makes.
+		queryCount = `UPDATE users
+	SET active_reminders = active_reminders + 1
+	WHERE id = ?1`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int32, 0, len(reminders))
+	for _, reminder := range reminders {
+		var id int32
+		err := tx.QueryRowContext(ctx, queryInsert, reminder.UserID, reminder.Text, toMicros(reminder.RemindAt),
+			toMicros(reminder.CreatedAt), microsOrNil(reminder.FiredAt), microsOrNil(reminder.RemovedAt)).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("reminder %d: %w", reminder.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, queryRecord, checksum, reminder.ID, id); err != nil {
+			return nil, err
+		}
+
+		if reminder.FiredAt == nil && reminder.RemovedAt == nil {
+			if _, err := tx.ExecContext(ctx, queryCount, reminder.UserID); err != nil {
+				return nil, err
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, tx.Commit()
+}
+
+func (sr SqliteRepo) RestoredReminders(ctx context.Context, checksum string) (map[int32]int32, error) {
+	const query = `SELECT source_id, reminder_id FROM restored_reminders WHERE checksum = ?1`
+
+	rows, err := sr.db.QueryContext(ctx, query, checksum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int32]int32)
+	for rows.Next() {
+		var sourceId, id int32
+		if err := rows.Scan(&sourceId, &id); err != nil {
+			return nil, err
+		}
+
+		ids[sourceId] = id
+	}
+
+	return ids, rows.Err()
+}
+
+func (sr SqliteRepo) FinishRestore(ctx context.Context, checksum string) error {
+	const query = `UPDATE restores
+	SET finished_at = coalesce(finished_at, ?2)
+	WHERE checksum = ?1`
+
+	result, err := sr.db.ExecContext(ctx, query, checksum, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	finished, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if finished == 0 {
+		return fmt.Errorf("restore %s: %w", checksum, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
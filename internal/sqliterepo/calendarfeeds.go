@@ -0,0 +1,109 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+func (sr SqliteRepo) CreateCalendarFeed(ctx context.Context, userId int64, hash []byte) error {
+	const query = `INSERT INTO calendar_feeds (user_id, secret_hash, created_at)
+	SELECT users.id, ?2, ?3
+	FROM users
+	WHERE users.id = ?1
+	ON CONFLICT (user_id) DO NOTHING`
+
+	result, err := sr.db.ExecContext(ctx, query, userId, hash, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil || created > 0 {
+		return err
+	}
+
+	// Nothing was inserted because either the user or its feed is missing.
+	var exists bool
+	if err := sr.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = ?1)`, userId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrAlreadyExists)
+}
+
+func (sr SqliteRepo) RotateCalendarFeed(ctx context.Context, userId int64, hash []byte) error {
+	const query = `UPDATE calendar_feeds SET secret_hash = ?2, created_at = ?3 WHERE user_id = ?1`
+
+	result, err := sr.db.ExecContext(ctx, query, userId, hash, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	rotated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rotated == 0 {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) DeleteCalendarFeed(ctx context.Context, userId int64) error {
+	const query = `DELETE FROM calendar_feeds WHERE user_id = ?1`
+
+	result, err := sr.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("calendar feed of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) FindCalendarFeed(ctx context.Context, hash []byte) (int64, todoserviceserver.CalendarFeedVersion, error) {
+	const query = `SELECT user_id, etag, modified_at FROM calendar_feeds WHERE secret_hash = ?1`
+
+	var (
+		userId     int64
+		version    todoserviceserver.CalendarFeedVersion
+		modifiedAt sql.NullInt64
+	)
+	err := sr.db.QueryRowContext(ctx, query, hash).Scan(&userId, &version.ETag, &modifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, version, fmt.Errorf("calendar feed: %w", todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return 0, version, err
+	}
+
+	if modifiedAt.Valid {
+		version.ModifiedAt = fromMicros(modifiedAt.Int64)
+	}
+
+	return userId, version, nil
+}
+
+func (sr SqliteRepo) SetCalendarFeedVersion(ctx context.Context, userId int64, version todoserviceserver.CalendarFeedVersion) error {
+	const query = `UPDATE calendar_feeds SET etag = ?2, modified_at = ?3 WHERE user_id = ?1`
+
+	_, err := sr.db.ExecContext(ctx, query, userId, version.ETag, nullMicros(version.ModifiedAt))
+
+	return err
+}
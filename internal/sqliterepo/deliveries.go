@@ -0,0 +1,241 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+)
+
+// dueReminder is a reminder about to be fired.
+type dueReminder struct {
+	id       int32
+	userId   int64
+	channels sql.NullString
+}
+
+func (sr SqliteRepo) FireDueReminders(ctx context.Context, now time.Time, limit int, route notify.Route) (fired int, err error) {
+	const (
+		queryDue = `SELECT reminders.id, reminders.user_id, users.notification_channels
+	FROM reminders
+	JOIN users ON users.id = reminders.user_id
+	WHERE reminders.deleted_at IS NULL AND reminders.fired_at IS NULL AND reminders.remind_at <= ?1
+	ORDER BY reminders.remind_at, reminders.id
+	LIMIT ?2`
+
+		queryFire = `UPDATE reminders
+	SET fired_at = ?2
+	WHERE id = ?1`
+
+		queryFreeQuota = `UPDATE users
+	SET active_reminders = active_reminders - 1
+	WHERE id = ?1`
+
+		queryInsertDelivery = `INSERT INTO deliveries (reminder_id, recipient_id, channel, address, next_attempt_at, created_at, updated_at)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6)`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	due, err := queryDueReminders(ctx, tx, queryDue, toMicros(now), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	firedAt := toMicros(time.Now())
+	for _, reminder := range due {
+		channels, err := decodeChannels(reminder.channels)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode channels of user %d: %w", reminder.userId, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, queryFire, reminder.id, firedAt); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, queryFreeQuota, reminder.userId); err != nil {
+			return 0, err
+		}
+
+		recipients, err := queryShareRecipients(ctx, tx, reminder.id)
+		if err != nil {
+			return 0, err
+		}
+		recipients = append([]notify.Recipient{{ID: reminder.userId, Channels: channels.GetChannels()}}, recipients...)
+
+		for _, target := range notify.Fanout(route, recipients) {
+			_, err := tx.ExecContext(ctx, queryInsertDelivery, reminder.id, target.RecipientID,
+				target.Channel.GetKind().String(), target.Channel.GetAddress(), toMicros(now), firedAt)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(due), tx.Commit()
+}
+
+func queryDueReminders(ctx context.Context, tx *sql.Tx, query string, now int64, limit int) ([]dueReminder, error) {
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueReminder
+	for rows.Next() {
+		var reminder dueReminder
+		if err := rows.Scan(&reminder.id, &reminder.userId, &reminder.channels); err != nil {
+			return nil, err
+		}
+
+		due = append(due, reminder)
+	}
+
+	return due, rows.Err()
+}
+
+func (sr SqliteRepo) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) (deliveries []notify.Delivery, err error) {
+	const (
+		queryDue = `SELECT deliveries.id, deliveries.channel, deliveries.address, deliveries.attempts + 1,
+		reminders.id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+	FROM deliveries
+	JOIN reminders ON reminders.id = deliveries.reminder_id
+	WHERE deliveries.status = 'pending' AND deliveries.next_attempt_at <= ?1
+	ORDER BY deliveries.next_attempt_at, deliveries.id
+	LIMIT ?2`
+
+		queryClaim = `UPDATE deliveries
+	SET attempts = attempts + 1, next_attempt_at = ?2, updated_at = ?3
+	WHERE id = ?1`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	deliveries, err = queryDueDeliveries(ctx, tx, queryDue, toMicros(now), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt := toMicros(time.Now())
+	for _, delivery := range deliveries {
+		if _, err := tx.ExecContext(ctx, queryClaim, delivery.ID, toMicros(leaseUntil), updatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return deliveries, tx.Commit()
+}
+
+func queryDueDeliveries(ctx context.Context, tx *sql.Tx, query string, now int64, limit int) ([]notify.Delivery, error) {
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []notify.Delivery
+	for rows.Next() {
+		var (
+			delivery notify.Delivery
+			channel  string
+			reminder pb.Reminder
+			remindAt int64
+		)
+
+		err := rows.Scan(
+			&delivery.ID, &channel, &delivery.Address, &delivery.Attempts,
+			&reminder.Id, &reminder.UserId, &reminder.ReminderText, &remindAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Kind = pb.NotificationChannel_Kind(pb.NotificationChannel_Kind_value[channel])
+		reminder.RemindTimestamp = timestamppb.New(fromMicros(remindAt))
+		delivery.Reminder = &reminder
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (sr SqliteRepo) MarkDeliverySent(ctx context.Context, id int64) error {
+	const query = `UPDATE deliveries
+	SET status = 'sent', last_error = NULL, updated_at = ?2
+	WHERE id = ?1`
+
+	_, err := sr.db.ExecContext(ctx, query, id, toMicros(time.Now()))
+
+	return err
+}
+
+func (sr SqliteRepo) MarkDeliveryFailed(ctx context.Context, id int64, lastError string, retryAt time.Time) error {
+	// A NULL retry_at gives the delivery up.
+	const query = `UPDATE deliveries
+	SET status = CASE WHEN ?3 IS NULL THEN 'failed' ELSE 'pending' END,
+		next_attempt_at = coalesce(?3, next_attempt_at),
+		last_error = ?2,
+		updated_at = ?4
+	WHERE id = ?1`
+
+	var retryAtMicros *int64
+	if !retryAt.IsZero() {
+		us := toMicros(retryAt)
+		retryAtMicros = &us
+	}
+
+	_, err := sr.db.ExecContext(ctx, query, id, lastError, retryAtMicros, toMicros(time.Now()))
+
+	return err
+}
+
+// encodeChannels returns the stored form of channels, NULL for nil.
+func encodeChannels(channels *pb.NotificationChannels) (*string, error) {
+	if channels == nil {
+		return nil, nil
+	}
+
+	data, err := protojson.Marshal(channels)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(data)
+
+	return &encoded, nil
+}
+
+func decodeChannels(data sql.NullString) (*pb.NotificationChannels, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+
+	channels := &pb.NotificationChannels{}
+	if err := protojson.Unmarshal([]byte(data.String), channels); err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
@@ -0,0 +1,66 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+)
+
+func (sr SqliteRepo) SetHookToken(ctx context.Context, userId int64, hash []byte) error {
+	const query = `INSERT INTO hook_tokens (user_id, token_hash, created_at)
+	SELECT users.id, ?2, ?3
+	FROM users
+	WHERE users.id = ?1
+	ON CONFLICT (user_id) DO UPDATE
+	SET token_hash = excluded.token_hash, created_at = excluded.created_at`
+
+	result, err := sr.db.ExecContext(ctx, query, userId, hash, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	set, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if set == 0 {
+		return fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) DeleteHookToken(ctx context.Context, userId int64) error {
+	const query = `DELETE FROM hook_tokens WHERE user_id = ?1`
+
+	result, err := sr.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("hook token of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) FindHookToken(ctx context.Context, hash []byte) (int64, error) {
+	const query = `SELECT user_id FROM hook_tokens WHERE token_hash = ?1`
+
+	var userId int64
+	err := sr.db.QueryRowContext(ctx, query, hash).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("hook token: %w", todoserviceserver.ErrNotFound)
+	}
+
+	return userId, err
+}
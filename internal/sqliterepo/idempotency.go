@@ -0,0 +1,78 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
+)
+
+// Reserve implements idempotency.Store.
+func (sr SqliteRepo) Reserve(ctx context.Context, caller, key string, requestHash []byte, ttl time.Duration) (*idempotency.Record, error) {
+	const (
+		queryReserve = `INSERT INTO idempotency_keys AS k (caller, key, request_hash, expires_at)
+	VALUES (?1, ?2, ?3, ?5)
+	ON CONFLICT (caller, key) DO UPDATE
+	SET request_hash = excluded.request_hash,
+		done = 0,
+		response = NULL,
+		status = NULL,
+		expires_at = excluded.expires_at
+	WHERE k.expires_at <= ?4
+	RETURNING caller`
+
+		queryExisting = `SELECT request_hash, done, response, status
+	FROM idempotency_keys
+	WHERE caller = ?1 AND key = ?2`
+	)
+
+	now := time.Now()
+
+	var reserved string
+	err := sr.db.QueryRowContext(
+		ctx, queryReserve, caller, key, requestHash, toMicros(now), toMicros(now.Add(ttl)),
+	).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var record idempotency.Record
+	err = sr.db.QueryRowContext(ctx, queryExisting, caller, key).Scan(
+		&record.RequestHash, &record.Done, &record.Response, &record.Status,
+	)
+
+	return &record, err
+}
+
+func (sr SqliteRepo) Complete(ctx context.Context, caller, key string, record idempotency.Record, ttl time.Duration) error {
+	const query = `UPDATE idempotency_keys
+	SET done = 1, response = ?3, status = ?4, expires_at = ?5
+	WHERE caller = ?1 AND key = ?2`
+
+	_, err := sr.db.ExecContext(ctx, query, caller, key, record.Response, record.Status, toMicros(time.Now().Add(ttl)))
+
+	return err
+}
+
+func (sr SqliteRepo) Release(ctx context.Context, caller, key string) error {
+	const query = `DELETE FROM idempotency_keys
+	WHERE caller = ?1 AND key = ?2 AND NOT done`
+
+	_, err := sr.db.ExecContext(ctx, query, caller, key)
+
+	return err
+}
+
+func (sr SqliteRepo) DeleteExpired(ctx context.Context) error {
+	const query = `DELETE FROM idempotency_keys
+	WHERE expires_at <= ?1`
+
+	_, err := sr.db.ExecContext(ctx, query, toMicros(time.Now()))
+
+	return err
+}
@@ -0,0 +1,78 @@
+package sqliterepo
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate applies the migrations which were not applied yet, in the order
+// of their file names. Each migration runs in its own transaction.
+func (sr SqliteRepo) Migrate(ctx context.Context) error {
+	const (
+		queryCreateTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version TEXT PRIMARY KEY,
+	applied_at INTEGER NOT NULL DEFAULT (unixepoch())
+	)`
+
+		queryApplied = `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?1)`
+
+		queryInsertVersion = `INSERT INTO schema_migrations (version) VALUES (?1)`
+	)
+
+	if _, err := sr.db.ExecContext(ctx, queryCreateTable); err != nil {
+		return fmt.Errorf("cannot create schema_migrations: %w", err)
+	}
+
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		version := entry.Name()
+
+		var applied bool
+		if err := sr.db.QueryRowContext(ctx, queryApplied, version).Scan(&applied); err != nil {
+			return fmt.Errorf("cannot check migration %s: %w", version, err)
+		}
+		if applied {
+			continue
+		}
+
+		migration, err := migrations.ReadFile("migrations/" + version)
+		if err != nil {
+			return err
+		}
+
+		if err := sr.apply(ctx, string(migration), queryInsertVersion, version); err != nil {
+			return fmt.Errorf("cannot apply migration %s: %w", version, err)
+		}
+
+		log.Printf("Applied migration %s", version)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) apply(ctx context.Context, migration, queryInsertVersion, version string) error {
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryInsertVersion, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
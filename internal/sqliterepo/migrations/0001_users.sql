@@ -0,0 +1,21 @@
+-- Timestamps are stored as microseconds since the Unix epoch,
+-- the precision Postgres keeps.
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY,
+    language_code TEXT,
+    utc_offset INTEGER,
+    -- Maintained by the repository to enforce the active reminders quota.
+    active_reminders INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE reminders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    reminder_text TEXT NOT NULL,
+    remind_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    deleted_at INTEGER
+);
+
+CREATE INDEX reminders_user_id_remind_at_idx ON reminders (user_id, remind_at)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    key_hash BLOB NOT NULL UNIQUE,
+    -- NULL for service accounts, which may act on any user.
+    user_id INTEGER,
+    created_at INTEGER NOT NULL,
+    revoked_at INTEGER
+);
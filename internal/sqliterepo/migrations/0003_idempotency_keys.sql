@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    caller TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash BLOB NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0,
+    -- google.protobuf.Any with the response, or google.rpc.Status with the error.
+    response BLOB,
+    status BLOB,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (caller, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,24 @@
+-- See migrations/0008_notifications.sql of postgresrepo.
+-- notification_channels holds protojson, NULL when the user set none.
+ALTER TABLE users ADD COLUMN notification_channels TEXT;
+
+ALTER TABLE reminders ADD COLUMN fired_at INTEGER;
+
+CREATE INDEX reminders_due_idx ON reminders (remind_at)
+    WHERE deleted_at IS NULL AND fired_at IS NULL;
+
+CREATE TABLE deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reminder_id INTEGER NOT NULL REFERENCES reminders (id),
+    channel TEXT NOT NULL,
+    address TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    last_error TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX deliveries_reminder_id_idx ON deliveries (reminder_id);
+CREATE INDEX deliveries_pending_idx ON deliveries (next_attempt_at) WHERE status = 'pending';
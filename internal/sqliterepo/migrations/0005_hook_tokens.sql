@@ -0,0 +1,6 @@
+-- See migrations/0010_hook_tokens.sql of postgresrepo.
+CREATE TABLE hook_tokens (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id),
+    token_hash BLOB NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL
+);
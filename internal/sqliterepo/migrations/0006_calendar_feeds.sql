@@ -0,0 +1,8 @@
+-- See migrations/0011_calendar_feeds.sql of postgresrepo.
+CREATE TABLE calendar_feeds (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id),
+    secret_hash BLOB NOT NULL UNIQUE,
+    etag TEXT NOT NULL DEFAULT '',
+    modified_at INTEGER,
+    created_at INTEGER NOT NULL
+);
@@ -0,0 +1,17 @@
+-- See migrations/0012_user_deletions.sql of postgresrepo.
+CREATE TABLE user_deletions (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id),
+    requested_at INTEGER NOT NULL,
+    delete_at INTEGER NOT NULL
+);
+
+CREATE INDEX user_deletions_delete_at_idx ON user_deletions (delete_at);
+
+CREATE TABLE user_deletion_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    requested_at INTEGER NOT NULL,
+    deleted_at INTEGER NOT NULL,
+    reminders INTEGER NOT NULL,
+    deliveries INTEGER NOT NULL,
+    webhooks INTEGER NOT NULL
+);
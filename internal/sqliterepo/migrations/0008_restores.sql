@@ -0,0 +1,13 @@
+-- See migrations/0013_restores.sql of postgresrepo.
+CREATE TABLE restores (
+    checksum TEXT PRIMARY KEY,
+    started_at INTEGER NOT NULL,
+    finished_at INTEGER
+);
+
+CREATE TABLE restored_reminders (
+    checksum TEXT NOT NULL REFERENCES restores (checksum),
+    source_id INTEGER NOT NULL,
+    reminder_id INTEGER NOT NULL,
+    PRIMARY KEY (checksum, source_id)
+);
@@ -0,0 +1,23 @@
+-- See migrations/0014_audit_events.sql of postgresrepo.
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    method TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    reminder_id INTEGER,
+    before TEXT,
+    after TEXT
+);
+
+CREATE INDEX audit_events_user_id_id_idx ON audit_events (user_id, id);
+CREATE INDEX audit_events_reminder_id_idx ON audit_events (reminder_id) WHERE reminder_id IS NOT NULL;
+CREATE INDEX audit_events_request_id_idx ON audit_events (request_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE TRIGGER audit_events_append_only
+BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit events are append-only');
+END;
@@ -0,0 +1,15 @@
+-- See migrations/0015_reminder_shares.sql of postgresrepo.
+CREATE TABLE reminder_shares (
+    id INTEGER PRIMARY KEY,
+    reminder_id INTEGER NOT NULL REFERENCES reminders (id),
+    user_id INTEGER REFERENCES users (id),
+    chat_id INTEGER,
+    role TEXT NOT NULL CHECK (role IN ('VIEWER', 'EDITOR')),
+    accepted_at INTEGER,
+    created_at INTEGER NOT NULL,
+    CHECK ((user_id IS NULL) <> (chat_id IS NULL))
+);
+
+CREATE UNIQUE INDEX reminder_shares_recipient_idx ON reminder_shares (reminder_id, coalesce(user_id, 0), coalesce(chat_id, 0));
+CREATE INDEX reminder_shares_user_id_idx ON reminder_shares (user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX reminder_shares_chat_id_idx ON reminder_shares (chat_id) WHERE chat_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- See migrations/0016_delivery_recipients.sql of postgresrepo.
+ALTER TABLE deliveries ADD COLUMN recipient_id INTEGER;
+
+CREATE INDEX deliveries_recipient_id_idx ON deliveries (recipient_id) WHERE recipient_id IS NOT NULL;
@@ -0,0 +1,248 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+)
+
+// recipientIds returns the user_id and chat_id columns of recipient.
+func recipientIds(recipient *pb.ShareRecipient) (userId, chatId *int64) {
+	switch recipient := recipient.GetRecipient().(type) {
+	case *pb.ShareRecipient_UserId:
+		return &recipient.UserId, nil
+	case *pb.ShareRecipient_ChatId:
+		return nil, &recipient.ChatId
+	default:
+		return nil, nil
+	}
+}
+
+func scanShare(row scanner) (*pb.ReminderShare, error) {
+	var (
+		share          pb.ReminderShare
+		userId, chatId sql.NullInt64
+		role           string
+		createdAt      int64
+	)
+
+	if err := row.Scan(&share.ReminderId, &userId, &chatId, &role, &share.Accepted, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if userId.Valid {
+		share.Recipient = &pb.ShareRecipient{Recipient: &pb.ShareRecipient_UserId{UserId: userId.Int64}}
+	} else {
+		share.Recipient = &pb.ShareRecipient{Recipient: &pb.ShareRecipient_ChatId{ChatId: chatId.Int64}}
+	}
+	share.Role = pb.ReminderShare_Role(pb.ReminderShare_Role_value[role])
+	share.CreatedAt = timestamppb.New(fromMicros(createdAt))
+
+	return &share, nil
+}
+
+func (sr SqliteRepo) ShareReminder(ctx context.Context, share *pb.ReminderShare) (*pb.ReminderShare, error) {
+	const (
+		queryReminderExists = `SELECT EXISTS (SELECT 1 FROM reminders WHERE id = ?1 AND deleted_at IS NULL)`
+		queryUserExists     = `SELECT EXISTS (SELECT 1 FROM users WHERE id = ?1)`
+
+		queryShare = `INSERT INTO reminder_shares (reminder_id, user_id, chat_id, role, created_at)
+	VALUES (?1, ?2, ?3, ?4, ?5)
+	ON CONFLICT (reminder_id, coalesce(user_id, 0), coalesce(chat_id, 0)) DO UPDATE
+	SET role = excluded.role,
+		accepted_at = CASE
+			WHEN reminder_shares.role = 'VIEWER' AND excluded.role = 'EDITOR' THEN NULL
+			ELSE reminder_shares.accepted_at
+		END
+	RETURNING reminder_id, user_id, chat_id, role, accepted_at IS NOT NULL, created_at`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, queryReminderExists, share.GetReminderId()).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("reminder %d: %w", share.GetReminderId(), todoserviceserver.ErrNotFound)
+	}
+
+	userId, chatId := recipientIds(share.GetRecipient())
+	if userId != nil {
+		if err := tx.QueryRowContext(ctx, queryUserExists, *userId).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("user %d: %w", *userId, todoserviceserver.ErrNotFound)
+		}
+	}
+
+	shared, err := scanShare(tx.QueryRowContext(ctx, queryShare, share.GetReminderId(), userId, chatId,
+		share.GetRole().String(), toMicros(time.Now())))
+	if err != nil {
+		return nil, err
+	}
+
+	return shared, tx.Commit()
+}
+
+func (sr SqliteRepo) AcceptReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	const query = `UPDATE reminder_shares
+	SET accepted_at = coalesce(accepted_at, ?4)
+	WHERE reminder_id = ?1 AND user_id IS ?2 AND chat_id IS ?3
+		AND reminder_id IN (SELECT id FROM reminders WHERE deleted_at IS NULL)`
+
+	userId, chatId := recipientIds(recipient)
+	result, err := sr.db.ExecContext(ctx, query, reminderId, userId, chatId, toMicros(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	accepted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if accepted == 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) RemoveReminderShare(ctx context.Context, reminderId int32, recipient *pb.ShareRecipient) error {
+	const query = `DELETE FROM reminder_shares
+	WHERE reminder_id = ?1 AND user_id IS ?2 AND chat_id IS ?3`
+
+	userId, chatId := recipientIds(recipient)
+	result, err := sr.db.ExecContext(ctx, query, reminderId, userId, chatId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("share of reminder %d: %w", reminderId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) GetReminderShares(ctx context.Context, reminderId int32) ([]*pb.ReminderShare, error) {
+	const query = `SELECT reminder_id, user_id, chat_id, role, accepted_at IS NOT NULL, created_at
+	FROM reminder_shares
+	WHERE reminder_id = ?1
+	ORDER BY id`
+
+	return sr.queryShares(ctx, query, reminderId)
+}
+
+func (sr SqliteRepo) GetReminderInvitations(ctx context.Context, recipient *pb.ShareRecipient) ([]*pb.ReminderShare, error) {
+	const query = `SELECT reminder_shares.reminder_id, reminder_shares.user_id, reminder_shares.chat_id,
+		reminder_shares.role, reminder_shares.accepted_at IS NOT NULL, reminder_shares.created_at
+	FROM reminder_shares
+	JOIN reminders ON reminders.id = reminder_shares.reminder_id
+	WHERE reminder_shares.user_id IS ?1 AND reminder_shares.chat_id IS ?2
+		AND reminder_shares.accepted_at IS NULL AND reminders.deleted_at IS NULL
+	ORDER BY reminder_shares.id`
+
+	userId, chatId := recipientIds(recipient)
+
+	return sr.queryShares(ctx, query, userId, chatId)
+}
+
+func (sr SqliteRepo) queryShares(ctx context.Context, query string, args ...any) ([]*pb.ReminderShare, error) {
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*pb.ReminderShare
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (sr SqliteRepo) GetSharedReminders(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	const query = `SELECT reminders.id, reminders.user_id, reminders.reminder_text, reminders.remind_at
+	FROM reminder_shares
+	JOIN reminders ON reminders.id = reminder_shares.reminder_id
+	WHERE reminder_shares.user_id = ?1 AND reminder_shares.accepted_at IS NOT NULL AND reminders.deleted_at IS NULL
+	ORDER BY reminders.remind_at, reminders.id`
+
+	rows, err := sr.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*pb.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// queryShareRecipients returns the recipients who accepted the reminder
+// being shared with them.
+func queryShareRecipients(ctx context.Context, tx *sql.Tx, reminderId int32) ([]notify.Recipient, error) {
+	const query = `SELECT coalesce(reminder_shares.user_id, reminder_shares.chat_id), users.notification_channels
+	FROM reminder_shares
+	LEFT JOIN users ON users.id = reminder_shares.user_id
+	WHERE reminder_shares.reminder_id = ?1 AND reminder_shares.accepted_at IS NOT NULL
+	ORDER BY reminder_shares.id`
+
+	rows, err := tx.QueryContext(ctx, query, reminderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []notify.Recipient
+	for rows.Next() {
+		var (
+			recipient notify.Recipient
+			data      sql.NullString
+		)
+		if err := rows.Scan(&recipient.ID, &data); err != nil {
+			return nil, err
+		}
+
+		channels, err := decodeChannels(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode channels of user %d: %w", recipient.ID, err)
+		}
+		recipient.Channels = channels.GetChannels()
+
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, rows.Err()
+}
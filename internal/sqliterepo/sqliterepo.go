@@ -0,0 +1,423 @@
+// Package sqliterepo implements todoserviceserver.Repo on top of a SQLite
+// database file, for single-node installs which do not want to run Postgres.
+// It behaves like postgresrepo.
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	_ "modernc.org/sqlite"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/audit"
+)
+
+type SqliteRepo struct {
+	db                 *sql.DB
+	maxActiveReminders int
+}
+
+type Option func(*SqliteRepo)
+
+// WithMaxActiveReminders limits the number of reminders a user may have
+// which were not removed. Zero means no limit.
+func WithMaxActiveReminders(n int) Option {
+	return func(sr *SqliteRepo) {
+		sr.maxActiveReminders = n
+	}
+}
+
+// Open opens the database file at path, creating it if needed.
+//
+// SQLite allows one writer at a time, so the returned pool holds a single
+// connection: requests queue in database/sql instead of failing with
+// SQLITE_BUSY, and transactions never interleave.
+func Open(path string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func New(db *sql.DB, opts ...Option) *SqliteRepo {
+	sr := &SqliteRepo{db: db}
+	for _, opt := range opts {
+		opt(sr)
+	}
+
+	return sr
+}
+
+// toMicros converts t to the representation of timestamps in the database.
+func toMicros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func fromMicros(us int64) time.Time {
+	return time.UnixMicro(us).UTC()
+}
+
+func (sr SqliteRepo) SetUser(ctx context.Context, user *pb.User) error {
+	// Fields missing from user are NULL and keep their stored values.
+	const query = `INSERT INTO users (id, language_code, utc_offset, notification_channels)
+	VALUES (?1, ?2, ?3, ?4)
+	ON CONFLICT (id)
+	DO UPDATE SET language_code = coalesce(excluded.language_code, language_code),
+		utc_offset = coalesce(excluded.utc_offset, utc_offset),
+		notification_channels = coalesce(excluded.notification_channels, notification_channels)`
+
+	var (
+		languageCode *string
+		utcOffset    *int32
+	)
+	if user.GetLanguageCode() != nil {
+		languageCode = &user.LanguageCode.Value
+	}
+	if user.GetUtcOffset() != nil {
+		utcOffset = &user.UtcOffset.Value
+	}
+
+	channels, err := encodeChannels(user.GetNotificationChannels())
+	if err != nil {
+		return err
+	}
+
+	if _, ok := audit.FromContext(ctx); !ok {
+		_, err = sr.db.ExecContext(ctx, query, user.GetId(), languageCode, utcOffset, channels)
+
+		return err
+	}
+
+	// The audit event holds the user as it was before and is after the
+	// change, which fields passed as NULL keep.
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getUser(ctx, tx, user.GetId())
+	if err != nil && !errors.Is(err, todoserviceserver.ErrNotFound) {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, user.GetId(), languageCode, utcOffset, channels); err != nil {
+		return err
+	}
+
+	after, err := getUser(ctx, tx, user.GetId())
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, audit.Change{UserID: user.GetId(), Before: before, After: after}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (sr SqliteRepo) GetUser(ctx context.Context, id int64) (*pb.User, error) {
+	return getUser(ctx, sr.db, id)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getUser(ctx context.Context, q queryer, id int64) (*pb.User, error) {
+	const query = `SELECT language_code, utc_offset, notification_channels
+	FROM users
+	WHERE id = ?1`
+
+	var (
+		languageCode sql.NullString
+		utcOffset    sql.NullInt32
+		channels     sql.NullString
+	)
+
+	err := q.QueryRowContext(ctx, query, id).Scan(&languageCode, &utcOffset, &channels)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := &pb.User{Id: id}
+	if languageCode.Valid {
+		user.LanguageCode = wrapperspb.String(languageCode.String)
+	}
+	if utcOffset.Valid {
+		user.UtcOffset = wrapperspb.Int32(utcOffset.Int32)
+	}
+
+	user.NotificationChannels, err = decodeChannels(channels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode channels of user %d: %w", id, err)
+	}
+
+	return user, nil
+}
+
+func (sr SqliteRepo) CreateReminder(ctx context.Context, reminder *pb.Reminder) (id int32, err error) {
+	const (
+		queryTakeQuota = `UPDATE users
+	SET active_reminders = active_reminders + 1
+	WHERE id = ?1 AND (?2 <= 0 OR active_reminders < ?2)`
+
+		queryInsert = `INSERT INTO reminders (user_id, reminder_text, remind_at, created_at)
+	VALUES (?1, ?2, ?3, ?4)
+	RETURNING id`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, queryTakeQuota, reminder.GetUserId(), sr.maxActiveReminders)
+	if err != nil {
+		return 0, err
+	}
+
+	taken, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if taken == 0 {
+		if _, err := getUser(ctx, tx, reminder.GetUserId()); err != nil {
+			return 0, err
+		}
+
+		return 0, fmt.Errorf("user %d already has %d reminders: %w", reminder.GetUserId(), sr.maxActiveReminders, todoserviceserver.ErrQuotaExceeded)
+	}
+
+	err = tx.QueryRowContext(
+		ctx, queryInsert, reminder.GetUserId(), reminder.GetReminderText(),
+		toMicros(reminder.GetRemindTimestamp().AsTime()), toMicros(time.Now()),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = recordAudit(ctx, tx, createdReminder(id, reminder.GetUserId(), reminder)); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// createdReminder is the audit.Change of creating reminder with id.
+func createdReminder(id int32, userId int64, reminder *pb.Reminder) audit.Change {
+	after := &pb.Reminder{
+		Id:              id,
+		UserId:          userId,
+		ReminderText:    reminder.GetReminderText(),
+		RemindTimestamp: reminder.GetRemindTimestamp(),
+	}
+
+	return audit.Change{UserID: userId, ReminderID: id, After: after}
+}
+
+func (sr SqliteRepo) CreateReminders(ctx context.Context, userId int64, reminders []*pb.Reminder) ([]int32, error) {
+	const (
+		queryTakeQuota = `UPDATE users
+	SET active_reminders = active_reminders + ?2
+	WHERE id = ?1 AND (?3 <= 0 OR active_reminders + ?2 <= ?3)`
+
+		queryInsert = `INSERT INTO reminders (user_id, reminder_text, remind_at, created_at)
+	VALUES (?1, ?2, ?3, ?4)
+	RETURNING id`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, queryTakeQuota, userId, len(reminders), sr.maxActiveReminders)
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if taken == 0 {
+		if _, err := getUser(ctx, tx, userId); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("user %d has no room for %d more reminders: %w", userId, len(reminders), todoserviceserver.ErrQuotaExceeded)
+	}
+
+	insert, err := tx.PrepareContext(ctx, queryInsert)
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+
+	now := toMicros(time.Now())
+	ids := make([]int32, 0, len(reminders))
+	changes := make([]audit.Change, 0, len(reminders))
+	for _, reminder := range reminders {
+		var id int32
+		if err := insert.QueryRowContext(ctx, userId, reminder.GetReminderText(), toMicros(reminder.GetRemindTimestamp().AsTime()), now).Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+		changes = append(changes, createdReminder(id, userId, reminder))
+	}
+
+	if err := recordAudit(ctx, tx, changes...); err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit()
+}
+
+func (sr SqliteRepo) GetReminder(ctx context.Context, id int32) (*pb.Reminder, error) {
+	const query = `SELECT id, user_id, reminder_text, remind_at
+	FROM reminders
+	WHERE id = ?1 AND deleted_at IS NULL`
+
+	reminder, err := scanReminder(sr.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+
+	return reminder, err
+}
+
+func (sr SqliteRepo) RemoveReminder(ctx context.Context, id int32) (err error) {
+	const (
+		queryRemove = `UPDATE reminders
+	SET deleted_at = ?2
+	WHERE id = ?1 AND deleted_at IS NULL
+	RETURNING user_id, reminder_text, remind_at, fired_at IS NOT NULL`
+
+		// Fired reminders no longer count as active.
+		queryFreeQuota = `UPDATE users
+	SET active_reminders = active_reminders - 1
+	WHERE id = ?1`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var (
+		userId   int64
+		text     string
+		remindAt int64
+		fired    bool
+	)
+	err = tx.QueryRowContext(ctx, queryRemove, id, toMicros(time.Now())).Scan(&userId, &text, &remindAt, &fired)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("reminder %d: %w", id, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !fired {
+		if _, err = tx.ExecContext(ctx, queryFreeQuota, userId); err != nil {
+			return err
+		}
+	}
+
+	if err = recordAudit(ctx, tx, removedReminder(id, userId, text, remindAt)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// removedReminder is the audit.Change of removing a reminder.
+func removedReminder(id int32, userId int64, text string, remindAt int64) audit.Change {
+	before := &pb.Reminder{Id: id, UserId: userId, ReminderText: text, RemindTimestamp: timestamppb.New(fromMicros(remindAt))}
+
+	return audit.Change{UserID: userId, ReminderID: id, Before: before}
+}
+
+func (sr SqliteRepo) GetRemindersByUserId(ctx context.Context, userId int64) ([]*pb.Reminder, error) {
+	const query = `SELECT id, user_id, reminder_text, remind_at
+	FROM reminders
+	WHERE user_id = ?1 AND deleted_at IS NULL
+	ORDER BY remind_at, id`
+
+	rows, err := sr.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*pb.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReminder(row scanner) (*pb.Reminder, error) {
+	var (
+		reminder pb.Reminder
+		remindAt int64
+	)
+
+	if err := row.Scan(&reminder.Id, &reminder.UserId, &reminder.ReminderText, &remindAt); err != nil {
+		return nil, err
+	}
+	reminder.RemindTimestamp = timestamppb.New(fromMicros(remindAt))
+
+	return &reminder, nil
+}
@@ -0,0 +1,161 @@
+package sqliterepo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/idempotency"
+	"github.com/awakair/awakair_todo_bot/internal/notify"
+	"github.com/awakair/awakair_todo_bot/internal/notify/notifytest"
+	"github.com/awakair/awakair_todo_bot/internal/repotest"
+)
+
+func testRepo(t *testing.T, opts ...Option) *SqliteRepo {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := New(db, opts...)
+	if err := repo.Migrate(context.Background()); err != nil {
+		t.Fatalf("cannot migrate database: %v", err)
+	}
+
+	return repo
+}
+
+func TestSqliteRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, opts repotest.Options) todoserviceserver.Repo {
+		return testRepo(t, WithMaxActiveReminders(opts.MaxActiveReminders))
+	})
+}
+
+func TestSqliteRepo_notify(t *testing.T) {
+	notifytest.RunStore(t, func(t *testing.T, maxActiveReminders int) (notify.Store, todoserviceserver.Repo) {
+		repo := testRepo(t, WithMaxActiveReminders(maxActiveReminders))
+
+		return repo, repo
+	})
+}
+
+func TestSqliteRepo_hookTokens(t *testing.T) {
+	repotest.RunHookTokens(t, func(t *testing.T) (todoserviceserver.HookTokenStore, todoserviceserver.Repo) {
+		repo := testRepo(t)
+
+		return repo, repo
+	})
+}
+
+func TestSqliteRepo_calendarFeeds(t *testing.T) {
+	repotest.RunCalendarFeeds(t, func(t *testing.T) (todoserviceserver.CalendarFeedStore, todoserviceserver.Repo) {
+		repo := testRepo(t)
+
+		return repo, repo
+	})
+}
+
+func TestSqliteRepo_userData(t *testing.T) {
+	repotest.RunUserData(t, func(t *testing.T) repotest.UserDataStore {
+		return testRepo(t)
+	})
+}
+
+func TestSqliteRepo_admin(t *testing.T) {
+	repotest.RunAdmin(t, func(t *testing.T, maxActiveReminders int) repotest.AdminStore {
+		return testRepo(t, WithMaxActiveReminders(maxActiveReminders))
+	})
+}
+
+func TestSqliteRepo_backup(t *testing.T) {
+	repotest.RunBackup(t, func(t *testing.T, maxActiveReminders int) repotest.BackupStore {
+		return testRepo(t, WithMaxActiveReminders(maxActiveReminders))
+	})
+}
+
+func TestSqliteRepo_audit(t *testing.T) {
+	repotest.RunAudit(t, func(t *testing.T) repotest.AuditStore {
+		return testRepo(t)
+	})
+}
+
+func TestSqliteRepo_shares(t *testing.T) {
+	repotest.RunShares(t, func(t *testing.T) repotest.ShareStore {
+		return testRepo(t)
+	})
+}
+
+func TestSqliteRepo_Migrate(t *testing.T) {
+	repo := testRepo(t)
+
+	if err := repo.Migrate(context.Background()); err != nil {
+		t.Errorf("expected applied migrations to be skipped got %v", err)
+	}
+}
+
+func TestSqliteRepo_APIKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := testRepo(t)
+	userId := int64(42)
+
+	id, err := repo.CreateAPIKey(ctx, "alice", &userId, []byte("hash"))
+	if err != nil {
+		t.Fatalf("cannot create key: %v", err)
+	}
+
+	caller, err := repo.FindAPIKey(ctx, []byte("hash"))
+	if err != nil || caller.Name != "alice" || caller.UserID == nil || *caller.UserID != userId {
+		t.Errorf("expected caller alice of user %d got %+v, %v", userId, caller, err)
+	}
+
+	if err := repo.RevokeAPIKey(ctx, id); err != nil {
+		t.Fatalf("cannot revoke key: %v", err)
+	}
+	if _, err := repo.FindAPIKey(ctx, []byte("hash")); err != auth.ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound for revoked key got %v", err)
+	}
+	if err := repo.RevokeAPIKey(ctx, id); err != auth.ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound revoking twice got %v", err)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("expected one revoked key got %+v, %v", keys, err)
+	}
+}
+
+func TestSqliteRepo_idempotency(t *testing.T) {
+	ctx := context.Background()
+	repo := testRepo(t)
+
+	if existing, err := repo.Reserve(ctx, "bot", "key", []byte("hash"), time.Minute); existing != nil || err != nil {
+		t.Fatalf("expected key to be reserved got %+v, %v", existing, err)
+	}
+
+	existing, err := repo.Reserve(ctx, "bot", "key", []byte("hash"), time.Minute)
+	if err != nil || existing == nil || existing.Done {
+		t.Fatalf("expected pending record got %+v, %v", existing, err)
+	}
+
+	record := idempotency.Record{RequestHash: []byte("hash"), Done: true, Response: []byte("response")}
+	if err := repo.Complete(ctx, "bot", "key", record, -time.Second); err != nil {
+		t.Fatalf("cannot complete record: %v", err)
+	}
+
+	if existing, err := repo.Reserve(ctx, "bot", "key", []byte("other"), time.Minute); existing != nil || err != nil {
+		t.Errorf("expected expired record to be replaced got %+v, %v", existing, err)
+	}
+
+	if err := repo.Release(ctx, "bot", "key"); err != nil {
+		t.Fatalf("cannot release record: %v", err)
+	}
+	if existing, _ := repo.Reserve(ctx, "bot", "key", []byte("hash"), time.Minute); existing != nil {
+		t.Errorf("expected released key to be reserved again")
+	}
+}
@@ -0,0 +1,347 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/userdata"
+)
+
+// timeOrNil returns the time of us, nil when it is NULL.
+func timeOrNil(us sql.NullInt64) *time.Time {
+	if !us.Valid {
+		return nil
+	}
+
+	t := fromMicros(us.Int64)
+
+	return &t
+}
+
+func (sr SqliteRepo) GetUserData(ctx context.Context, userId int64) (*userdata.Data, error) {
+	// Read in one transaction for a consistent export.
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := getUser(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data := &userdata.Data{Profile: userdata.ProfileOf(user)}
+
+	if data.Reminders, err = getUserDataReminders(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	if data.Deliveries, err = getUserDataDeliveries(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	if data.Shares, err = getUserDataShares(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	if data.Settings.APIKeys, err = getUserDataAPIKeys(ctx, tx, userId); err != nil {
+		return nil, err
+	}
+	// Webhooks are only kept by the postgres backend.
+	data.Settings.Webhooks = []userdata.Webhook{}
+
+	const querySettings = `SELECT hook_tokens.created_at, calendar_feeds.created_at, user_deletions.delete_at
+	FROM users
+	LEFT JOIN hook_tokens ON hook_tokens.user_id = users.id
+	LEFT JOIN calendar_feeds ON calendar_feeds.user_id = users.id
+	LEFT JOIN user_deletions ON user_deletions.user_id = users.id
+	WHERE users.id = ?1`
+
+	var hookToken, calendarFeed, deleteAt sql.NullInt64
+	if err := tx.QueryRowContext(ctx, querySettings, userId).Scan(&hookToken, &calendarFeed, &deleteAt); err != nil {
+		return nil, err
+	}
+	data.Settings.HookTokenCreatedAt = timeOrNil(hookToken)
+	data.Settings.CalendarFeedCreatedAt = timeOrNil(calendarFeed)
+	data.Settings.DeleteAt = timeOrNil(deleteAt)
+
+	return data, nil
+}
+
+func getUserDataReminders(ctx context.Context, tx *sql.Tx, userId int64) ([]userdata.Reminder, error) {
+	const query = `SELECT id, reminder_text, remind_at, created_at, fired_at, deleted_at
+	FROM reminders
+	WHERE user_id = ?1
+	ORDER BY id`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []userdata.Reminder{}
+	for rows.Next() {
+		var (
+			reminder            userdata.Reminder
+			remindAt, createdAt int64
+			firedAt, deletedAt  sql.NullInt64
+		)
+		if err := rows.Scan(&reminder.ID, &reminder.Text, &remindAt, &createdAt, &firedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+
+		reminder.RemindAt, reminder.CreatedAt = fromMicros(remindAt), fromMicros(createdAt)
+		reminder.FiredAt, reminder.RemovedAt = timeOrNil(firedAt), timeOrNil(deletedAt)
+
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+func getUserDataDeliveries(ctx context.Context, tx *sql.Tx, userId int64) ([]userdata.Delivery, error) {
+	const query = `SELECT deliveries.id, deliveries.reminder_id, deliveries.channel, deliveries.address,
+		deliveries.status, deliveries.attempts, coalesce(deliveries.last_error, ''),
+		deliveries.created_at, deliveries.updated_at
+	FROM deliveries
+	JOIN reminders ON reminders.id = deliveries.reminder_id
+	WHERE reminders.user_id = ?1 OR deliveries.recipient_id = ?1
+	ORDER BY deliveries.id`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []userdata.Delivery{}
+	for rows.Next() {
+		var (
+			delivery             userdata.Delivery
+			createdAt, updatedAt int64
+		)
+		if err := rows.Scan(&delivery.ID, &delivery.ReminderID, &delivery.Channel, &delivery.Address,
+			&delivery.Status, &delivery.Attempts, &delivery.LastError, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		delivery.CreatedAt, delivery.UpdatedAt = fromMicros(createdAt), fromMicros(updatedAt)
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func getUserDataShares(ctx context.Context, tx *sql.Tx, userId int64) ([]userdata.Share, error) {
+	const query = `SELECT reminder_shares.reminder_id, reminders.user_id, reminder_shares.user_id, reminder_shares.chat_id,
+		reminder_shares.role, reminder_shares.accepted_at, reminder_shares.created_at
+	FROM reminder_shares
+	JOIN reminders ON reminders.id = reminder_shares.reminder_id
+	WHERE reminders.user_id = ?1 OR reminder_shares.user_id = ?1
+	ORDER BY reminder_shares.id`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []userdata.Share{}
+	for rows.Next() {
+		var (
+			share                           userdata.Share
+			recipientId, chatId, acceptedAt sql.NullInt64
+			createdAt                       int64
+		)
+		if err := rows.Scan(&share.ReminderID, &share.OwnerID, &recipientId, &chatId, &share.Role, &acceptedAt, &createdAt); err != nil {
+			return nil, err
+		}
+		if recipientId.Valid {
+			share.UserID = &recipientId.Int64
+		}
+		if chatId.Valid {
+			share.ChatID = &chatId.Int64
+		}
+		share.AcceptedAt, share.CreatedAt = timeOrNil(acceptedAt), fromMicros(createdAt)
+
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func getUserDataAPIKeys(ctx context.Context, tx *sql.Tx, userId int64) ([]userdata.APIKey, error) {
+	const query = `SELECT name, created_at, revoked_at
+	FROM api_keys
+	WHERE user_id = ?1
+	ORDER BY id`
+
+	rows, err := tx.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []userdata.APIKey{}
+	for rows.Next() {
+		var (
+			key       userdata.APIKey
+			createdAt int64
+			revokedAt sql.NullInt64
+		)
+		if err := rows.Scan(&key.Name, &createdAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		key.CreatedAt, key.RevokedAt = fromMicros(createdAt), timeOrNil(revokedAt)
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (sr SqliteRepo) ScheduleUserDeletion(ctx context.Context, userId int64, deleteAt time.Time) (time.Time, error) {
+	const (
+		querySchedule = `INSERT INTO user_deletions (user_id, requested_at, delete_at)
+	SELECT users.id, ?2, ?3
+	FROM users
+	WHERE users.id = ?1
+	ON CONFLICT (user_id) DO NOTHING`
+		queryScheduled = `SELECT delete_at FROM user_deletions WHERE user_id = ?1`
+	)
+
+	if _, err := sr.db.ExecContext(ctx, querySchedule, userId, toMicros(time.Now()), toMicros(deleteAt)); err != nil {
+		return time.Time{}, err
+	}
+
+	// The user was either scheduled now or before, or does not exist.
+	var scheduled int64
+	err := sr.db.QueryRowContext(ctx, queryScheduled, userId).Scan(&scheduled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return fromMicros(scheduled), nil
+}
+
+func (sr SqliteRepo) CancelUserDeletion(ctx context.Context, userId int64) error {
+	result, err := sr.db.ExecContext(ctx, `DELETE FROM user_deletions WHERE user_id = ?1`, userId)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cancelled == 0 {
+		return fmt.Errorf("deletion of user %d: %w", userId, todoserviceserver.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (sr SqliteRepo) DueUserDeletions(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	const query = `SELECT user_id
+	FROM user_deletions
+	WHERE delete_at <= ?1
+	ORDER BY delete_at
+	LIMIT ?2`
+
+	rows, err := sr.db.QueryContext(ctx, query, toMicros(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIds []int64
+	for rows.Next() {
+		var userId int64
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+
+		userIds = append(userIds, userId)
+	}
+
+	return userIds, rows.Err()
+}
+
+func (sr SqliteRepo) DeleteUser(ctx context.Context, userId int64, now time.Time) (userdata.Deletion, bool, error) {
+	const (
+		queryTake = `DELETE FROM user_deletions
+	WHERE user_id = ?1 AND delete_at <= ?2
+	RETURNING requested_at`
+		queryDeleteDeliveries = `DELETE FROM deliveries
+	WHERE recipient_id = ?1 OR reminder_id IN (SELECT id FROM reminders WHERE user_id = ?1)`
+		queryDeleteShares = `DELETE FROM reminder_shares
+	WHERE user_id = ?1 OR reminder_id IN (SELECT id FROM reminders WHERE user_id = ?1)`
+		queryDeleteReminders    = `DELETE FROM reminders WHERE user_id = ?1`
+		queryDeleteAuditEvents  = `DELETE FROM audit_events WHERE user_id = ?1`
+		queryDeleteIdempotency  = `DELETE FROM idempotency_keys WHERE caller LIKE 'user:' || ?1 || ':%'`
+		queryDeleteAPIKeys      = `DELETE FROM api_keys WHERE user_id = ?1`
+		queryDeleteHookToken    = `DELETE FROM hook_tokens WHERE user_id = ?1`
+		queryDeleteCalendarFeed = `DELETE FROM calendar_feeds WHERE user_id = ?1`
+		queryDeleteUser         = `DELETE FROM users WHERE id = ?1`
+		queryAudit              = `INSERT INTO user_deletion_audit (requested_at, deleted_at, reminders, deliveries, webhooks)
+	VALUES (?1, ?2, ?3, ?4, 0)`
+	)
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return userdata.Deletion{}, false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		deletion    userdata.Deletion
+		requestedAt int64
+	)
+	err = tx.QueryRowContext(ctx, queryTake, userId, toMicros(now)).Scan(&requestedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userdata.Deletion{}, false, nil
+	}
+	if err != nil {
+		return userdata.Deletion{}, false, err
+	}
+	deletion.RequestedAt = fromMicros(requestedAt)
+
+	exec := func(query string) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, userId)
+		if err != nil {
+			return 0, err
+		}
+
+		return result.RowsAffected()
+	}
+
+	if deletion.Deliveries, err = exec(queryDeleteDeliveries); err != nil {
+		return userdata.Deletion{}, false, err
+	}
+	if _, err = exec(queryDeleteShares); err != nil {
+		return userdata.Deletion{}, false, err
+	}
+	if deletion.Reminders, err = exec(queryDeleteReminders); err != nil {
+		return userdata.Deletion{}, false, err
+	}
+	for _, query := range []string{queryDeleteAuditEvents, queryDeleteIdempotency, queryDeleteAPIKeys, queryDeleteHookToken, queryDeleteCalendarFeed, queryDeleteUser} {
+		if _, err = exec(query); err != nil {
+			return userdata.Deletion{}, false, err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, queryAudit, requestedAt, toMicros(time.Now()), deletion.Reminders, deletion.Deliveries); err != nil {
+		return userdata.Deletion{}, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return userdata.Deletion{}, false, err
+	}
+
+	return deletion, true, nil
+}
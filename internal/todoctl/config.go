@@ -0,0 +1,259 @@
+package todoctl
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/awakair/awakair_todo_bot/internal/config"
+)
+
+// Config says how todoctl reaches the TodoService.
+//
+// Settings come, from highest to lowest precedence, from flags, environment
+// variables, the YAML file named by -config or TODOCTL_CONFIG (by default
+// todoctl/config.yaml in the user configuration directory, if it exists)
+// and Default.
+type Config struct {
+	Addr string `yaml:"addr"`
+	// Token is an API key or a JWT, sent as a bearer token.
+	Token   config.Secret `yaml:"token"`
+	TLS     TLS           `yaml:"tls"`
+	Output  string        `yaml:"output"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type TLS struct {
+	// Enabled connects over TLS, verifying the server with the system roots
+	// or CAFile. It is implied by the other settings.
+	Enabled bool   `yaml:"enabled"`
+	CAFile  string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate for mutual TLS.
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"`
+	// InsecureSkipVerify accepts any server certificate, for development.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+func (t TLS) enabled() bool {
+	return t.Enabled || t.CAFile != "" || t.CertFile != "" || t.ServerName != "" || t.InsecureSkipVerify
+}
+
+// Default returns the configuration used when nothing else is set,
+// talking to a server on this machine without TLS.
+func Default() Config {
+	return Config{
+		Addr:    "localhost:50051",
+		Output:  "table",
+		Timeout: 10 * time.Second,
+	}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr must be set"))
+	}
+	if c.Output != "table" && c.Output != "json" {
+		errs = append(errs, fmt.Errorf("output must be table or json, got %q", c.Output))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout must be positive, got %v", c.Timeout))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// setting binds one configuration field to its flag and environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(*Config) any
+}
+
+var settings = []setting{
+	{
+		flag: "addr", env: "TODOCTL_ADDR",
+		usage: "address of the TodoService",
+		field: func(c *Config) any { return &c.Addr },
+	},
+	{
+		flag: "token", env: "TODOCTL_TOKEN",
+		usage: "API key or JWT, prefer the environment variable over this flag",
+		field: func(c *Config) any { return &c.Token },
+	},
+	{
+		flag: "tls", env: "TODOCTL_TLS",
+		usage: "connect over TLS, implied by the other -tls flags",
+		field: func(c *Config) any { return &c.TLS.Enabled },
+	},
+	{
+		flag: "tls-ca-file", env: "TODOCTL_TLS_CA_FILE",
+		usage: "CA certificates verifying the server instead of the system ones",
+		field: func(c *Config) any { return &c.TLS.CAFile },
+	},
+	{
+		flag: "tls-cert-file", env: "TODOCTL_TLS_CERT_FILE",
+		usage: "client certificate for mutual TLS",
+		field: func(c *Config) any { return &c.TLS.CertFile },
+	},
+	{
+		flag: "tls-key-file", env: "TODOCTL_TLS_KEY_FILE",
+		usage: "key of the client certificate",
+		field: func(c *Config) any { return &c.TLS.KeyFile },
+	},
+	{
+		flag: "tls-server-name", env: "TODOCTL_TLS_SERVER_NAME",
+		usage: "name expected in the server certificate, the host of -addr by default",
+		field: func(c *Config) any { return &c.TLS.ServerName },
+	},
+	{
+		flag: "tls-insecure-skip-verify", env: "TODOCTL_TLS_INSECURE_SKIP_VERIFY",
+		usage: "accept any server certificate, for development only",
+		field: func(c *Config) any { return &c.TLS.InsecureSkipVerify },
+	},
+	{
+		flag: "o", env: "TODOCTL_OUTPUT",
+		usage: "output format (table, json)",
+		field: func(c *Config) any { return &c.Output },
+	},
+	{
+		flag: "timeout", env: "TODOCTL_TIMEOUT",
+		usage: "how long to wait for the server",
+		field: func(c *Config) any { return &c.Timeout },
+	},
+}
+
+func (s setting) set(cfg *Config, value string) error {
+	switch field := s.field(cfg).(type) {
+	case *string:
+		*field = value
+	case *config.Secret:
+		*field = config.Secret(value)
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = v
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+
+	return nil
+}
+
+// boolFunc is flag.Func for booleans, so that -tls needs no value.
+type boolFunc func(string) error
+
+func (f boolFunc) Set(v string) error { return f(v) }
+func (f boolFunc) String() string     { return "" }
+func (f boolFunc) IsBoolFlag() bool   { return true }
+
+// Load registers the settings on fs, parses args with it and returns the
+// resulting configuration.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	path := fs.String("config", os.Getenv("TODOCTL_CONFIG"), "path to the YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		set := func(v string) error {
+			flagValues[s.flag] = v
+
+			return nil
+		}
+
+		if _, ok := s.field(&cfg).(*bool); ok {
+			fs.Var(boolFunc(set), s.flag, s.usage)
+		} else {
+			fs.Func(s.flag, s.usage, set)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path == "" {
+		*path = defaultPath()
+	}
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(&cfg, v); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flagValues[s.flag]; ok {
+			if err := s.set(&cfg, v); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return &cfg, nil
+}
+
+// defaultPath returns todoctl/config.yaml in the user configuration
+// directory if it exists.
+func defaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	path := filepath.Join(dir, "todoctl", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+
+	return path
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
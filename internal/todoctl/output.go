@@ -0,0 +1,99 @@
+package todoctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// marshalOptions match the field names of the REST gateway.
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+func printJSON(w io.Writer, m proto.Message) error {
+	b, err := marshalOptions.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return printIndented(w, b)
+}
+
+// printJSONList prints messages as a JSON array, even when there is one
+// message or none.
+func printJSONList[M proto.Message](w io.Writer, messages []M) error {
+	raw := make([]json.RawMessage, 0, len(messages))
+	for _, m := range messages {
+		b, err := marshalOptions.Marshal(m)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, b)
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	return printIndented(w, b)
+}
+
+func printIndented(w io.Writer, b []byte) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+
+	_, err := out.WriteTo(w)
+
+	return err
+}
+
+func printUsers(w io.Writer, users ...*pb.User) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLANGUAGE\tUTC OFFSET\tCHANNELS")
+	for _, user := range users {
+		language, offset := "-", "-"
+		if user.GetLanguageCode() != nil {
+			language = user.GetLanguageCode().GetValue()
+		}
+		if user.GetUtcOffset() != nil {
+			offset = strconv.Itoa(int(user.GetUtcOffset().GetValue()))
+		}
+
+		channels := make([]string, 0, len(user.GetNotificationChannels().GetChannels()))
+		for _, channel := range user.GetNotificationChannels().GetChannels() {
+			channels = append(channels, strings.ToLower(channel.GetKind().String())+":"+channel.GetAddress())
+		}
+		if len(channels) == 0 {
+			channels = append(channels, "-")
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", user.GetId(), language, offset, strings.Join(channels, ", "))
+	}
+
+	return tw.Flush()
+}
+
+// printReminders prints reminders with their times in the local time zone.
+func printReminders(w io.Writer, reminders []*pb.Reminder) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tTEXT")
+	for _, reminder := range reminders {
+		at := reminder.GetRemindTimestamp().AsTime().Local().Format(time.DateTime)
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", reminder.GetId(), at, reminder.GetReminderText())
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,378 @@
+// Package todoctl implements todoctl, the command-line client of the
+// TodoService:
+//
+//	todoctl user set -utc-offset 2 42
+//	todoctl reminder add -user 42 -at 'tomorrow 09:00' water the plants
+//	todoctl reminder list -o json 42
+//
+// Every command accepts the connection flags of Config, which can also be
+// set by environment variables and a configuration file.
+package todoctl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+const usage = `Usage:
+  todoctl user set [-language CODE] [-utc-offset HOURS] [-channel KIND:ADDRESS]... [flags] ID
+  todoctl user get [flags] ID
+  todoctl reminder add -user ID -at TIME [flags] TEXT...
+  todoctl reminder list [flags] USER_ID
+  todoctl reminder rm [flags] ID
+
+TIME is "2024-05-01 09:00", "tomorrow 09:00", "09:00", "in 2 hours",
+"90m" or an RFC 3339 time. KIND is telegram, email or webhook.
+Run a command with -help to list its flags.
+`
+
+// ErrUsage is returned by Run for invalid command lines, after printing
+// the usage to stderr.
+var ErrUsage = errors.New("invalid command line")
+
+// cli runs one todoctl command.
+type cli struct {
+	stdout, stderr io.Writer
+}
+
+// Run runs the todoctl command in args, without the program name.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	c := &cli{stdout: stdout, stderr: stderr}
+
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+
+		return ErrUsage
+	}
+
+	fs := flag.NewFlagSet("todoctl "+args[0]+" "+args[1], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var run func(context.Context, *flag.FlagSet, []string) error
+	switch args[0] + " " + args[1] {
+	case "user set":
+		run = c.setUser
+	case "user get":
+		run = c.getUser
+	case "reminder add":
+		run = c.addReminder
+	case "reminder list":
+		run = c.listReminders
+	case "reminder rm":
+		run = c.removeReminder
+	default:
+		fmt.Fprint(stderr, usage)
+
+		return ErrUsage
+	}
+
+	err := run(ctx, fs, args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	return err
+}
+
+// session is a connection to the server made by connect.
+type session struct {
+	pb.TodoServiceClient
+	cfg    *Config
+	conn   *grpc.ClientConn
+	cancel context.CancelFunc
+}
+
+func (s *session) close() {
+	s.cancel()
+	s.conn.Close()
+}
+
+// connect loads the configuration from the flags in args and connects to
+// the server. The returned context carries the token and the timeout.
+func connect(ctx context.Context, fs *flag.FlagSet, args []string) (context.Context, *session, error) {
+	cfg, err := Load(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS.enabled() {
+		tlsConfig, err := clientTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(cfg.Addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot connect to %s: %w", cfg.Addr, err)
+	}
+
+	if token := cfg.Token.Reveal(); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+
+	return ctx, &session{TodoServiceClient: pb.NewTodoServiceClient(conn), cfg: cfg, conn: conn, cancel: cancel}, nil
+}
+
+func clientTLSConfig(cfg TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %s", cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// rpcError drops the "rpc error: code = ... desc =" noise from errors
+// returned by the server.
+func rpcError(err error) error {
+	if s, ok := status.FromError(err); ok {
+		return fmt.Errorf("%v: %s", s.Code(), s.Message())
+	}
+
+	return err
+}
+
+// intArg returns the only positional argument of fs as a number.
+func intArg(fs *flag.FlagSet, name string) (int64, error) {
+	if fs.NArg() != 1 {
+		return 0, fmt.Errorf("%s: expected %s", fs.Name(), name)
+	}
+
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s %q is not a number", fs.Name(), name, fs.Arg(0))
+	}
+
+	return id, nil
+}
+
+var channelKinds = map[string]pb.NotificationChannel_Kind{
+	"telegram": pb.NotificationChannel_TELEGRAM,
+	"email":    pb.NotificationChannel_EMAIL,
+	"webhook":  pb.NotificationChannel_WEBHOOK,
+}
+
+func (c *cli) setUser(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	user := &pb.User{}
+
+	fs.Func("language", "two-letter language code", func(v string) error {
+		user.LanguageCode = wrapperspb.String(v)
+
+		return nil
+	})
+	fs.Func("utc-offset", "offset of the user's time zone from UTC in hours", func(v string) error {
+		offset, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return err
+		}
+		user.UtcOffset = wrapperspb.Int32(int32(offset))
+
+		return nil
+	})
+	fs.Func("channel", "where to deliver reminders as KIND:ADDRESS, repeat for several, \"none\" for the default", func(v string) error {
+		if user.NotificationChannels == nil {
+			user.NotificationChannels = &pb.NotificationChannels{}
+		}
+		if v == "none" {
+			return nil
+		}
+
+		kind, address, _ := strings.Cut(v, ":")
+		k, ok := channelKinds[strings.ToLower(kind)]
+		if !ok {
+			return fmt.Errorf("unknown channel kind %q", kind)
+		}
+		user.NotificationChannels.Channels = append(user.NotificationChannels.Channels, &pb.NotificationChannel{Kind: k, Address: address})
+
+		return nil
+	})
+
+	ctx, client, err := connect(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	if user.Id, err = intArg(fs, "user ID"); err != nil {
+		return err
+	}
+
+	if _, err := client.SetUser(ctx, user); err != nil {
+		return rpcError(err)
+	}
+
+	fmt.Fprintf(c.stderr, "Set user %d\n", user.Id)
+
+	return nil
+}
+
+func (c *cli) getUser(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	ctx, client, err := connect(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	id, err := intArg(fs, "user ID")
+	if err != nil {
+		return err
+	}
+
+	user, err := client.GetUser(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return rpcError(err)
+	}
+
+	if client.cfg.Output == "json" {
+		return printJSON(c.stdout, user)
+	}
+
+	return printUsers(c.stdout, user)
+}
+
+func (c *cli) addReminder(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	userId := fs.Int64("user", 0, "ID of the user to remind")
+	at := fs.String("at", "", "when to remind, e.g. \"tomorrow 09:00\" or \"in 2 hours\"")
+
+	ctx, client, err := connect(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	if *userId == 0 || *at == "" {
+		return fmt.Errorf("%s: -user and -at are required", fs.Name())
+	}
+	text := strings.Join(fs.Args(), " ")
+	if text == "" {
+		return fmt.Errorf("%s: expected reminder text", fs.Name())
+	}
+
+	remindAt, err := ParseTime(*at, time.Now())
+	if err != nil {
+		return err
+	}
+
+	id, err := client.CreateReminder(ctx, &pb.Reminder{
+		UserId:          *userId,
+		ReminderText:    text,
+		RemindTimestamp: timestamppb.New(remindAt),
+	})
+	if err != nil {
+		return rpcError(err)
+	}
+
+	if client.cfg.Output == "json" {
+		return printJSON(c.stdout, id)
+	}
+
+	fmt.Fprintf(c.stderr, "Created reminder at %s\n", remindAt.Local().Format(time.DateTime))
+	fmt.Fprintln(c.stdout, id.GetId())
+
+	return nil
+}
+
+func (c *cli) listReminders(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	ctx, client, err := connect(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	id, err := intArg(fs, "user ID")
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.GetRemindersByUserId(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return rpcError(err)
+	}
+
+	var reminders []*pb.Reminder
+	for {
+		reminder, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return rpcError(err)
+		}
+
+		reminders = append(reminders, reminder)
+	}
+
+	if client.cfg.Output == "json" {
+		return printJSONList(c.stdout, reminders)
+	}
+
+	return printReminders(c.stdout, reminders)
+}
+
+func (c *cli) removeReminder(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	ctx, client, err := connect(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	id, err := intArg(fs, "reminder ID")
+	if err != nil {
+		return err
+	}
+	if id <= 0 || id > 1<<31-1 {
+		return fmt.Errorf("%s: reminder ID %d is out of range", fs.Name(), id)
+	}
+
+	if _, err := client.RemoveReminder(ctx, &pb.ReminderId{Id: int32(id)}); err != nil {
+		return rpcError(err)
+	}
+
+	fmt.Fprintf(c.stderr, "Removed reminder %d\n", id)
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package todoctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+)
+
+const key = auth.APIKeyPrefix + "service"
+
+// StubKeyStore only knows key, a service key.
+type StubKeyStore struct{}
+
+func (StubKeyStore) FindAPIKey(_ context.Context, hash []byte) (auth.Caller, error) {
+	if string(hash) != string(auth.HashAPIKey(key)) {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+
+	return auth.Caller{Name: "test"}, nil
+}
+
+// server serves a TodoService keeping data in memory on a local port and
+// returns its address.
+func server(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+
+	// Keep a configuration file of the user running the tests out of them.
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	authenticator := auth.NewAuthenticator(StubKeyStore{}, nil)
+	s := grpc.NewServer(append(opts,
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	)...)
+	pb.RegisterTodoServiceServer(s, todoserviceserver.New(memrepo.New()))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+// run runs todoctl with args and returns what it printed to stdout.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := Run(context.Background(), args, &stdout, &stderr)
+
+	return stdout.String(), err
+}
+
+func TestRun(t *testing.T) {
+	addr := server(t)
+	t.Setenv("TODOCTL_ADDR", addr)
+	t.Setenv("TODOCTL_TOKEN", key)
+
+	t.Run("user", func(t *testing.T) {
+		if _, err := run(t, "user", "set", "-language", "en", "-utc-offset", "2", "-channel", "email:alice@example.com", "42"); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		out, err := run(t, "user", "get", "42")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 2 || strings.Join(strings.Fields(lines[1]), " ") != "42 en 2 email:alice@example.com" {
+			t.Errorf("expected user 42 in a table got %q", out)
+		}
+
+		out, err = run(t, "user", "get", "-o", "json", "42")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		var user struct {
+			Id           string `json:"id"`
+			LanguageCode string `json:"language_code"`
+			UtcOffset    int    `json:"utc_offset"`
+		}
+		if err := json.Unmarshal([]byte(out), &user); err != nil || user.Id != "42" || user.LanguageCode != "en" || user.UtcOffset != 2 {
+			t.Errorf("expected user 42 in JSON got %q, %v", out, err)
+		}
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		out, err := run(t, "reminder", "add", "-user", "42", "-at", "in 2 hours", "water", "the", "plants")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		id := strings.TrimSpace(out)
+
+		if _, err := run(t, "reminder", "add", "-user", "42", "-at", time.Now().Add(time.Hour).Format(time.RFC3339), "-o", "json", "call mom"); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		out, err = run(t, "reminder", "list", "42")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 3 || !strings.HasSuffix(lines[1], "call mom") || !strings.HasPrefix(lines[2], id+" ") || !strings.HasSuffix(lines[2], "water the plants") {
+			t.Errorf("expected both reminders in a table, the sooner first, got %q", out)
+		}
+
+		if _, err := run(t, "reminder", "rm", id); err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+
+		out, err = run(t, "reminder", "list", "-o", "json", "42")
+		if err != nil {
+			t.Fatalf("did not expect error got %v", err)
+		}
+		var reminders []struct {
+			ReminderText string `json:"reminder_text"`
+		}
+		if err := json.Unmarshal([]byte(out), &reminders); err != nil || len(reminders) != 1 || reminders[0].ReminderText != "call mom" {
+			t.Errorf("expected the remaining reminder in JSON got %q, %v", out, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			args []string
+			err  string
+		}{
+			{[]string{"user", "get", "7"}, "NotFound"},
+			{[]string{"user", "get", "-token", "tdk_wrong", "42"}, "Unauthenticated"},
+			{[]string{"user", "get", "alice"}, "not a number"},
+			{[]string{"user", "get", "-o", "yaml", "42"}, "output must be table or json"},
+			{[]string{"reminder", "add", "-user", "42", "-at", "someday", "text"}, "neither a time nor an offset"},
+			{[]string{"reminder", "add", "-user", "42", "-at", "1h"}, "expected reminder text"},
+			{[]string{"reminder", "rm", "100"}, "NotFound"},
+			{[]string{"reminder", "snooze", "1"}, ErrUsage.Error()},
+		} {
+			if _, err := run(t, tt.args...); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q for %v got %v", tt.err, tt.args, err)
+			}
+		}
+	})
+}
+
+func TestRun_config(t *testing.T) {
+	addr := server(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, []byte("addr: "+addr+"\ntoken: "+key+"\noutput: json\n"))
+
+	out, err := run(t, "user", "set", "-config", path, "1")
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	// The flag wins over the file.
+	if out, err = run(t, "user", "get", "-config", path, "-o", "table", "1"); err != nil || !strings.HasPrefix(out, "ID") {
+		t.Errorf("expected a table got %q, %v", out, err)
+	}
+
+	t.Setenv("TODOCTL_CONFIG", path)
+	t.Setenv("TODOCTL_TOKEN", "tdk_wrong")
+	if _, err := run(t, "user", "get", "1"); err == nil || !strings.Contains(err.Error(), "Unauthenticated") {
+		t.Errorf("expected the environment to win over the file got %v", err)
+	}
+}
+
+func TestRun_TLS(t *testing.T) {
+	certPEM, keyPEM := newCertificate(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("cannot build key pair: %v", err)
+	}
+
+	addr := server(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeFile(t, caFile, certPEM)
+
+	if _, err := run(t, "user", "set", "-addr", addr, "-token", key, "-tls-ca-file", caFile, "-tls-server-name", "localhost", "1"); err != nil {
+		t.Errorf("did not expect error got %v", err)
+	}
+	if _, err := run(t, "user", "set", "-addr", addr, "-token", key, "-tls", "1"); err == nil {
+		t.Errorf("expected the certificate to be rejected without the CA")
+	}
+	if _, err := run(t, "user", "set", "-addr", addr, "-token", key, "1"); err == nil {
+		t.Errorf("expected an error connecting without TLS")
+	}
+}
+
+// newCertificate returns a self-signed certificate for localhost and its key.
+func newCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("cannot write %s: %v", path, err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-05-02T09:00:00Z", time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)},
+		{"2024-05-02 09:00", time.Date(2024, 5, 2, 9, 0, 0, 0, now.Location())},
+		{"18:00", time.Date(2024, 5, 1, 18, 0, 0, 0, now.Location())},
+		{"09:00", time.Date(2024, 5, 2, 9, 0, 0, 0, now.Location())},
+		{"today 13:00", time.Date(2024, 5, 1, 13, 0, 0, 0, now.Location())},
+		{"Tomorrow 13:00", time.Date(2024, 5, 2, 13, 0, 0, 0, now.Location())},
+		{"in 2 hours", now.Add(2 * time.Hour)},
+		{"90m", now.Add(90 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("expected %v for %q got %v, %v", tt.want, tt.in, got, err)
+		}
+	}
+
+	for _, in := range []string{"", "someday", "yesterday 09:00", "25:00"} {
+		if _, err := ParseTime(in, now); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package todoctl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awakair/awakair_todo_bot/internal/offset"
+)
+
+// ParseTime parses when to remind, relative to now and in its location:
+//
+//   - an RFC 3339 time, "2024-05-01T09:00:00+02:00";
+//   - a date and time, "2024-05-01 09:00";
+//   - a time of day, "09:00", today or tomorrow if it has passed,
+//     optionally after "today" or "tomorrow";
+//   - an offset accepted by offset.Parse, "in 2 hours" or "90m".
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	day, clock, hasDay := strings.Cut(strings.ToLower(s), " ")
+	if !hasDay {
+		day, clock = "", day
+	}
+	if day == "" || day == "today" || day == "tomorrow" {
+		if t, err := time.ParseInLocation("15:04", clock, now.Location()); err == nil {
+			y, m, d := now.Date()
+			at := time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, now.Location())
+
+			switch {
+			case day == "tomorrow", day == "" && !at.After(now):
+				at = at.AddDate(0, 0, 1)
+			}
+
+			return at, nil
+		}
+	}
+
+	in, err := offset.Parse(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a time nor an offset: %w", s, err)
+	}
+
+	return now.Add(in), nil
+}
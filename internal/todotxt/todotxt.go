@@ -0,0 +1,105 @@
+// Package todotxt parses tasks in the todo.txt format, http://todotxt.org:
+//
+//	x (A) 2024-05-02 2024-04-30 Call mom @phone +family due:2024-05-03
+//
+// is a done task of priority A, completed on May 2nd and created on April
+// 30th, with a context, a project and a due tag.
+package todotxt
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DateLayout is how todo.txt writes dates.
+const DateLayout = "2006-01-02"
+
+type Task struct {
+	Done bool
+	// Priority is 'A' to 'Z', zero for none.
+	Priority byte
+	// CompletedAt and CreatedAt are dates at midnight UTC, zero when the
+	// task has none.
+	CompletedAt time.Time
+	CreatedAt   time.Time
+	// Text is the description without its key:value tags, contexts and
+	// projects included.
+	Text string
+	// Contexts and Projects without their @ and +.
+	Contexts []string
+	Projects []string
+	// Tags are the key:value tags of the description, like due.
+	Tags map[string]string
+}
+
+// Parse parses a line holding a task.
+func Parse(line string) (Task, error) {
+	task := Task{Tags: make(map[string]string)}
+	rest := strings.TrimSpace(line)
+
+	if after, ok := strings.CutPrefix(rest, "x "); ok {
+		task.Done = true
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	if len(rest) >= 4 && rest[0] == '(' && rest[1] >= 'A' && rest[1] <= 'Z' && rest[2] == ')' && rest[3] == ' ' {
+		task.Priority = rest[1]
+		rest = strings.TrimLeft(rest[4:], " ")
+	}
+
+	// Done tasks may have a completion date, which comes before the
+	// creation date. A single date of a done task is its completion date.
+	dates := []*time.Time{&task.CreatedAt}
+	if task.Done {
+		dates = []*time.Time{&task.CompletedAt, &task.CreatedAt}
+	}
+	for _, date := range dates {
+		word, after, _ := strings.Cut(rest, " ")
+		d, err := time.Parse(DateLayout, word)
+		if err != nil {
+			break
+		}
+
+		*date = d
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	var text []string
+	for _, word := range strings.Fields(rest) {
+		switch {
+		case len(word) > 1 && word[0] == '@':
+			task.Contexts = append(task.Contexts, word[1:])
+		case len(word) > 1 && word[0] == '+':
+			task.Projects = append(task.Projects, word[1:])
+		default:
+			if key, value, ok := tag(word); ok {
+				task.Tags[key] = value
+
+				continue
+			}
+		}
+
+		text = append(text, word)
+	}
+
+	if len(text) == 0 {
+		return Task{}, errors.New("no description")
+	}
+	task.Text = strings.Join(text, " ")
+
+	return task, nil
+}
+
+// tag splits a key:value tag, telling it from URLs and times like 10:30.
+func tag(word string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(word, ":")
+	if !ok || key == "" || value == "" || strings.HasPrefix(value, "//") {
+		return "", "", false
+	}
+	if key[0] >= '0' && key[0] <= '9' {
+		return "", "", false
+	}
+
+	return key, value, true
+}
@@ -0,0 +1,62 @@
+package todotxt
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+func TestParse(t *testing.T) {
+	for line, expected := range map[string]Task{
+		"Call mom": {Text: "Call mom"},
+		"(A) 2024-04-30 Call mom @phone +family due:2024-05-03": {
+			Priority:  'A',
+			CreatedAt: date("2024-04-30"),
+			Text:      "Call mom @phone +family",
+			Contexts:  []string{"phone"},
+			Projects:  []string{"family"},
+			Tags:      map[string]string{"due": "2024-05-03"},
+		},
+		"x 2024-05-02 2024-04-30 Call mom": {Done: true, CompletedAt: date("2024-05-02"), CreatedAt: date("2024-04-30"), Text: "Call mom"},
+		"x 2024-05-02 Call mom":            {Done: true, CompletedAt: date("2024-05-02"), Text: "Call mom"},
+		"(a) x Call mom at 10:30":          {Text: "(a) x Call mom at 10:30"},
+		"Read https://example.com due:2024-05-01T18:30 rec:1w": {
+			Text: "Read https://example.com",
+			Tags: map[string]string{"due": "2024-05-01T18:30", "rec": "1w"},
+		},
+		"xylophone 2024-05-01": {Text: "xylophone 2024-05-01"},
+	} {
+		task, err := Parse(line)
+		if err != nil {
+			t.Errorf("%q: did not expect error got %v", line, err)
+
+			continue
+		}
+
+		if expected.Tags == nil {
+			expected.Tags = map[string]string{}
+		}
+		if task.Done != expected.Done || task.Priority != expected.Priority || task.Text != expected.Text ||
+			!task.CompletedAt.Equal(expected.CompletedAt) || !task.CreatedAt.Equal(expected.CreatedAt) ||
+			!slices.Equal(task.Contexts, expected.Contexts) || !slices.Equal(task.Projects, expected.Projects) ||
+			!maps.Equal(task.Tags, expected.Tags) {
+			t.Errorf("%q: expected %+v got %+v", line, expected, task)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "(B) due:2024-05-01", "x 2024-05-02"} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package userdata
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Deletion is what was deleted with a user, as recorded in the audit log of
+// deletions, which keeps nothing identifying the user.
+type Deletion struct {
+	RequestedAt time.Time
+	Reminders   int64
+	Deliveries  int64
+	Webhooks    int64
+}
+
+type Store interface {
+	// DueUserDeletions returns up to limit users whose deletion is due at
+	// now.
+	DueUserDeletions(ctx context.Context, now time.Time, limit int) ([]int64, error)
+	// DeleteUser deletes the user with everything kept about them and
+	// records the deletion in the audit log, atomically. It deletes nothing
+	// and returns false unless the deletion of the user is due at now, e.g.
+	// because it was cancelled meanwhile.
+	DeleteUser(ctx context.Context, userId int64, now time.Time) (Deletion, bool, error)
+}
+
+type Deleter struct {
+	store     Store
+	purge     func(userId int64)
+	batchSize int
+	now       func() time.Time
+}
+
+type Option func(*Deleter)
+
+// WithPurge calls purge with every deleted user, e.g. to drop them from
+// caches which are not told about deletions otherwise.
+func WithPurge(purge func(userId int64)) Option {
+	return func(d *Deleter) {
+		d.purge = purge
+	}
+}
+
+// WithClock replaces time.Now, which decides which deletions are due.
+func WithClock(now func() time.Time) Option {
+	return func(d *Deleter) {
+		d.now = now
+	}
+}
+
+func NewDeleter(store Store, opts ...Option) *Deleter {
+	d := &Deleter{store: store, purge: func(int64) {}, batchSize: 100, now: time.Now}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Run deletes the users whose deletion is due every interval until ctx is
+// done.
+func (d *Deleter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.DeleteDue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error deleting users: %v", err)
+			}
+		}
+	}
+}
+
+// DeleteDue deletes the users whose deletion is due, in batches until none
+// are left, and returns how many it deleted.
+func (d *Deleter) DeleteDue(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		now := d.now()
+		userIds, err := d.store.DueUserDeletions(ctx, now, d.batchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		batchDeleted := 0
+		for _, userId := range userIds {
+			deletion, ok, err := d.store.DeleteUser(ctx, userId, now)
+			if err != nil {
+				return deleted, err
+			}
+			if !ok {
+				continue
+			}
+
+			d.purge(userId)
+			deleted++
+			batchDeleted++
+			log.Printf("Deleted user %d as requested at %s with %d reminders, %d deliveries and %d webhooks",
+				userId, deletion.RequestedAt.Format(time.RFC3339), deletion.Reminders, deletion.Deliveries, deletion.Webhooks)
+		}
+
+		if len(userIds) < d.batchSize || batchDeleted == 0 {
+			return deleted, nil
+		}
+	}
+}
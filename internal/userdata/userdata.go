@@ -0,0 +1,202 @@
+// Package userdata exports everything kept about a user, as ExportUserData
+// returns it, and carries out the deletions of users scheduled by
+// DeleteUser once their grace period passed.
+//
+// Exports are versioned: Version changes whenever a field is renamed or
+// removed, new fields are added without changing it.
+package userdata
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+// Version of the format of exports.
+const Version = 1
+
+// Data is everything kept about a user. Secrets are left out, only whether
+// the user has them is.
+type Data struct {
+	Profile    Profile    `json:"profile"`
+	Reminders  []Reminder `json:"reminders"`
+	Deliveries []Delivery `json:"deliveries"`
+	Shares     []Share    `json:"shares"`
+	Settings   Settings   `json:"settings"`
+}
+
+type Profile struct {
+	ID           int64   `json:"id"`
+	LanguageCode *string `json:"language_code"`
+	UTCOffset    *int32  `json:"utc_offset"`
+	// NotificationChannels is empty for users who set none.
+	NotificationChannels []Channel `json:"notification_channels"`
+}
+
+type Channel struct {
+	// Kind is TELEGRAM, EMAIL or WEBHOOK.
+	Kind    string `json:"kind"`
+	Address string `json:"address"`
+}
+
+// Reminder of the user, including fired ones and removed ones not purged
+// yet.
+type Reminder struct {
+	ID        int32      `json:"id"`
+	Text      string     `json:"text"`
+	RemindAt  time.Time  `json:"remind_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	FiredAt   *time.Time `json:"fired_at"`
+	RemovedAt *time.Time `json:"removed_at"`
+}
+
+// Delivery of a fired reminder of the user, or of one shared with them to
+// one of their channels.
+type Delivery struct {
+	ID         int64     `json:"id"`
+	ReminderID int32     `json:"reminder_id"`
+	Channel    string    `json:"channel"`
+	Address    string    `json:"address"`
+	Status     string    `json:"status"`
+	Attempts   int32     `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Share of a reminder of the user with another user or a group chat, or of
+// a reminder of another user with the user.
+type Share struct {
+	ReminderID int32 `json:"reminder_id"`
+	OwnerID    int64 `json:"owner_id"`
+	// Exactly one of UserID and ChatID is set.
+	UserID *int64 `json:"user_id"`
+	ChatID *int64 `json:"chat_id"`
+	// Role is VIEWER or EDITOR.
+	Role string `json:"role"`
+	// AcceptedAt is nil for invitations.
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type Settings struct {
+	Webhooks []Webhook `json:"webhooks"`
+	APIKeys  []APIKey  `json:"api_keys"`
+	// HookTokenCreatedAt and CalendarFeedCreatedAt are nil when the user
+	// has no hook token or calendar feed.
+	HookTokenCreatedAt    *time.Time `json:"hook_token_created_at"`
+	CalendarFeedCreatedAt *time.Time `json:"calendar_feed_created_at"`
+	// DeleteAt is when the user is deleted, nil unless DeleteUser
+	// scheduled it.
+	DeleteAt *time.Time `json:"delete_at"`
+}
+
+type Webhook struct {
+	ID             int64     `json:"id"`
+	URL            string    `json:"url"`
+	Events         []string  `json:"events"`
+	Enabled        bool      `json:"enabled"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// APIKey issued to the user, without the key.
+type APIKey struct {
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// ProfileOf returns the profile of user.
+func ProfileOf(user *pb.User) Profile {
+	profile := Profile{ID: user.GetId(), NotificationChannels: []Channel{}}
+	if user.GetLanguageCode() != nil {
+		language := user.GetLanguageCode().GetValue()
+		profile.LanguageCode = &language
+	}
+	if user.GetUtcOffset() != nil {
+		offset := user.GetUtcOffset().GetValue()
+		profile.UTCOffset = &offset
+	}
+	for _, channel := range user.GetNotificationChannels().GetChannels() {
+		profile.NotificationChannels = append(profile.NotificationChannels, Channel{
+			Kind:    channel.GetKind().String(),
+			Address: channel.GetAddress(),
+		})
+	}
+
+	return profile
+}
+
+// manifest describes an export.
+type manifest struct {
+	Version    int       `json:"version"`
+	UserID     int64     `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Files of a ZIP archive besides manifest.json.
+	Files []string `json:"files,omitempty"`
+}
+
+// WriteJSON writes data exported at exportedAt as a single JSON document,
+// the manifest fields followed by those of data.
+func WriteJSON(w io.Writer, data *Data, exportedAt time.Time) error {
+	document := struct {
+		manifest
+		*Data
+	}{
+		manifest: manifest{Version: Version, UserID: data.Profile.ID, ExportedAt: exportedAt.UTC()},
+		Data:     data,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(document)
+}
+
+// WriteZIP writes data exported at exportedAt as a ZIP archive of
+// manifest.json and a JSON file for each part of data.
+func WriteZIP(w io.Writer, data *Data, exportedAt time.Time) error {
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", data.Profile},
+		{"reminders.json", data.Reminders},
+		{"deliveries.json", data.Deliveries},
+		{"shares.json", data.Shares},
+		{"settings.json", data.Settings},
+	}
+
+	m := manifest{Version: Version, UserID: data.Profile.ID, ExportedAt: exportedAt.UTC()}
+	for _, file := range files {
+		m.Files = append(m.Files, file.name)
+	}
+
+	archive := zip.NewWriter(w)
+	if err := writeFile(archive, "manifest.json", exportedAt, m); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := writeFile(archive, file.name, exportedAt, file.v); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeFile(archive *zip.Writer, name string, modified time.Time, v any) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
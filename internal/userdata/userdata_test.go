@@ -0,0 +1,190 @@
+package userdata
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+func testData() *Data {
+	remindAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	return &Data{
+		Profile:    ProfileOf(&pb.User{Id: 42, UtcOffset: wrapperspb.Int32(2)}),
+		Reminders:  []Reminder{{ID: 1, Text: "water the plants", RemindAt: remindAt, CreatedAt: remindAt.Add(-time.Hour)}},
+		Deliveries: []Delivery{},
+		Settings:   Settings{Webhooks: []Webhook{}, APIKeys: []APIKey{}},
+	}
+}
+
+func TestProfileOf(t *testing.T) {
+	profile := ProfileOf(&pb.User{Id: 42, LanguageCode: wrapperspb.String("en"), NotificationChannels: &pb.NotificationChannels{
+		Channels: []*pb.NotificationChannel{{Kind: pb.NotificationChannel_TELEGRAM, Address: "42"}},
+	}})
+
+	if profile.ID != 42 || profile.LanguageCode == nil || *profile.LanguageCode != "en" || profile.UTCOffset != nil {
+		t.Errorf("expected user 42 speaking en without offset got %+v", profile)
+	}
+	if expected := []Channel{{Kind: "TELEGRAM", Address: "42"}}; !slices.Equal(profile.NotificationChannels, expected) {
+		t.Errorf("expected channels %v got %v", expected, profile.NotificationChannels)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	exportedAt := time.Date(2026, 5, 2, 10, 0, 0, 0, time.FixedZone("", 2*60*60))
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testData(), exportedAt); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("cannot decode %s: %v", buf.Bytes(), err)
+	}
+
+	for key, expected := range map[string]string{
+		"version":     "1",
+		"user_id":     "42",
+		"exported_at": `"2026-05-02T08:00:00Z"`,
+		"deliveries":  "[]",
+	} {
+		if got := string(document[key]); got != expected {
+			t.Errorf("expected %s to be %s got %s", key, expected, got)
+		}
+	}
+
+	var reminders []Reminder
+	if err := json.Unmarshal(document["reminders"], &reminders); err != nil || len(reminders) != 1 || reminders[0].Text != "water the plants" {
+		t.Errorf("expected the reminder got %s, %v", document["reminders"], err)
+	}
+}
+
+func TestWriteZIP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteZIP(&buf, testData(), time.Now()); err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("cannot open archive: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("cannot open %s: %v", f.Name, err)
+		}
+		files[f.Name], err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("cannot read %s: %v", f.Name, err)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(files["manifest.json"], &m); err != nil {
+		t.Fatalf("cannot decode manifest: %v", err)
+	}
+	if m.Version != Version || m.UserID != 42 {
+		t.Errorf("expected version %d of user 42 got %+v", Version, m)
+	}
+
+	for _, name := range m.Files {
+		if !json.Valid(files[name]) {
+			t.Errorf("expected JSON in %s got %q", name, files[name])
+		}
+	}
+	if len(files) != len(m.Files)+1 {
+		t.Errorf("expected the files of the manifest %v got %d", m.Files, len(files))
+	}
+}
+
+// StubStore has the deletion of the users in deleteAt scheduled.
+type StubStore struct {
+	mu       sync.Mutex
+	deleteAt map[int64]time.Time
+	deleted  []int64
+	err      error
+}
+
+func (s *StubStore) DueUserDeletions(_ context.Context, now time.Time, limit int) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []int64
+	for userId, deleteAt := range s.deleteAt {
+		if !deleteAt.After(now) {
+			due = append(due, userId)
+		}
+	}
+	slices.SortFunc(due, func(a, b int64) int {
+		return s.deleteAt[a].Compare(s.deleteAt[b])
+	})
+
+	return due[:min(len(due), limit)], nil
+}
+
+func (s *StubStore) DeleteUser(_ context.Context, userId int64, now time.Time) (Deletion, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return Deletion{}, false, s.err
+	}
+
+	deleteAt, ok := s.deleteAt[userId]
+	if !ok || deleteAt.After(now) {
+		return Deletion{}, false, nil
+	}
+	delete(s.deleteAt, userId)
+	s.deleted = append(s.deleted, userId)
+
+	return Deletion{RequestedAt: deleteAt.Add(-time.Hour), Reminders: 2}, true, nil
+}
+
+func TestDeleter(t *testing.T) {
+	now := time.Now()
+	store := &StubStore{deleteAt: map[int64]time.Time{
+		1: now.Add(-time.Minute),
+		2: now.Add(-time.Hour),
+		3: now.Add(time.Hour),
+	}}
+
+	var purged []int64
+	deleter := NewDeleter(store, WithClock(func() time.Time { return now }), WithPurge(func(userId int64) {
+		purged = append(purged, userId)
+	}))
+	deleter.batchSize = 1
+
+	deleted, err := deleter.DeleteDue(context.Background())
+	if err != nil {
+		t.Fatalf("did not expect error got %v", err)
+	}
+
+	if expected := []int64{2, 1}; deleted != 2 || !slices.Equal(store.deleted, expected) || !slices.Equal(purged, expected) {
+		t.Errorf("expected users %v deleted and purged got %d, %v, %v", expected, deleted, store.deleted, purged)
+	}
+	if _, ok := store.deleteAt[3]; !ok {
+		t.Errorf("expected the deletion of user 3 to wait")
+	}
+
+	store.deleteAt[4] = now
+	store.err = errors.New("database is down")
+	if _, err := deleter.DeleteDue(context.Background()); !errors.Is(err, store.err) {
+		t.Errorf("expected %v got %v", store.err, err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package web serves the TodoService to browsers, which cannot speak gRPC,
+// over the Connect protocol and gRPC-Web, besides gRPC itself. Calls are
+// translated with vanguard and handed to the gRPC server, so they go through
+// its interceptors like any other.
+//
+// Both HTTP/1.1 and HTTP/2, with TLS or cleartext (h2c), are accepted.
+// Browsers on other origins may call the service when their origin is
+// allowed, see WithAllowedOrigins.
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	connectcors "connectrpc.com/cors"
+	"connectrpc.com/vanguard/vanguardgrpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+)
+
+var (
+	allowedMethods = strings.Join(connectcors.AllowedMethods(), ", ")
+	// allowedHeaders adds the metadata read by the server to the headers
+	// of the protocols.
+	allowedHeaders = strings.Join(append(connectcors.AllowedHeaders(), "Authorization", "Idempotency-Key"), ", ")
+	exposedHeaders = strings.Join(connectcors.ExposedHeaders(), ", ")
+)
+
+type options struct {
+	allowedOrigins []string
+	maxAge         time.Duration
+}
+
+type Option func(*options)
+
+// WithAllowedOrigins lets browsers on origins, such as
+// "https://todo.example.com", call the service. "*" allows any origin.
+// Browsers may cache the answers to their preflight requests for maxAge.
+func WithAllowedOrigins(origins []string, maxAge time.Duration) Option {
+	return func(o *options) {
+		o.allowedOrigins = origins
+		o.maxAge = maxAge
+	}
+}
+
+// New returns a handler serving the TodoService registered on server.
+func New(server *grpc.Server, opts ...Option) (http.Handler, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	transcoder, err := vanguardgrpc.NewTranscoder(server)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transcoder also understands the REST mapping, which the gateway
+	// serves instead.
+	mux := http.NewServeMux()
+	mux.Handle("/"+pb.TodoService_ServiceDesc.ServiceName+"/", transcoder)
+
+	return h2c.NewHandler(withCORS(o, mux), &http2.Server{}), nil
+}
+
+func withCORS(o options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" || !slices.Contains(o.allowedOrigins, origin) && !slices.Contains(o.allowedOrigins, "*") {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", allowedMethods)
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(o.maxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		header.Set("Access-Control-Expose-Headers", exposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,196 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/TodoServiceServer"
+	"github.com/awakair/awakair_todo_bot/internal/auth"
+	"github.com/awakair/awakair_todo_bot/internal/memrepo"
+)
+
+const (
+	key     = auth.APIKeyPrefix + "service"
+	baseURL = "http://todo.test/todoservice.TodoService/"
+	origin  = "https://todo.example.com"
+)
+
+// StubKeyStore only knows key, a service key.
+type StubKeyStore struct{}
+
+func (StubKeyStore) FindAPIKey(_ context.Context, hash []byte) (auth.Caller, error) {
+	if string(hash) != string(auth.HashAPIKey(key)) {
+		return auth.Caller{}, auth.ErrKeyNotFound
+	}
+
+	return auth.Caller{Name: "test"}, nil
+}
+
+// server serves the handler on an in-process listener and returns a client
+// speaking HTTP/1.1 to it and one speaking HTTP/2 without TLS.
+func server(t *testing.T) (http1, h2c *http.Client) {
+	t.Helper()
+
+	authenticator := auth.NewAuthenticator(StubKeyStore{}, nil)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	pb.RegisterTodoServiceServer(s, todoserviceserver.New(memrepo.New()))
+
+	handler, err := New(s, WithAllowedOrigins([]string{origin}, time.Hour))
+	if err != nil {
+		t.Fatalf("cannot create handler: %v", err)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+
+	http1 = &http.Client{Transport: &http.Transport{DialContext: dial}}
+	h2c = &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+	}}
+
+	return http1, h2c
+}
+
+func request[T any](msg *T) *connect.Request[T] {
+	req := connect.NewRequest(msg)
+	req.Header().Set("Authorization", "Bearer "+key)
+
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	http1, h2c := server(t)
+
+	for _, tc := range []struct {
+		name   string
+		client *http.Client
+		opts   []connect.ClientOption
+	}{
+		{"connect", http1, nil},
+		{"connect json", http1, []connect.ClientOption{connect.WithProtoJSON()}},
+		{"grpc-web", http1, []connect.ClientOption{connect.WithGRPCWeb()}},
+		{"grpc-web over h2c", h2c, []connect.ClientOption{connect.WithGRPCWeb()}},
+		{"grpc over h2c", h2c, []connect.ClientOption{connect.WithGRPC()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setUser := connect.NewClient[pb.User, emptypb.Empty](tc.client, baseURL+"SetUser", tc.opts...)
+			getUser := connect.NewClient[pb.UserId, pb.User](tc.client, baseURL+"GetUser", tc.opts...)
+			createReminder := connect.NewClient[pb.Reminder, pb.ReminderId](tc.client, baseURL+"CreateReminder", tc.opts...)
+			getReminders := connect.NewClient[pb.UserId, pb.Reminder](tc.client, baseURL+"GetRemindersByUserId", tc.opts...)
+
+			userId := time.Now().UnixNano()
+			if _, err := setUser.CallUnary(ctx, request(&pb.User{Id: userId, LanguageCode: wrapperspb.String("en")})); err != nil {
+				t.Fatalf("cannot set user: %v", err)
+			}
+
+			user, err := getUser.CallUnary(ctx, request(&pb.UserId{Id: userId}))
+			if err != nil || user.Msg.GetLanguageCode().GetValue() != "en" {
+				t.Fatalf("expected user %d got %+v, %v", userId, user, err)
+			}
+
+			for _, text := range []string{"first", "second"} {
+				reminder := &pb.Reminder{UserId: userId, ReminderText: text, RemindTimestamp: timestamppb.New(time.Now().Add(time.Hour))}
+				if _, err := createReminder.CallUnary(ctx, request(reminder)); err != nil {
+					t.Fatalf("cannot create reminder: %v", err)
+				}
+			}
+
+			stream, err := getReminders.CallServerStream(ctx, request(&pb.UserId{Id: userId}))
+			if err != nil {
+				t.Fatalf("cannot get reminders: %v", err)
+			}
+			defer stream.Close()
+
+			var texts []string
+			for stream.Receive() {
+				texts = append(texts, stream.Msg().GetReminderText())
+			}
+			if err := stream.Err(); err != nil || len(texts) != 2 || texts[0] != "first" {
+				t.Errorf("expected reminders first and second got %v, %v", texts, err)
+			}
+
+			_, err = getUser.CallUnary(ctx, connect.NewRequest(&pb.UserId{Id: userId}))
+			if connect.CodeOf(err) != connect.CodeUnauthenticated {
+				t.Errorf("expected Unauthenticated without a key got %v", err)
+			}
+
+			_, err = createReminder.CallUnary(ctx, request(&pb.Reminder{UserId: userId}))
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) || connectErr.Code() != connect.CodeInvalidArgument {
+				t.Errorf("expected InvalidArgument got %v", err)
+			}
+		})
+	}
+}
+
+func TestHandler_cors(t *testing.T) {
+	http1, _ := server(t)
+
+	preflight := func(origin string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodOptions, baseURL+"GetUser", nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %v", err)
+		}
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "authorization,content-type,connect-protocol-version")
+
+		resp, err := http1.Do(req)
+		if err != nil {
+			t.Fatalf("cannot send preflight request: %v", err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	resp := preflight(origin)
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != origin {
+		t.Errorf("expected %s to be allowed got %d, %v", origin, resp.StatusCode, resp.Header)
+	}
+	if resp.Header.Get("Access-Control-Allow-Headers") == "" || resp.Header.Get("Access-Control-Max-Age") != "3600" {
+		t.Errorf("expected allowed headers and max age got %v", resp.Header)
+	}
+
+	if resp := preflight("https://evil.example.com"); resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected other origins not to be allowed got %v", resp.Header)
+	}
+
+	getUser := connect.NewClient[pb.UserId, pb.User](http1, baseURL+"GetUser")
+	req := request(&pb.UserId{Id: 1})
+	req.Header().Set("Origin", origin)
+	_, err := getUser.CallUnary(context.Background(), req)
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Meta().Get("Access-Control-Expose-Headers") == "" {
+		t.Errorf("expected exposed headers with the answer got %v", err)
+	}
+}
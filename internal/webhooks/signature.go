@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of every request sent to a webhook.
+const (
+	HeaderWebhookID = "X-Todo-Webhook-Id"
+	HeaderEventSeq  = "X-Todo-Event-Seq"
+	HeaderTimestamp = "X-Todo-Timestamp"
+	// HeaderSignature holds "sha256=" and the hex encoded HMAC-SHA256 of the
+	// timestamp header, a dot and the body, keyed with the secret of the
+	// webhook.
+	HeaderSignature = "X-Todo-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// NewSecret returns a random secret for a new webhook.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign returns the value of HeaderSignature for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+var (
+	ErrBadSignature = errors.New("webhook signature does not match")
+	ErrStale        = errors.New("webhook timestamp is too old")
+)
+
+// Verify checks the values of HeaderTimestamp and HeaderSignature received
+// with body, rejecting requests signed more than tolerance before now so
+// that captured requests cannot be replayed later. For receivers written
+// in Go.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrBadSignature
+	}
+
+	if now.Sub(time.Unix(unix, 0)) > tolerance {
+		return ErrStale
+	}
+
+	return nil
+}
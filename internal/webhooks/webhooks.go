@@ -0,0 +1,152 @@
+// Package webhooks sends the events of users to the webhooks they
+// registered with CreateWebhook.
+//
+// Events are queued for the webhooks of their user as they are recorded,
+// see migrations/0009_webhooks.sql of postgresrepo. The Sender posts each
+// one as a JSON encoded todoservice.UserEvent with proto field names,
+// signed as described by HeaderSignature, and retries failed attempts with
+// exponential backoff. Deliveries failing too many times become dead
+// letters, webhooks failing too many times in a row are disabled.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/egress"
+	"github.com/awakair/awakair_todo_bot/internal/retry"
+)
+
+// Delivery of an event to a webhook.
+type Delivery struct {
+	ID        int64
+	WebhookID int64
+	URL       string
+	Secret    string
+	Event     *pb.UserEvent
+	// Attempts made so far, the one the delivery was claimed for included.
+	Attempts int
+}
+
+type Store interface {
+	// ClaimWebhookDeliveries returns up to limit deliveries to enabled
+	// webhooks due at now, counting an attempt for each and hiding them
+	// from other claims until leaseUntil.
+	ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error)
+	// WebhookDelivered forgets the delivery and the failures of its webhook.
+	WebhookDelivered(ctx context.Context, id int64) error
+	// WebhookDeliveryFailed records the error of the last attempt. The
+	// delivery is attempted again at retryAt or, when retryAt is zero,
+	// becomes a dead letter. The webhook is disabled by its disableAfter-th
+	// failure in a row.
+	WebhookDeliveryFailed(ctx context.Context, id int64, lastError string, retryAt time.Time, disableAfter int) error
+}
+
+type Sender struct {
+	store  Store
+	client *http.Client
+	policy retry.Policy
+
+	disableAfter int
+}
+
+// NewSender attempts deliveries up to 8 times, 30s after the first failure
+// and at most 6h apart, for 10s each, 100 at once. disableAfter failed
+// attempts in a row, over all the deliveries of a webhook, disable it.
+func NewSender(store Store, client *http.Client, disableAfter int, opts ...retry.Option) *Sender {
+	policy := retry.Policy{
+		BatchSize:   100,
+		MaxAttempts: 8,
+		Backoff:     30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		Timeout:     10 * time.Second,
+		Now:         time.Now,
+	}
+
+	return &Sender{store: store, client: client, policy: policy.With(opts...), disableAfter: disableAfter}
+}
+
+// Run calls Send every interval until ctx is done.
+func (s *Sender) Run(ctx context.Context, interval time.Duration) {
+	retry.Run(ctx, interval, "sending webhooks", s.Send)
+}
+
+// Send attempts the due deliveries, in batches until none are left.
+func (s *Sender) Send(ctx context.Context) error {
+	return retry.Process(ctx, s.policy, s.store.ClaimWebhookDeliveries, s.deliver)
+}
+
+func (s *Sender) deliver(ctx context.Context, delivery Delivery) {
+	err := s.policy.Attempt(ctx, func(ctx context.Context) error {
+		return s.post(ctx, delivery)
+	})
+
+	if err == nil {
+		if err := s.store.WebhookDelivered(ctx, delivery.ID); err != nil {
+			log.Printf("Error recording webhook delivery %d as sent: %v", delivery.ID, err)
+		}
+
+		return
+	}
+
+	// Webhooks in the network of the server are not retried.
+	var retryAt time.Time
+	if !errors.Is(err, egress.ErrForbiddenAddress) {
+		retryAt = s.policy.RetryAt(delivery.Attempts)
+	}
+
+	log.Printf("Error in delivery %d to webhook %d, attempt %d: %v", delivery.ID, delivery.WebhookID, delivery.Attempts, err)
+
+	if err := s.store.WebhookDeliveryFailed(ctx, delivery.ID, err.Error(), retryAt, s.disableAfter); err != nil {
+		log.Printf("Error recording failure of webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (s *Sender) post(ctx context.Context, delivery Delivery) error {
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := s.policy.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-service")
+	req.Header.Set(HeaderWebhookID, strconv.FormatInt(delivery.WebhookID, 10))
+	req.Header.Set(HeaderEventSeq, strconv.FormatInt(delivery.Event.GetSeq(), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return fmt.Errorf("cannot reach webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package webhooks_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/awakair/awakair_todo_bot/api/todo-service"
+	"github.com/awakair/awakair_todo_bot/internal/egress"
+	"github.com/awakair/awakair_todo_bot/internal/notify/notifytest"
+	"github.com/awakair/awakair_todo_bot/internal/retry"
+	"github.com/awakair/awakair_todo_bot/internal/webhooks"
+)
+
+func TestSign(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"seq":"1"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := webhooks.Sign("secret", now, body)
+
+	if err := webhooks.Verify("secret", timestamp, signature, body, time.Minute, now); err != nil {
+		t.Errorf("did not expect error got %v", err)
+	}
+
+	for name, err := range map[string]error{
+		"other secret":    webhooks.Verify("other", timestamp, signature, body, time.Minute, now),
+		"other body":      webhooks.Verify("secret", timestamp, signature, []byte(`{"seq":"2"}`), time.Minute, now),
+		"other timestamp": webhooks.Verify("secret", strconv.FormatInt(now.Unix()+1, 10), signature, body, time.Minute, now),
+		"no prefix":       webhooks.Verify("secret", timestamp, signature[len("sha256="):], body, time.Minute, now),
+	} {
+		if err != webhooks.ErrBadSignature {
+			t.Errorf("%s: expected webhooks.ErrBadSignature got %v", name, err)
+		}
+	}
+
+	if err := webhooks.Verify("secret", timestamp, signature, body, time.Minute, now.Add(2*time.Minute)); err != webhooks.ErrStale {
+		t.Errorf("expected webhooks.ErrStale got %v", err)
+	}
+}
+
+// stubDelivery is a delivery kept by StubStore.
+type stubDelivery struct {
+	webhooks.Delivery
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+// StubStore keeps deliveries of webhooks in memory.
+type StubStore struct {
+	mu          sync.Mutex
+	deliveries  map[int64]*stubDelivery
+	deadLetters []stubDelivery
+	failures    map[int64]int
+	disabled    map[int64]bool
+}
+
+func newStubStore(deliveries ...webhooks.Delivery) *StubStore {
+	s := &StubStore{
+		deliveries: make(map[int64]*stubDelivery),
+		failures:   make(map[int64]int),
+		disabled:   make(map[int64]bool),
+	}
+	for _, delivery := range deliveries {
+		s.deliveries[delivery.ID] = &stubDelivery{Delivery: delivery}
+	}
+
+	return s
+}
+
+func (s *StubStore) ClaimWebhookDeliveries(_ context.Context, now, leaseUntil time.Time, limit int) ([]webhooks.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []webhooks.Delivery
+	for _, delivery := range s.deliveries {
+		if len(claimed) == limit || s.disabled[delivery.WebhookID] || delivery.nextAttemptAt.After(now) {
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.nextAttemptAt = leaseUntil
+		claimed = append(claimed, delivery.Delivery)
+	}
+
+	return claimed, nil
+}
+
+func (s *StubStore) WebhookDelivered(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[s.deliveries[id].WebhookID] = 0
+	delete(s.deliveries, id)
+
+	return nil
+}
+
+func (s *StubStore) WebhookDeliveryFailed(_ context.Context, id int64, lastError string, retryAt time.Time, disableAfter int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery := s.deliveries[id]
+	delivery.lastError = lastError
+	delivery.nextAttemptAt = retryAt
+	if retryAt.IsZero() {
+		s.deadLetters = append(s.deadLetters, *delivery)
+		delete(s.deliveries, id)
+	}
+
+	s.failures[delivery.WebhookID]++
+	if s.failures[delivery.WebhookID] >= disableAfter {
+		s.disabled[delivery.WebhookID] = true
+	}
+
+	return nil
+}
+
+func TestSender(t *testing.T) {
+	ctx := context.Background()
+	hook := notifytest.NewHTTPReceiver(t)
+
+	event := &pb.UserEvent{
+		Seq:    7,
+		UserId: 42,
+		Time:   timestamppb.New(time.Unix(1700000000, 0)),
+		Event:  &pb.UserEvent_ReminderFired{ReminderFired: &pb.Reminder{Id: 3, UserId: 42, ReminderText: "stand up"}},
+	}
+	store := newStubStore(webhooks.Delivery{ID: 1, WebhookID: 5, URL: hook.URL + "/events", Secret: "secret", Event: event})
+
+	now := time.Now()
+	sender := webhooks.NewSender(store, http.DefaultClient, 50,
+		retry.WithMaxAttempts(3),
+		retry.WithBackoff(time.Minute, time.Hour),
+		retry.WithClock(func() time.Time { return now }),
+	)
+
+	send := func() {
+		t.Helper()
+
+		if err := sender.Send(ctx); err != nil {
+			t.Fatalf("cannot send: %v", err)
+		}
+	}
+
+	hook.RespondWith(http.StatusInternalServerError)
+	send()
+	now = now.Add(30 * time.Second)
+	send()
+	if requests := hook.Requests(); len(requests) != 1 || store.deliveries[1].lastError == "" {
+		t.Fatalf("expected a failed attempt, not retried before the backoff, got %d attempts and %+v", len(requests), store.deliveries[1])
+	}
+
+	now = now.Add(30 * time.Second)
+	send()
+
+	requests := hook.Requests()
+	if len(requests) != 2 || len(store.deliveries) != 0 || store.failures[5] != 0 {
+		t.Fatalf("expected a successful retry resetting failures got %d attempts, %+v", len(requests), store)
+	}
+
+	request := requests[1]
+	if request.Path != "/events" || request.Header.Get(webhooks.HeaderWebhookID) != "5" || request.Header.Get(webhooks.HeaderEventSeq) != "7" {
+		t.Errorf("expected event 7 posted to webhook 5 got %+v", request)
+	}
+	err := webhooks.Verify("secret", request.Header.Get(webhooks.HeaderTimestamp), request.Header.Get(webhooks.HeaderSignature), request.Body, time.Minute, now)
+	if err != nil {
+		t.Errorf("expected a valid signature got %v", err)
+	}
+
+	got := &pb.UserEvent{}
+	if err := protojson.Unmarshal(request.Body, got); err != nil || !proto.Equal(got, event) {
+		t.Errorf("expected event %v got %s, %v", event, request.Body, err)
+	}
+}
+
+func TestSender_failures(t *testing.T) {
+	ctx := context.Background()
+	hook := notifytest.NewHTTPReceiver(t)
+	for i := 0; i < 10; i++ {
+		hook.RespondWith(http.StatusServiceUnavailable)
+	}
+
+	event := &pb.UserEvent{Seq: 1, UserId: 42}
+	store := newStubStore(
+		webhooks.Delivery{ID: 1, WebhookID: 5, URL: hook.URL, Secret: "secret", Event: event},
+		webhooks.Delivery{ID: 2, WebhookID: 6, URL: hook.URL, Secret: "secret", Event: event},
+		webhooks.Delivery{ID: 3, WebhookID: 6, URL: hook.URL, Secret: "secret", Event: event},
+	)
+
+	now := time.Now()
+	sender := webhooks.NewSender(store, http.DefaultClient, 3,
+		retry.WithMaxAttempts(2),
+		retry.WithBackoff(time.Minute, time.Minute),
+		retry.WithClock(func() time.Time { return now }),
+	)
+
+	for i := 0; i < 3; i++ {
+		if err := sender.Send(ctx); err != nil {
+			t.Fatalf("cannot send: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	// Every delivery fails twice and becomes a dead letter. Webhook 6 fails
+	// 4 times in a row and is disabled, webhook 5 only twice.
+	if len(store.deadLetters) != 3 || len(store.deliveries) != 0 || !store.disabled[6] || store.disabled[5] {
+		t.Errorf("expected 3 dead letters and webhook 6 disabled got %+v", store)
+	}
+	if requests := hook.Requests(); len(requests) != 6 {
+		t.Errorf("expected 6 attempts got %d", len(requests))
+	}
+}
+
+func TestSender_forbiddenAddress(t *testing.T) {
+	hook := notifytest.NewHTTPReceiver(t)
+	store := newStubStore(webhooks.Delivery{ID: 1, WebhookID: 5, URL: hook.URL, Secret: "secret", Event: &pb.UserEvent{Seq: 1, UserId: 42}})
+	sender := webhooks.NewSender(store, egress.NewClient(time.Second), 50)
+
+	if err := sender.Send(context.Background()); err != nil {
+		t.Fatalf("cannot send: %v", err)
+	}
+
+	// A loopback webhook becomes a dead letter without being retried.
+	if len(store.deadLetters) != 1 || len(hook.Requests()) != 0 {
+		t.Errorf("expected a dead letter without requests got %+v", store)
+	}
+}